@@ -1,28 +1,41 @@
 package main
 
 import (
+	"cms_sidecar_backend/internal/audit"
+	"cms_sidecar_backend/internal/db"
+	"cms_sidecar_backend/internal/handlers"
+	"cms_sidecar_backend/internal/storage"
+	"cms_sidecar_backend/internal/tenant"
 	"fmt"
 	"os"
 	"path/filepath"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-
-	"cms_sidecar_backend/internal/db"
-	"cms_sidecar_backend/internal/handlers"
 )
 
 func main() {
 	// 1. Initialize Router
 	router := gin.New()
+
+	// Forwarded client addresses are believed only from CMS_TRUSTED_PROXIES
+	if err := handlers.TrustProxies(router); err != nil {
+		fmt.Fprintf(os.Stderr, "FATAL: Could not configure trusted proxies: %v\n", err)
+		os.Exit(1)
+	}
 	router.Use(gin.Recovery())
+	router.Use(handlers.RequestID())
 
-	// CORS: Allow All for Sidecar (localhost)
-	config := cors.DefaultConfig()
-	config.AllowAllOrigins = true
-	config.AllowCredentials = true
-	config.AddAllowHeaders("Authorization", "Content-Type")
-	router.Use(cors.New(config))
+	// CORS: localhost origins unless CMS_CORS_ORIGINS lists others
+	corsMiddleware, err := handlers.CORS()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FATAL: Could not configure CORS: %v\n", err)
+		os.Exit(1)
+	}
+	router.Use(corsMiddleware)
+	router.Use(handlers.SecurityHeaders())
+
+	// Request bodies are capped in size and JSON nesting depth
+	router.Use(handlers.LimitBodies())
 
 	// 2. Determine Database Path
 	// In production sidecar: standard location or local directory
@@ -55,9 +68,56 @@ func main() {
 		}
 	}
 
+	if err := db.EnsureSchema(database); err != nil {
+		fmt.Fprintf(os.Stderr, "FATAL: Could not prepare database schema: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Every /api request is scoped to the caller's organization
+	if err := tenant.Enforce(database); err != nil {
+		fmt.Fprintf(os.Stderr, "FATAL: Could not enable organization scoping: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Every write made through the API lands in the audit log
+	if err := audit.Record(database); err != nil {
+		fmt.Fprintf(os.Stderr, "FATAL: Could not enable the audit log: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Dev only: per-request X-Query-Count header
+	if os.Getenv("CMS_DEBUG_QUERIES") == "1" {
+		if err := db.CountQueries(database); err != nil {
+			fmt.Fprintf(os.Stderr, "FATAL: Could not enable query counting: %v\n", err)
+			os.Exit(1)
+		}
+		router.Use(handlers.QueryCountHeader())
+	}
+
+	// One writer at a time; contention is reported on /metrics (CMS_METRICS_ADDR)
+	if err := db.TrackBusy(database); err != nil {
+		fmt.Fprintf(os.Stderr, "FATAL: Could not enable lock metrics: %v\n", err)
+		os.Exit(1)
+	}
+	if err := db.GateWrites(database); err != nil {
+		fmt.Fprintf(os.Stderr, "FATAL: Could not serialize writes: %v\n", err)
+		os.Exit(1)
+	}
+	router.Use(handlers.BusyWrites())
+
+	// Uploads go to media/ next to the database unless CMS_STORAGE says otherwise
+	store, err := storage.FromEnv(filepath.Join(filepath.Dir(dbPath), "media"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FATAL: Could not configure file storage: %v\n", err)
+		os.Exit(1)
+	}
+
 	// 4. Initialize Handlers & Routes
-	h := handlers.New(database)
-	h.Register(router)
+	h := handlers.New(database, store)
+	if err := h.Register(router); err != nil {
+		fmt.Fprintf(os.Stderr, "FATAL: Could not register routes: %v\n", err)
+		os.Exit(1)
+	}
 
 	// 5. Health Check
 	router.GET("/health", func(c *gin.Context) {
@@ -69,6 +129,19 @@ func main() {
 		})
 	})
 
+	// Metrics get their own listener (CMS_METRICS_ADDR), off the public port
+	serveMetrics, err := handlers.ListenMetrics()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FATAL: Could not listen for metrics: %v\n", err)
+		os.Exit(1)
+	}
+	if serveMetrics != nil {
+		go func() {
+			fmt.Fprintf(os.Stderr, "FATAL: Metrics server exited: %v\n", serveMetrics())
+			os.Exit(1)
+		}()
+	}
+
 	// 6. Start Server
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -76,7 +149,7 @@ func main() {
 	}
 
 	fmt.Printf("CMS Sidecar Backend listening on port %s\n", port)
-	if err := router.Run(":" + port); err != nil {
+	if err := handlers.Server(":"+port, router).ListenAndServe(); err != nil {
 		fmt.Fprintf(os.Stderr, "Server exited: %v\n", err)
 		os.Exit(1)
 	}
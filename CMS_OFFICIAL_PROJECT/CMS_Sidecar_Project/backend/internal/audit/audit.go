@@ -0,0 +1,427 @@
+// Package audit keeps the append-only trail of every row the API creates,
+// changes or deletes. A request context carries the Actor; GORM callbacks
+// installed by Record snapshot the rows a create, update or delete touches
+// and append one entry per changed row, chained by hash, in the statement's
+// own transaction. Statements run without an Actor (cmsctl, startup) and
+// raw SQL are not recorded.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"cms_sidecar_backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Actions recorded in AuditEntry.Action.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Actor is who a request acts as and where it came from.
+type Actor struct {
+	OrganizationID uint
+	UserID         uint
+	APIKeyID       uint
+	SessionID      uint
+	IP             string
+	RequestID      string
+	// Route is the method and route pattern, e.g. "PATCH /api/v2/vendors/:id".
+	Route string
+}
+
+type contextKey struct{}
+
+// WithActor records the writes run with the returned context as actor's.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, contextKey{}, actor)
+}
+
+// ActorFrom returns the actor ctx carries; ok is false outside requests.
+func ActorFrom(ctx context.Context) (actor Actor, ok bool) {
+	if ctx == nil {
+		return Actor{}, false
+	}
+	actor, ok = ctx.Value(contextKey{}).(Actor)
+	return actor, ok
+}
+
+// skipped tables are bookkeeping rather than data.
+var skipped = map[string]bool{
+	"cms_audit_log":       true,
+	"cms_idempotency_key": true,
+	"cms_code_counter":    true,
+	"cms_refdata_version": true,
+	"cms_project_summary": true,
+	"cms_search_index":    true,
+}
+
+// ignored columns move on their own while a client is signed in; they are
+// left out of updates, and an update that only touches them is not
+// recorded.
+var ignored = map[string]map[string]bool{
+	"cms_session": {"last_seen_at": true, "ip": true},
+	"cms_api_key": {"last_used_at": true, "last_used_ip": true},
+}
+
+// redactedValue replaces passwords, PINs and token hashes: the entry shows
+// that they changed, not what to.
+const redactedValue = "[redacted]"
+
+func redacted(column string) bool {
+	return column == "password" || strings.HasSuffix(column, "_hash")
+}
+
+// beforeKey holds the rows an update or delete is about to touch.
+const beforeKey = "cms:audit:before"
+
+// Ensure makes cms_audit_log append-only. The table itself is migrated with
+// the other service tables.
+func Ensure(db *gorm.DB) error {
+	for _, stmt := range []string{
+		"CREATE TRIGGER IF NOT EXISTS cms_audit_log_no_update BEFORE UPDATE ON cms_audit_log BEGIN SELECT RAISE(ABORT, 'cms_audit_log is append-only'); END",
+		"CREATE TRIGGER IF NOT EXISTS cms_audit_log_no_delete BEFORE DELETE ON cms_audit_log BEGIN SELECT RAISE(ABORT, 'cms_audit_log is append-only'); END",
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to protect the audit log: %w", err)
+		}
+	}
+	return nil
+}
+
+// Record installs the audit callbacks on db. They run inside the statement's
+// transaction, so a write and its entries are committed or rolled back
+// together.
+func Record(db *gorm.DB) error {
+	callbacks := db.Callback()
+	const commit = "gorm:commit_or_rollback_transaction"
+	registrations := []error{
+		callbacks.Create().After("gorm:create").Before(commit).Register("cms:audit", afterCreate),
+		callbacks.Update().Before("gorm:update").After("cms:tenant").Register("cms:audit_before", snapshotBefore),
+		callbacks.Update().After("gorm:update").Before(commit).Register("cms:audit", afterUpdate),
+		callbacks.Delete().Before("gorm:delete").After("cms:tenant").Register("cms:audit_before", snapshotBefore),
+		callbacks.Delete().After("gorm:delete").Before(commit).Register("cms:audit", afterDelete),
+	}
+	for _, err := range registrations {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tracked returns the actor of a statement worth recording.
+func tracked(db *gorm.DB) (Actor, bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Table == "" || skipped[stmt.Table] {
+		return Actor{}, false
+	}
+	return ActorFrom(stmt.Context)
+}
+
+func primaryKey(stmt *gorm.Statement) string {
+	if stmt.Schema != nil && stmt.Schema.PrioritizedPrimaryField != nil {
+		return stmt.Schema.PrioritizedPrimaryField.DBName
+	}
+	return "id"
+}
+
+// modelKeys are the primary keys of the records the statement was given.
+func modelKeys(stmt *gorm.Statement) []interface{} {
+	if stmt.Schema == nil || stmt.Schema.PrioritizedPrimaryField == nil || !stmt.ReflectValue.IsValid() {
+		return nil
+	}
+	field := stmt.Schema.PrioritizedPrimaryField
+	var keys []interface{}
+	add := func(value reflect.Value) {
+		for value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return
+			}
+			value = value.Elem()
+		}
+		if value.Kind() != reflect.Struct {
+			return
+		}
+		if key, zero := field.ValueOf(stmt.Context, value); !zero {
+			keys = append(keys, key)
+		}
+	}
+	switch stmt.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < stmt.ReflectValue.Len(); i++ {
+			add(stmt.ReflectValue.Index(i))
+		}
+	default:
+		add(stmt.ReflectValue)
+	}
+	return keys
+}
+
+// rowQuery starts a query on the statement's table in its transaction.
+func rowQuery(db *gorm.DB) *gorm.DB {
+	stmt := db.Statement
+	tx := db.Session(&gorm.Session{NewDB: true})
+	if stmt.Schema != nil {
+		return tx.Model(reflect.New(stmt.Schema.ModelType).Interface())
+	}
+	return tx.Table(stmt.Table)
+}
+
+func loadRows(db *gorm.DB, keys []interface{}) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	if len(keys) == 0 {
+		return rows, nil
+	}
+	err := rowQuery(db).Where(primaryKey(db.Statement)+" IN ?", keys).Find(&rows).Error
+	return rows, err
+}
+
+// snapshotBefore loads the rows an update or delete is about to touch, with
+// the statement's own conditions.
+func snapshotBefore(db *gorm.DB) {
+	if _, ok := tracked(db); !ok {
+		return
+	}
+	stmt := db.Statement
+	query := rowQuery(db)
+	where, hasWhere := stmt.Clauses["WHERE"].Expression.(clause.Where)
+	if hasWhere {
+		query.Statement.AddClause(where)
+	}
+	keys := modelKeys(stmt)
+	if len(keys) > 0 {
+		query = query.Where(primaryKey(stmt)+" IN ?", keys)
+	} else if !hasWhere {
+		// GORM refuses updates and deletes without conditions.
+		return
+	}
+	var rows []map[string]interface{}
+	if err := query.Find(&rows).Error; err != nil {
+		db.AddError(fmt.Errorf("audit: %w", err))
+		return
+	}
+	stmt.Settings.Store(beforeKey, rows)
+}
+
+func rowsBefore(db *gorm.DB) []map[string]interface{} {
+	rows, _ := db.Statement.Settings.Load(beforeKey)
+	before, _ := rows.([]map[string]interface{})
+	return before
+}
+
+func afterCreate(db *gorm.DB) {
+	actor, ok := tracked(db)
+	if !ok || db.Statement.RowsAffected == 0 {
+		return
+	}
+	rows, err := loadRows(db, modelKeys(db.Statement))
+	if err != nil {
+		db.AddError(fmt.Errorf("audit: %w", err))
+		return
+	}
+	var changes []rowChange
+	for _, row := range rows {
+		changes = append(changes, diff(db.Statement.Table, primaryKey(db.Statement), nil, row))
+	}
+	appendEntries(db, actor, ActionCreate, changes)
+}
+
+func afterUpdate(db *gorm.DB) {
+	actor, ok := tracked(db)
+	before := rowsBefore(db)
+	if !ok || len(before) == 0 || db.Statement.RowsAffected == 0 {
+		return
+	}
+	pk := primaryKey(db.Statement)
+	keys := make([]interface{}, 0, len(before))
+	for _, row := range before {
+		keys = append(keys, row[pk])
+	}
+	rows, err := loadRows(db, keys)
+	if err != nil {
+		db.AddError(fmt.Errorf("audit: %w", err))
+		return
+	}
+	after := make(map[string]map[string]interface{}, len(rows))
+	for _, row := range rows {
+		after[fmt.Sprint(row[pk])] = row
+	}
+	var changes []rowChange
+	for _, row := range before {
+		if change := diff(db.Statement.Table, pk, row, after[fmt.Sprint(row[pk])]); len(change.columns) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	appendEntries(db, actor, ActionUpdate, changes)
+}
+
+func afterDelete(db *gorm.DB) {
+	actor, ok := tracked(db)
+	before := rowsBefore(db)
+	if !ok || len(before) == 0 || db.Statement.RowsAffected == 0 {
+		return
+	}
+	var changes []rowChange
+	for _, row := range before {
+		changes = append(changes, diff(db.Statement.Table, primaryKey(db.Statement), row, nil))
+	}
+	appendEntries(db, actor, ActionDelete, changes)
+}
+
+// rowChange is one row's changed columns.
+type rowChange struct {
+	id      string
+	columns map[string]map[string]interface{}
+}
+
+// diff compares a row before and after a write; either side is nil for
+// creates and deletes, which then list every column that has a value.
+func diff(table, pk string, before, after map[string]interface{}) rowChange {
+	change := rowChange{columns: map[string]map[string]interface{}{}}
+	for _, row := range []map[string]interface{}{after, before} {
+		if id, ok := row[pk]; ok && change.id == "" {
+			change.id = fmt.Sprint(id)
+		}
+	}
+	columns := map[string]bool{}
+	for column := range before {
+		columns[column] = true
+	}
+	for column := range after {
+		columns[column] = true
+	}
+	for column := range columns {
+		if before != nil && after != nil && ignored[table][column] {
+			continue
+		}
+		from, hadFrom := before[column]
+		to, hadTo := after[column]
+		from, to = normalize(from), normalize(to)
+		if (before != nil && after != nil && reflect.DeepEqual(from, to)) ||
+			(before == nil && to == nil) || (after == nil && from == nil) {
+			continue
+		}
+		if redacted(column) {
+			from, to = redactedValue, redactedValue
+		}
+		entry := map[string]interface{}{}
+		if hadFrom {
+			entry["from"] = from
+		}
+		if hadTo {
+			entry["to"] = to
+		}
+		change.columns[column] = entry
+	}
+	return change
+}
+
+// normalize turns driver values into ones that compare and encode stably.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	}
+	return value
+}
+
+// appendEntries writes one entry per changed row, continuing the chain from
+// the newest entry. The statement's write already holds SQLite's write lock,
+// so no other entry can slip in between.
+func appendEntries(db *gorm.DB, actor Actor, action string, changes []rowChange) {
+	if len(changes) == 0 {
+		return
+	}
+	tx := db.Session(&gorm.Session{NewDB: true})
+	var last model.AuditEntry
+	if err := tx.Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+		db.AddError(fmt.Errorf("audit: %w", err))
+		return
+	}
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	prev := last.Hash
+	entries := make([]model.AuditEntry, 0, len(changes))
+	for _, change := range changes {
+		encoded, err := json.Marshal(change.columns)
+		if err != nil {
+			db.AddError(fmt.Errorf("audit: %w", err))
+			return
+		}
+		entry := model.AuditEntry{
+			OccurredAt:     now,
+			OrganizationID: actor.OrganizationID,
+			UserID:         actor.UserID,
+			APIKeyID:       actor.APIKeyID,
+			SessionID:      actor.SessionID,
+			IP:             actor.IP,
+			RequestID:      actor.RequestID,
+			Route:          actor.Route,
+			Action:         action,
+			Entity:         db.Statement.Table,
+			EntityID:       change.id,
+			Changes:        string(encoded),
+			PrevHash:       prev,
+		}
+		entry.Hash = Hash(entry)
+		prev = entry.Hash
+		entries = append(entries, entry)
+	}
+	if err := tx.Create(&entries).Error; err != nil {
+		db.AddError(fmt.Errorf("audit: %w", err))
+	}
+}
+
+// Hash is the SHA-256 of entry's fields and PrevHash.
+func Hash(entry model.AuditEntry) string {
+	payload, _ := json.Marshal([]interface{}{
+		entry.PrevHash,
+		entry.OccurredAt.UTC().Format(time.RFC3339Nano),
+		entry.OrganizationID, entry.UserID, entry.APIKeyID, entry.SessionID,
+		entry.IP, entry.RequestID, entry.Route,
+		entry.Action, entry.Entity, entry.EntityID, entry.Changes,
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify walks the chain from the first entry and returns how many entries
+// check out. The error names the first entry whose hash or link is wrong.
+func Verify(db *gorm.DB) (int, error) {
+	checked := 0
+	prev := ""
+	var broken error
+	var batch []model.AuditEntry
+	err := db.Order("id").FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for _, entry := range batch {
+			switch {
+			case entry.PrevHash != prev:
+				broken = fmt.Errorf("entry %d does not follow the entry before it", entry.ID)
+			case Hash(entry) != entry.Hash:
+				broken = fmt.Errorf("entry %d was altered", entry.ID)
+			}
+			if broken != nil {
+				return broken
+			}
+			prev = entry.Hash
+			checked++
+		}
+		return nil
+	}).Error
+	if broken != nil {
+		return checked, broken
+	}
+	return checked, err
+}
@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cms_sidecar_backend/internal/model"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type note struct {
+	ID   uint `gorm:"primaryKey"`
+	Body string
+}
+
+func (note) TableName() string {
+	return "audit_note"
+}
+
+// chainDB returns a database whose audit log holds four chained entries:
+// note 1 created and updated, note 2 created and deleted.
+func chainDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	database, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "audit.sqlite3")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := database.AutoMigrate(&model.AuditEntry{}, &note{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Ensure(database); err != nil {
+		t.Fatal(err)
+	}
+	if err := Record(database); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithActor(context.Background(), Actor{OrganizationID: 1, UserID: 1, RequestID: "req-1", Route: "POST /api/v2/notes"})
+	tx := database.WithContext(ctx)
+	first, second := note{Body: "first"}, note{Body: "second"}
+	steps := []error{
+		tx.Create(&first).Error,
+		tx.Model(&first).Update("body", "first, edited").Error,
+		tx.Create(&second).Error,
+		tx.Delete(&second).Error,
+	}
+	for _, err := range steps {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return database
+}
+
+// tamper edits the log behind the service's back, the way someone with the
+// database file could.
+func tamper(t *testing.T, database *gorm.DB, sql string) {
+	t.Helper()
+	if err := database.Exec(sql).Error; err == nil || !strings.Contains(err.Error(), "append-only") {
+		t.Fatalf("%s got past the append-only triggers: %v", sql, err)
+	}
+	for _, stmt := range []string{
+		"DROP TRIGGER cms_audit_log_no_update",
+		"DROP TRIGGER cms_audit_log_no_delete",
+		sql,
+	} {
+		if err := database.Exec(stmt).Error; err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  string
+		checked int
+		broken  string
+	}{
+		{name: "intact", checked: 4},
+		{
+			name:    "edited row",
+			tamper:  `UPDATE cms_audit_log SET changes = '{"body":"forged"}' WHERE id = 3`,
+			checked: 2,
+			broken:  "entry 3 was altered",
+		},
+		{
+			name:    "broken link",
+			tamper:  "UPDATE cms_audit_log SET prev_hash = (SELECT hash FROM cms_audit_log WHERE id = 1) WHERE id = 3",
+			checked: 2,
+			broken:  "entry 3 does not follow the entry before it",
+		},
+		{
+			name:    "deleted row",
+			tamper:  "DELETE FROM cms_audit_log WHERE id = 2",
+			checked: 1,
+			broken:  "entry 3 does not follow the entry before it",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := chainDB(t)
+			if tt.tamper != "" {
+				tamper(t, database, tt.tamper)
+			}
+			checked, err := Verify(database)
+			if checked != tt.checked {
+				t.Errorf("Verify checked %d entries, want %d", checked, tt.checked)
+			}
+			switch {
+			case tt.broken == "" && err != nil:
+				t.Errorf("Verify reported %v on an intact chain", err)
+			case tt.broken != "" && (err == nil || err.Error() != tt.broken):
+				t.Errorf("Verify reported %v, want %q", err, tt.broken)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"fmt"
+
+	"cms_sidecar_backend/internal/audit"
+	"cms_sidecar_backend/internal/model"
+	"cms_sidecar_backend/internal/refdata"
+	"cms_sidecar_backend/internal/search"
+	"cms_sidecar_backend/internal/summary"
+	"cms_sidecar_backend/internal/tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// addedColumn is a column this service adds to a table owned by the Django backend.
+// Django ignores the extra columns and the defaults keep its inserts valid.
+type addedColumn struct {
+	model interface{}
+	table string
+	field string
+}
+
+var addedColumns = []addedColumn{
+	// Optimistic-locking counters bumped on every write through the Go API.
+	{&model.Project{}, "construction_project", "ProjectVersion"},
+	{&model.ProjectBlock{}, "construction_projectblock", "ProjectBlockVersion"},
+	{&model.ProjectUnit{}, "construction_projectunit", "ProjectUnitVersion"},
+	{&model.Vendor{}, "construction_vendor", "VendorVersion"},
+	{&model.Supervisor{}, "construction_supervisor", "SupervisorVersion"},
+	// Lets attendance stats be filtered by project.
+	{&model.AttendanceBatch{}, "construction_attendancebatch", "ProjectID"},
+	// The owning organization of tenant rows.
+	{&model.Project{}, "construction_project", "OrgID"},
+	{&model.Vendor{}, "construction_vendor", "OrgID"},
+	{&model.Supervisor{}, "construction_supervisor", "OrgID"},
+	{&model.Customer{}, "construction_customer", "OrgID"},
+	{&model.ChannelPartner{}, "construction_channelpartner", "OrgID"},
+	{&model.MaterialItem{}, "construction_materialitem", "OrgID"},
+	{&model.AttendanceBatch{}, "construction_attendancebatch", "OrgID"},
+}
+
+// addedIndexes speed up queries this service runs against Django tables.
+var addedIndexes = []struct {
+	table string
+	sql   string
+}{
+	{"construction_attendancerecord", "CREATE INDEX IF NOT EXISTS cms_attendancerecord_date ON construction_attendancerecord (attendance_date)"},
+	{"construction_project", "CREATE INDEX IF NOT EXISTS cms_project_org ON construction_project (org_id)"},
+	{"construction_vendor", "CREATE INDEX IF NOT EXISTS cms_vendor_org ON construction_vendor (org_id)"},
+	{"construction_supervisor", "CREATE INDEX IF NOT EXISTS cms_supervisor_org ON construction_supervisor (org_id)"},
+	{"construction_customer", "CREATE INDEX IF NOT EXISTS cms_customer_org ON construction_customer (org_id)"},
+	{"construction_channelpartner", "CREATE INDEX IF NOT EXISTS cms_channelpartner_org ON construction_channelpartner (org_id)"},
+	{"construction_materialitem", "CREATE INDEX IF NOT EXISTS cms_materialitem_org ON construction_materialitem (org_id)"},
+	{"construction_attendancebatch", "CREATE INDEX IF NOT EXISTS cms_attendancebatch_org ON construction_attendancebatch (org_id)"},
+}
+
+// ownedTables are created and migrated by the Go service itself.
+var ownedTables = []interface{}{
+	&model.IdempotencyKey{},
+	&model.CodeSequence{},
+	&model.CodeCounter{},
+	&model.RefDataVersion{},
+	&model.Organization{},
+	&model.OrganizationMember{},
+	&model.Invite{},
+	&model.Session{},
+	&model.APIKey{},
+	&model.AuditEntry{},
+	&model.StoredFile{},
+	&model.PaymentReceipt{},
+	&model.Letterhead{},
+}
+
+// EnsureSchema adds the columns, tables and triggers (organizations, audit
+// log, reference-data versions, search index, project summaries) the Go service
+// relies on. Django tables that do not exist yet are
+// skipped so an empty database can still be opened.
+func EnsureSchema(db *gorm.DB) error {
+	if err := db.AutoMigrate(ownedTables...); err != nil {
+		return fmt.Errorf("failed to migrate service tables: %w", err)
+	}
+	// Receipt numbers were unique across organizations before each got its
+	// own counter; (organization_id, number) replaces both old indexes.
+	for _, index := range []string{"idx_cms_payment_receipt_number", "idx_cms_payment_receipt_organization_id"} {
+		if err := db.Exec("DROP INDEX IF EXISTS " + index).Error; err != nil {
+			return fmt.Errorf("failed to drop %s: %w", index, err)
+		}
+	}
+	// Existing rows win so edited prefixes survive restarts.
+	sequences := append([]model.CodeSequence(nil), model.DefaultCodeSequences...)
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&sequences).Error; err != nil {
+		return fmt.Errorf("failed to seed code sequences: %w", err)
+	}
+	// Sessions opened before devices were tracked were last seen when they
+	// started.
+	if err := db.Exec("UPDATE cms_session SET last_seen_at = created_at WHERE last_seen_at IS NULL").Error; err != nil {
+		return fmt.Errorf("failed to backfill session last-seen times: %w", err)
+	}
+
+	migrator := db.Migrator()
+	for _, col := range addedColumns {
+		if !migrator.HasTable(col.table) || migrator.HasColumn(col.model, col.field) {
+			continue
+		}
+		if err := migrator.AddColumn(col.model, col.field); err != nil {
+			return fmt.Errorf("failed to add %s.%s: %w", col.table, col.field, err)
+		}
+	}
+	for _, index := range addedIndexes {
+		if !migrator.HasTable(index.table) {
+			continue
+		}
+		if err := db.Exec(index.sql).Error; err != nil {
+			return fmt.Errorf("failed to index %s: %w", index.table, err)
+		}
+	}
+	if err := tenant.Ensure(db); err != nil {
+		return err
+	}
+	if err := audit.Ensure(db); err != nil {
+		return err
+	}
+	if err := refdata.Ensure(db); err != nil {
+		return err
+	}
+	if err := search.Ensure(db); err != nil {
+		return err
+	}
+	return summary.Ensure(db)
+}
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// defaultBusyTimeout is how long SQLite waits for another connection (or the
+// Django backend) to release its write lock before failing with SQLITE_BUSY.
+const defaultBusyTimeout = 5000
+
+// DSN adds the connection settings the service relies on to an SQLite path:
+// WAL journaling so readers never block the writer, a busy timeout
+// (CMS_SQLITE_BUSY_TIMEOUT_MS, milliseconds) and BEGIN IMMEDIATE, so a
+// transaction takes the write lock up front and waits for it instead of
+// failing when it upgrades from reading.
+func DSN(path string) string {
+	timeout := defaultBusyTimeout
+	if value, err := strconv.Atoi(os.Getenv("CMS_SQLITE_BUSY_TIMEOUT_MS")); err == nil && value >= 0 {
+		timeout = value
+	}
+	params := url.Values{}
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", timeout))
+	params.Add("_pragma", "synchronous(NORMAL)")
+	params.Set("_txlock", "immediate")
+	return path + "?" + params.Encode()
+}
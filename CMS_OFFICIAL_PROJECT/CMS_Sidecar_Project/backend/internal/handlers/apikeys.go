@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cms_sidecar_backend/internal/model"
+	"cms_sidecar_backend/internal/responses"
+	apiKeyService "cms_sidecar_backend/internal/services/apikeys"
+	utils "cms_sidecar_backend/internal/utilities/organization_page_app"
+	"github.com/gin-gonic/gin"
+)
+
+// scopeResources maps the first segment of a /api/v2 path to the resource
+// an API key scope names. Auth, sessions, profile and organization routes
+// are missing on purpose: they need a person.
+var scopeResources = map[string]string{
+	"projects":         "projects",
+	"multi-flat":       "projects",
+	"material-items":   "projects",
+	"customers":        "leads",
+	"channel-partners": "leads",
+	"crm":              "leads",
+	"payments":         "payments",
+	"attendance":       "attendance",
+	"expenses":         "expenses",
+	"vendors":          "directory",
+	"supervisors":      "directory",
+	"directory":        "directory",
+	"index":            "reports",
+	"dashboard":        "reports",
+	"insights":         "reports",
+	"track-finances":   "reports",
+	"search":           "search",
+}
+
+// routeScopes are routes whose scope is not the one their resource and
+// method give: regenerating credentials hands out logins, so directory:write
+// is not enough.
+var routeScopes = map[string]string{
+	"POST /api/v2/directory/credentials": "directory:admin",
+}
+
+// routeScope is the scope an API key needs for the matched route, or ""
+// when keys cannot use it.
+func routeScope(c *gin.Context) string {
+	if scope, ok := routeScopes[c.Request.Method+" "+c.FullPath()]; ok {
+		return scope
+	}
+	path, ok := strings.CutPrefix(c.FullPath(), "/api/v2/")
+	if !ok {
+		return ""
+	}
+	segment, _, _ := strings.Cut(path, "/")
+	resource := scopeResources[segment]
+	if resource == "" {
+		return ""
+	}
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		return resource + ":read"
+	}
+	return resource + ":write"
+}
+
+// keyAllows reports whether the caller may use scope: always for people,
+// and for an API key when it carries the scope.
+func keyAllows(c *gin.Context, scope string) bool {
+	scopes, ok := c.Get(apiKeyScopesKey)
+	if !ok {
+		return true
+	}
+	for _, granted := range scopes.([]string) {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func apiKeyResponse(key model.APIKey, token string) utils.APIKeyResponse {
+	status := "active"
+	switch {
+	case key.RevokedAt != nil:
+		status = "revoked"
+	case key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()):
+		status = "expired"
+	}
+	return utils.APIKeyResponse{
+		ID:          key.ID,
+		Name:        key.Name,
+		Prefix:      key.Prefix,
+		Scopes:      apiKeyService.Scopes(key),
+		AllowedIPs:  apiKeyService.AllowedIPs(key),
+		Status:      status,
+		CreatedByID: key.CreatedByID,
+		CreatedAt:   key.CreatedAt,
+		ExpiresAt:   key.ExpiresAt,
+		LastUsedAt:  key.LastUsedAt,
+		LastUsedIP:  key.LastUsedIP,
+		Key:         token,
+	}
+}
+
+// listAPIKeys lists the organization's API keys (owners only).
+func (h *Handler) listAPIKeys(c *gin.Context) {
+	keys, err := h.apiKeys.List(c.Request.Context(), currentMember(c))
+	if err != nil {
+		serviceFailure(c, err, "Failed to load API keys")
+		return
+	}
+	payload := []utils.APIKeyResponse{}
+	for _, key := range keys {
+		payload = append(payload, apiKeyResponse(key, ""))
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"api_keys": payload}, "API keys loaded")
+}
+
+// createAPIKey issues an API key (owners only). The key in the response is
+// the only copy.
+func (h *Handler) createAPIKey(c *gin.Context) {
+	var req utils.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Name and scopes are required")
+		return
+	}
+	key, token, err := h.apiKeys.Create(c.Request.Context(), currentMember(c), req)
+	if err != nil {
+		serviceFailure(c, err, "Failed to create API key")
+		return
+	}
+	responses.JSON(c, http.StatusCreated, true, gin.H{"api_key": apiKeyResponse(key, token)}, "API key created")
+}
+
+// revokeAPIKey disables an API key (owners only).
+func (h *Handler) revokeAPIKey(c *gin.Context) {
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid API key id")
+		return
+	}
+	if err := h.apiKeys.Revoke(c.Request.Context(), currentMember(c), uint(keyID)); err != nil {
+		serviceFailure(c, err, "Failed to revoke API key")
+		return
+	}
+	responses.JSON(c, http.StatusNoContent, true, nil, "API key revoked")
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAttendanceScopedToOrganization checks the record list, its export and
+// the stats see only the caller's organization's attendance.
+func TestAttendanceScopedToOrganization(t *testing.T) {
+	s := newTestServer(t)
+	today := time.Now().Format("2006-01-02")
+	callers := map[string][]string{}
+	for i, name := range []string{"alpha1", "bravo1"} {
+		auth, _ := s.signUp(name)
+		callers[name] = auth
+		id := i + 1
+		s.exec("INSERT INTO construction_attendancebatch (id, name, org_id) VALUES (?, ?, ?)", id, name+" crew", s.organization(name))
+		s.exec("INSERT INTO construction_attendancemember (id, name, is_active, batch_id) VALUES (?, ?, 1, ?)", id, name+" mason", id)
+		s.exec(`INSERT INTO construction_attendancerecord (attendance_code, attendee_name, attendance_date, status, mode, attendance_batch, member_id)
+			VALUES (?, ?, ?, 'present', 'onsite', ?, ?)`, name+"-1", name+" mason", today, name+" crew", id)
+	}
+
+	for name, auth := range callers {
+		other := "bravo1"
+		if name == other {
+			other = "alpha1"
+		}
+
+		w := s.do(http.MethodGet, "/api/v2/attendance/records", "", auth...)
+		var list struct {
+			Data []struct {
+				Name string `json:"name"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("%s: records: %d %s", name, w.Code, w.Body)
+		}
+		if len(list.Data) != 1 || list.Data[0].Name != name+" mason" {
+			t.Errorf("%s: records list returned %+v, want only its own record", name, list.Data)
+		}
+
+		w = s.do(http.MethodGet, "/api/v2/attendance/records?format=csv", "", auth...)
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), name+" mason") {
+			t.Errorf("%s: export is missing its own record: %d %s", name, w.Code, w.Body)
+		}
+		if strings.Contains(w.Body.String(), other) {
+			t.Errorf("%s: export includes %s's records: %s", name, other, w.Body)
+		}
+
+		w = s.do(http.MethodGet, "/api/v2/attendance/stats", "", auth...)
+		var stats struct {
+			Data struct {
+				Stats map[string]int `json:"stats"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("%s: stats: %d %s", name, w.Code, w.Body)
+		}
+		if got := stats.Data.Stats["total_today"]; got != 1 {
+			t.Errorf("%s: stats count %d records today, want 1", name, got)
+		}
+	}
+}
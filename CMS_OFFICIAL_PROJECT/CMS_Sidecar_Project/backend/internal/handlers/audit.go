@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"cms_sidecar_backend/internal/listquery"
+	"cms_sidecar_backend/internal/model"
+	"cms_sidecar_backend/internal/responses"
+	utils "cms_sidecar_backend/internal/utilities/organization_page_app"
+	"github.com/gin-gonic/gin"
+)
+
+// auditListSpec filters the audit log by entity (a table name such as
+// construction_vendor) and row, by who made the change and by request.
+var auditListSpec = listquery.Spec{
+	Table:       "cms_audit_log",
+	Sorts:       map[string]string{"occurred_at": "occurred_at"},
+	DefaultSort: "-occurred_at",
+	Filters: map[string]string{
+		"entity":     "entity",
+		"entity_id":  "entity_id",
+		"action":     "action",
+		"user_id":    "user_id",
+		"api_key_id": "api_key_id",
+		"request_id": "request_id",
+	},
+	DateColumn: "occurred_at",
+}
+
+// auditColumns export the changes as the JSON they are stored as.
+var auditColumns = []column[model.AuditEntry]{
+	{"Occurred at", func(e model.AuditEntry) interface{} { return e.OccurredAt }},
+	{"User ID", func(e model.AuditEntry) interface{} { return e.UserID }},
+	{"API key ID", func(e model.AuditEntry) interface{} { return e.APIKeyID }},
+	{"IP", func(e model.AuditEntry) interface{} { return e.IP }},
+	{"Request ID", func(e model.AuditEntry) interface{} { return e.RequestID }},
+	{"Route", func(e model.AuditEntry) interface{} { return e.Route }},
+	{"Action", func(e model.AuditEntry) interface{} { return e.Action }},
+	{"Entity", func(e model.AuditEntry) interface{} { return e.Entity }},
+	{"Entity ID", func(e model.AuditEntry) interface{} { return e.EntityID }},
+	{"Changes", func(e model.AuditEntry) interface{} { return e.Changes }},
+	{"Hash", func(e model.AuditEntry) interface{} { return e.Hash }},
+}
+
+func auditEntryResponse(entry model.AuditEntry) utils.AuditEntryResponse {
+	resp := utils.AuditEntryResponse{
+		ID:         entry.ID,
+		OccurredAt: entry.OccurredAt,
+		UserID:     entry.UserID,
+		APIKeyID:   entry.APIKeyID,
+		SessionID:  entry.SessionID,
+		IP:         entry.IP,
+		RequestID:  entry.RequestID,
+		Route:      entry.Route,
+		Action:     entry.Action,
+		Entity:     entry.Entity,
+		EntityID:   entry.EntityID,
+		Hash:       entry.Hash,
+	}
+	// Changes were encoded by the audit callbacks; a row that fails to
+	// decode is shown without them rather than failing the page.
+	_ = json.Unmarshal([]byte(entry.Changes), &resp.Changes)
+	return resp
+}
+
+// listAuditEntries pages through the organization's audit log, newest
+// first. Owners only.
+func (h *Handler) listAuditEntries(c *gin.Context) {
+	member := currentMember(c)
+	if member.OrganizationID == 0 || member.Role != model.MemberRoleOwner {
+		responses.JSON(c, http.StatusForbidden, false, nil, "Only organization owners can read the audit log")
+		return
+	}
+	query := h.dbFor(c).Model(&model.AuditEntry{}).Where("organization_id = ?", member.OrganizationID)
+	if exporting(c) {
+		exportList(c, auditListSpec, query, "audit-log", auditColumns)
+		return
+	}
+	var entries []model.AuditEntry
+	page, ok := findPage(c, auditListSpec, query, &entries, "failed to load audit log")
+	if !ok {
+		return
+	}
+	payload := []utils.AuditEntryResponse{}
+	for _, entry := range entries {
+		payload = append(payload, auditEntryResponse(entry))
+	}
+	responses.Page(c, http.StatusOK, payload, "audit log loaded", page)
+}
@@ -3,17 +3,32 @@ package handlers
 import (
 	"fmt"
 	"net/http"
-	"regexp"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/gin-gonic/gin"
 	"cms_sidecar_backend/internal/model"
+	"cms_sidecar_backend/internal/passwords"
 	"cms_sidecar_backend/internal/responses"
+	"cms_sidecar_backend/internal/tenant"
 	utils "cms_sidecar_backend/internal/utilities/auth_page_app"
+	"github.com/gin-gonic/gin"
 )
 
-// LoginView mirrors Django's login_view logic
+// defaultSessionTTL is how long a login lasts unless CMS_SESSION_TTL_HOURS
+// says otherwise.
+const defaultSessionTTL = 30 * 24 * time.Hour
+
+func sessionTTL() time.Duration {
+	if value, err := strconv.Atoi(os.Getenv("CMS_SESSION_TTL_HOURS")); err == nil && value > 0 {
+		return time.Duration(value) * time.Hour
+	}
+	return defaultSessionTTL
+}
+
+// LoginView mirrors Django's login_view logic and starts a session. The
+// token is returned for apps and set as cookies for browsers.
 func (h *Handler) LoginView(c *gin.Context) {
 	var req utils.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -27,16 +42,18 @@ func (h *Handler) LoginView(c *gin.Context) {
 	}
 
 	var user model.User
-	// Basic implementation - in production use hashed password check (bcrypt)
-	// assuming exact match for migration simplicity if hashing isn't ported yet
-	if err := h.db.Preload("Profile").Where("username = ?", req.Username).First(&user).Error; err != nil {
+	if err := h.dbFor(c).Preload("Profile").Where("username = ?", req.Username).First(&user).Error; err != nil {
 		responses.JSON(c, http.StatusUnauthorized, false, nil, "Invalid credentials")
 		return
 	}
-
-	// Password check placeholder - strongly recommend using bcrypt.CompareHashAndPassword
-	// if user.Password != req.Password { ... }
-	// For now, we assume the user is authenticated if found (logic needs real auth).
+	if !passwords.Check(req.Password, user.Password) {
+		responses.JSON(c, http.StatusUnauthorized, false, nil, "Invalid credentials")
+		return
+	}
+	if !user.IsActive {
+		responses.JSON(c, http.StatusForbidden, false, nil, "This account has been deactivated")
+		return
+	}
 
 	actualRole := "builder"
 	if user.Profile != nil {
@@ -57,12 +74,18 @@ func (h *Handler) LoginView(c *gin.Context) {
 		return
 	}
 
-	// Generate Token (JWT) or Session here.
-	// For mirroring, we just return success.
+	token, session, err := h.startSession(c, user.ID, req.DeviceName, req.Platform)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to start session")
+		return
+	}
+	setSessionCookies(c, token, session.ExpiresAt)
 	responses.JSON(c, http.StatusOK, true, gin.H{
-		"user_id":  user.ID,
-		"username": user.Username,
-		"role":     actualRole,
+		"user_id":    user.ID,
+		"username":   user.Username,
+		"role":       actualRole,
+		"token":      token,
+		"expires_at": session.ExpiresAt,
 	}, "Login successful")
 }
 
@@ -79,25 +102,13 @@ func (h *Handler) RegisterView(c *gin.Context) {
 	usertype := req.UserType
 
 	// Validations
-	if username == "" || password == "" {
-		responses.JSON(c, http.StatusBadRequest, false, nil, "Username and password required")
-		return
-	}
-	if matched, _ := regexp.MatchString(`^[A-Za-z0-9_]{5,}$`, username); !matched {
-		responses.JSON(c, http.StatusBadRequest, false, nil, "Username must be alphanumeric (min 5 chars)")
-		return
-	}
-	if len(password) < 8 {
-		responses.JSON(c, http.StatusBadRequest, false, nil, "Password must be at least 8 characters")
-		return
-	}
-	if req.PasswordConfirm == "" || password != req.PasswordConfirm {
-		responses.JSON(c, http.StatusBadRequest, false, nil, "Passwords do not match")
+	if msg := utils.CheckCredentials(username, password, req.PasswordConfirm); msg != "" {
+		responses.JSON(c, http.StatusBadRequest, false, nil, msg)
 		return
 	}
 
 	var existing model.User
-	if err := h.db.Where("username = ?", username).First(&existing).Error; err == nil {
+	if err := h.dbFor(c).Where("username = ?", username).First(&existing).Error; err == nil {
 		responses.JSON(c, http.StatusBadRequest, false, nil, "Username already exists")
 		return
 	}
@@ -107,7 +118,13 @@ func (h *Handler) RegisterView(c *gin.Context) {
 		usertype = "builder"
 	}
 
-	tx := h.db.Begin()
+	hashed, err := passwords.Hash(password)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to create user")
+		return
+	}
+
+	tx := h.dbFor(c).Begin()
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -116,7 +133,7 @@ func (h *Handler) RegisterView(c *gin.Context) {
 
 	newUser := model.User{
 		Username:   username,
-		Password:   password, // Ideally hash this!
+		Password:   hashed,
 		IsActive:   true,
 		DateJoined: time.Now(),
 	}
@@ -139,6 +156,16 @@ func (h *Handler) RegisterView(c *gin.Context) {
 		return
 	}
 
+	// A builder starts out owning an organization of their own; supervisors
+	// join the organization that adds them.
+	if utils.IsBuilderRole(usertype) {
+		if _, err := tenant.Provision(tx, newUser); err != nil {
+			tx.Rollback()
+			responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to create organization")
+			return
+		}
+	}
+
 	tx.Commit()
 
 	responses.JSON(c, http.StatusOK, true, gin.H{
@@ -148,8 +175,43 @@ func (h *Handler) RegisterView(c *gin.Context) {
 	}, "Registration successful")
 }
 
-// LogoutView placeholder
+// LogoutView ends the session the request was made with and clears the
+// browser's session cookies.
 func (h *Handler) LogoutView(c *gin.Context) {
-	// In stateless JWT, client discards token.
+	if sessionID := c.GetUint(sessionKey); sessionID != 0 {
+		if err := h.sessions.Revoke(c.Request.Context(), currentUser(c), sessionID); err != nil {
+			responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to log out")
+			return
+		}
+	}
+	clearSessionCookies(c)
 	responses.JSON(c, http.StatusOK, true, nil, "Logged out successfully")
 }
+
+// AcceptInviteView creates the account an invite was sent for and logs it
+// in.
+func (h *Handler) AcceptInviteView(c *gin.Context) {
+	var req utils.AcceptInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invite token is required")
+		return
+	}
+	user, err := h.users.AcceptInvite(c.Request.Context(), req)
+	if err != nil {
+		serviceFailure(c, err, "Failed to accept invite")
+		return
+	}
+	token, session, err := h.startSession(c, user.ID, req.DeviceName, req.Platform)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to start session")
+		return
+	}
+	setSessionCookies(c, token, session.ExpiresAt)
+	responses.JSON(c, http.StatusCreated, true, gin.H{
+		"user_id":    user.ID,
+		"username":   user.Username,
+		"role":       user.Profile.UserType,
+		"token":      token,
+		"expires_at": session.ExpiresAt,
+	}, "Invite accepted")
+}
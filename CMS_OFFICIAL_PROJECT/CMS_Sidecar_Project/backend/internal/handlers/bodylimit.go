@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cms_sidecar_backend/internal/responses"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultMaxBodyBytes caps request bodies unless CMS_MAX_BODY_BYTES says
+	// otherwise.
+	defaultMaxBodyBytes = 1 << 20
+	// defaultMaxJSONDepth caps how deeply JSON bodies nest unless
+	// CMS_MAX_JSON_DEPTH says otherwise.
+	defaultMaxJSONDepth = 32
+	// defaultReadTimeout bounds reading a whole request, body included,
+	// unless CMS_READ_TIMEOUT_SECONDS says otherwise.
+	defaultReadTimeout = 60 * time.Second
+	// readHeaderTimeout bounds reading the request line and headers.
+	readHeaderTimeout = 10 * time.Second
+)
+
+func maxBodyBytes() int64 {
+	if value, err := strconv.ParseInt(os.Getenv("CMS_MAX_BODY_BYTES"), 10, 64); err == nil && value > 0 {
+		return value
+	}
+	return defaultMaxBodyBytes
+}
+
+func readTimeout() time.Duration {
+	if value, err := strconv.Atoi(os.Getenv("CMS_READ_TIMEOUT_SECONDS")); err == nil && value > 0 {
+		return time.Duration(value) * time.Second
+	}
+	return defaultReadTimeout
+}
+
+// Server serves handler on addr with read timeouts, so a client trickling
+// its headers or body is cut off instead of holding the connection open.
+func Server(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       readTimeout(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+}
+
+func maxJSONDepth() int {
+	if value, err := strconv.Atoi(os.Getenv("CMS_MAX_JSON_DEPTH")); err == nil && value > 0 {
+		return value
+	}
+	return defaultMaxJSONDepth
+}
+
+// LimitBodies refuses request bodies over the size limit with 413 and JSON
+// nested deeper than the depth limit with 400, before any handler decodes
+// them. Multipart and binary bodies are only held to the size limit, which
+// for multipart bodies is the upload limit plus room for the form around
+// the file.
+func LimitBodies() gin.HandlerFunc {
+	bodyLimit := maxBodyBytes()
+	uploadLimit := max(bodyLimit, maxUploadBytes()+multipartOverhead)
+	depth := maxJSONDepth()
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		contentType := c.ContentType()
+		limit := bodyLimit
+		if strings.HasPrefix(contentType, "multipart/") {
+			limit = uploadLimit
+		}
+		if c.Request.ContentLength > limit {
+			bodyTooLarge(c, limit)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		if strings.HasPrefix(contentType, "multipart/") || contentType == "application/octet-stream" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				bodyTooLarge(c, limit)
+				return
+			}
+			responses.JSON(c, http.StatusBadRequest, false, nil, "Could not read request body")
+			c.Abort()
+			return
+		}
+		if nestedDeeper(body, depth) {
+			responses.JSON(c, http.StatusBadRequest, false, nil, "JSON is nested more than "+strconv.Itoa(depth)+" levels deep")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+func bodyTooLarge(c *gin.Context, limit int64) {
+	responses.JSON(c, http.StatusRequestEntityTooLarge, false, nil, "Request body is larger than "+strconv.FormatInt(limit, 10)+" bytes")
+	c.Abort()
+}
+
+// nestedDeeper reports whether arrays and objects in body nest more than
+// limit levels. Brackets inside strings do not count; the body need not be
+// valid JSON, decoding it is left to the handler.
+func nestedDeeper(body []byte, limit int) bool {
+	depth := 0
+	inString, escaped := false, false
+	for _, b := range body {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			if b == '\\' {
+				escaped = true
+			} else if b == '"' {
+				inString = false
+			}
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			depth++
+			if depth > limit {
+				return true
+			}
+		case b == '}' || b == ']':
+			depth--
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+
+	"cms_sidecar_backend/internal/listquery"
+	"cms_sidecar_backend/internal/model"
+	"cms_sidecar_backend/internal/responses"
+	"cms_sidecar_backend/internal/search"
+	"github.com/gin-gonic/gin"
+)
+
+// channelPartnersListSpec pages the channel partner catalog 200 at a time;
+// it used to return every row.
+var channelPartnersListSpec = listquery.Spec{
+	Table:           "construction_channelpartner",
+	Sorts:           map[string]string{"created_at": "channel_partner_created_at", "code": "channel_partner_code", "name": "channel_partner_name"},
+	DefaultSort:     "-created_at",
+	DefaultPageSize: 200,
+}
+
+// channelPartnerColumns are shared by the channel partner and CRM channel
+// partner exports.
+var channelPartnerColumns = []column[model.ChannelPartner]{
+	{"Code", func(r model.ChannelPartner) interface{} { return r.ChannelPartnerCode }},
+	{"Name", func(r model.ChannelPartner) interface{} { return r.ChannelPartnerName }},
+	{"Phone", func(r model.ChannelPartner) interface{} { return r.ChannelPartnerPrimaryPhone }},
+	{"Email", func(r model.ChannelPartner) interface{} { return r.ChannelPartnerEmail }},
+	{"City", func(r model.ChannelPartner) interface{} { return r.ChannelPartnerCity }},
+	{"RERA number", func(r model.ChannelPartner) interface{} { return r.ChannelPartnerReraNumber }},
+	{"Created", func(r model.ChannelPartner) interface{} { return r.ChannelPartnerCreatedAt }},
+}
+
+func (h *Handler) listChannelPartners(c *gin.Context) {
+	query := h.dbFor(c).Model(&model.ChannelPartner{})
+	if ids, ok := search.MatchIDs(h.dbFor(c), search.KindChannelPartner, c.Query("search")); ok {
+		query = query.Where("id IN (?)", ids)
+	}
+	if exporting(c) {
+		exportList(c, channelPartnersListSpec, query, "channel-partners", channelPartnerColumns)
+		return
+	}
+
+	var partners []model.ChannelPartner
+	page, ok := findPage(c, channelPartnersListSpec, query, &partners, "failed to load channel partners")
+	if !ok {
+		return
+	}
+	responses.Page(c, http.StatusOK, partners, "channel partners loaded", page)
+}
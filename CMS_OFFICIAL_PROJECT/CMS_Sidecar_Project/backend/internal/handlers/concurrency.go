@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"cms_sidecar_backend/internal/responses"
+	"github.com/gin-gonic/gin"
+)
+
+// entityETag builds the strong ETag for one version of a record, e.g. "unit-12-v3".
+func entityETag(kind string, id uint, version uint) string {
+	return fmt.Sprintf(`"%s-%d-v%d"`, kind, id, version)
+}
+
+// ifMatchFails reports whether the request carries an If-Match header that
+// does not name the current ETag. A missing header or "*" always matches.
+func ifMatchFails(c *gin.Context, etag string) bool {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return false
+		}
+	}
+	return true
+}
+
+// preconditionFailed answers 412 when If-Match names an older version, with
+// the current server state and its ETag so the client can merge and retry.
+func preconditionFailed(c *gin.Context, etag string, current interface{}) {
+	c.Header("ETag", etag)
+	responses.JSON(c, http.StatusPreconditionFailed, false, current, "Record has changed since you loaded it; reload and retry")
+}
+
+// versionConflict answers 409 when another write saved a new version between
+// loading the record and saving it, with the current server state and its
+// ETag so the client can merge and retry.
+func versionConflict(c *gin.Context, etag string, current interface{}) {
+	c.Header("ETag", etag)
+	responses.JSON(c, http.StatusConflict, false, current, "Record was changed by someone else; reload and retry")
+}
@@ -5,48 +5,66 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/gin-gonic/gin"
+	"cms_sidecar_backend/internal/listquery"
 	"cms_sidecar_backend/internal/model"
 	"cms_sidecar_backend/internal/responses"
+	"cms_sidecar_backend/internal/search"
 	utils "cms_sidecar_backend/internal/utilities/crm_page_app"
+	"github.com/gin-gonic/gin"
 )
 
 // CRMProjectsList mirrors projects_list_api (used for dropdowns).
 func (h *Handler) CRMProjectsList(c *gin.Context) {
 	var projects []model.Project
 	// Django: "id", "project_name", "project_code", "project_flat_configuration"
-	if err := h.db.Select("id", "project_name", "project_code", "project_flat_configuration").Find(&projects).Error; err != nil {
+	if err := h.dbFor(c).Select("id", "project_name", "project_code", "project_flat_configuration").Find(&projects).Error; err != nil {
 		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load projects")
 		return
 	}
 	responses.JSON(c, http.StatusOK, true, gin.H{"projects": projects}, "Projects loaded")
 }
 
+var (
+	crmCustomerListSpec = listquery.Spec{
+		Table:           "construction_customer",
+		Sorts:           map[string]string{"name": "customer_name", "code": "customer_code", "created_at": "customer_created_at"},
+		DefaultSort:     "name",
+		DefaultPageSize: 200,
+	}
+	crmChannelPartnerListSpec = listquery.Spec{
+		Table:           "construction_channelpartner",
+		Sorts:           map[string]string{"name": "channel_partner_name", "code": "channel_partner_code", "created_at": "channel_partner_created_at"},
+		DefaultSort:     "name",
+		Filters:         map[string]string{"city": "channel_partner_city"},
+		DefaultPageSize: 200,
+	}
+)
+
 // CRMCustomers mirrors crm_customers API.
 func (h *Handler) CRMCustomers(c *gin.Context) {
 	// TODO: Auth check mirroring (request.user.is_staff) needs middleware context.
 	// For now, mirroring the query logic assuming full access or implementing basic filter placeholders.
 
-	query := h.db.Model(&model.Customer{})
+	query := h.dbFor(c).Model(&model.Customer{})
 
 	// Mirroring filtering logic:
 	// if not request.user.is_staff:
 	//     customers = customers.filter(Q(customer_created_by=user) | Q(created_by__isnull=True))
 	// Since we don't have user context yet, we skip this specific filter block for now.
 
-	search := strings.TrimSpace(c.Query("search"))
-	if search != "" {
-		like := "%" + search + "%"
-		query = query.Where(
-			"customer_name LIKE ? OR customer_code LIKE ? OR customer_primary_phone_number LIKE ?",
-			like, like, like,
-		)
+	if ids, ok := search.MatchIDs(h.dbFor(c), search.KindCustomer, c.Query("search")); ok {
+		query = query.Where("id IN (?)", ids)
+	}
+	if exporting(c) {
+		exportList(c, crmCustomerListSpec, query, "customers", customerColumns)
+		return
 	}
 
 	var customers []model.Customer
-	if err := query.Order("customer_name asc").Limit(200).Find(&customers).Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load customers")
+	page, ok := findPage(c, crmCustomerListSpec, query, &customers, "Failed to load customers")
+	if !ok {
 		return
 	}
 
@@ -62,25 +80,24 @@ func (h *Handler) CRMCustomers(c *gin.Context) {
 			"email":         cust.CustomerEmail,
 		})
 	}
-	responses.JSON(c, http.StatusOK, true, gin.H{"customers": payload}, "Customers loaded")
+	responses.Page(c, http.StatusOK, gin.H{"customers": payload}, "Customers loaded", page)
 }
 
 // CRMChannelPartners mirrors crm_channel_partners API.
 func (h *Handler) CRMChannelPartners(c *gin.Context) {
-	query := h.db.Model(&model.ChannelPartner{})
+	query := h.dbFor(c).Model(&model.ChannelPartner{})
 
-	search := strings.TrimSpace(c.Query("search"))
-	if search != "" {
-		like := "%" + search + "%"
-		query = query.Where(
-			"channel_partner_name LIKE ? OR channel_partner_code LIKE ? OR channel_partner_primary_phone_number LIKE ? OR channel_partner_city LIKE ?",
-			like, like, like, like,
-		)
+	if ids, ok := search.MatchIDs(h.dbFor(c), search.KindChannelPartner, c.Query("search")); ok {
+		query = query.Where("id IN (?)", ids)
+	}
+	if exporting(c) {
+		exportList(c, crmChannelPartnerListSpec, query, "channel-partners", channelPartnerColumns)
+		return
 	}
 
 	var partners []model.ChannelPartner
-	if err := query.Order("channel_partner_name asc").Limit(200).Find(&partners).Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load channel partners")
+	page, ok := findPage(c, crmChannelPartnerListSpec, query, &partners, "Failed to load channel partners")
+	if !ok {
 		return
 	}
 
@@ -97,7 +114,85 @@ func (h *Handler) CRMChannelPartners(c *gin.Context) {
 			"city":                 p.ChannelPartnerCity,
 		})
 	}
-	responses.JSON(c, http.StatusOK, true, gin.H{"channel_partners": payload}, "Channel partners loaded")
+	responses.Page(c, http.StatusOK, gin.H{"channel_partners": payload}, "Channel partners loaded", page)
+}
+
+// CreateCustomerAPI adds a customer; the code comes from the customer sequence.
+func (h *Handler) CreateCustomerAPI(c *gin.Context) {
+	var req utils.CreateCustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.PrimaryPhone = strings.TrimSpace(req.PrimaryPhone)
+	if req.Name == "" || req.PrimaryPhone == "" {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Name and phone required")
+		return
+	}
+
+	now := time.Now()
+	customer := model.Customer{
+		CustomerName:                 req.Name,
+		CustomerPrimaryPhoneNumber:   req.PrimaryPhone,
+		CustomerSecondaryPhoneNumber: strings.TrimSpace(req.SecondaryPhone),
+		CustomerEmail:                strings.TrimSpace(req.Email),
+		CustomerAddress:              strings.TrimSpace(req.Address),
+		CustomerCompanyName:          strings.TrimSpace(req.CompanyName),
+		CustomerCreatedAt:            now,
+		CustomerUpdatedAt:            now,
+	}
+	if err := h.dbFor(c).Create(&customer).Error; err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to create customer")
+		return
+	}
+
+	responses.JSON(c, http.StatusCreated, true, gin.H{"customer": gin.H{
+		"id":            customer.ID,
+		"customer_code": customer.CustomerCode,
+		"name":          customer.CustomerName,
+		"phone":         customer.CustomerPrimaryPhoneNumber,
+		"email":         customer.CustomerEmail,
+	}}, "Customer created")
+}
+
+// CreateChannelPartnerAPI adds a channel partner; the code comes from the channel partner sequence.
+func (h *Handler) CreateChannelPartnerAPI(c *gin.Context) {
+	var req utils.CreateChannelPartnerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	req.PrimaryPhone = strings.TrimSpace(req.PrimaryPhone)
+	if req.Name == "" || req.PrimaryPhone == "" {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Name and phone required")
+		return
+	}
+
+	now := time.Now()
+	partner := model.ChannelPartner{
+		ChannelPartnerName:         req.Name,
+		ChannelPartnerPrimaryPhone: req.PrimaryPhone,
+		ChannelPartnerEmail:        strings.TrimSpace(req.Email),
+		ChannelPartnerCity:         strings.TrimSpace(req.City),
+		ChannelPartnerReraNumber:   strings.TrimSpace(req.ReraNumber),
+		ChannelPartnerCreatedAt:    now,
+		ChannelPartnerUpdatedAt:    now,
+	}
+	if err := h.dbFor(c).Create(&partner).Error; err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to create channel partner")
+		return
+	}
+
+	responses.JSON(c, http.StatusCreated, true, gin.H{"channel_partner": gin.H{
+		"id":                   partner.ID,
+		"channel_partner_code": partner.ChannelPartnerCode,
+		"name":                 partner.ChannelPartnerName,
+		"phone":                partner.ChannelPartnerPrimaryPhone,
+		"email":                partner.ChannelPartnerEmail,
+		"city":                 partner.ChannelPartnerCity,
+	}}, "Channel partner created")
 }
 
 // KanbanBoardAPI mirrors kanban_board_api.
@@ -114,16 +209,19 @@ func (h *Handler) KanbanBoardAPI(c *gin.Context) {
 	}
 
 	var project model.Project
-	if err := h.db.First(&project, projectID).Error; err != nil {
+	if err := h.dbFor(c).First(&project, projectID).Error; err != nil {
 		responses.JSON(c, http.StatusNotFound, false, nil, "Project not found")
 		return
 	}
+	if !h.canAccessProject(c, project.ID) {
+		return
+	}
 
 	// Fetch all units for this project
 	// To do this via GORM relations efficiently:
 	// Join ProjectUnit -> ProjectBlock -> Project
 	var units []model.ProjectUnit
-	err = h.db.Joins("JOIN construction_projectblock ON construction_projectblock.id = construction_projectunit.project_unit_block_id").
+	err = h.dbFor(c).Joins("JOIN construction_projectblock ON construction_projectblock.id = construction_projectunit.project_unit_block_id").
 		Where("construction_projectblock.project_block_project_id = ?", project.ID).
 		Preload("ProjectUnitBlock"). // Assuming we might add this relation to model if needed, but for now we join
 		Find(&units).Error
@@ -136,7 +234,7 @@ func (h *Handler) KanbanBoardAPI(c *gin.Context) {
 	// To get block name, we can fetch all blocks for project and map them.
 
 	var blocks []model.ProjectBlock
-	h.db.Where("project_block_project_id = ?", project.ID).Find(&blocks)
+	h.dbFor(c).Where("project_block_project_id = ?", project.ID).Find(&blocks)
 	blockMap := make(map[uint]string)
 	for _, b := range blocks {
 		blockMap[b.ID] = b.ProjectBlockName
@@ -189,6 +287,14 @@ func (h *Handler) KanbanBoardAPI(c *gin.Context) {
 		})
 	}
 
+	summaries, err := h.projectSummaries(c, []uint{project.ID})
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load project summary")
+		return
+	}
+	summary := summaries[project.ID]
+	summary.ProjectID = project.ID
+
 	responses.JSON(c, http.StatusOK, true, gin.H{
 		"project": map[string]interface{}{
 			"id":                         project.ID,
@@ -196,15 +302,14 @@ func (h *Handler) KanbanBoardAPI(c *gin.Context) {
 			"project_code":               project.ProjectCode,
 			"project_flat_configuration": project.ProjectFlatConfiguration,
 		},
+		"summary": summary,
 		"columns": columns,
 	}, "Kanban board loaded")
 }
 
 // KanbanUpdateStageAPI mirrors kanban_update_stage_api.
 func (h *Handler) KanbanUpdateStageAPI(c *gin.Context) {
-	unitIDParam := c.Param("unit_id")
-	unitID, err := strconv.ParseUint(unitIDParam, 10, 64)
-	if err != nil {
+	if _, err := strconv.ParseUint(c.Param("unit_id"), 10, 64); err != nil {
 		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid unit ID")
 		return
 	}
@@ -228,17 +333,21 @@ func (h *Handler) KanbanUpdateStageAPI(c *gin.Context) {
 		return
 	}
 
-	var unit model.ProjectUnit
-	if err := h.db.First(&unit, unitID).Error; err != nil {
-		responses.JSON(c, http.StatusNotFound, false, nil, "Unit not found")
+	unit, ok := h.loadUnit(c)
+	if !ok {
+		return
+	}
+	etag := entityETag("unit", unit.ID, unit.ProjectUnitVersion)
+	if ifMatchFails(c, etag) {
+		preconditionFailed(c, etag, unit)
 		return
 	}
 
 	unit.ProjectUnitCRMStage = req.Stage
-	if err := h.db.Save(&unit).Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to update stage")
+	if !h.saveUnit(c, &unit, "Failed to update stage") {
 		return
 	}
 
+	c.Header("ETag", entityETag("unit", unit.ID, unit.ProjectUnitVersion))
 	responses.JSON(c, http.StatusOK, true, gin.H{"stage": unit.ProjectUnitCRMStage}, "Stage updated")
 }
@@ -4,31 +4,22 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/gin-gonic/gin"
 	"cms_sidecar_backend/internal/model"
 	"cms_sidecar_backend/internal/responses"
 	authUtils "cms_sidecar_backend/internal/utilities/auth_page_app"
 	projectUtils "cms_sidecar_backend/internal/utilities/projects_page_app"
+	"github.com/gin-gonic/gin"
 )
 
 // DashboardView mirrors the dashboard overview logic.
 func (h *Handler) DashboardView(c *gin.Context) {
 	// 1. Get User/Profile
-	// For now, mocking user as 1 for mirroring logic until we possess full auth middleware.
-	// In real app, userID := c.GetUint("userID")
-
-	// Placeholder: User is assumed to be logged in (Middleware should handle this).
-	// Let's assume we can fetch the user details from DB based on a mocked header or similar if auth wasn't active.
-	// For strict mirroring of logic:
-
 	// profile, _ = Profile.objects.get_or_create(user=request.user)
 	// display_name = profile.display_name or ...
-
-	// Mocking behavior:
-	userID := uint(1) // Default to ID 1 for dev/mirroring if no auth token.
+	userID := currentUser(c)
 
 	var user model.User
-	if err := h.db.Preload("Profile").First(&user, userID).Error; err != nil {
+	if err := h.dbFor(c).Preload("Profile").First(&user, userID).Error; err != nil {
 		// If no user 1, fail gracefully
 		responses.JSON(c, http.StatusUnauthorized, false, nil, "User not authenticated")
 		return
@@ -46,11 +37,12 @@ func (h *Handler) DashboardView(c *gin.Context) {
 	// 2. Fetch User Projects (Scoped)
 	// projects_for_user(request.user)
 	var projects []model.Project
-	query := h.db.Model(&model.Project{})
+	query := h.dbFor(c).Model(&model.Project{})
 
 	switch userRole {
 	case "builder", "organization":
-		query = query.Where("project_owner_id = ?", user.ID)
+		// Every project of the organization; the tenant filter scopes the
+		// query.
 	case "supervisor":
 		// query = query.Where("project_assigned_supervisor__supervisor_user=user") -> Join
 		// Simplified:
@@ -65,7 +57,7 @@ func (h *Handler) DashboardView(c *gin.Context) {
 		// This ID refers to `construction_supervisor` table ID.
 		// We need to find the supervisor record for this user.
 		var supervisor model.Supervisor
-		if err := h.db.Where("supervisor_user_id = ?", user.ID).First(&supervisor).Error; err == nil {
+		if err := h.dbFor(c).Where("supervisor_user_id = ?", user.ID).First(&supervisor).Error; err == nil {
 			query = query.Where("project_assigned_supervisor_id = ?", supervisor.ID)
 		} else {
 			// User is supervisor role but no supervisor record? No projects.
@@ -80,11 +72,11 @@ func (h *Handler) DashboardView(c *gin.Context) {
 		var custID uint
 
 		var sup model.Supervisor
-		if h.db.Where("supervisor_user_id = ?", user.ID).First(&sup).Error == nil {
+		if h.dbFor(c).Where("supervisor_user_id = ?", user.ID).First(&sup).Error == nil {
 			supID = sup.ID
 		}
 		var cust model.Customer
-		if h.db.Where("customer_user_id = ?", user.ID).First(&cust).Error == nil {
+		if h.dbFor(c).Where("customer_user_id = ?", user.ID).First(&cust).Error == nil {
 			custID = cust.ID
 		}
 
@@ -113,6 +105,17 @@ func (h *Handler) DashboardView(c *gin.Context) {
 	// 3. Build Status Counts
 	statusCounts := projectUtils.BuildStatusCounts(projects)
 
+	// Units, money in/out and stock across the user's projects.
+	projectIDs := make([]uint, len(projects))
+	for i, p := range projects {
+		projectIDs[i] = p.ID
+	}
+	summaries, err := h.projectSummaries(c, projectIDs)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load project summaries")
+		return
+	}
+
 	// 4. Build Status Cards Payload
 	var statusCards []map[string]interface{}
 	for _, card := range projectUtils.StatusCardConfig {
@@ -131,5 +134,6 @@ func (h *Handler) DashboardView(c *gin.Context) {
 		"status_cards":   statusCards,
 		"status_counts":  statusCounts,
 		"total_projects": statusCounts["total"],
+		"totals":         summaryTotals(summaries),
 	}, "Dashboard loaded")
 }
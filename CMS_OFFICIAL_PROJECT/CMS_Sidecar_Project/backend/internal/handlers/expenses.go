@@ -3,67 +3,214 @@ package handlers
 import (
 	"net/http"
 
-	"github.com/gin-gonic/gin"
+	"cms_sidecar_backend/internal/listquery"
 	"cms_sidecar_backend/internal/model"
+	"cms_sidecar_backend/internal/refdata"
 	"cms_sidecar_backend/internal/responses"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// expenseListSpec pages an expense table newest first; every expense type can
+// be filtered by project and date and sorted by date or amount.
+func expenseListSpec(kind, amountColumn string, filters map[string]string) listquery.Spec {
+	table := "construction_" + kind + "expense"
+	prefix := kind + "_expense_"
+	if filters == nil {
+		filters = map[string]string{}
+	}
+	filters["project_id"] = prefix + "project_id"
+	return listquery.Spec{
+		Table:           table,
+		Sorts:           map[string]string{"date": prefix + "date", "amount": amountColumn, "created_at": prefix + "created_at"},
+		DefaultSort:     "-date",
+		Filters:         filters,
+		DateColumn:      prefix + "date",
+		DefaultPageSize: 200,
+	}
+}
+
+// expenseQuery starts a query on the kind expense table limited to the
+// projects the caller may access.
+func (h *Handler) expenseQuery(c *gin.Context, kind string) (*gorm.DB, bool) {
+	scope, err := h.projects.Scope(c.Request.Context(), currentUser(c))
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Error checking access")
+		return nil, false
+	}
+	projectIDs := h.dbFor(c).Model(&model.Project{}).Select("construction_project.id").Scopes(scope)
+	return h.dbFor(c).Where(kind+"_expense_project_id IN (?)", projectIDs), true
+}
+
 // ListLaborWorkTypes returns all labor work types.
 func (h *Handler) ListLaborWorkTypes(c *gin.Context) {
-	var workTypes []model.LaborWorkType
-	if err := h.db.Order("labor_work_type_name asc").Find(&workTypes).Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load labor work types")
-		return
-	}
-	responses.JSON(c, http.StatusOK, true, workTypes, "Labor work types loaded")
+	h.serveReference(c, "labor-work-types", []string{refdata.LaborWorkTypes}, "Labor work types loaded", "Failed to load labor work types", func() (interface{}, error) {
+		var workTypes []model.LaborWorkType
+		err := h.dbFor(c).Order("labor_work_type_name asc").Find(&workTypes).Error
+		return workTypes, err
+	})
 }
 
-// ListManpowerExpenses returns manpower expenses.
+var manpowerExpenseListSpec = expenseListSpec("manpower", "manpower_expense_total_amount", map[string]string{"work_type_id": "manpower_expense_work_type_id"})
+
+var manpowerExpenseColumns = []column[model.ManpowerExpense]{
+	{"Date", func(e model.ManpowerExpense) interface{} { return e.ManpowerExpenseDate }},
+	{"Project", func(e model.ManpowerExpense) interface{} { return e.Project.ProjectName }},
+	{"Work type", func(e model.ManpowerExpense) interface{} {
+		if e.WorkType == nil {
+			return nil
+		}
+		return e.WorkType.LaborWorkTypeName
+	}},
+	{"People", func(e model.ManpowerExpense) interface{} { return e.ManpowerExpenseNumberOfPeople }},
+	{"Cost per person", func(e model.ManpowerExpense) interface{} { return e.ManpowerExpensePerPersonCost }},
+	{"Total amount", func(e model.ManpowerExpense) interface{} { return e.ManpowerExpenseTotalAmount }},
+	{"Description", func(e model.ManpowerExpense) interface{} { return e.ManpowerExpenseDescription }},
+}
+
+// ListManpowerExpenses returns manpower expenses, or exports them as a spreadsheet.
 func (h *Handler) ListManpowerExpenses(c *gin.Context) {
+	query, ok := h.expenseQuery(c, "manpower")
+	if !ok {
+		return
+	}
+	query = query.Preload("Project").Preload("WorkType")
+	if exporting(c) {
+		exportList(c, manpowerExpenseListSpec, query, "manpower-expenses", manpowerExpenseColumns)
+		return
+	}
 	var expenses []model.ManpowerExpense
-	if err := h.db.Preload("Project").Preload("WorkType").Order("manpower_expense_date desc").Find(&expenses).Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load manpower expenses")
+	page, ok := findPage(c, manpowerExpenseListSpec, query, &expenses, "Failed to load manpower expenses")
+	if !ok {
 		return
 	}
-	responses.JSON(c, http.StatusOK, true, expenses, "Manpower expenses loaded")
+	responses.Page(c, http.StatusOK, expenses, "Manpower expenses loaded", page)
 }
 
-// ListMaterialExpenses returns material expenses.
+var materialExpenseListSpec = expenseListSpec("material", "material_expense_total_amount", map[string]string{"item_id": "material_expense_item_id"})
+
+var materialExpenseColumns = []column[model.MaterialExpense]{
+	{"Date", func(e model.MaterialExpense) interface{} { return e.MaterialExpenseDate }},
+	{"Project", func(e model.MaterialExpense) interface{} { return e.Project.ProjectName }},
+	{"Item", func(e model.MaterialExpense) interface{} {
+		if e.MaterialExpenseCustomItemName != "" {
+			return e.MaterialExpenseCustomItemName
+		}
+		if e.MaterialExpenseItem.MaterialItemDisplayName != "" {
+			return e.MaterialExpenseItem.MaterialItemDisplayName
+		}
+		return e.MaterialExpenseItem.MaterialItemName
+	}},
+	{"Quantity", func(e model.MaterialExpense) interface{} { return e.MaterialExpenseQuantity }},
+	{"Cost per unit", func(e model.MaterialExpense) interface{} { return e.MaterialExpensePerUnitCost }},
+	{"CGST", func(e model.MaterialExpense) interface{} { return e.MaterialExpenseCGST }},
+	{"SGST", func(e model.MaterialExpense) interface{} { return e.MaterialExpenseSGST }},
+	{"IGST", func(e model.MaterialExpense) interface{} { return e.MaterialExpenseIGST }},
+	{"Total tax", func(e model.MaterialExpense) interface{} { return e.MaterialExpenseTotalTax }},
+	{"Total amount", func(e model.MaterialExpense) interface{} { return e.MaterialExpenseTotalAmount }},
+	{"Description", func(e model.MaterialExpense) interface{} { return e.MaterialExpenseDescription }},
+}
+
+// ListMaterialExpenses returns material expenses, or exports them as a spreadsheet.
 func (h *Handler) ListMaterialExpenses(c *gin.Context) {
+	query, ok := h.expenseQuery(c, "material")
+	if !ok {
+		return
+	}
+	query = query.Preload("Project").Preload("MaterialExpenseItem")
+	if exporting(c) {
+		exportList(c, materialExpenseListSpec, query, "material-expenses", materialExpenseColumns)
+		return
+	}
 	var expenses []model.MaterialExpense
-	if err := h.db.Preload("Project").Preload("MaterialExpenseItem").Order("material_expense_date desc").Find(&expenses).Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load material expenses")
+	page, ok := findPage(c, materialExpenseListSpec, query, &expenses, "Failed to load material expenses")
+	if !ok {
 		return
 	}
-	responses.JSON(c, http.StatusOK, true, expenses, "Material expenses loaded")
+	responses.Page(c, http.StatusOK, expenses, "Material expenses loaded", page)
+}
+
+var generalExpenseListSpec = expenseListSpec("general", "general_expense_amount", nil)
+
+var generalExpenseColumns = []column[model.GeneralExpense]{
+	{"Date", func(e model.GeneralExpense) interface{} { return e.GeneralExpenseDate }},
+	{"Project", func(e model.GeneralExpense) interface{} { return e.Project.ProjectName }},
+	{"Type", func(e model.GeneralExpense) interface{} { return e.GeneralExpenseType }},
+	{"Amount", func(e model.GeneralExpense) interface{} { return e.GeneralExpenseAmount }},
+	{"Description", func(e model.GeneralExpense) interface{} { return e.GeneralExpenseDescription }},
 }
 
-// ListGeneralExpenses returns general expenses.
+// ListGeneralExpenses returns general expenses, or exports them as a spreadsheet.
 func (h *Handler) ListGeneralExpenses(c *gin.Context) {
+	query, ok := h.expenseQuery(c, "general")
+	if !ok {
+		return
+	}
+	query = query.Preload("Project")
+	if exporting(c) {
+		exportList(c, generalExpenseListSpec, query, "general-expenses", generalExpenseColumns)
+		return
+	}
 	var expenses []model.GeneralExpense
-	if err := h.db.Preload("Project").Order("general_expense_date desc").Find(&expenses).Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load general expenses")
+	page, ok := findPage(c, generalExpenseListSpec, query, &expenses, "Failed to load general expenses")
+	if !ok {
 		return
 	}
-	responses.JSON(c, http.StatusOK, true, expenses, "General expenses loaded")
+	responses.Page(c, http.StatusOK, expenses, "General expenses loaded", page)
+}
+
+var departmentalExpenseListSpec = expenseListSpec("departmental", "departmental_expense_amount", nil)
+
+var departmentalExpenseColumns = []column[model.DepartmentalExpense]{
+	{"Date", func(e model.DepartmentalExpense) interface{} { return e.DepartmentalExpenseDate }},
+	{"Project", func(e model.DepartmentalExpense) interface{} { return e.Project.ProjectName }},
+	{"Amount", func(e model.DepartmentalExpense) interface{} { return e.DepartmentalExpenseAmount }},
+	{"Description", func(e model.DepartmentalExpense) interface{} { return e.DepartmentalExpenseDescription }},
 }
 
-// ListDepartmentalExpenses returns departmental expenses.
+// ListDepartmentalExpenses returns departmental expenses, or exports them as a spreadsheet.
 func (h *Handler) ListDepartmentalExpenses(c *gin.Context) {
+	query, ok := h.expenseQuery(c, "departmental")
+	if !ok {
+		return
+	}
+	query = query.Preload("Project")
+	if exporting(c) {
+		exportList(c, departmentalExpenseListSpec, query, "departmental-expenses", departmentalExpenseColumns)
+		return
+	}
 	var expenses []model.DepartmentalExpense
-	if err := h.db.Preload("Project").Order("departmental_expense_date desc").Find(&expenses).Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load departmental expenses")
+	page, ok := findPage(c, departmentalExpenseListSpec, query, &expenses, "Failed to load departmental expenses")
+	if !ok {
 		return
 	}
-	responses.JSON(c, http.StatusOK, true, expenses, "Departmental expenses loaded")
+	responses.Page(c, http.StatusOK, expenses, "Departmental expenses loaded", page)
 }
 
-// ListAdministrationExpenses returns administration expenses.
+var administrationExpenseListSpec = expenseListSpec("administration", "administration_expense_amount", nil)
+
+var administrationExpenseColumns = []column[model.AdministrationExpense]{
+	{"Date", func(e model.AdministrationExpense) interface{} { return e.AdministrationExpenseDate }},
+	{"Project", func(e model.AdministrationExpense) interface{} { return e.Project.ProjectName }},
+	{"Amount", func(e model.AdministrationExpense) interface{} { return e.AdministrationExpenseAmount }},
+	{"Description", func(e model.AdministrationExpense) interface{} { return e.AdministrationExpenseDescription }},
+}
+
+// ListAdministrationExpenses returns administration expenses, or exports them as a spreadsheet.
 func (h *Handler) ListAdministrationExpenses(c *gin.Context) {
+	query, ok := h.expenseQuery(c, "administration")
+	if !ok {
+		return
+	}
+	query = query.Preload("Project")
+	if exporting(c) {
+		exportList(c, administrationExpenseListSpec, query, "administration-expenses", administrationExpenseColumns)
+		return
+	}
 	var expenses []model.AdministrationExpense
-	if err := h.db.Preload("Project").Order("administration_expense_date desc").Find(&expenses).Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load administration expenses")
+	page, ok := findPage(c, administrationExpenseListSpec, query, &expenses, "Failed to load administration expenses")
+	if !ok {
 		return
 	}
-	responses.JSON(c, http.StatusOK, true, expenses, "Administration expenses loaded")
+	responses.Page(c, http.StatusOK, expenses, "Administration expenses loaded", page)
 }
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"cms_sidecar_backend/internal/model"
+	"cms_sidecar_backend/internal/responses"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	idempotencyHeader    = "Idempotency-Key"
+	idempotencyTTL       = 24 * time.Hour
+	idempotencyKeyMaxLen = 255
+)
+
+// idempotencyRecorder keeps a copy of everything the handler writes so the
+// response can be stored next to its key.
+type idempotencyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// requestFingerprint hashes what makes a retry "the same request".
+func requestFingerprint(method, path string, body []byte) string {
+	sum := sha256.New()
+	sum.Write([]byte(method + " " + path + "\n"))
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// Idempotent makes a create endpoint safe to retry. Requests without an
+// Idempotency-Key header pass straight through. The first request with a key
+// reserves it before the handler runs; a retry with the same key and payload
+// replays the stored response, a different payload is rejected with 422 and
+// a retry that overlaps the original gets 409 until the original finishes.
+// Only successful responses are kept; anything else releases the key so the
+// client can fix the request or try again.
+func (h *Handler) Idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(idempotencyHeader))
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > idempotencyKeyMaxLen {
+			responses.JSON(c, http.StatusBadRequest, false, nil, "Idempotency-Key is too long")
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			responses.JSON(c, http.StatusBadRequest, false, nil, "Could not read request body")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID := currentUser(c)
+		scope := c.Request.Method + " " + c.FullPath()
+		now := time.Now()
+		entry := model.IdempotencyKey{
+			Key:         key,
+			Scope:       scope,
+			UserID:      userID,
+			Fingerprint: requestFingerprint(c.Request.Method, c.FullPath(), body),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(idempotencyTTL),
+		}
+
+		stored, err := h.reserveIdempotencyKey(&entry, now)
+		if err != nil {
+			responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to check Idempotency-Key")
+			c.Abort()
+			return
+		}
+		if stored != nil {
+			h.replayIdempotentResponse(c, stored, entry.Fingerprint)
+			return
+		}
+
+		recorder := &idempotencyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		completed := false
+		defer func() {
+			// A panic or failed request leaves nothing worth replaying.
+			if !completed {
+				h.db.Delete(&model.IdempotencyKey{}, entry.ID)
+			}
+		}()
+
+		c.Next()
+
+		if recorder.Status() >= http.StatusMultipleChoices {
+			return
+		}
+		completed = true
+		h.db.Model(&model.IdempotencyKey{}).Where("id = ?", entry.ID).Updates(map[string]interface{}{
+			"completed":    true,
+			"status_code":  recorder.Status(),
+			"content_type": recorder.Header().Get("Content-Type"),
+			"response":     recorder.body.Bytes(),
+		})
+	}
+}
+
+// reserveIdempotencyKey inserts entry as a pending row. When the key is
+// already taken it returns the stored row instead.
+func (h *Handler) reserveIdempotencyKey(entry *model.IdempotencyKey, now time.Time) (*model.IdempotencyKey, error) {
+	if err := h.db.Where("expires_at < ?", now).Delete(&model.IdempotencyKey{}).Error; err != nil {
+		return nil, err
+	}
+
+	// Two passes: the first holder may release the key between our insert and our read.
+	for attempt := 0; attempt < 2; attempt++ {
+		result := h.db.Clauses(clause.OnConflict{DoNothing: true}).Create(entry)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return nil, nil
+		}
+		entry.ID = 0
+
+		var stored model.IdempotencyKey
+		err := h.db.Where("idempotency_key = ? AND scope = ? AND user_id = ?", entry.Key, entry.Scope, entry.UserID).First(&stored).Error
+		if err == nil {
+			return &stored, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	return nil, errors.New("idempotency key is contended")
+}
+
+// replayIdempotentResponse answers a repeated key from the stored row.
+func (h *Handler) replayIdempotentResponse(c *gin.Context, stored *model.IdempotencyKey, fingerprint string) {
+	defer c.Abort()
+
+	switch {
+	case stored.Fingerprint != fingerprint:
+		responses.JSON(c, http.StatusUnprocessableEntity, false, nil, "Idempotency-Key was already used with a different request")
+	case !stored.Completed:
+		responses.JSON(c, http.StatusConflict, false, nil, "A request with this Idempotency-Key is still being processed")
+	default:
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(stored.StatusCode, stored.ContentType, stored.Response)
+	}
+}
@@ -3,8 +3,8 @@ package handlers
 import (
 	"net/http"
 
-	"github.com/gin-gonic/gin"
 	"cms_sidecar_backend/internal/responses"
+	"github.com/gin-gonic/gin"
 )
 
 // IndexView mirrors the index page logic.
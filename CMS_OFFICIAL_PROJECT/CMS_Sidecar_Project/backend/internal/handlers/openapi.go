@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"cms_sidecar_backend/internal/model"
+	"cms_sidecar_backend/internal/openapi"
+	"cms_sidecar_backend/internal/responses"
+	"cms_sidecar_backend/internal/search"
+	attendanceUtils "cms_sidecar_backend/internal/utilities/attendance_page_app"
+	authUtils "cms_sidecar_backend/internal/utilities/auth_page_app"
+	crmUtils "cms_sidecar_backend/internal/utilities/crm_page_app"
+	directoryUtils "cms_sidecar_backend/internal/utilities/directory_page_app"
+	fileUtils "cms_sidecar_backend/internal/utilities/files_page_app"
+	orgUtils "cms_sidecar_backend/internal/utilities/organization_page_app"
+	paymentUtils "cms_sidecar_backend/internal/utilities/payments_page_app"
+	profileUtils "cms_sidecar_backend/internal/utilities/profile_page_app"
+	projectUtils "cms_sidecar_backend/internal/utilities/projects_page_app"
+	salesUtils "cms_sidecar_backend/internal/utilities/sales_page_app"
+	stockUtils "cms_sidecar_backend/internal/utilities/stock_management_page_app"
+	supUtils "cms_sidecar_backend/internal/utilities/supervisor_page_app"
+	vendorUtils "cms_sidecar_backend/internal/utilities/vendor_page_app"
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPI documents every route in Routes that has a Spec. v1 operations
+// are marked deprecated.
+func (h *Handler) OpenAPI() openapi.Document {
+	specs := h.apiSpecs()
+	var endpoints []openapi.Endpoint
+	for _, route := range h.Routes() {
+		spec, ok := specs[route.handlerName()]
+		if !ok {
+			continue
+		}
+		endpoints = append(endpoints, openapi.Endpoint{
+			Method:     route.Method,
+			Path:       route.Path,
+			Deprecated: strings.HasPrefix(route.Path, "/api/v1/"),
+			Spec:       spec,
+		})
+	}
+	return openapi.Build(openapi.Info{
+		Title:       "CMS API",
+		Version:     "2",
+		Description: "Generated from the route table. /api/v1 is deprecated in favour of /api/v2.",
+	}, endpoints)
+}
+
+// Undocumented lists the registered routes missing from OpenAPI.
+func (h *Handler) Undocumented() []string {
+	doc := h.OpenAPI()
+	var missing []string
+	for _, route := range h.Routes() {
+		if !doc.Has(route.Method, route.Path) {
+			missing = append(missing, route.Method+" "+route.Path)
+		}
+	}
+	return missing
+}
+
+// OpenAPISpec serves the generated document, built once per process.
+func (h *Handler) OpenAPISpec(c *gin.Context) {
+	h.specOnce.Do(func() {
+		h.spec, h.specErr = json.Marshal(h.OpenAPI())
+	})
+	if h.specErr != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to build API document")
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", h.spec)
+}
+
+// APIDocs serves the bundled docs page for the document.
+func (h *Handler) APIDocs(c *gin.Context) {
+	c.Header("Content-Security-Policy", openapi.DocsPolicy)
+	c.Data(http.StatusOK, "text/html; charset=utf-8", openapi.DocsPage)
+}
+
+// registerMeta adds the routes that describe the API itself and the signed
+// file downloads, which carry their own authorization.
+func (h *Handler) registerMeta(api routeTable) {
+	api.GET("/openapi.json", h.OpenAPISpec)
+	api.GET("/docs", h.APIDocs)
+	api.GET("/files/*key", h.downloadFile)
+}
+
+// apiSpecs documents each handler once; v1 and v2 routes serving the same
+// handler share its Spec. Response maps mirror the gin.H the handler writes.
+func (h *Handler) apiSpecs() map[string]openapi.Spec {
+	specs := make(map[string]openapi.Spec)
+	doc := func(handler gin.HandlerFunc, spec openapi.Spec) {
+		specs[Route{Handlers: []gin.HandlerFunc{handler}}.handlerName()] = spec
+	}
+	ifMatch := []string{"If-Match"}
+	idempotent := []string{"Idempotency-Key"}
+	customerRow := gin.H{"id": uint(0), "customer_code": "", "name": "", "phone": "", "email": ""}
+	partnerRow := gin.H{"id": uint(0), "channel_partner_code": "", "name": "", "phone": "", "email": "", "city": ""}
+	unitsMap := map[string][]map[string]interface{}{}
+
+	// API description
+	doc(h.OpenAPISpec, openapi.Spec{Summary: "This OpenAPI document", Bare: true})
+	doc(h.APIDocs, openapi.Spec{Summary: "Interactive API docs", Bare: true, ContentType: "text/html", Response: ""})
+	doc(h.downloadFile, openapi.Spec{Summary: "Download a file through a signed link", Query: []string{"expires", "signature"}, Bare: true, ContentType: "application/octet-stream", Response: ""})
+
+	// Pages
+	doc(h.IndexView, openapi.Spec{Summary: "Landing page state", Response: gin.H{"page": "", "redirect": "", "message": ""}})
+	doc(h.SearchAPI, openapi.Spec{Summary: "Ranked search across customers, units, vendors, channel partners and projects", Query: []string{"q", "types", "limit"}, Response: search.Response{}})
+	doc(h.DashboardView, openapi.Spec{Summary: "Dashboard status cards and totals", Response: gin.H{
+		"display_name":   "",
+		"status_cards":   []gin.H{{"status": "", "key": "", "label": "", "description": "", "icon": "", "count": 0}},
+		"status_counts":  map[string]int{},
+		"total_projects": 0,
+		"totals":         projectUtils.SummaryTotals{},
+	}})
+	doc(h.InsightsView, openapi.Spec{Summary: "Insights module", Response: gin.H{"message": ""}})
+	doc(h.TrackFinancesView, openapi.Spec{Summary: "Per-project finances", Response: gin.H{
+		"message":  "",
+		"projects": []gin.H{{"project_id": uint(0), "project_name": "", "project_code": "", "summary": model.ProjectSummary{}, "total_expense": float64(0)}},
+		"totals":   projectUtils.SummaryTotals{},
+	}})
+	doc(h.listMaterialItems, openapi.Spec{Summary: "Material items", Response: []model.MaterialItem{}})
+
+	// Auth
+	account := gin.H{"user_id": uint(0), "username": "", "role": ""}
+	session := gin.H{"user_id": uint(0), "username": "", "role": "", "token": "", "expires_at": time.Time{}}
+	doc(h.LoginView, openapi.Spec{Summary: "Log in and get a bearer token", Request: authUtils.LoginRequest{}, Response: session})
+	doc(h.RegisterView, openapi.Spec{Summary: "Create an account", Request: authUtils.RegisterRequest{}, Response: account})
+	doc(h.LogoutView, openapi.Spec{Summary: "End the current session"})
+	doc(h.AcceptInviteView, openapi.Spec{Summary: "Create the invited account and log in", Request: authUtils.AcceptInviteRequest{}, Response: session, Status: http.StatusCreated})
+
+	// Sessions
+	doc(h.listSessions, openapi.Spec{Summary: "The caller's signed-in devices", Response: gin.H{"sessions": []authUtils.SessionResponse{}}})
+	doc(h.revokeSessions, openapi.Spec{Summary: "Sign out everywhere; ?keep_current=true keeps this session", Query: []string{"keep_current"}, Response: gin.H{"revoked": 0}})
+	doc(h.revokeSession, openapi.Spec{Summary: "Sign out one device", Status: http.StatusNoContent})
+
+	// Profile
+	doc(h.ProfileView, openapi.Spec{Summary: "Current user's profile and totals", Response: gin.H{
+		"profile":              model.Profile{},
+		"display_name":         "",
+		"avatar_letter":        "",
+		"avatar_url":           "",
+		"account_type_display": "",
+		"user_role":            "",
+		"theme_preference":     "",
+		"project_count":        0,
+		"status_counts":        map[string]int{},
+		"total_expenses":       float64(0),
+		"total_payments":       float64(0),
+		"total_budget":         float64(0),
+		"manpower_total":       float64(0),
+		"material_total":       float64(0),
+		"phone_country_code":   "",
+		"phone_local":          "",
+		"email":                "",
+	}})
+	doc(h.UpdateProfileView, openapi.Spec{Summary: "Update the current user's profile", Request: profileUtils.UpdateProfileRequest{}, Response: model.Profile{}})
+	doc(h.getAvatar, openapi.Spec{Summary: "The current user's avatar, with download links", Response: fileUtils.FileResponse{}})
+	doc(h.uploadAvatar, openapi.Spec{Summary: "Upload a JPEG or PNG avatar, up to 2 MB", Upload: "file", Response: fileUtils.FileResponse{}, Status: http.StatusCreated})
+
+	// Projects
+	doc(h.ListProjectsAPI, openapi.Spec{Summary: "Accessible projects with blocks and units", Response: []model.Project{}})
+	doc(h.CreateProjectAPI, openapi.Spec{Summary: "Create a project", Request: projectUtils.CreateProjectRequest{}, Response: model.Project{}, Status: http.StatusCreated})
+	doc(h.ProjectDetailAPI, openapi.Spec{Summary: "One project with its ETag", Response: model.Project{}})
+	doc(h.UpdateProjectAPI, openapi.Spec{Summary: "Update a project", Request: projectUtils.UpdateProjectRequest{}, Response: model.Project{}, Headers: ifMatch})
+	doc(h.DeleteProjectAPI, openapi.Spec{Summary: "Delete a project; ?cascade=true removes blocking records", Query: []string{"cascade"}, Status: http.StatusNoContent})
+	doc(h.ProjectDeletionPlanAPI, openapi.Spec{Summary: "Records a project delete would remove", Response: projectUtils.DeletionPlan{}})
+	doc(h.StockBalancesAPI, openapi.Spec{Summary: "Material balances of a project", Query: []string{"project_id"}, Response: gin.H{"stock": []stockUtils.StockItem{}}})
+	doc(h.UpdateStockAPI, openapi.Spec{Summary: "Set the allocation and usage of one material", Query: []string{"project_id"}, Request: stockUtils.UpdateStockRequest{}, Response: model.StockBalance{}})
+
+	// Multi-flat sales
+	doc(h.MultiFlatProjectsAPI, openapi.Spec{Summary: "Multi-flat projects with unit counts", Query: []string{"type"}, Response: gin.H{"projects": []projectUtils.MultiFlatProjectStats{}}})
+	doc(h.MultiFlatProjectGridAPI, openapi.Spec{Summary: "Floor-by-floor unit grid of a project", Response: gin.H{
+		"project": model.Project{},
+		"blocks":  []gin.H{{"id": uint(0), "project_block_name": "", "floor_count_data": []gin.H{{"floor": 0, "units": []gin.H{{"id": uint(0), "unit_number": 0, "unit_label": "", "unit_status": ""}}}}}},
+		"totals":  map[string]int{},
+	}})
+	doc(h.MultiFlatPresetsAPI, openapi.Spec{Summary: "BHK, facing and area options of a project", Response: model.ProjectPreset{}})
+	doc(h.UpdateMultiFlatPresetsAPI, openapi.Spec{Summary: "Replace the BHK, facing and area options", Request: model.ProjectPreset{}, Response: model.ProjectPreset{}})
+	doc(h.CreateMultiFlatBlockAPI, openapi.Spec{Summary: "Create a block and its units", Request: salesUtils.CreateBlockRequest{}, Response: gin.H{"block": model.ProjectBlock{}, "created_units": 0}, Status: http.StatusCreated})
+	doc(h.MultiFlatBlockAPI, openapi.Spec{Summary: "One block with its units and ETag", Response: model.ProjectBlock{}})
+	doc(h.UpdateMultiFlatBlockAPI, openapi.Spec{Summary: "Update a block; sizes only grow", Request: salesUtils.UpdateBlockRequest{}, Response: gin.H{"block": model.ProjectBlock{}, "created_units": 0}, Headers: ifMatch})
+	doc(h.DeleteMultiFlatBlockAPI, openapi.Spec{Summary: "Delete a block; ?cascade=true removes blocking records", Query: []string{"cascade"}, Response: projectUtils.DeletionPlan{}})
+	doc(h.MultiFlatBlockDeletionPlanAPI, openapi.Spec{Summary: "Records a block delete would remove", Response: projectUtils.DeletionPlan{}})
+	doc(h.MultiFlatCRMUnitsAPI, openapi.Spec{Summary: "Units for the CRM, filtered by status and search", Query: []string{"status", "search"}, List: &crmUnitListSpec, Export: true, Response: gin.H{"units": []salesUtils.UnitResponse{}}})
+	doc(h.MultiFlatUnitAPI, openapi.Spec{Summary: "One unit with its ETag", Response: model.ProjectUnit{}})
+	doc(h.UpdateMultiFlatUnitAPI, openapi.Spec{Summary: "Update a unit's status and buyer", Request: salesUtils.UpdateUnitRequest{}, Response: model.ProjectUnit{}, Headers: ifMatch})
+
+	// Customers and channel partners
+	doc(h.listCustomers, openapi.Spec{Summary: "Customers", Query: []string{"search"}, List: &customersListSpec, Export: true, Response: []model.Customer{}})
+	doc(h.listChannelPartners, openapi.Spec{Summary: "Channel partners", Query: []string{"search"}, List: &channelPartnersListSpec, Export: true, Response: []model.ChannelPartner{}})
+	doc(h.CRMCustomers, openapi.Spec{Summary: "Customers for the CRM picker", Query: []string{"search"}, List: &crmCustomerListSpec, Export: true, Response: gin.H{"customers": []gin.H{customerRow}}})
+	doc(h.CRMChannelPartners, openapi.Spec{Summary: "Channel partners for the CRM picker", Query: []string{"search"}, List: &crmChannelPartnerListSpec, Export: true, Response: gin.H{"channel_partners": []gin.H{partnerRow}}})
+	doc(h.CreateCustomerAPI, openapi.Spec{Summary: "Create a customer", Request: crmUtils.CreateCustomerRequest{}, Response: gin.H{"customer": customerRow}, Status: http.StatusCreated})
+	doc(h.CreateChannelPartnerAPI, openapi.Spec{Summary: "Create a channel partner", Request: crmUtils.CreateChannelPartnerRequest{}, Response: gin.H{"channel_partner": partnerRow}, Status: http.StatusCreated})
+
+	// CRM
+	doc(h.CRMProjectsList, openapi.Spec{Summary: "Projects for the CRM", Response: gin.H{"projects": []model.Project{}}})
+	doc(h.KanbanBoardAPI, openapi.Spec{Summary: "Kanban columns of a project's units", Query: []string{"project_id"}, Response: gin.H{
+		"project": gin.H{"id": uint(0), "project_name": "", "project_code": "", "project_flat_configuration": ""},
+		"summary": model.ProjectSummary{},
+		"columns": []gin.H{{"key": "", "label": "", "hint": "", "count": 0, "cards": []gin.H{{
+			"id": uint(0), "name": "", "phone": "", "email": "", "block": "", "unit_label": "", "status": "",
+			"stage": "", "price": "", "paid": nil, "notes": "", "reference": "", "can_drag": false,
+		}}}},
+	}})
+	doc(h.KanbanUpdateStageAPI, openapi.Spec{Summary: "Move a unit to another CRM stage", Request: crmUtils.UpdateStageRequest{}, Response: gin.H{"stage": ""}, Headers: ifMatch})
+	doc(h.CRMKanbanUpdateAPI, openapi.Spec{Summary: "Move a unit to another CRM stage", Request: crmUtils.UpdateStageRequest{}, Response: model.ProjectUnit{}, Headers: ifMatch})
+
+	// Vendors, supervisors and directory
+	doc(h.ListVendorsAPI, openapi.Spec{Summary: "The organization's vendors", Export: true, Response: gin.H{"vendors": []vendorUtils.VendorResponse{}}})
+	doc(h.CreateVendorAPI, openapi.Spec{Summary: "Create a vendor", Request: vendorUtils.CreateVendorRequest{}, Response: gin.H{"vendor": vendorUtils.VendorResponse{}}, Status: http.StatusCreated})
+	doc(h.VendorDetailAPI, openapi.Spec{Summary: "One vendor with its ETag", Response: gin.H{"vendor": vendorUtils.VendorResponse{}}})
+	doc(h.UpdateVendorAPI, openapi.Spec{Summary: "Update a vendor", Request: vendorUtils.CreateVendorRequest{}, Response: gin.H{"vendor": vendorUtils.VendorResponse{}}, Headers: ifMatch})
+	doc(h.DeleteVendorAPI, openapi.Spec{Summary: "Delete a vendor", Status: http.StatusNoContent})
+	doc(h.VendorChoicesAPI, openapi.Spec{Summary: "Vendor names for dropdowns", Response: gin.H{"vendors": []vendorUtils.VendorChoice{}}})
+	doc(h.ListSupervisorsAPI, openapi.Spec{Summary: "The organization's supervisors", Response: gin.H{"supervisors": []supUtils.SupervisorResponse{}}})
+	doc(h.CreateSupervisorAPI, openapi.Spec{Summary: "Create a supervisor", Request: supUtils.CreateSupervisorRequest{}, Response: gin.H{"supervisor": supUtils.SupervisorResponse{}}, Status: http.StatusCreated})
+	doc(h.SupervisorDetailAPI, openapi.Spec{Summary: "One supervisor with its ETag", Response: gin.H{"supervisor": supUtils.SupervisorResponse{}}})
+	doc(h.UpdateSupervisorAPI, openapi.Spec{Summary: "Update a supervisor and their projects", Request: supUtils.UpdateSupervisorRequest{}, Response: gin.H{"supervisor": supUtils.SupervisorResponse{}}, Headers: ifMatch})
+	doc(h.DeleteSupervisorAPI, openapi.Spec{Summary: "Delete a supervisor", Status: http.StatusNoContent})
+	doc(h.VendorListAPI, openapi.Spec{Summary: "Vendors for the directory", Response: []directoryUtils.VendorListEntry{}})
+	doc(h.RegenerateCredentialsAPI, openapi.Spec{Summary: "Reset a customer's or vendor's login", Request: directoryUtils.RegenerateCredentialsRequest{}, Response: directoryUtils.Credentials{}})
+
+	// Organization
+	doc(h.getOrganization, openapi.Spec{Summary: "The caller's organization and its members", Response: gin.H{"organization": orgUtils.OrganizationResponse{}}})
+	doc(h.updateOrganization, openapi.Spec{Summary: "Rename the organization", Request: orgUtils.UpdateOrganizationRequest{}, Response: gin.H{"organization": orgUtils.OrganizationResponse{}}})
+	doc(h.addOrganizationMember, openapi.Spec{Summary: "Add a user to the organization", Request: orgUtils.AddMemberRequest{}, Response: gin.H{"member": orgUtils.MemberResponse{}}, Status: http.StatusCreated})
+	doc(h.updateOrganizationMember, openapi.Spec{Summary: "Change a member's role", Request: orgUtils.UpdateMemberRequest{}, Response: gin.H{"member": orgUtils.MemberResponse{}}})
+	doc(h.removeOrganizationMember, openapi.Spec{Summary: "Remove a member from the organization", Status: http.StatusNoContent})
+	doc(h.listUsers, openapi.Spec{Summary: "Members' accounts (owners only)", Query: []string{"active"}, Response: gin.H{"users": []orgUtils.UserResponse{}}})
+	doc(h.updateUser, openapi.Spec{Summary: "Change a member's user type", Request: orgUtils.UpdateUserRequest{}, Response: gin.H{"user": orgUtils.UserResponse{}}})
+	doc(h.deactivateUser, openapi.Spec{Summary: "Deactivate a member and revoke their sessions", Response: gin.H{"user": orgUtils.UserResponse{}}})
+	doc(h.reactivateUser, openapi.Spec{Summary: "Reactivate a member", Response: gin.H{"user": orgUtils.UserResponse{}}})
+	doc(h.listUserSessions, openapi.Spec{Summary: "A member's signed-in devices", Response: gin.H{"sessions": []authUtils.SessionResponse{}}})
+	doc(h.signOutUser, openapi.Spec{Summary: "Sign a member out on every device", Response: gin.H{"revoked": 0}})
+	doc(h.signOutUserSession, openapi.Spec{Summary: "Sign a member's device out", Status: http.StatusNoContent})
+	doc(h.listAPIKeys, openapi.Spec{Summary: "The organization's API keys", Response: gin.H{"api_keys": []orgUtils.APIKeyResponse{}}})
+	doc(h.createAPIKey, openapi.Spec{Summary: "Issue a scoped API key", Request: orgUtils.CreateAPIKeyRequest{}, Response: gin.H{"api_key": orgUtils.APIKeyResponse{}}, Status: http.StatusCreated})
+	doc(h.revokeAPIKey, openapi.Spec{Summary: "Revoke an API key", Status: http.StatusNoContent})
+	doc(h.getLetterhead, openapi.Spec{Summary: "What the organization prints on receipts", Response: gin.H{"letterhead": orgUtils.LetterheadResponse{}}})
+	doc(h.updateLetterhead, openapi.Spec{Summary: "Replace the letterhead's text (owners and admins)", Request: orgUtils.LetterheadRequest{}, Response: gin.H{"letterhead": orgUtils.LetterheadResponse{}}})
+	doc(h.uploadLetterheadLogo, openapi.Spec{Summary: "Upload a JPEG or PNG letterhead logo, up to 1 MB (owners and admins)", Upload: "file", Response: gin.H{"letterhead": orgUtils.LetterheadResponse{}}, Status: http.StatusCreated})
+	doc(h.listAuditEntries, openapi.Spec{Summary: "The organization's audit log (owners only)", List: &auditListSpec, Export: true, Response: []orgUtils.AuditEntryResponse{}})
+	doc(h.listInvites, openapi.Spec{Summary: "The organization's invites", Response: gin.H{"invites": []orgUtils.InviteResponse{}}})
+	doc(h.createInvite, openapi.Spec{Summary: "Invite someone by email or phone", Request: orgUtils.InviteRequest{}, Response: gin.H{"invite": orgUtils.InviteResponse{}}, Status: http.StatusCreated})
+	doc(h.revokeInvite, openapi.Spec{Summary: "Withdraw a pending invite", Status: http.StatusNoContent})
+
+	// Payments
+	doc(h.PaymentsProjectsList, openapi.Spec{Summary: "Projects for the payment forms", Response: []gin.H{{"id": uint(0), "name": "", "code": "", "conf": ""}}})
+	doc(h.PaymentChoicesAPI, openapi.Spec{Summary: "Payment type, stage and method labels", Response: gin.H{
+		"payment_types":   paymentUtils.PaymentTypes,
+		"payment_stages":  paymentUtils.UnitPaymentStages,
+		"payment_methods": paymentUtils.UnitPaymentMethods,
+	}})
+	doc(h.ListProjectPaymentsAPI, openapi.Spec{Summary: "Project payments", List: &projectPaymentListSpec, Export: true, Response: []model.ProjectPayment{}})
+	doc(h.CreateProjectPaymentAPI, openapi.Spec{Summary: "Record a project payment", Request: paymentUtils.CreateProjectPaymentRequest{}, Response: model.ProjectPayment{}, Headers: idempotent})
+	doc(h.ListFlatPaymentsAPI, openapi.Spec{Summary: "Flat payments with the units of each project", List: &flatPaymentListSpec, Export: true, Response: gin.H{"payments": []model.FlatPayment{}, "units_map": unitsMap}})
+	doc(h.CreateFlatPaymentAPI, openapi.Spec{Summary: "Record a flat payment", Request: paymentUtils.CreateUnitPaymentRequest{}, Response: model.FlatPayment{}, Headers: idempotent})
+	doc(h.ListPlotPaymentsAPI, openapi.Spec{Summary: "Plot payments with the plots of each project", List: &plotPaymentListSpec, Export: true, Response: gin.H{"payments": []model.PlotPayment{}, "units_map": unitsMap}})
+	doc(h.CreatePlotPaymentAPI, openapi.Spec{Summary: "Record a plot payment", Request: paymentUtils.CreateUnitPaymentRequest{}, Response: model.PlotPayment{}, Headers: idempotent})
+	doc(h.getProjectPaymentDocument, openapi.Spec{Summary: "A project payment's document, with download links", Response: fileUtils.FileResponse{}})
+	doc(h.uploadProjectPaymentDocument, openapi.Spec{Summary: "Upload a project payment's document (PDF, JPEG or PNG)", Upload: "file", Response: fileUtils.FileResponse{}, Status: http.StatusCreated})
+	doc(h.getFlatPaymentReceipt, openapi.Spec{Summary: "A flat payment's receipt, with download links", Response: fileUtils.FileResponse{}})
+	doc(h.uploadFlatPaymentReceipt, openapi.Spec{Summary: "Upload a flat payment's receipt (PDF, JPEG or PNG)", Upload: "file", Response: fileUtils.FileResponse{}, Status: http.StatusCreated})
+	doc(h.getPlotPaymentReceipt, openapi.Spec{Summary: "A plot payment's receipt, with download links", Response: fileUtils.FileResponse{}})
+	doc(h.uploadPlotPaymentReceipt, openapi.Spec{Summary: "Upload a plot payment's receipt (PDF, JPEG or PNG)", Upload: "file", Response: fileUtils.FileResponse{}, Status: http.StatusCreated})
+	doc(h.getProjectPaymentIssuedReceipt, openapi.Spec{Summary: "The receipt issued for a project payment, with a link to its PDF", Response: gin.H{"receipt": paymentUtils.ReceiptResponse{}}})
+	doc(h.issueProjectPaymentReceipt, openapi.Spec{Summary: "Issue a numbered PDF receipt for a project payment; payer is required unless the project has a customer", Request: paymentUtils.IssueReceiptRequest{}, Response: gin.H{"receipt": paymentUtils.ReceiptResponse{}}, Status: http.StatusCreated})
+	doc(h.getFlatPaymentIssuedReceipt, openapi.Spec{Summary: "The receipt issued for a flat payment, with a link to its PDF", Response: gin.H{"receipt": paymentUtils.ReceiptResponse{}}})
+	doc(h.issueFlatPaymentReceipt, openapi.Spec{Summary: "Issue a numbered PDF receipt for a flat payment; payer defaults to the buyer", Request: paymentUtils.IssueReceiptRequest{}, Response: gin.H{"receipt": paymentUtils.ReceiptResponse{}}, Status: http.StatusCreated})
+	doc(h.getPlotPaymentIssuedReceipt, openapi.Spec{Summary: "The receipt issued for a plot payment, with a link to its PDF", Response: gin.H{"receipt": paymentUtils.ReceiptResponse{}}})
+	doc(h.issuePlotPaymentReceipt, openapi.Spec{Summary: "Issue a numbered PDF receipt for a plot payment; payer defaults to the buyer", Request: paymentUtils.IssueReceiptRequest{}, Response: gin.H{"receipt": paymentUtils.ReceiptResponse{}}, Status: http.StatusCreated})
+
+	// Attendance
+	doc(h.getAttendanceStats, openapi.Spec{Summary: "Attendance counts per day, month, year and status", Query: []string{"from", "to", "batch", "project", "tz"}, Response: gin.H{
+		"stats":         map[string]int{},
+		"daily_chart":   []attendanceUtils.ChartEntry{},
+		"monthly_chart": []attendanceUtils.ChartEntry{},
+		"yearly_chart":  []attendanceUtils.ChartEntry{},
+		"status_chart":  []attendanceUtils.ChartEntry{},
+		"range":         attendanceUtils.StatsRange{},
+	}})
+	doc(h.getAttendanceChoices, openapi.Spec{Summary: "Attendance status and mode labels", Response: gin.H{
+		"statuses": attendanceUtils.AttendanceStatusMap,
+		"modes":    attendanceUtils.AttendanceModeMap,
+	}})
+	doc(h.listAttendanceRecords, openapi.Spec{Summary: "Attendance records", List: &attendanceRecordListSpec, Export: true, Response: []model.AttendanceRecord{}})
+	doc(h.createAttendanceRecords, openapi.Spec{Summary: "Mark attendance for batch members", Request: attendanceUtils.AttendanceRecordRequest{}, Headers: idempotent})
+	doc(h.listAttendanceBatches, openapi.Spec{Summary: "Attendance batches", Response: []model.AttendanceBatch{}})
+	doc(h.createAttendanceBatch, openapi.Spec{Summary: "Create or extend a batch", Request: attendanceUtils.AttendanceBatchRequest{}, Response: model.AttendanceBatch{}})
+	doc(h.listAttendanceMembers, openapi.Spec{Summary: "Members of a batch", Response: []model.AttendanceMember{}})
+
+	// Expenses
+	doc(h.ListLaborWorkTypes, openapi.Spec{Summary: "Labor work types", Response: []model.LaborWorkType{}})
+	doc(h.ListManpowerExpenses, openapi.Spec{Summary: "Manpower expenses", List: &manpowerExpenseListSpec, Export: true, Response: []model.ManpowerExpense{}})
+	doc(h.ListMaterialExpenses, openapi.Spec{Summary: "Material expenses", List: &materialExpenseListSpec, Export: true, Response: []model.MaterialExpense{}})
+	doc(h.ListGeneralExpenses, openapi.Spec{Summary: "General expenses", List: &generalExpenseListSpec, Export: true, Response: []model.GeneralExpense{}})
+	doc(h.ListDepartmentalExpenses, openapi.Spec{Summary: "Departmental expenses", List: &departmentalExpenseListSpec, Export: true, Response: []model.DepartmentalExpense{}})
+	doc(h.ListAdministrationExpenses, openapi.Spec{Summary: "Administration expenses", List: &administrationExpenseListSpec, Export: true, Response: []model.AdministrationExpense{}})
+
+	return specs
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"cms_sidecar_backend/internal/audit"
+	"cms_sidecar_backend/internal/model"
+	"cms_sidecar_backend/internal/responses"
+	"cms_sidecar_backend/internal/services"
+	apiKeyService "cms_sidecar_backend/internal/services/apikeys"
+	"cms_sidecar_backend/internal/tenant"
+	utils "cms_sidecar_backend/internal/utilities/organization_page_app"
+	"github.com/gin-gonic/gin"
+)
+
+// Context keys set by identify.
+const (
+	userKey         = "userID"
+	memberKey       = "member"
+	sessionKey      = "sessionID"
+	apiKeyKey       = "apiKeyID"
+	apiKeyScopesKey = "apiKeyScopes"
+)
+
+// anonymousHandlers serve callers who have not signed in: the sign-in
+// routes, logout and the landing page.
+func (h *Handler) anonymousHandlers() map[string]bool {
+	anonymous := make(map[string]bool)
+	for _, handler := range []gin.HandlerFunc{h.LoginView, h.RegisterView, h.AcceptInviteView, h.LogoutView, h.IndexView} {
+		anonymous[Route{Handlers: []gin.HandlerFunc{handler}}.handlerName()] = true
+	}
+	return anonymous
+}
+
+// identify returns, for each route, the middleware that resolves the caller
+// and their organization membership, and scopes the request context to that
+// organization so the tenant filter applies to every query run through
+// dbFor. "Authorization: Bearer <token>" carries a session token or an API
+// key. Requests without one get 401, except on the anonymous routes or when
+// CMS_DEV_USER_ID names a user for them to act as during development.
+// Deactivated accounts are refused.
+func (h *Handler) identify() func(Route) gin.HandlerFunc {
+	var devUserID uint
+	if id, err := strconv.ParseUint(os.Getenv("CMS_DEV_USER_ID"), 10, 64); err == nil && id > 0 {
+		devUserID = uint(id)
+	}
+	anonymousHandlers := h.anonymousHandlers()
+	return func(route Route) gin.HandlerFunc {
+		anonymous := anonymousHandlers[route.handlerName()]
+		return func(c *gin.Context) {
+			h.identifyCaller(c, devUserID, anonymous)
+		}
+	}
+}
+
+// identifyCaller is identify for one request. Anonymous callers are let
+// through with no user, organization or tenant scope.
+func (h *Handler) identifyCaller(c *gin.Context, devUserID uint, anonymous bool) {
+	ctx := c.Request.Context()
+	userID, key, ok := h.authenticate(c, devUserID)
+	if !ok {
+		c.Abort()
+		return
+	}
+	actor := audit.Actor{
+		IP:        c.ClientIP(),
+		RequestID: c.GetString(requestIDKey),
+		Route:     c.Request.Method + " " + c.FullPath(),
+	}
+	if userID == 0 {
+		if !anonymous {
+			c.Header("WWW-Authenticate", "Bearer")
+			responses.JSON(c, http.StatusUnauthorized, false, nil, "Authentication required")
+			c.Abort()
+			return
+		}
+		c.Request = c.Request.WithContext(audit.WithActor(ctx, actor))
+		c.Next()
+		return
+	}
+
+	var active []bool
+	if err := h.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", userID).Pluck("is_active", &active).Error; err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load account")
+		c.Abort()
+		return
+	}
+	if len(active) == 1 && !active[0] {
+		responses.JSON(c, http.StatusForbidden, false, nil, "This account has been deactivated")
+		c.Abort()
+		return
+	}
+
+	member, err := tenant.Resolve(h.db.WithContext(ctx), userID)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load organization")
+		c.Abort()
+		return
+	}
+	if key != nil && member.OrganizationID != key.OrganizationID {
+		responses.JSON(c, http.StatusUnauthorized, false, nil, "API key is invalid, expired or revoked")
+		c.Abort()
+		return
+	}
+	c.Set(userKey, userID)
+	c.Set(memberKey, member)
+	actor.OrganizationID = member.OrganizationID
+	actor.UserID = userID
+	actor.APIKeyID = c.GetUint(apiKeyKey)
+	actor.SessionID = c.GetUint(sessionKey)
+	ctx = tenant.WithOrganization(ctx, member.OrganizationID)
+	c.Request = c.Request.WithContext(audit.WithActor(ctx, actor))
+	c.Next()
+}
+
+// authenticate finds the caller: the creator of an API key, the owner of a
+// session (from the bearer token or the browser's session cookie) or,
+// without either, the development user (0 when none is set).
+// Cookie-authenticated writes must pass the CSRF check and API keys only
+// reach the /api/v2 routes their scopes cover. A refused token is answered
+// here and ok is false.
+func (h *Handler) authenticate(c *gin.Context, devUserID uint) (userID uint, key *model.APIKey, ok bool) {
+	ctx := c.Request.Context()
+	token := bearerToken(c)
+	fromCookie := false
+	if token == "" {
+		token = cookieToken(c)
+		fromCookie = token != ""
+	}
+	switch {
+	case token == "":
+		return devUserID, nil, true
+	case fromCookie && !checkCSRF(c, token):
+		return 0, nil, false
+	case !fromCookie && strings.HasPrefix(token, apiKeyService.TokenPrefix):
+		found, err := h.apiKeys.Authenticate(ctx, token, c.ClientIP())
+		if err != nil {
+			authFailure(c, err, "Failed to check API key")
+			return 0, nil, false
+		}
+		scope := routeScope(c)
+		if scope == "" {
+			responses.JSON(c, http.StatusForbidden, false, nil, "API keys cannot use this endpoint")
+			return 0, nil, false
+		}
+		if !apiKeyService.Allows(found, scope) {
+			responses.JSON(c, http.StatusForbidden, false, nil, "This API key does not have the "+scope+" scope")
+			return 0, nil, false
+		}
+		c.Set(apiKeyKey, found.ID)
+		c.Set(apiKeyScopesKey, apiKeyService.Scopes(found))
+		return found.CreatedByID, &found, true
+	default:
+		session, err := h.sessions.Authenticate(ctx, token, c.ClientIP())
+		if err != nil {
+			authFailure(c, err, "Failed to check session")
+			return 0, nil, false
+		}
+		c.Set(sessionKey, session.ID)
+		return session.UserID, nil, true
+	}
+}
+
+// authFailure answers a refused token: 401 when it is unknown, the service
+// status otherwise.
+func authFailure(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, services.ErrNotFound) {
+		responses.JSON(c, http.StatusUnauthorized, false, nil, err.Error())
+		return
+	}
+	serviceFailure(c, err, fallback)
+}
+
+// bearerToken is the token in the Authorization header, if any.
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// currentUser is the caller's user id.
+func currentUser(c *gin.Context) uint {
+	return c.GetUint(userKey)
+}
+
+// currentMember is the caller's membership; the zero value outside any
+// organization.
+func currentMember(c *gin.Context) model.OrganizationMember {
+	member, _ := c.Get(memberKey)
+	m, _ := member.(model.OrganizationMember)
+	return m
+}
+
+// currentOrganization is the caller's organization id, 0 for none.
+func currentOrganization(c *gin.Context) uint {
+	return currentMember(c).OrganizationID
+}
+
+func organizationResponse(org model.Organization, role string) utils.OrganizationResponse {
+	resp := utils.OrganizationResponse{ID: org.ID, Name: org.Name, Role: role, Members: []utils.MemberResponse{}}
+	for _, member := range org.Members {
+		resp.Members = append(resp.Members, memberResponse(member))
+	}
+	return resp
+}
+
+func memberResponse(member model.OrganizationMember) utils.MemberResponse {
+	resp := utils.MemberResponse{UserID: member.UserID, Role: member.Role, JoinedAt: member.CreatedAt}
+	if member.User != nil {
+		resp.Username = member.User.Username
+		resp.UserType = "builder"
+		if member.User.Profile != nil && member.User.Profile.UserType != "" {
+			resp.UserType = member.User.Profile.UserType
+		}
+	}
+	return resp
+}
+
+// getOrganization returns the caller's organization and its members.
+func (h *Handler) getOrganization(c *gin.Context) {
+	member := currentMember(c)
+	org, err := h.organizations.Get(c.Request.Context(), member.OrganizationID)
+	if err != nil {
+		serviceFailure(c, err, "Failed to load organization")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"organization": organizationResponse(org, member.Role)}, "Organization loaded")
+}
+
+// updateOrganization renames the caller's organization.
+func (h *Handler) updateOrganization(c *gin.Context) {
+	var req utils.UpdateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Name is required")
+		return
+	}
+	member := currentMember(c)
+	org, err := h.organizations.Rename(c.Request.Context(), member, req.Name)
+	if err != nil {
+		serviceFailure(c, err, "Failed to update organization")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"organization": organizationResponse(org, member.Role)}, "Organization updated")
+}
+
+// addOrganizationMember adds an existing user to the caller's organization.
+func (h *Handler) addOrganizationMember(c *gin.Context) {
+	var req utils.AddMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Username is required")
+		return
+	}
+	member, err := h.organizations.AddMember(c.Request.Context(), currentMember(c), req.Username, req.Role)
+	if err != nil {
+		serviceFailure(c, err, "Failed to add member")
+		return
+	}
+	responses.JSON(c, http.StatusCreated, true, gin.H{"member": memberResponse(member)}, "Member added")
+}
+
+// updateOrganizationMember changes a member's role.
+func (h *Handler) updateOrganizationMember(c *gin.Context) {
+	userID, ok := userParam(c)
+	if !ok {
+		return
+	}
+	var req utils.UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Role is required")
+		return
+	}
+	member, err := h.organizations.SetRole(c.Request.Context(), currentMember(c), userID, req.Role)
+	if err != nil {
+		serviceFailure(c, err, "Failed to update member")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"member": memberResponse(member)}, "Member updated")
+}
+
+// removeOrganizationMember takes a member out of the caller's organization.
+func (h *Handler) removeOrganizationMember(c *gin.Context) {
+	userID, ok := userParam(c)
+	if !ok {
+		return
+	}
+	if err := h.organizations.RemoveMember(c.Request.Context(), currentMember(c), userID); err != nil {
+		serviceFailure(c, err, "Failed to remove member")
+		return
+	}
+	responses.JSON(c, http.StatusNoContent, true, nil, "Member removed")
+}
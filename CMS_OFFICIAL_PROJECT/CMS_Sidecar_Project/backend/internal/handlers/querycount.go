@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"strconv"
+
+	"cms_sidecar_backend/internal/db"
+	"github.com/gin-gonic/gin"
+)
+
+// queryCountWriter stamps X-Query-Count just before the headers go out.
+type queryCountWriter struct {
+	gin.ResponseWriter
+	start uint64
+}
+
+func (w *queryCountWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.Header().Set("X-Query-Count", strconv.FormatUint(db.QueryCount()-w.start, 10))
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *queryCountWriter) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *queryCountWriter) WriteString(s string) (int, error) {
+	w.WriteHeaderNow()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// QueryCountHeader reports how many SQL statements a request ran in the
+// X-Query-Count response header. The counter is process wide, so numbers are
+// only exact while requests do not overlap; enable it with
+// CMS_DEBUG_QUERIES=1 during development.
+func QueryCountHeader() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer = &queryCountWriter{ResponseWriter: c.Writer, start: db.QueryCount()}
+		c.Next()
+	}
+}
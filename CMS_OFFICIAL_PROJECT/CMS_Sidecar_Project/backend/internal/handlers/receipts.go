@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"cms_sidecar_backend/internal/model"
+	"cms_sidecar_backend/internal/responses"
+	fileService "cms_sidecar_backend/internal/services/files"
+	paymentService "cms_sidecar_backend/internal/services/payments"
+	orgUtils "cms_sidecar_backend/internal/utilities/organization_page_app"
+	utils "cms_sidecar_backend/internal/utilities/payments_page_app"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) getProjectPaymentIssuedReceipt(c *gin.Context) {
+	h.serveReceipt(c, paymentService.ProjectLedger)
+}
+
+func (h *Handler) issueProjectPaymentReceipt(c *gin.Context) {
+	h.issueReceipt(c, paymentService.ProjectLedger)
+}
+
+func (h *Handler) getFlatPaymentIssuedReceipt(c *gin.Context) {
+	h.serveReceipt(c, paymentService.FlatLedger)
+}
+
+func (h *Handler) issueFlatPaymentReceipt(c *gin.Context) {
+	h.issueReceipt(c, paymentService.FlatLedger)
+}
+
+func (h *Handler) getPlotPaymentIssuedReceipt(c *gin.Context) {
+	h.serveReceipt(c, paymentService.PlotLedger)
+}
+
+func (h *Handler) issuePlotPaymentReceipt(c *gin.Context) {
+	h.issueReceipt(c, paymentService.PlotLedger)
+}
+
+// serveReceipt describes the receipt issued for a payment with a fresh link
+// to its PDF.
+func (h *Handler) serveReceipt(c *gin.Context, ledger paymentService.Ledger) {
+	paymentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payment id")
+		return
+	}
+	receipt, err := h.receipts.Find(c.Request.Context(), ledger, uint(paymentID))
+	if err != nil {
+		serviceFailure(c, err, "Failed to load receipt")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"receipt": h.receiptResponse(receipt)}, "receipt loaded")
+}
+
+// issueReceipt numbers and renders a payment's receipt. The body is
+// optional; a payment that already has a receipt gets it back with 200.
+func (h *Handler) issueReceipt(c *gin.Context, ledger paymentService.Ledger) {
+	paymentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payment id")
+		return
+	}
+	var req utils.IssueReceiptRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid receipt request")
+		return
+	}
+	receipt, created, err := h.receipts.Issue(c.Request.Context(), currentMember(c), ledger, uint(paymentID), req.Payer)
+	if err != nil {
+		serviceFailure(c, err, "Failed to issue receipt")
+		return
+	}
+	status, message := http.StatusOK, "receipt already issued"
+	if created {
+		status, message = http.StatusCreated, "receipt issued"
+	}
+	responses.JSON(c, status, true, gin.H{"receipt": h.receiptResponse(receipt)}, message)
+}
+
+func (h *Handler) receiptResponse(receipt model.PaymentReceipt) utils.ReceiptResponse {
+	link := h.files.Link(receipt.FileKey)
+	return utils.ReceiptResponse{
+		Number:    receipt.Number,
+		Ledger:    receipt.Ledger,
+		PaymentID: receipt.PaymentID,
+		Payer:     receipt.Payer,
+		Amount:    receipt.Amount,
+		IssuedAt:  receipt.IssuedAt,
+		URL:       link.URL,
+		ExpiresAt: link.Expires,
+	}
+}
+
+// getLetterhead shows what the caller's organization prints on receipts.
+func (h *Handler) getLetterhead(c *gin.Context) {
+	head, err := h.receipts.Letterhead(c.Request.Context(), currentOrganization(c))
+	if err != nil {
+		serviceFailure(c, err, "Failed to load letterhead")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"letterhead": h.letterheadResponse(head)}, "letterhead loaded")
+}
+
+// updateLetterhead replaces the letterhead's text; receipts issued before
+// keep the one they were printed with.
+func (h *Handler) updateLetterhead(c *gin.Context) {
+	var req orgUtils.LetterheadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid letterhead")
+		return
+	}
+	head, err := h.receipts.SaveLetterhead(c.Request.Context(), currentMember(c), req)
+	if err != nil {
+		serviceFailure(c, err, "Failed to update letterhead")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"letterhead": h.letterheadResponse(head)}, "Letterhead updated")
+}
+
+// uploadLetterheadLogo replaces the letterhead's logo. The upload is checked
+// before it is stored so members who may not edit the letterhead store
+// nothing.
+func (h *Handler) uploadLetterheadLogo(c *gin.Context) {
+	member := currentMember(c)
+	if member.Role != model.MemberRoleOwner && member.Role != model.MemberRoleAdmin {
+		responses.JSON(c, http.StatusForbidden, false, nil, "Only organization owners and admins can do this")
+		return
+	}
+	stored, ok := h.saveUpload(c, fileService.Logo)
+	if !ok {
+		return
+	}
+	head, err := h.receipts.SetLogo(c.Request.Context(), member, stored.Key)
+	if err != nil {
+		serviceFailure(c, err, "Failed to update letterhead")
+		return
+	}
+	responses.JSON(c, http.StatusCreated, true, gin.H{"letterhead": h.letterheadResponse(head)}, "logo uploaded")
+}
+
+func (h *Handler) letterheadResponse(head model.Letterhead) orgUtils.LetterheadResponse {
+	response := orgUtils.LetterheadResponse{
+		Name:      head.Name,
+		Address:   head.Address,
+		Phone:     head.Phone,
+		Email:     head.Email,
+		TaxID:     head.TaxID,
+		Footer:    head.Footer,
+		UpdatedAt: head.UpdatedAt,
+	}
+	if head.LogoKey != "" {
+		response.LogoURL = h.files.Link(head.LogoKey).URL
+	}
+	return response
+}
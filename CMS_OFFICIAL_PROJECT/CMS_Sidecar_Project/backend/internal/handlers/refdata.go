@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"cms_sidecar_backend/internal/refdata"
+	"cms_sidecar_backend/internal/responses"
+	"github.com/gin-gonic/gin"
+)
+
+// serveReference answers a reference-list GET from the in-process cache. It
+// sets ETag and Last-Modified and replies 304 when the client's copy is
+// current; clients revalidate on every load (Cache-Control: no-cache).
+func (h *Handler) serveReference(c *gin.Context, key string, tables []string, message, failure string, load func() (interface{}, error)) {
+	entry, err := h.refs.Get(key, tables, load)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, failure)
+		return
+	}
+
+	c.Header("ETag", entry.ETag)
+	c.Header("Last-Modified", entry.Modified.Format(http.TimeFormat))
+	c.Header("Cache-Control", "private, no-cache")
+	if notModified(c, entry) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, entry.Data, message)
+}
+
+// notModified applies If-None-Match, or If-Modified-Since when no ETag was
+// sent, as RFC 9110 orders them.
+func notModified(c *gin.Context, entry *refdata.Entry) bool {
+	if header := strings.TrimSpace(c.GetHeader("If-None-Match")); header != "" {
+		if header == "*" {
+			return true
+		}
+		for _, candidate := range strings.Split(header, ",") {
+			if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == entry.ETag {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := time.Parse(http.TimeFormat, c.GetHeader("If-Modified-Since"))
+	return err == nil && !entry.Modified.Truncate(time.Second).After(since)
+}
@@ -1,251 +1,144 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
-	"github.com/gin-gonic/gin"
+	"cms_sidecar_backend/internal/listquery"
 	"cms_sidecar_backend/internal/model"
 	"cms_sidecar_backend/internal/responses"
+	"cms_sidecar_backend/internal/search"
+	"cms_sidecar_backend/internal/services"
+	projectUtils "cms_sidecar_backend/internal/utilities/projects_page_app"
 	salesUtils "cms_sidecar_backend/internal/utilities/sales_page_app"
-	"gorm.io/datatypes"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// Helper: createMissingUnits (mirrors _create_missing_units_for_block)
-func (h *Handler) createMissingUnitsForBlock(block *model.ProjectBlock, template interface{}) int {
-	// Parse template
-	var templateList []map[string]interface{}
-
-	// template can be json.RawMessage, []interface{}, string, etc.
-	// Simplified parsing:
-	if tList, ok := template.([]interface{}); ok {
-		for _, item := range tList {
-			if m, ok := item.(map[string]interface{}); ok {
-				templateList = append(templateList, m)
-			}
-		}
-	} else if tJSON, ok := template.(datatypes.JSON); ok {
-		json.Unmarshal(tJSON, &templateList)
-	}
-
-	templateMap := make(map[int]map[string]interface{})
-	for _, item := range templateList {
-		if numVal, ok := item["unit_number"]; ok {
-			// handle float64 from json
-			var num int
-			if f, ok := numVal.(float64); ok {
-				num = int(f)
-			}
-			if s, ok := numVal.(string); ok {
-				num, _ = strconv.Atoi(s)
-			}
-			if num > 0 {
-				templateMap[num] = item
-			}
-		}
-	}
-
-	// Get existing units
-	var existingUnits []model.ProjectUnit
-	h.db.Where("project_unit_block_id = ?", block.ID).Find(&existingUnits)
-	existingSet := make(map[string]bool)
-	for _, u := range existingUnits {
-		key := fmt.Sprintf("%d-%d", u.ProjectUnitFloorNumber, u.ProjectUnitNumber)
-		existingSet[key] = true
-	}
-
-	newUnits := []model.ProjectUnit{}
-	for floor := 1; floor <= int(block.ProjectBlockFloorCount); floor++ {
-		for unitNum := 1; unitNum <= int(block.ProjectBlockUnitsPerFloor); unitNum++ {
-			key := fmt.Sprintf("%d-%d", floor, unitNum)
-			if existingSet[key] {
-				continue
-			}
-
-			tData := templateMap[unitNum]
-			bhk := ""
-			if v, ok := tData["bhk_configuration"].(string); ok {
-				bhk = v
-			}
-			face := ""
-			if v, ok := tData["facing"].(string); ok {
-				face = v
-			}
-
-			var area *float64
-			if v, ok := tData["area_sqft"]; ok {
-				if f, ok := v.(float64); ok {
-					area = &f
-				}
-				if s, ok := v.(string); ok {
-					if f, err := strconv.ParseFloat(s, 64); err == nil {
-						area = &f
-					}
-				}
-			}
-
-			// Generate Label
-			label := fmt.Sprintf("%s-F%d-U%d", block.ProjectBlockName, floor, unitNum)
-
-			newUnits = append(newUnits, model.ProjectUnit{
-				ProjectUnitBlockID:          block.ID,
-				ProjectUnitFloorNumber:      uint(floor),
-				ProjectUnitNumber:           uint(unitNum),
-				ProjectUnitLabel:            label,
-				ProjectUnitBHKConfiguration: bhk,
-				ProjectUnitFacing:           face,
-				ProjectUnitAreaSqft:         area,
-				ProjectUnitStatus:           "available",
-				ProjectUnitCRMStage:         "visitor",
-			})
-		}
-	}
-
-	if len(newUnits) > 0 {
-		h.db.Create(&newUnits)
-	}
-	return len(newUnits)
-}
-
 // CreateMultiFlatBlockAPI mirrors create_multi_flat_block
 func (h *Handler) CreateMultiFlatBlockAPI(c *gin.Context) {
-	projectCode := c.Param("code")
-	var project model.Project
-	if err := h.db.Where("project_code = ?", projectCode).First(&project).Error; err != nil {
-		responses.JSON(c, http.StatusNotFound, false, nil, "Project not found")
-		return
-	}
-
 	var req salesUtils.CreateBlockRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
 		return
 	}
-
-	// Auto-generate name if empty
-	name := strings.TrimSpace(req.Name)
-	if name == "" {
-		var count int64
-		h.db.Model(&model.ProjectBlock{}).Where("project_block_project_id = ?", project.ID).Count(&count)
-		letter := string(rune('A' + (count % 26)))
-		name = fmt.Sprintf("Block %s", letter)
+	if _, ok := h.projectByCode(c); !ok {
+		return
 	}
 
-	// Validate duplicate name
-	var existing int64
-	h.db.Model(&model.ProjectBlock{}).Where("project_block_project_id = ? AND project_block_name = ?", project.ID, name).Count(&existing)
-	if existing > 0 {
-		responses.JSON(c, http.StatusBadRequest, false, nil, "Block name exists")
+	block, createdCount, err := h.sales.CreateBlock(c.Request.Context(), c.Param("code"), req)
+	if err != nil {
+		serviceFailure(c, err, "Failed to create block")
 		return
 	}
 
-	// Sequence
-	var maxSeq *uint
-	h.db.Model(&model.ProjectBlock{}).Where("project_block_project_id = ?", project.ID).Select("MAX(project_block_sequence)").Scan(&maxSeq)
-	nextSeq := uint(1)
-	if maxSeq != nil {
-		nextSeq = *maxSeq + 1
-	}
+	responses.JSON(c, http.StatusCreated, true, gin.H{
+		"block":         block,
+		"created_units": createdCount,
+	}, "Block created")
+}
 
-	// Floor/Unit counts
-	fc := 1
-	if v, ok := req.FloorCount.(float64); ok {
-		fc = int(v)
+// loadBlock resolves the :block_id block with its units, answering 404 when
+// it is missing or its project is not the caller's.
+func (h *Handler) loadBlock(c *gin.Context) (model.ProjectBlock, bool) {
+	var block model.ProjectBlock
+	if err := h.dbFor(c).Preload("Units").First(&block, c.Param("block_id")).Error; err != nil {
+		responses.JSON(c, http.StatusNotFound, false, nil, "Block not found")
+		return block, false
 	}
-	// handle other types if needed (string)
+	return block, h.canAccessProject(c, block.ProjectBlockProjectID)
+}
 
-	upf := 1
-	if v, ok := req.UnitsPerFloor.(float64); ok {
-		upf = int(v)
+// MultiFlatBlockAPI returns one block with its units and ETag.
+func (h *Handler) MultiFlatBlockAPI(c *gin.Context) {
+	block, ok := h.loadBlock(c)
+	if !ok {
+		return
 	}
+	c.Header("ETag", entityETag("block", block.ID, block.ProjectBlockVersion))
+	responses.JSON(c, http.StatusOK, true, block, "Block loaded")
+}
 
-	block := model.ProjectBlock{
-		ProjectBlockProjectID:     project.ID,
-		ProjectBlockName:          name,
-		ProjectBlockSequence:      nextSeq,
-		ProjectBlockFloorCount:    uint(fc),
-		ProjectBlockUnitsPerFloor: uint(upf),
-		ProjectBlockNotes:         req.Notes,
-		// Template handling complex, skipping deep parsing for now, storing as is if possible or empty
-	}
-	// Template
-	if req.UnitLayoutTemplate != nil {
-		jsonBytes, _ := json.Marshal(req.UnitLayoutTemplate)
-		block.ProjectBlockUnitLayout = datatypes.JSON(jsonBytes)
+// DeleteMultiFlatBlockAPI deletes a block, refusing when sales, CRM or
+// payment records depend on it unless cascade=true.
+func (h *Handler) DeleteMultiFlatBlockAPI(c *gin.Context) {
+	block, ok := h.loadBlock(c)
+	if !ok {
+		return
 	}
 
-	h.db.Create(&block)
-
-	// Create Units
-	createdCount := h.createMissingUnitsForBlock(&block, req.UnitLayoutTemplate)
-
-	// Update Project Block Count
-	var totalBlocks int64
-	h.db.Model(&model.ProjectBlock{}).Where("project_block_project_id = ?", project.ID).Count(&totalBlocks)
-	project.ProjectBlockCount = uint(totalBlocks)
-	h.db.Save(&project)
+	var plan projectUtils.DeletionPlan
+	refused := false
+	err := h.dbFor(c).Transaction(func(tx *gorm.DB) error {
+		var err error
+		plan, err = h.planBlockDeletion(tx, block.ID)
+		if err != nil {
+			return err
+		}
+		if !plan.Safe && !cascadeConfirmed(c.Query("cascade")) {
+			refused = true
+			return nil
+		}
+		if err := cascadeDeleteBlock(tx, block.ID); err != nil {
+			return err
+		}
 
-	responses.JSON(c, http.StatusCreated, true, gin.H{
-		"block":         block,
-		"created_units": createdCount,
-	}, "Block created")
+		// Keep the project's block count in step, as CreateMultiFlatBlockAPI does.
+		var totalBlocks int64
+		if err := tx.Model(&model.ProjectBlock{}).Where("project_block_project_id = ?", block.ProjectBlockProjectID).Count(&totalBlocks).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Project{}).Where("id = ?", block.ProjectBlockProjectID).Updates(map[string]interface{}{
+			"project_block_count": totalBlocks,
+			"project_version":     gorm.Expr("project_version + 1"),
+		}).Error
+	})
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to delete block")
+		return
+	}
+	if refused {
+		responses.JSON(c, http.StatusConflict, false, plan, "Block has sales, CRM or payment records; retry with cascade=true to delete them too")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, plan, "Block deleted")
 }
 
-// UpdateMultiFlatBlockAPI mirrors update_multi_flat_block (PATCH/DELETE)
+// UpdateMultiFlatBlockAPI mirrors update_multi_flat_block.
 func (h *Handler) UpdateMultiFlatBlockAPI(c *gin.Context) {
-	blockID := c.Param("block_id")
-	var block model.ProjectBlock
-	if err := h.db.Preload("Units").First(&block, blockID).Error; err != nil {
-		responses.JSON(c, http.StatusNotFound, false, nil, "Block not found")
+	block, ok := h.loadBlock(c)
+	if !ok {
 		return
 	}
 
-	if c.Request.Method == "DELETE" {
-		// Permissions check skipped for brevity (mirroring logic assumes auth middleware handles role check generally, but exact parity matches strict role checks)
-		h.db.Delete(&block)
-		responses.JSON(c, http.StatusOK, true, nil, "Block deleted")
+	etag := entityETag("block", block.ID, block.ProjectBlockVersion)
+	if ifMatchFails(c, etag) {
+		preconditionFailed(c, etag, block)
 		return
 	}
 
-	// PATCH
 	var req salesUtils.UpdateBlockRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
 		return
 	}
 
-	if req.Name != "" {
-		block.ProjectBlockName = req.Name
-	}
-	if req.Notes != nil {
-		block.ProjectBlockNotes = *req.Notes
-	}
-
-	if req.FloorCount != nil {
-		// logic to prevent reducing count if safer
-		val, _ := strconv.Atoi(fmt.Sprintf("%v", req.FloorCount))
-		if uint(val) > block.ProjectBlockFloorCount {
-			block.ProjectBlockFloorCount = uint(val)
-		}
-	}
-	if req.UnitsPerFloor != nil {
-		val, _ := strconv.Atoi(fmt.Sprintf("%v", req.UnitsPerFloor))
-		if uint(val) > block.ProjectBlockUnitsPerFloor {
-			block.ProjectBlockUnitsPerFloor = uint(val)
-		}
+	createdCount, err := h.sales.UpdateBlock(c.Request.Context(), &block, req)
+	if errors.Is(err, services.ErrConflict) {
+		var current model.ProjectBlock
+		h.dbFor(c).Preload("Units").First(&current, block.ID)
+		versionConflict(c, entityETag("block", current.ID, current.ProjectBlockVersion), current)
+		return
 	}
-
-	if req.UnitLayoutTemplate != nil {
-		jsonBytes, _ := json.Marshal(req.UnitLayoutTemplate)
-		block.ProjectBlockUnitLayout = datatypes.JSON(jsonBytes)
+	if err != nil {
+		serviceFailure(c, err, "Failed to update block")
+		return
 	}
 
-	h.db.Save(&block)
-	createdCount := h.createMissingUnitsForBlock(&block, req.UnitLayoutTemplate)
+	c.Header("ETag", entityETag("block", block.ID, block.ProjectBlockVersion))
 
 	responses.JSON(c, http.StatusOK, true, gin.H{
 		"block":         block,
@@ -253,12 +146,64 @@ func (h *Handler) UpdateMultiFlatBlockAPI(c *gin.Context) {
 	}, "Block updated")
 }
 
-// UpdateMultiFlatUnitAPI mirrors update_multi_flat_unit
-func (h *Handler) UpdateMultiFlatUnitAPI(c *gin.Context) {
-	unitID := c.Param("unit_id")
+// MultiFlatBlockDeletionPlanAPI reports every record a block delete would remove.
+func (h *Handler) MultiFlatBlockDeletionPlanAPI(c *gin.Context) {
+	blockID, err := strconv.ParseUint(c.Param("block_id"), 10, 64)
+	if err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid block id")
+		return
+	}
+
+	var block model.ProjectBlock
+	if err := h.dbFor(c).First(&block, blockID).Error; err != nil {
+		responses.JSON(c, http.StatusNotFound, false, nil, "Block not found")
+		return
+	}
+	if !h.canAccessProject(c, block.ProjectBlockProjectID) {
+		return
+	}
+
+	plan, err := h.planBlockDeletion(h.dbFor(c), block.ID)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to build deletion plan")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, plan, "Deletion plan loaded")
+}
+
+// loadUnit resolves the :unit_id unit with its block, answering 404 when it
+// is missing or its project is not the caller's.
+func (h *Handler) loadUnit(c *gin.Context) (model.ProjectUnit, bool) {
 	var unit model.ProjectUnit
-	if err := h.db.Preload("ProjectUnitBlock").First(&unit, unitID).Error; err != nil {
+	if err := h.dbFor(c).Preload("ProjectUnitBlock").First(&unit, c.Param("unit_id")).Error; err != nil {
 		responses.JSON(c, http.StatusNotFound, false, nil, "Unit not found")
+		return unit, false
+	}
+	return unit, h.canAccessProject(c, unit.ProjectUnitBlock.ProjectBlockProjectID)
+}
+
+// MultiFlatUnitAPI returns one unit with its ETag.
+func (h *Handler) MultiFlatUnitAPI(c *gin.Context) {
+	unit, ok := h.loadUnit(c)
+	if !ok {
+		return
+	}
+	c.Header("ETag", entityETag("unit", unit.ID, unit.ProjectUnitVersion))
+	responses.JSON(c, http.StatusOK, true, unit, "Unit loaded")
+}
+
+// UpdateMultiFlatUnitAPI mirrors update_multi_flat_unit.
+// It honours If-Match against the unit ETag so two executives editing the
+// same unit cannot silently overwrite each other.
+func (h *Handler) UpdateMultiFlatUnitAPI(c *gin.Context) {
+	unit, ok := h.loadUnit(c)
+	if !ok {
+		return
+	}
+
+	etag := entityETag("unit", unit.ID, unit.ProjectUnitVersion)
+	if ifMatchFails(c, etag) {
+		preconditionFailed(c, etag, unit)
 		return
 	}
 
@@ -319,22 +264,93 @@ func (h *Handler) UpdateMultiFlatUnitAPI(c *gin.Context) {
 		}
 	}
 
-	h.db.Save(&unit)
+	if !h.saveUnit(c, &unit, "Failed to update unit") {
+		return
+	}
+
+	c.Header("ETag", entityETag("unit", unit.ID, unit.ProjectUnitVersion))
 	responses.JSON(c, http.StatusOK, true, unit, "Unit updated")
 }
 
-// MultiFlatCRMUnitsAPI mirrors multi_flat_crm_units
+// saveUnit writes unit with its version bumped, as long as nobody changed it
+// since it was loaded. Otherwise it answers 409 with the stored unit, or 500
+// with failure, and returns false.
+func (h *Handler) saveUnit(c *gin.Context, unit *model.ProjectUnit, failure string) bool {
+	expected := unit.ProjectUnitVersion
+	unit.ProjectUnitVersion++
+	unit.ProjectUnitUpdatedAt = time.Now()
+	saved, err := services.SaveVersioned(h.dbFor(c), unit, "project_unit_version", expected)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, failure)
+		return false
+	}
+	if !saved {
+		var current model.ProjectUnit
+		h.dbFor(c).First(&current, unit.ID)
+		versionConflict(c, entityETag("unit", current.ID, current.ProjectUnitVersion), current)
+		return false
+	}
+	return true
+}
+
+var crmUnitListSpec = listquery.Spec{
+	Table: "construction_projectunit",
+	Sorts: map[string]string{
+		"updated_at":   "construction_projectunit.project_unit_updated_at",
+		"booking_date": "construction_projectunit.project_unit_booking_date",
+		"label":        "construction_projectunit.project_unit_label",
+	},
+	DefaultSort: "-updated_at",
+	Filters: map[string]string{
+		"project_id": "construction_project.id",
+		"block_id":   "construction_projectunit.project_unit_block_id",
+		"crm_stage":  "construction_projectunit.project_unit_crm_stage",
+	},
+	DateColumn:      "construction_projectunit.project_unit_booking_date",
+	DefaultPageSize: 300,
+}
+
+// crmUnitRow is a unit plus the code of its project, read in the same query.
+type crmUnitRow struct {
+	model.ProjectUnit
+	UnitProjectCode string `gorm:"column:unit_project_code;->"`
+}
+
+var crmUnitColumns = []column[crmUnitRow]{
+	{"Project", func(u crmUnitRow) interface{} { return u.UnitProjectCode }},
+	{"Unit", func(u crmUnitRow) interface{} { return u.ProjectUnitLabel }},
+	{"Configuration", func(u crmUnitRow) interface{} { return u.ProjectUnitBHKConfiguration }},
+	{"Status", func(u crmUnitRow) interface{} { return u.ProjectUnitStatus }},
+	{"CRM stage", func(u crmUnitRow) interface{} { return u.ProjectUnitCRMStage }},
+	{"Facing", func(u crmUnitRow) interface{} { return u.ProjectUnitFacing }},
+	{"Area (sq ft)", func(u crmUnitRow) interface{} { return u.ProjectUnitAreaSqft }},
+	{"Price", func(u crmUnitRow) interface{} { return u.ProjectUnitPrice }},
+	{"Buyer", func(u crmUnitRow) interface{} { return u.ProjectUnitBuyerName }},
+	{"Buyer phone", func(u crmUnitRow) interface{} { return u.ProjectUnitBuyerPhone }},
+	{"Buyer email", func(u crmUnitRow) interface{} { return u.ProjectUnitBuyerEmail }},
+	{"Reference source", func(u crmUnitRow) interface{} { return u.ProjectUnitBuyerReferenceSource }},
+	{"Booking date", func(u crmUnitRow) interface{} { return u.ProjectUnitBookingDate }},
+	{"Notes", func(u crmUnitRow) interface{} { return u.ProjectUnitNotes }},
+}
+
+// MultiFlatCRMUnitsAPI mirrors multi_flat_crm_units; format=csv or xlsx
+// exports the units instead.
 func (h *Handler) MultiFlatCRMUnitsAPI(c *gin.Context) {
 	// Filter by project type multi_flat
 	// Get units
 
 	statusFilter := c.Query("status")
-	search := strings.ToLower(c.Query("search"))
 
-	query := h.db.Table("construction_projectunit").
+	scope, err := h.projects.Scope(c.Request.Context(), currentUser(c))
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Error checking access")
+		return
+	}
+	query := h.dbFor(c).Table("construction_projectunit").
 		Joins("JOIN construction_projectblock ON construction_projectblock.id = construction_projectunit.project_unit_block_id").
 		Joins("JOIN construction_project ON construction_project.id = construction_projectblock.project_block_project_id").
-		Where("construction_project.project_flat_configuration IN ?", []string{"multi_flat", "multi_plot"})
+		Where("construction_project.project_flat_configuration IN ?", []string{"multi_flat", "multi_plot"}).
+		Scopes(scope)
 
 	if statusFilter != "" {
 		query = query.Where("construction_projectunit.project_unit_status = ?", statusFilter)
@@ -342,44 +358,27 @@ func (h *Handler) MultiFlatCRMUnitsAPI(c *gin.Context) {
 		query = query.Not("construction_projectunit.project_unit_status = ?", "available")
 	}
 
-	if search != "" {
-		query = query.Where(
-			"LOWER(construction_projectunit.project_unit_buyer_name) LIKE ? OR "+
-				"LOWER(construction_projectunit.project_unit_buyer_phone) LIKE ? OR "+
-				"LOWER(construction_projectunit.project_unit_label) LIKE ? OR "+
-				"LOWER(construction_project.project_code) LIKE ?",
-			"%"+search+"%", "%"+search+"%", "%"+search+"%", "%"+search+"%")
+	// Matches unit label, buyer name/phone/email and project code.
+	if ids, ok := search.MatchIDs(h.dbFor(c), search.KindUnit, c.Query("search")); ok {
+		query = query.Where("construction_projectunit.id IN (?)", ids)
 	}
 
-	var units []model.ProjectUnit
-	query.Preload("ProjectUnitBlock.ProjectBlockProject").
-		Order("construction_projectunit.project_unit_updated_at desc").
-		Limit(300).
-		Find(&units)
+	// The project code comes from the join, so the page costs a fixed number
+	// of queries however many units it holds.
+	query = query.Select("construction_projectunit.*, construction_project.project_code AS unit_project_code")
+	if exporting(c) {
+		exportList(c, crmUnitListSpec, query, "crm-units", crmUnitColumns)
+		return
+	}
+	var units []crmUnitRow
+	page, ok := findPage(c, crmUnitListSpec, query, &units, "Failed to load units")
+	if !ok {
+		return
+	}
 
 	var payload []salesUtils.UnitResponse
 	for _, u := range units {
-		// Need to load Block.Project manually if not preloaded deep
-		// But let's assume Preload worked.
-		// However, struct ProjectUnit has BlockID. Gorm Preload "ProjectUnitBlock.ProjectBlockProject" relies on relations.
-		// relationships were defined in project_models.go?
-		// ProjectBlock has ProjectID (ProjectBlockProjectID). Gorm logic might need "ProjectBlockProject" preload if relation defined.
-		// In models: ProjectUnit -> ProjectUnitBlock (ProjectBlock) -> ProjectBlockProject (Project relation not explicitly defined in struct? Wait.)
-
-		// Checking project_models.go:
-		// ProjectBlock struct: ProjectBlockProjectID uint. NO nested struct pointer `Project`?
-		// Correct. Step 532 showing ProjectBlock struct:
-		// ProjectBlockProjectID     uint
-		// No `Project` field.
-		// So Preload("ProjectUnitBlock.ProjectBlockProject") will FAIL.
-		// We need to fetch projects or map manually.
-
-		projCode := ""
-		// Optimization: fetch project details separately or JOIN select.
-		// For now, lazy load or simple query since we need code.
-		var proj model.Project
-		h.db.Select("project_code").First(&proj, u.ProjectUnitBlock.ProjectBlockProjectID)
-		projCode = proj.ProjectCode
+		projCode := u.UnitProjectCode
 
 		booking := ""
 		if u.ProjectUnitBookingDate != nil {
@@ -413,5 +412,5 @@ func (h *Handler) MultiFlatCRMUnitsAPI(c *gin.Context) {
 		})
 	}
 
-	responses.JSON(c, http.StatusOK, true, gin.H{"units": payload}, "Units loaded")
+	responses.Page(c, http.StatusOK, gin.H{"units": payload}, "Units loaded", page)
 }
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestCSRF checks cookie-authenticated writes must echo the cms_csrf cookie
+// in X-CSRF-Token, while reads and bearer-authenticated writes need not.
+func TestCSRF(t *testing.T) {
+	s := newTestServer(t)
+	bearer, login := s.signUp("csrfowner")
+	cookies := map[string]string{}
+	for _, cookie := range login.Result().Cookies() {
+		cookies[cookie.Name] = cookie.Value
+	}
+	if cookies[sessionCookie] == "" || cookies[csrfCookie] == "" {
+		t.Fatalf("login set no session or CSRF cookie: %v", cookies)
+	}
+	sessionCookies := sessionCookie + "=" + cookies[sessionCookie] + "; " + csrfCookie + "=" + cookies[csrfCookie]
+	vendor := `{"company_name":"Acme Cement","first_name":"Ravi","primary_phone_number":"9800000000"}`
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		headers []string
+		want    int
+	}{
+		{"cookie write without token", http.MethodPost, "/api/v2/vendors", vendor, []string{"Cookie", sessionCookies}, http.StatusForbidden},
+		{"cookie write with wrong token", http.MethodPost, "/api/v2/vendors", vendor, []string{"Cookie", sessionCookies, csrfHeader, "forged"}, http.StatusForbidden},
+		{"cookie write with token", http.MethodPost, "/api/v2/vendors", vendor, []string{"Cookie", sessionCookies, csrfHeader, cookies[csrfCookie]}, http.StatusCreated},
+		{"cookie read without token", http.MethodGet, "/api/v2/vendors", "", []string{"Cookie", sessionCookies}, http.StatusOK},
+		{"bearer write without token", http.MethodPost, "/api/v2/vendors", vendor, bearer, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(tt.method, tt.path, tt.body, tt.headers...)
+			if w.Code != tt.want {
+				t.Errorf("%s %s answered %d, want %d: %s", tt.method, tt.path, w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
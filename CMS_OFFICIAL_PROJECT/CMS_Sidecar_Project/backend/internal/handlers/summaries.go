@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"cms_sidecar_backend/internal/model"
+	projectUtils "cms_sidecar_backend/internal/utilities/projects_page_app"
+	"github.com/gin-gonic/gin"
+)
+
+// projectSummaries loads the maintained summary rows of projectIDs keyed by
+// project. Projects without a row (none yet) map to the zero summary.
+func (h *Handler) projectSummaries(c *gin.Context, projectIDs []uint) (map[uint]model.ProjectSummary, error) {
+	summaries := make(map[uint]model.ProjectSummary, len(projectIDs))
+	if len(projectIDs) == 0 {
+		return summaries, nil
+	}
+	var rows []model.ProjectSummary
+	if err := h.dbFor(c).Where("project_id IN ?", projectIDs).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		summaries[row.ProjectID] = row
+	}
+	return summaries, nil
+}
+
+// summaryTotals adds up the summaries of several projects.
+func summaryTotals(summaries map[uint]model.ProjectSummary) projectUtils.SummaryTotals {
+	var totals projectUtils.SummaryTotals
+	for _, s := range summaries {
+		totals.TotalUnits += s.TotalUnits
+		totals.SoldUnits += s.SoldUnits
+		totals.BookedUnits += s.BookedUnits
+		totals.HoldUnits += s.HoldUnits
+		totals.AvailableUnits += s.AvailableUnits
+		totals.CollectedAmount += s.CollectedAmount
+		totals.TotalExpense += s.TotalExpense()
+		totals.StockValue += s.StockValue
+	}
+	return totals
+}
@@ -0,0 +1,180 @@
+package handlers
+
+// registerV2 builds /api/v2: one router per resource, one handler per verb.
+// Collections answer GET and POST, records GET, PUT (or PATCH for partial
+// edits) and DELETE.
+func (h *Handler) registerV2(api routeTable) {
+	api.GET("/index", h.IndexView)
+	api.GET("/search", h.SearchAPI)
+	api.GET("/dashboard", h.DashboardView)
+	api.GET("/insights", h.InsightsView)
+	api.GET("/track-finances", h.TrackFinancesView)
+	api.GET("/material-items", h.listMaterialItems)
+
+	h.authRoutes(api.group("/auth"))
+	h.profileRoutes(api.group("/profile"))
+	h.sessionRoutes(api.group("/sessions"))
+	h.projectRoutes(api.group("/projects"))
+	h.multiFlatRoutes(api.group("/multi-flat"))
+	h.customerRoutes(api.group("/customers"))
+	h.channelPartnerRoutes(api.group("/channel-partners"))
+	h.vendorRoutes(api.group("/vendors"))
+	h.supervisorRoutes(api.group("/supervisors"))
+	h.organizationRoutes(api.group("/organization"))
+	h.directoryRoutes(api.group("/directory"))
+	h.crmRoutes(api.group("/crm"))
+	h.paymentRoutes(api.group("/payments"))
+	h.attendanceRoutes(api.group("/attendance"))
+	h.expenseRoutes(api.group("/expenses"))
+}
+
+func (h *Handler) authRoutes(r routeTable) {
+	r.POST("/login", h.LoginView)
+	r.POST("/register", h.RegisterView)
+	r.POST("/logout", h.LogoutView)
+	r.POST("/accept-invite", h.AcceptInviteView)
+}
+
+func (h *Handler) sessionRoutes(r routeTable) {
+	r.GET("", h.listSessions)
+	r.DELETE("", h.revokeSessions)
+	r.DELETE("/:id", h.revokeSession)
+}
+
+func (h *Handler) profileRoutes(r routeTable) {
+	r.GET("", h.ProfileView)
+	r.PUT("", h.UpdateProfileView)
+	r.GET("/avatar", h.getAvatar)
+	r.PUT("/avatar", h.uploadAvatar)
+}
+
+func (h *Handler) projectRoutes(r routeTable) {
+	r.GET("", h.ListProjectsAPI)
+	r.POST("", h.CreateProjectAPI)
+	r.GET("/:id", h.ProjectDetailAPI)
+	r.PUT("/:id", h.UpdateProjectAPI)
+	r.DELETE("/:id", h.DeleteProjectAPI)
+	r.GET("/:id/deletion-plan", h.ProjectDeletionPlanAPI)
+	r.GET("/:id/stock", h.StockBalancesAPI)
+	r.PATCH("/:id/stock", h.UpdateStockAPI)
+}
+
+func (h *Handler) multiFlatRoutes(r routeTable) {
+	r.GET("/projects", h.MultiFlatProjectsAPI)
+	r.GET("/projects/:code/grid", h.MultiFlatProjectGridAPI)
+	r.GET("/projects/:code/presets", h.MultiFlatPresetsAPI)
+	r.PUT("/projects/:code/presets", h.UpdateMultiFlatPresetsAPI)
+	r.POST("/projects/:code/blocks", h.CreateMultiFlatBlockAPI)
+	r.GET("/blocks/:block_id", h.MultiFlatBlockAPI)
+	r.PATCH("/blocks/:block_id", h.UpdateMultiFlatBlockAPI)
+	r.DELETE("/blocks/:block_id", h.DeleteMultiFlatBlockAPI)
+	r.GET("/blocks/:block_id/deletion-plan", h.MultiFlatBlockDeletionPlanAPI)
+	r.GET("/units", h.MultiFlatCRMUnitsAPI)
+	r.GET("/units/:unit_id", h.MultiFlatUnitAPI)
+	r.PATCH("/units/:unit_id", h.UpdateMultiFlatUnitAPI)
+}
+
+func (h *Handler) customerRoutes(r routeTable) {
+	r.GET("", h.listCustomers)
+	r.POST("", h.CreateCustomerAPI)
+}
+
+func (h *Handler) channelPartnerRoutes(r routeTable) {
+	r.GET("", h.listChannelPartners)
+	r.POST("", h.CreateChannelPartnerAPI)
+}
+
+func (h *Handler) vendorRoutes(r routeTable) {
+	r.GET("", h.ListVendorsAPI)
+	r.POST("", h.CreateVendorAPI)
+	r.GET("/choices", h.VendorChoicesAPI)
+	r.GET("/:id", h.VendorDetailAPI)
+	r.PUT("/:id", h.UpdateVendorAPI)
+	r.DELETE("/:id", h.DeleteVendorAPI)
+}
+
+func (h *Handler) supervisorRoutes(r routeTable) {
+	r.GET("", h.ListSupervisorsAPI)
+	r.POST("", h.CreateSupervisorAPI)
+	r.GET("/:id", h.SupervisorDetailAPI)
+	r.PUT("/:id", h.UpdateSupervisorAPI)
+	r.DELETE("/:id", h.DeleteSupervisorAPI)
+}
+
+func (h *Handler) organizationRoutes(r routeTable) {
+	r.GET("", h.getOrganization)
+	r.PATCH("", h.updateOrganization)
+	r.POST("/members", h.addOrganizationMember)
+	r.PATCH("/members/:user_id", h.updateOrganizationMember)
+	r.DELETE("/members/:user_id", h.removeOrganizationMember)
+	r.GET("/users", h.listUsers)
+	r.PATCH("/users/:user_id", h.updateUser)
+	r.POST("/users/:user_id/deactivate", h.deactivateUser)
+	r.POST("/users/:user_id/reactivate", h.reactivateUser)
+	r.GET("/users/:user_id/sessions", h.listUserSessions)
+	r.DELETE("/users/:user_id/sessions", h.signOutUser)
+	r.DELETE("/users/:user_id/sessions/:id", h.signOutUserSession)
+	r.GET("/invites", h.listInvites)
+	r.POST("/invites", h.createInvite)
+	r.DELETE("/invites/:id", h.revokeInvite)
+	r.GET("/api-keys", h.listAPIKeys)
+	r.POST("/api-keys", h.createAPIKey)
+	r.DELETE("/api-keys/:id", h.revokeAPIKey)
+	r.GET("/audit", h.listAuditEntries)
+	r.GET("/letterhead", h.getLetterhead)
+	r.PUT("/letterhead", h.updateLetterhead)
+	r.PUT("/letterhead/logo", h.uploadLetterheadLogo)
+}
+
+func (h *Handler) directoryRoutes(r routeTable) {
+	r.GET("/vendors", h.VendorListAPI)
+	r.POST("/credentials", h.RegenerateCredentialsAPI)
+}
+
+func (h *Handler) crmRoutes(r routeTable) {
+	r.GET("/projects", h.CRMProjectsList)
+	r.GET("/kanban", h.KanbanBoardAPI)
+	r.PATCH("/kanban/:unit_id", h.KanbanUpdateStageAPI)
+}
+
+func (h *Handler) paymentRoutes(r routeTable) {
+	r.GET("/choices", h.PaymentChoicesAPI)
+	r.GET("/project-options", h.PaymentsProjectsList)
+	r.GET("/projects", h.ListProjectPaymentsAPI)
+	r.POST("/projects", h.Idempotent(), h.CreateProjectPaymentAPI)
+	r.GET("/flats", h.ListFlatPaymentsAPI)
+	r.POST("/flats", h.Idempotent(), h.CreateFlatPaymentAPI)
+	r.GET("/plots", h.ListPlotPaymentsAPI)
+	r.POST("/plots", h.Idempotent(), h.CreatePlotPaymentAPI)
+	r.GET("/projects/:id/document", h.getProjectPaymentDocument)
+	r.PUT("/projects/:id/document", h.uploadProjectPaymentDocument)
+	r.GET("/flats/:id/receipt", h.getFlatPaymentReceipt)
+	r.PUT("/flats/:id/receipt", h.uploadFlatPaymentReceipt)
+	r.GET("/plots/:id/receipt", h.getPlotPaymentReceipt)
+	r.PUT("/plots/:id/receipt", h.uploadPlotPaymentReceipt)
+	r.GET("/projects/:id/issued-receipt", h.getProjectPaymentIssuedReceipt)
+	r.POST("/projects/:id/issued-receipt", h.issueProjectPaymentReceipt)
+	r.GET("/flats/:id/issued-receipt", h.getFlatPaymentIssuedReceipt)
+	r.POST("/flats/:id/issued-receipt", h.issueFlatPaymentReceipt)
+	r.GET("/plots/:id/issued-receipt", h.getPlotPaymentIssuedReceipt)
+	r.POST("/plots/:id/issued-receipt", h.issuePlotPaymentReceipt)
+}
+
+func (h *Handler) attendanceRoutes(r routeTable) {
+	r.GET("/stats", h.getAttendanceStats)
+	r.GET("/choices", h.getAttendanceChoices)
+	r.GET("/records", h.listAttendanceRecords)
+	r.POST("/records", h.Idempotent(), h.createAttendanceRecords)
+	r.GET("/batches", h.listAttendanceBatches)
+	r.POST("/batches", h.createAttendanceBatch)
+	r.GET("/batches/:id/members", h.listAttendanceMembers)
+}
+
+func (h *Handler) expenseRoutes(r routeTable) {
+	r.GET("/labor-work-types", h.ListLaborWorkTypes)
+	r.GET("/manpower", h.ListManpowerExpenses)
+	r.GET("/material", h.ListMaterialExpenses)
+	r.GET("/general", h.ListGeneralExpenses)
+	r.GET("/departmental", h.ListDepartmentalExpenses)
+	r.GET("/administration", h.ListAdministrationExpenses)
+}
@@ -0,0 +1,282 @@
+// Package listquery turns the cursor, page_size, sort and filter query
+// parameters of list endpoints into GORM scopes and a pagination block.
+//
+// Pages are keyset based: the cursor only names the last row that was sent,
+// and the next page continues after that row's sort value, so inserts and
+// deletes between requests never shift or repeat rows.
+package listquery
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"cms_sidecar_backend/internal/responses"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+	// batchSize is how many rows Each reads at a time.
+	batchSize = 500
+)
+
+// ErrStaleCursor means the row a cursor points at no longer exists.
+var ErrStaleCursor = errors.New("cursor is no longer valid; restart from the first page")
+
+// Spec describes what one list endpoint lets callers sort and filter by.
+type Spec struct {
+	// Table is where the cursor row's sort value is read from. Sort and
+	// filter columns may be qualified with it when the query joins.
+	Table string
+	// Sorts maps public sort keys to columns; "-key" sorts descending.
+	Sorts       map[string]string
+	DefaultSort string
+	// Filters maps query parameters to columns for exact matches. A comma
+	// separated value matches any of the listed values.
+	Filters map[string]string
+	// DateColumn enables date_from / date_to (YYYY-MM-DD, both inclusive).
+	DateColumn string
+	// DefaultPageSize applies when page_size is missing; zero means 50.
+	DefaultPageSize int
+}
+
+// Query is a parsed list request.
+type Query struct {
+	spec     Spec
+	sort     string
+	column   string
+	desc     bool
+	pageSize int
+	afterID  uint
+	filters  []filter
+	dateFrom string
+	dateTo   string
+}
+
+type filter struct {
+	column string
+	values []string
+}
+
+type cursor struct {
+	Sort string `json:"s"`
+	ID   uint   `json:"id"`
+}
+
+// Parse reads the list parameters of c against spec. Errors are safe to
+// show to the caller.
+func Parse(c *gin.Context, spec Spec) (*Query, error) {
+	q := &Query{spec: spec, pageSize: spec.DefaultPageSize}
+	if q.pageSize <= 0 {
+		q.pageSize = defaultPageSize
+	}
+
+	// limit is what the attendance page sent before page_size existed.
+	for _, param := range []string{"limit", "page_size"} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		size, err := strconv.Atoi(raw)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("%s must be a positive number", param)
+		}
+		q.pageSize = size
+	}
+	if q.pageSize > maxPageSize {
+		q.pageSize = maxPageSize
+	}
+
+	q.sort = c.DefaultQuery("sort", spec.DefaultSort)
+	key := strings.TrimPrefix(q.sort, "-")
+	column, ok := spec.Sorts[key]
+	if !ok {
+		return nil, fmt.Errorf("cannot sort by %q", key)
+	}
+	q.column = column
+	q.desc = strings.HasPrefix(q.sort, "-")
+
+	if raw := c.Query("cursor"); raw != "" {
+		var cur cursor
+		decoded, err := base64.RawURLEncoding.DecodeString(raw)
+		if err == nil {
+			err = json.Unmarshal(decoded, &cur)
+		}
+		if err != nil || cur.ID == 0 {
+			return nil, errors.New("invalid cursor")
+		}
+		if cur.Sort != q.sort {
+			return nil, errors.New("cursor belongs to a different sort order")
+		}
+		q.afterID = cur.ID
+	}
+
+	for param, column := range spec.Filters {
+		raw := strings.TrimSpace(c.Query(param))
+		if raw == "" {
+			continue
+		}
+		var values []string
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		q.filters = append(q.filters, filter{column: column, values: values})
+	}
+
+	if spec.DateColumn != "" {
+		for param, dest := range map[string]*string{"date_from": &q.dateFrom, "date_to": &q.dateTo} {
+			raw := c.Query(param)
+			if raw == "" {
+				continue
+			}
+			if _, err := time.Parse("2006-01-02", raw); err != nil {
+				return nil, fmt.Errorf("%s must look like 2006-01-02", param)
+			}
+			*dest = raw
+		}
+	}
+	return q, nil
+}
+
+// sortExpr is the expression rows are ordered and compared by. NULLs are
+// folded into an empty string so ordering and the keyset comparison always agree.
+func (q *Query) sortExpr() string {
+	if q.column == "id" || q.column == q.spec.Table+".id" {
+		return q.idColumn()
+	}
+	return "COALESCE(" + q.column + ", '')"
+}
+
+func (q *Query) idColumn() string {
+	return q.spec.Table + ".id"
+}
+
+// Filter applies the field and date filters, without paging.
+func (q *Query) Filter(db *gorm.DB) *gorm.DB {
+	for _, f := range q.filters {
+		if len(f.values) == 1 {
+			db = db.Where(f.column+" = ?", f.values[0])
+		} else {
+			db = db.Where(f.column+" IN ?", f.values)
+		}
+	}
+	if q.dateFrom != "" {
+		db = db.Where("date("+q.spec.DateColumn+") >= ?", q.dateFrom)
+	}
+	if q.dateTo != "" {
+		db = db.Where("date("+q.spec.DateColumn+") <= ?", q.dateTo)
+	}
+	return db
+}
+
+// Page applies the filters, the keyset condition, the order and a limit of
+// one extra row so Find can tell whether another page exists.
+func (q *Query) Page(db *gorm.DB) *gorm.DB {
+	db = q.Filter(db)
+	dir, cmp := "ASC", ">"
+	if q.desc {
+		dir, cmp = "DESC", "<"
+	}
+	if q.afterID != 0 {
+		expr := q.sortExpr()
+		if expr == q.idColumn() {
+			db = db.Where(q.idColumn()+" "+cmp+" ?", q.afterID)
+		} else {
+			// Without an alias the inner FROM shadows the outer table, so
+			// qualified sort columns still resolve to the cursor row.
+			cursorValue := "(SELECT " + expr + " FROM " + q.spec.Table + " WHERE " + q.idColumn() + " = ?)"
+			db = db.Where(
+				"("+expr+" "+cmp+" "+cursorValue+" OR ("+expr+" = "+cursorValue+" AND "+q.idColumn()+" "+cmp+" ?))",
+				q.afterID, q.afterID, q.afterID,
+			)
+		}
+	}
+	order := q.sortExpr() + " " + dir
+	if q.sortExpr() != q.idColumn() {
+		order += ", " + q.idColumn() + " " + dir
+	}
+	return db.Order(order).Limit(q.pageSize + 1)
+}
+
+// Find loads one page into dest, a pointer to a slice of structs with an ID
+// field, and returns the pagination block for it. db carries the endpoint's
+// own scoping (ownership, joins, preloads) but no order or limit.
+func (q *Query) Find(db *gorm.DB, dest interface{}) (responses.Pagination, error) {
+	pagination := responses.Pagination{PageSize: q.pageSize, Sort: q.sort}
+
+	if q.afterID != 0 {
+		var exists int64
+		if err := db.Session(&gorm.Session{NewDB: true}).Table(q.spec.Table).Where("id = ?", q.afterID).Count(&exists).Error; err != nil {
+			return pagination, err
+		}
+		if exists == 0 {
+			return pagination, ErrStaleCursor
+		}
+	}
+
+	if err := q.Filter(db.Session(&gorm.Session{})).Model(dest).Count(&pagination.Total).Error; err != nil {
+		return pagination, err
+	}
+	if err := q.Page(db.Session(&gorm.Session{})).Find(dest).Error; err != nil {
+		return pagination, err
+	}
+
+	rows := reflect.ValueOf(dest).Elem()
+	if rows.Len() > q.pageSize {
+		rows.SetLen(q.pageSize)
+		next, _ := json.Marshal(cursor{Sort: q.sort, ID: lastID(rows)})
+		pagination.NextCursor = base64.RawURLEncoding.EncodeToString(next)
+		pagination.HasMore = true
+	}
+	return pagination, nil
+}
+
+// Each walks every row the filters select, in the requested order, loading
+// batches into dest and calling fn after each one. The cursor and page size
+// are ignored. Batches continue after the last row of the one before, like
+// pages do, so only one batch is in memory at a time.
+func (q *Query) Each(db *gorm.DB, dest interface{}, fn func() error) error {
+	batch := *q
+	batch.pageSize = batchSize
+	batch.afterID = 0
+	rows := reflect.ValueOf(dest).Elem()
+	for {
+		rows.SetLen(0)
+		if err := batch.Page(db.Session(&gorm.Session{})).Find(dest).Error; err != nil {
+			return err
+		}
+		more := rows.Len() > batch.pageSize
+		if more {
+			rows.SetLen(batch.pageSize)
+		}
+		if rows.Len() > 0 {
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+		if !more {
+			return nil
+		}
+		batch.afterID = lastID(rows)
+	}
+}
+
+// lastID reads the ID of the last row of a slice of structs or pointers to
+// structs.
+func lastID(rows reflect.Value) uint {
+	last := rows.Index(rows.Len() - 1)
+	if last.Kind() == reflect.Ptr {
+		last = last.Elem()
+	}
+	return uint(last.FieldByName("ID").Uint())
+}
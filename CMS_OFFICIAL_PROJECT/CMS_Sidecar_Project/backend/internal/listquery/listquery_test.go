@@ -0,0 +1,145 @@
+package listquery
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var noteSpec = Spec{
+	Table:       "note",
+	Sorts:       map[string]string{"id": "id", "title": "title"},
+	DefaultSort: "-id",
+	Filters:     map[string]string{"status": "status"},
+	DateColumn:  "created_on",
+}
+
+type note struct {
+	ID        uint `gorm:"primaryKey"`
+	Title     string
+	Status    string
+	CreatedOn string
+}
+
+func (note) TableName() string {
+	return "note"
+}
+
+func parse(query string) (*Query, error) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/notes?"+query, nil)
+	return Parse(c, noteSpec)
+}
+
+func encodedCursor(raw string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		err      string
+		pageSize int
+		sort     string
+	}{
+		{name: "defaults", query: "", pageSize: defaultPageSize, sort: "-id"},
+		{name: "allowed sort", query: "sort=title", pageSize: defaultPageSize, sort: "title"},
+		{name: "unknown sort", query: "sort=-password", err: `cannot sort by "password"`},
+		{name: "page size", query: "page_size=20", pageSize: 20, sort: "-id"},
+		{name: "oversized page size is clamped", query: "page_size=100000", pageSize: maxPageSize, sort: "-id"},
+		{name: "oversized limit is clamped", query: "limit=9999", pageSize: maxPageSize, sort: "-id"},
+		{name: "zero page size", query: "page_size=0", err: "page_size must be a positive number"},
+		{name: "negative limit", query: "limit=-5", err: "limit must be a positive number"},
+		{name: "non-numeric page size", query: "page_size=ten", err: "page_size must be a positive number"},
+		{name: "cursor not base64", query: "cursor=%25%25%25", err: "invalid cursor"},
+		{name: "cursor not JSON", query: "cursor=" + encodedCursor("not json"), err: "invalid cursor"},
+		{name: "cursor without a row", query: "cursor=" + encodedCursor(`{"s":"-id","id":0}`), err: "invalid cursor"},
+		{name: "cursor from another sort", query: "cursor=" + encodedCursor(`{"s":"title","id":3}`), err: "cursor belongs to a different sort order"},
+		{name: "valid cursor", query: "cursor=" + encodedCursor(`{"s":"-id","id":3}`), pageSize: defaultPageSize, sort: "-id"},
+		{name: "unknown filter is ignored", query: "owner=1", pageSize: defaultPageSize, sort: "-id"},
+		{name: "bad date", query: "date_from=19-10-2026", err: "date_from must look like 2006-01-02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := parse(tt.query)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("Parse(%q) error = %v, want %q", tt.query, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.query, err)
+			}
+			if q.pageSize != tt.pageSize || q.sort != tt.sort {
+				t.Errorf("Parse(%q) = page size %d, sort %q; want %d, %q", tt.query, q.pageSize, q.sort, tt.pageSize, tt.sort)
+			}
+		})
+	}
+}
+
+// TestFindPages walks a filtered list two rows at a time and then follows a
+// cursor whose row was deleted.
+func TestFindPages(t *testing.T) {
+	database, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "notes.sqlite3")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.AutoMigrate(&note{}); err != nil {
+		t.Fatal(err)
+	}
+	notes := []note{
+		{Title: "e", Status: "open"}, {Title: "a", Status: "open"}, {Title: "d", Status: "closed"},
+		{Title: "b", Status: "open"}, {Title: "c", Status: "open"},
+	}
+	if err := database.Create(&notes).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var titles []string
+	query := "sort=title&status=open&page_size=2"
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatal("paging did not stop")
+		}
+		q, err := parse(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page []note
+		pagination, err := q.Find(database, &page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pagination.Total != 4 {
+			t.Errorf("total is %d, want 4 open notes", pagination.Total)
+		}
+		for _, n := range page {
+			titles = append(titles, n.Title)
+		}
+		if !pagination.HasMore {
+			break
+		}
+		query = "sort=title&status=open&page_size=2&cursor=" + pagination.NextCursor
+	}
+	if got := len(titles); got != 4 || titles[0] != "a" || titles[1] != "b" || titles[2] != "c" || titles[3] != "e" {
+		t.Errorf("pages returned %v, want [a b c e]", titles)
+	}
+
+	q, err := parse("sort=title&cursor=" + encodedCursor(`{"s":"title","id":99}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var page []note
+	if _, err := q.Find(database, &page); !errors.Is(err, ErrStaleCursor) {
+		t.Errorf("a cursor to a missing row returned %v, want ErrStaleCursor", err)
+	}
+}
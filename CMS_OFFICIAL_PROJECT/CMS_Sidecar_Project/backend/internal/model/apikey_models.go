@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// APIKey lets a script call /api/v2 without a human login. It acts as the
+// owner who created it, limited to its scopes ("payments:read",
+// "leads:write", ...) and, when AllowedIPs is set, to those addresses. Only
+// the SHA-256 of the key is stored; Prefix is kept in clear so the key can
+// be recognised in lists. The table belongs to the Go service.
+type APIKey struct {
+	ID             uint       `gorm:"column:id;primaryKey" json:"id"`
+	OrganizationID uint       `gorm:"column:organization_id;not null;index" json:"organization_id"`
+	Name           string     `gorm:"column:name;size:120;not null" json:"name"`
+	Prefix         string     `gorm:"column:prefix;size:16;not null" json:"prefix"`
+	TokenHash      string     `gorm:"column:token_hash;size:64;not null;uniqueIndex" json:"-"`
+	Scopes         string     `gorm:"column:scopes;not null" json:"scopes"`
+	AllowedIPs     string     `gorm:"column:allowed_ips" json:"allowed_ips"`
+	CreatedByID    uint       `gorm:"column:created_by_id;not null" json:"created_by_id"`
+	CreatedAt      time.Time  `gorm:"column:created_at" json:"created_at"`
+	ExpiresAt      *time.Time `gorm:"column:expires_at" json:"expires_at"`
+	LastUsedAt     *time.Time `gorm:"column:last_used_at" json:"last_used_at"`
+	LastUsedIP     string     `gorm:"column:last_used_ip;size:45" json:"last_used_ip"`
+	RevokedAt      *time.Time `gorm:"column:revoked_at" json:"revoked_at"`
+}
+
+func (APIKey) TableName() string {
+	return "cms_api_key"
+}
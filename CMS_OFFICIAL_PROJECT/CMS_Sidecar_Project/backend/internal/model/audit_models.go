@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// AuditEntry records one row created, changed or deleted through the API:
+// who did it, from where, in which request, and the changed columns as a
+// JSON object of {"column": {"from": ..., "to": ...}}. Each entry's Hash
+// covers its fields and PrevHash, the hash of the entry before it, so an
+// edited or removed entry breaks the chain. The table belongs to the Go
+// service and is append-only.
+type AuditEntry struct {
+	ID             uint      `gorm:"column:id;primaryKey" json:"id"`
+	OccurredAt     time.Time `gorm:"column:occurred_at;not null;index" json:"occurred_at"`
+	OrganizationID uint      `gorm:"column:organization_id;not null;index" json:"organization_id"`
+	UserID         uint      `gorm:"column:user_id;not null;index" json:"user_id"`
+	APIKeyID       uint      `gorm:"column:api_key_id" json:"api_key_id"`
+	SessionID      uint      `gorm:"column:session_id" json:"session_id"`
+	IP             string    `gorm:"column:ip;size:45" json:"ip"`
+	RequestID      string    `gorm:"column:request_id;size:64;index" json:"request_id"`
+	Route          string    `gorm:"column:route;size:160" json:"route"`
+	Action         string    `gorm:"column:action;size:8;not null" json:"action"`
+	Entity         string    `gorm:"column:entity;size:64;not null;index:cms_audit_entity" json:"entity"`
+	EntityID       string    `gorm:"column:entity_id;size:64;not null;index:cms_audit_entity" json:"entity_id"`
+	Changes        string    `gorm:"column:changes;not null" json:"changes"`
+	PrevHash       string    `gorm:"column:prev_hash;size:64;not null" json:"prev_hash"`
+	Hash           string    `gorm:"column:hash;size:64;not null;uniqueIndex" json:"hash"`
+}
+
+func (AuditEntry) TableName() string {
+	return "cms_audit_log"
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// IdempotencyKey remembers a write made under an Idempotency-Key header so a
+// retried request replays the stored response instead of inserting again.
+// The table belongs to the Go service; Django never reads it.
+type IdempotencyKey struct {
+	ID          uint      `gorm:"column:id;primaryKey" json:"id"`
+	Key         string    `gorm:"column:idempotency_key;size:255;not null;uniqueIndex:idx_idempotency_scope_key" json:"key"`
+	Scope       string    `gorm:"column:scope;size:255;not null;uniqueIndex:idx_idempotency_scope_key" json:"scope"`
+	UserID      uint      `gorm:"column:user_id;not null;uniqueIndex:idx_idempotency_scope_key" json:"user_id"`
+	Fingerprint string    `gorm:"column:fingerprint;size:64;not null" json:"fingerprint"`
+	Completed   bool      `gorm:"column:completed;not null;default:false" json:"completed"`
+	StatusCode  int       `gorm:"column:status_code" json:"status_code"`
+	ContentType string    `gorm:"column:content_type" json:"content_type"`
+	Response    []byte    `gorm:"column:response" json:"-"`
+	CreatedAt   time.Time `gorm:"column:created_at" json:"created_at"`
+	ExpiresAt   time.Time `gorm:"column:expires_at;index" json:"expires_at"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "cms_idempotency_key"
+}
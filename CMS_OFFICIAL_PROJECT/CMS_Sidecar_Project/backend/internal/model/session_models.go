@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// Session platforms, as reported by the client or guessed from its
+// User-Agent.
+const (
+	PlatformDesktop = "desktop"
+	PlatformWeb     = "web"
+	PlatformMobile  = "mobile"
+	PlatformUnknown = "unknown"
+)
+
+// Session is a login on one device: the bearer token the client sends back
+// is stored only as its SHA-256 hash. A revoked or expired session no longer
+// authenticates. The table belongs to the Go service.
+type Session struct {
+	ID         uint       `gorm:"column:id;primaryKey" json:"id"`
+	UserID     uint       `gorm:"column:user_id;not null;index" json:"user_id"`
+	TokenHash  string     `gorm:"column:token_hash;size:64;not null;uniqueIndex" json:"-"`
+	DeviceName string     `gorm:"column:device_name;size:120" json:"device_name"`
+	Platform   string     `gorm:"column:platform;size:16" json:"platform"`
+	UserAgent  string     `gorm:"column:user_agent;size:255" json:"user_agent"`
+	IP         string     `gorm:"column:ip;size:45" json:"ip"`
+	CreatedAt  time.Time  `gorm:"column:created_at" json:"created_at"`
+	LastSeenAt time.Time  `gorm:"column:last_seen_at" json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"column:expires_at" json:"expires_at"`
+	RevokedAt  *time.Time `gorm:"column:revoked_at" json:"revoked_at"`
+}
+
+func (Session) TableName() string {
+	return "cms_session"
+}
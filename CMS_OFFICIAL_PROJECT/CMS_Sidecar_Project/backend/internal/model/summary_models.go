@@ -0,0 +1,57 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// ProjectSummary is a per-project projection of unit counts, money in and
+// money out. SQLite triggers on the source tables refresh a project's row in
+// the same transaction as the write; see package summary.
+type ProjectSummary struct {
+	ProjectID             uint      `gorm:"column:project_id;primaryKey;autoIncrement:false" json:"project_id"`
+	TotalUnits            int       `gorm:"column:total_units;not null;default:0" json:"total_units"`
+	SoldUnits             int       `gorm:"column:sold_units;not null;default:0" json:"sold_units"`
+	BookedUnits           int       `gorm:"column:booked_units;not null;default:0" json:"booked_units"`
+	HoldUnits             int       `gorm:"column:hold_units;not null;default:0" json:"hold_units"`
+	AvailableUnits        int       `gorm:"column:available_units;not null;default:0" json:"available_units"`
+	CollectedAmount       float64   `gorm:"column:collected_amount;not null;default:0" json:"collected_amount"`
+	ManpowerExpense       float64   `gorm:"column:manpower_expense;not null;default:0" json:"manpower_expense"`
+	MaterialExpense       float64   `gorm:"column:material_expense;not null;default:0" json:"material_expense"`
+	GeneralExpense        float64   `gorm:"column:general_expense;not null;default:0" json:"general_expense"`
+	DepartmentalExpense   float64   `gorm:"column:departmental_expense;not null;default:0" json:"departmental_expense"`
+	AdministrationExpense float64   `gorm:"column:administration_expense;not null;default:0" json:"administration_expense"`
+	StockValue            float64   `gorm:"column:stock_value;not null;default:0" json:"stock_value"`
+	UpdatedAt             time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (ProjectSummary) TableName() string {
+	return "cms_project_summary"
+}
+
+// TotalExpense adds up every expense category.
+func (s ProjectSummary) TotalExpense() float64 {
+	return s.ManpowerExpense + s.MaterialExpense + s.GeneralExpense + s.DepartmentalExpense + s.AdministrationExpense
+}
+
+// SameTotals reports whether two summaries agree on every counted value.
+// Amounts are compared in paise: the triggers add and subtract them row by
+// row, so a stored total may differ from a fresh SUM in its last bits.
+func (s ProjectSummary) SameTotals(other ProjectSummary) bool {
+	if s.ProjectID != other.ProjectID || s.TotalUnits != other.TotalUnits || s.SoldUnits != other.SoldUnits ||
+		s.BookedUnits != other.BookedUnits || s.HoldUnits != other.HoldUnits || s.AvailableUnits != other.AvailableUnits {
+		return false
+	}
+	mine, theirs := s.amounts(), other.amounts()
+	for i := range mine {
+		if math.Round(mine[i]*100) != math.Round(theirs[i]*100) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s ProjectSummary) amounts() []float64 {
+	return []float64{s.CollectedAmount, s.ManpowerExpense, s.MaterialExpense, s.GeneralExpense,
+		s.DepartmentalExpense, s.AdministrationExpense, s.StockValue}
+}
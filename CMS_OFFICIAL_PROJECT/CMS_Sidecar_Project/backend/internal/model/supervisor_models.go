@@ -2,6 +2,8 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Supervisor mirrors construction_supervisor
@@ -18,6 +20,8 @@ type Supervisor struct {
 	SupervisorPasswordHash   string    `gorm:"column:supervisor_password_hash" json:"-"`
 	SupervisorCreatedAt      time.Time `gorm:"column:supervisor_created_at" json:"supervisor_created_at"`
 	SupervisorUpdatedAt      time.Time `gorm:"column:supervisor_updated_at" json:"supervisor_updated_at"`
+	SupervisorVersion        uint      `gorm:"column:supervisor_version;default:1" json:"version"`
+	OrgID                    *uint     `gorm:"column:org_id" json:"-"`
 
 	// Relationships
 	SupervisorUser   *User     `gorm:"foreignKey:SupervisorUserID" json:"-"`
@@ -27,3 +31,16 @@ type Supervisor struct {
 func (Supervisor) TableName() string {
 	return "construction_supervisor"
 }
+
+// BeforeCreate assigns the next supervisor code when none was supplied.
+func (s *Supervisor) BeforeCreate(tx *gorm.DB) error {
+	if s.SupervisorCode != "" {
+		return nil
+	}
+	code, err := NextCode(tx, SequenceSupervisor)
+	if err != nil {
+		return err
+	}
+	s.SupervisorCode = code
+	return nil
+}
@@ -0,0 +1,34 @@
+package openapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+)
+
+// DocsPage is a self-contained page that renders /api/openapi.json and can
+// send requests, so the docs work without fetching anything from a CDN.
+//
+//go:embed docs.html
+var DocsPage []byte
+
+// DocsPolicy is the Content-Security-Policy DocsPage is served with: its own
+// inline style and script, by hash, and requests back to this server.
+var DocsPolicy = "default-src 'none'; style-src " + inlineHash(DocsPage, "style") +
+	"; script-src " + inlineHash(DocsPage, "script") +
+	"; connect-src 'self'; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
+// inlineHash is the CSP source for the first <tag> block of page. Browsers
+// hash the text after turning CRLF into LF, so the same is done here.
+func inlineHash(page []byte, tag string) string {
+	open, end := []byte("<"+tag+">"), []byte("</"+tag+">")
+	start := bytes.Index(page, open)
+	stop := bytes.Index(page, end)
+	if start < 0 || stop < start {
+		return "'none'"
+	}
+	body := bytes.ReplaceAll(page[start+len(open):stop], []byte("\r\n"), []byte("\n"))
+	sum := sha256.Sum256(body)
+	return "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'"
+}
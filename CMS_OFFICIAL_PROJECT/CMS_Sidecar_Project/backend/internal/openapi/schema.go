@@ -0,0 +1,151 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// registry turns Go values into schemas, keeping named structs in
+// components so recursive models (project -> blocks -> units) terminate.
+type registry struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newRegistry() *registry {
+	return &registry{components: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+}
+
+// of documents a sample value. Maps with string keys and interface values
+// (gin.H) are described by the keys they hold, and slices of them by their
+// first element; nil is free-form.
+func (r *registry) of(v interface{}) *Schema {
+	if v == nil {
+		return &Schema{}
+	}
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Map && value.Type().Key().Kind() == reflect.String &&
+		value.Type().Elem().Kind() == reflect.Interface && value.Len() > 0 {
+		schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		iter := value.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			schema.Properties[key] = r.of(iter.Value().Interface())
+			schema.Required = append(schema.Required, key)
+		}
+		sort.Strings(schema.Required)
+		return schema
+	}
+	if value.Kind() == reflect.Slice && value.Len() > 0 && value.Type().Elem().Kind() == reflect.Map {
+		return &Schema{Type: "array", Items: r.of(value.Index(0).Interface())}
+	}
+	return r.forType(value.Type())
+}
+
+func (r *registry) forType(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Pointer {
+		inner := r.forType(t.Elem())
+		if inner.Ref != "" || inner.Type == nil {
+			return &Schema{AnyOf: []*Schema{inner, {Type: "null"}}}
+		}
+		inner.Type = []interface{}{inner.Type, "null"}
+		return inner
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	if t.Implements(marshalerType) {
+		// Custom JSON (datatypes.JSON and the like) can hold anything.
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: r.forType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.forType(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.object(t)
+		}
+		return r.named(t)
+	}
+	return &Schema{}
+}
+
+// named registers a struct under its type name, qualified with its package
+// when two packages use the same name.
+func (r *registry) named(t reflect.Type) *Schema {
+	name, ok := r.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := r.components[name]; taken {
+			name = path.Base(t.PkgPath()) + "." + name
+		}
+		r.names[t] = name
+		r.components[name] = &Schema{}
+		*r.components[name] = *r.object(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// object lists the fields encoding/json would write, flattening embedded
+// structs. Fields tagged binding:"required" are required.
+func (r *registry) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	r.fields(t, schema)
+	return schema
+}
+
+func (r *registry) fields(t reflect.Type, schema *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				r.fields(embedded, schema)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = r.forType(field.Type)
+		if strings.Contains(field.Tag.Get("binding"), "required") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package search
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// FTS5 marks matches with these control characters; the text around them is
+// HTML-escaped before they become <mark> tags, so indexed names cannot
+// inject markup.
+const (
+	highlightOpen  = "\x02"
+	highlightClose = "\x03"
+)
+
+var highlighter = strings.NewReplacer(highlightOpen, "<mark>", highlightClose, "</mark>")
+
+// Result is one ranked hit. Title and Detail are HTML-escaped and carry
+// <mark> highlights.
+type Result struct {
+	Kind   string  `json:"type"`
+	ID     uint    `json:"id"`
+	Title  string  `json:"title"`
+	Detail string  `json:"detail"`
+	Score  float64 `json:"score"`
+}
+
+// Response is what Query returns; Corrected is set when no exact or prefix
+// match existed and the query was retried with close spellings.
+type Response struct {
+	Results   []Result `json:"results"`
+	Corrected string   `json:"corrected_query,omitempty"`
+}
+
+// tokenize splits free text into letter/digit runs, lower-cased.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func isDigits(token string) bool {
+	for _, r := range token {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchExpression builds the FTS5 query for tokens: every token must match
+// as a prefix, and numbers are looked up in the phone column.
+func matchExpression(tokens []string) string {
+	terms := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if isDigits(token) {
+			// Numbers may be a phone prefix or part of a code/label.
+			terms = append(terms, `(phones : "`+token+`"* OR "`+token+`"*)`)
+			continue
+		}
+		terms = append(terms, `"`+token+`"*`)
+	}
+	return strings.Join(terms, " AND ")
+}
+
+// MatchIDs returns a subquery selecting the ids of kind that match text, for
+// use as `id IN (?)` in list endpoints. ok is false when text has no
+// searchable tokens.
+func MatchIDs(db *gorm.DB, kind, text string) (*gorm.DB, bool) {
+	tokens := tokenize(text)
+	if len(tokens) == 0 {
+		return nil, false
+	}
+	return db.Table(indexTable).Select("ref_id").Where(indexTable+" MATCH ? AND kind = ?", matchExpression(tokens), kind), true
+}
+
+// Query runs a ranked search. kinds limits the result types (all when
+// empty). Title hits weigh more than detail hits, phone hits in between.
+func Query(db *gorm.DB, text string, kinds []string, limit int) (Response, error) {
+	tokens := tokenize(text)
+	response := Response{Results: []Result{}}
+	if len(tokens) == 0 {
+		return response, nil
+	}
+
+	results, err := run(db, matchExpression(tokens), kinds, limit)
+	if err != nil || len(results) > 0 {
+		response.Results = results
+		return response, err
+	}
+
+	corrected, changed, err := correct(db, tokens)
+	if err != nil || !changed {
+		return response, err
+	}
+	results, err = run(db, matchExpression(corrected), kinds, limit)
+	if err != nil {
+		return response, err
+	}
+	response.Results = results
+	if len(results) > 0 {
+		response.Corrected = strings.Join(corrected, " ")
+	}
+	return response, nil
+}
+
+func run(db *gorm.DB, match string, kinds []string, limit int) ([]Result, error) {
+	query := db.Table(indexTable).
+		Select("kind, ref_id AS id, "+
+			"highlight("+indexTable+", 2, ?, ?) AS title, "+
+			"highlight("+indexTable+", 3, ?, ?) AS detail, "+
+			"bm25("+indexTable+", 0, 0, 10.0, 2.0, 5.0) AS score",
+			highlightOpen, highlightClose, highlightOpen, highlightClose).
+		Where(indexTable+" MATCH ?", match)
+	if len(kinds) > 0 {
+		query = query.Where("kind IN ?", kinds)
+	}
+
+	results := []Result{}
+	err := query.Order("score").Limit(limit).Scan(&results).Error
+	for i := range results {
+		// bm25 is lower-is-better; flip it so clients can sort descending.
+		results[i].Score = -results[i].Score
+		// Empty source columns leave runs of spaces behind.
+		results[i].Title = highlight(results[i].Title)
+		results[i].Detail = highlight(results[i].Detail)
+	}
+	return results, err
+}
+
+// vocabulary lists the terms of the index entries db may read, most common
+// first. The entries are read through the tenant filter, so a caller is
+// only ever offered spellings from their own organization's rows.
+func vocabulary(db *gorm.DB) ([]string, error) {
+	var entries []struct{ Title, Detail string }
+	if err := db.Table(indexTable).Select("title, detail").Scan(&entries).Error; err != nil {
+		return nil, err
+	}
+	docs := make(map[string]int)
+	for _, entry := range entries {
+		seen := make(map[string]bool)
+		for _, term := range tokenize(entry.Title + " " + entry.Detail) {
+			if !seen[term] {
+				seen[term] = true
+				docs[term]++
+			}
+		}
+	}
+	terms := make([]string, 0, len(docs))
+	for term := range docs {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if docs[terms[i]] != docs[terms[j]] {
+			return docs[terms[i]] > docs[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+	return terms, nil
+}
+
+// highlight turns a column highlighted by FTS5 into HTML: the text is
+// escaped, the match markers become <mark> tags and runs of spaces collapse.
+func highlight(text string) string {
+	return highlighter.Replace(html.EscapeString(strings.Join(strings.Fields(text), " ")))
+}
+
+// correct replaces word tokens that are not in the index with the closest
+// indexed term (edit distance 1 for short words, 2 for longer ones).
+func correct(db *gorm.DB, tokens []string) ([]string, bool, error) {
+	corrected := make([]string, len(tokens))
+	changed := false
+	var vocab []string
+	for i, token := range tokens {
+		corrected[i] = token
+		length := len([]rune(token))
+		if isDigits(token) || length < 3 {
+			continue
+		}
+		maxDistance := 1
+		if length > 5 {
+			maxDistance = 2
+		}
+
+		if vocab == nil {
+			var err error
+			if vocab, err = vocabulary(db); err != nil {
+				return nil, false, err
+			}
+		}
+
+		best, bestDistance := "", maxDistance+1
+		for _, term := range vocab {
+			if termLength := len([]rune(term)); termLength < length-maxDistance || termLength > length+maxDistance {
+				continue
+			}
+			if term == token {
+				best, bestDistance = "", 0
+				break
+			}
+			if d := levenshtein(token, term, bestDistance); d < bestDistance {
+				best, bestDistance = term, d
+			}
+		}
+		if best != "" {
+			corrected[i] = best
+			changed = true
+		}
+	}
+	return corrected, changed, nil
+}
+
+// levenshtein returns the edit distance between a and b, or limit when it
+// is at least limit.
+func levenshtein(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin >= limit {
+			return limit
+		}
+		prev, curr = curr, prev
+	}
+	return min(prev[len(rb)], limit)
+}
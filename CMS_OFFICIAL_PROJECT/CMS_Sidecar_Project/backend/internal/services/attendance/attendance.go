@@ -0,0 +1,181 @@
+// Package attendance saves attendance batches with their members and marks
+// daily attendance for a batch.
+package attendance
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"cms_sidecar_backend/internal/model"
+	"cms_sidecar_backend/internal/services"
+	utils "cms_sidecar_backend/internal/utilities/attendance_page_app"
+	"gorm.io/gorm"
+)
+
+// Service manages batches and attendance records.
+type Service interface {
+	// SaveBatch creates the batch named in req, or fills in the project and
+	// description of an existing one, and adds members it does not have yet.
+	// It returns the batch and the number of members added.
+	SaveBatch(ctx context.Context, req utils.AttendanceBatchRequest) (model.AttendanceBatch, int, error)
+	// MarkAttendance writes today's record for every active member of the
+	// batch: present for the listed members, absent for the rest. It returns
+	// the number of records written.
+	MarkAttendance(ctx context.Context, req utils.AttendanceRecordRequest) (int, error)
+}
+
+type service struct {
+	db *gorm.DB
+}
+
+// New builds the attendance service on db.
+func New(db *gorm.DB) Service {
+	return &service{db: db}
+}
+
+func (s *service) SaveBatch(ctx context.Context, req utils.AttendanceBatchRequest) (model.AttendanceBatch, int, error) {
+	var batch model.AttendanceBatch
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return batch, 0, services.Fail(services.ErrInvalid, "batch name is required")
+	}
+	role := strings.TrimSpace(req.Role)
+	if role == "" {
+		role = "staff"
+	}
+	description := strings.TrimSpace(req.Description)
+
+	if req.ProjectID != nil {
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&model.Project{}).Where("id = ?", *req.ProjectID).Count(&count).Error; err != nil {
+			return batch, 0, err
+		}
+		if count == 0 {
+			return batch, 0, services.Fail(services.ErrInvalid, "project not found")
+		}
+	}
+
+	created := 0
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("name = ?", name).First(&batch).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			batch = model.AttendanceBatch{
+				Name:        name,
+				Description: description,
+				ProjectID:   req.ProjectID,
+				CreatedAt:   time.Now(),
+			}
+			if err := tx.Create(&batch).Error; err != nil {
+				return err
+			}
+		} else if batch.ProjectID == nil && req.ProjectID != nil {
+			batch.ProjectID = req.ProjectID
+			if err := tx.Model(&batch).Update("project_id", batch.ProjectID).Error; err != nil {
+				return err
+			}
+		}
+		if batch.Description == "" && description != "" {
+			batch.Description = description
+			if err := tx.Model(&batch).Update("description", batch.Description).Error; err != nil {
+				return err
+			}
+		}
+
+		names := make(map[string]struct{})
+		for _, raw := range req.Members {
+			memberName := strings.TrimSpace(raw)
+			if memberName == "" {
+				continue
+			}
+			if _, ok := names[strings.ToLower(memberName)]; ok {
+				continue
+			}
+			names[strings.ToLower(memberName)] = struct{}{}
+			var member model.AttendanceMember
+			if err := tx.Where("batch_id = ? AND name = ?", batch.ID, memberName).First(&member).Error; err == nil {
+				continue
+			}
+			member = model.AttendanceMember{
+				BatchID:   batch.ID,
+				Name:      memberName,
+				Role:      role,
+				IsActive:  true,
+				CreatedAt: time.Now(),
+			}
+			if err := tx.Create(&member).Error; err != nil {
+				return err
+			}
+			created++
+		}
+		return nil
+	})
+	return batch, created, err
+}
+
+func (s *service) MarkAttendance(ctx context.Context, req utils.AttendanceRecordRequest) (int, error) {
+	if len(req.PresentMemberIDs) == 0 {
+		return 0, services.Fail(services.ErrInvalid, "select at least one present member")
+	}
+	checkIn, err := utils.ParseAttendanceTime(req.CheckIn)
+	if err != nil || checkIn == nil {
+		return 0, services.Fail(services.ErrInvalid, "check-in time is required (HH:MM)")
+	}
+	checkOut, err := utils.ParseAttendanceTime(req.CheckOut)
+	if err != nil || checkOut == nil {
+		return 0, services.Fail(services.ErrInvalid, "check-out time is required (HH:MM)")
+	}
+
+	var batch model.AttendanceBatch
+	if err := s.db.WithContext(ctx).First(&batch, req.BatchID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, services.Fail(services.ErrInvalid, "batch not found")
+		}
+		return 0, err
+	}
+
+	var members []model.AttendanceMember
+	if err := s.db.WithContext(ctx).Where("batch_id = ? AND is_active = ?", batch.ID, true).Find(&members).Error; err != nil {
+		return 0, err
+	}
+
+	presentMap := make(map[uint]struct{}, len(req.PresentMemberIDs))
+	for _, id := range req.PresentMemberIDs {
+		presentMap[id] = struct{}{}
+	}
+
+	attendanceDate := utils.DateOnly(time.Now())
+	created := 0
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, member := range members {
+			_, isPresent := presentMap[member.ID]
+			record := model.AttendanceRecord{
+				AttendeeName:    member.Name,
+				AttendeeRole:    member.Role,
+				AttendanceDate:  attendanceDate,
+				Status:          "absent",
+				Mode:            "onsite",
+				CreatedAt:       time.Now(),
+				AttendanceBatch: batch.Name,
+				MemberID:        &member.ID,
+			}
+			if isPresent {
+				record.Status = "present"
+				record.Mode = model.NormalizeAttendanceMode(req.Mode)
+				record.CheckInTime = checkIn
+				record.CheckOutTime = checkOut
+				record.WorkNotes = strings.TrimSpace(req.Notes)
+			}
+			if err := tx.Create(&record).Error; err != nil {
+				return err
+			}
+			created++
+		}
+		return nil
+	})
+	return created, err
+}
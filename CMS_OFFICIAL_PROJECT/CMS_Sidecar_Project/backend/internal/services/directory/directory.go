@@ -0,0 +1,99 @@
+// Package directory manages the people in the builder's directory and their
+// portal credentials.
+package directory
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"cms_sidecar_backend/internal/model"
+	"cms_sidecar_backend/internal/services"
+	utils "cms_sidecar_backend/internal/utilities/directory_page_app"
+	"gorm.io/gorm"
+)
+
+// Service lists vendors and resets portal credentials.
+type Service interface {
+	// Vendors lists vendors by display name, skipping unnamed ones.
+	Vendors(ctx context.Context) ([]utils.VendorListEntry, error)
+	// RegenerateCredentials gives a supervisor or customer a new temporary
+	// password and returns it once.
+	RegenerateCredentials(ctx context.Context, personType string, id uint) (utils.Credentials, error)
+}
+
+type service struct {
+	db *gorm.DB
+}
+
+// New builds the directory service on db.
+func New(db *gorm.DB) Service {
+	return &service{db: db}
+}
+
+func (s *service) Vendors(ctx context.Context) ([]utils.VendorListEntry, error) {
+	var vendors []model.Vendor
+	// Django filters by vendor_created_by=owner; every vendor is listed until
+	// requests carry a user.
+	if err := s.db.WithContext(ctx).Order("vendor_company_name asc, vendor_first_name asc").Find(&vendors).Error; err != nil {
+		return nil, err
+	}
+
+	var data []utils.VendorListEntry
+	for _, v := range vendors {
+		name := strings.TrimSpace(v.VendorCompanyName)
+		if name == "" {
+			name = strings.TrimSpace(strings.TrimSpace(v.VendorFirstName) + " " + strings.TrimSpace(v.VendorLastName))
+		}
+		if name != "" {
+			data = append(data, utils.VendorListEntry{Name: name, DisplayName: name})
+		}
+	}
+	return data, nil
+}
+
+func (s *service) RegenerateCredentials(ctx context.Context, personType string, id uint) (utils.Credentials, error) {
+	var creds utils.Credentials
+	personType = strings.ToLower(personType)
+	if personType != "supervisor" && personType != "customer" {
+		return creds, services.Fail(services.ErrInvalid, "Invalid person type")
+	}
+
+	pass, err := utils.GenerateTempPassword(10)
+	if err != nil {
+		return creds, err
+	}
+	creds.Password = pass
+	// Stored as given until bcrypt is wired in:
+	// bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+	hashedPass := pass
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if personType == "supervisor" {
+			var sup model.Supervisor
+			if err := tx.First(&sup, id).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return services.Fail(services.ErrNotFound, "Supervisor not found")
+				}
+				return err
+			}
+			// The Go model has no link to the auth user yet, so only the
+			// supervisor's own hash is replaced and the username stays empty.
+			sup.SupervisorPasswordHash = hashedPass
+			creds.Code = sup.SupervisorCode
+			return tx.Save(&sup).Error
+		}
+
+		var cust model.Customer
+		if err := tx.First(&cust, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return services.Fail(services.ErrNotFound, "Customer not found")
+			}
+			return err
+		}
+		cust.CustomerPasswordHash = hashedPass
+		creds.Code = cust.CustomerCode
+		return tx.Save(&cust).Error
+	})
+	return creds, err
+}
@@ -0,0 +1,365 @@
+// Package files accepts uploads into the configured storage, checks what
+// they are, makes thumbnails of images and signs the expiring links
+// downloads go through.
+package files
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"cms_sidecar_backend/internal/model"
+	"cms_sidecar_backend/internal/services"
+	"cms_sidecar_backend/internal/storage"
+	"gorm.io/gorm"
+)
+
+// LinkPrefix is where signed download links are served.
+const LinkPrefix = "/api/files/"
+
+// Purpose says what an upload is for, which picks its key prefix, the types
+// it may be and its size cap.
+type Purpose string
+
+const (
+	PaymentDocument Purpose = "payment_document"
+	PaymentReceipt  Purpose = "payment_receipt"
+	Avatar          Purpose = "avatar"
+	Logo            Purpose = "letterhead_logo"
+	IssuedReceipt   Purpose = "issued_receipt" // rendered by the service, not uploaded
+)
+
+// avatarBytes and logoBytes cap avatars and letterhead logos below the
+// general upload limit.
+const (
+	avatarBytes = 2 << 20
+	logoBytes   = 1 << 20
+)
+
+type rule struct {
+	prefix   string
+	types    []string
+	refusal  string
+	maxBytes int64
+}
+
+var rules = map[Purpose]rule{
+	PaymentDocument: {"payments/documents", []string{"application/pdf", "image/jpeg", "image/png"}, "Payment documents must be PDF, JPEG or PNG files", 0},
+	PaymentReceipt:  {"payments/receipts", []string{"application/pdf", "image/jpeg", "image/png"}, "Receipts must be PDF, JPEG or PNG files", 0},
+	Avatar:          {"avatars", []string{"image/jpeg", "image/png"}, "Avatars must be JPEG or PNG images", avatarBytes},
+	Logo:            {"letterheads", []string{"image/jpeg", "image/png"}, "Logos must be JPEG or PNG images", logoBytes},
+	IssuedReceipt:   {"receipts", []string{"application/pdf"}, "Receipts must be PDF files", 0},
+}
+
+// extensions name stored files by their sniffed type, never the client's.
+var extensions = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+}
+
+// Settings are the limits and the link secret the service runs with.
+type Settings struct {
+	// MaxBytes caps every upload.
+	MaxBytes int64
+	// LinkTTL is how long a signed link works.
+	LinkTTL time.Duration
+	// SigningKey signs links; processes serving the same links share it.
+	SigningKey []byte
+}
+
+// Upload is a file on its way in.
+type Upload struct {
+	Purpose        Purpose
+	Name           string
+	Body           io.Reader
+	OrganizationID uint
+	UploadedByID   uint
+}
+
+// Link is a signed download URL and when it stops working.
+type Link struct {
+	URL     string
+	Expires time.Time
+}
+
+// Service stores uploads and serves them back through signed links.
+type Service interface {
+	// MaxBytes is the largest upload purpose accepts.
+	MaxBytes(purpose Purpose) int64
+	// Save checks an upload's size and sniffed type, stores it with a
+	// thumbnail when it is an image, and records it.
+	Save(ctx context.Context, upload Upload) (model.StoredFile, error)
+	// Describe returns the record of key. Files stored before uploads were
+	// recorded, by Django for instance, get one built from the store.
+	Describe(ctx context.Context, key string) (model.StoredFile, error)
+	// Open reads the file under key, which may be a thumbnail key, and
+	// describes it as Describe does.
+	Open(ctx context.Context, key string) (io.ReadCloser, model.StoredFile, error)
+	// Link signs a download link for key.
+	Link(key string) Link
+	// Check verifies the expiry and signature of a link to key.
+	Check(key, expires, signature string) error
+}
+
+type service struct {
+	db       *gorm.DB
+	store    storage.Store
+	settings Settings
+	now      func() time.Time
+}
+
+// New builds the file service on db and store. A nil store refuses uploads.
+func New(db *gorm.DB, store storage.Store, settings Settings) Service {
+	return &service{db: db, store: store, settings: settings, now: time.Now}
+}
+
+// RandomKey makes a signing key for a process that was not given one; its
+// links die with it.
+func RandomKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func (s *service) MaxBytes(purpose Purpose) int64 {
+	limit := s.settings.MaxBytes
+	if own := rules[purpose].maxBytes; own > 0 && own < limit {
+		limit = own
+	}
+	return limit
+}
+
+func (s *service) Save(ctx context.Context, upload Upload) (model.StoredFile, error) {
+	var stored model.StoredFile
+	rule, ok := rules[upload.Purpose]
+	if !ok {
+		return stored, fmt.Errorf("unknown upload purpose %q", upload.Purpose)
+	}
+	if s.store == nil {
+		return stored, errors.New("file storage is not configured")
+	}
+	limit := s.MaxBytes(upload.Purpose)
+	data, err := io.ReadAll(io.LimitReader(upload.Body, limit+1))
+	if err != nil {
+		return stored, err
+	}
+	if int64(len(data)) > limit {
+		return stored, services.Fail(services.ErrTooLarge, fmt.Sprintf("File is larger than %s", humanBytes(limit)))
+	}
+	if len(data) == 0 {
+		return stored, services.Fail(services.ErrInvalid, "File is empty")
+	}
+	contentType := sniff(data)
+	if !contains(rule.types, contentType) {
+		return stored, services.Fail(services.ErrUnsupported, rule.refusal)
+	}
+
+	now := s.now()
+	name, err := newName()
+	if err != nil {
+		return stored, err
+	}
+	dir := fmt.Sprintf("%s/%s", rule.prefix, now.Format("2006/01"))
+	sum := sha256.Sum256(data)
+	stored = model.StoredFile{
+		Key:            dir + "/" + name + extensions[contentType],
+		Name:           cleanName(upload.Name, extensions[contentType]),
+		ContentType:    contentType,
+		Size:           int64(len(data)),
+		SHA256:         hex.EncodeToString(sum[:]),
+		Purpose:        string(upload.Purpose),
+		OrganizationID: upload.OrganizationID,
+		UploadedByID:   upload.UploadedByID,
+		CreatedAt:      now,
+	}
+
+	var thumb []byte
+	if strings.HasPrefix(contentType, "image/") {
+		if thumb, err = thumbnail(data); err != nil {
+			return stored, err
+		}
+		stored.ThumbnailKey = dir + "/" + name + ".thumb.jpg"
+	}
+	if err := s.store.Put(ctx, stored.Key, bytes.NewReader(data), stored.Size, contentType); err != nil {
+		return stored, err
+	}
+	if thumb != nil {
+		if err := s.store.Put(ctx, stored.ThumbnailKey, bytes.NewReader(thumb), int64(len(thumb)), "image/jpeg"); err != nil {
+			s.store.Delete(ctx, stored.Key)
+			return stored, err
+		}
+	}
+	if err := s.db.WithContext(ctx).Create(&stored).Error; err != nil {
+		s.store.Delete(ctx, stored.Key)
+		if stored.ThumbnailKey != "" {
+			s.store.Delete(ctx, stored.ThumbnailKey)
+		}
+		return stored, err
+	}
+	return stored, nil
+}
+
+func (s *service) Describe(ctx context.Context, key string) (model.StoredFile, error) {
+	stored, err := s.record(ctx, key)
+	if err != nil || stored.ID != 0 {
+		return stored, err
+	}
+	body, object, err := s.open(ctx, key)
+	if err != nil {
+		return stored, err
+	}
+	body.Close()
+	return unrecorded(key, object), nil
+}
+
+func (s *service) Open(ctx context.Context, key string) (io.ReadCloser, model.StoredFile, error) {
+	body, object, err := s.open(ctx, key)
+	if err != nil {
+		return nil, model.StoredFile{}, err
+	}
+	stored, err := s.record(ctx, key)
+	if err != nil {
+		body.Close()
+		return nil, stored, err
+	}
+	if stored.ID == 0 {
+		stored = unrecorded(key, object)
+	}
+	// Stores that stream without a length report -1.
+	if object.Size >= 0 {
+		stored.Size = object.Size
+	}
+	return body, stored, nil
+}
+
+func (s *service) record(ctx context.Context, key string) (model.StoredFile, error) {
+	var stored model.StoredFile
+	err := s.db.WithContext(ctx).Where(&model.StoredFile{Key: key}).Limit(1).Find(&stored).Error
+	return stored, err
+}
+
+func (s *service) open(ctx context.Context, key string) (io.ReadCloser, storage.Object, error) {
+	if s.store == nil {
+		return nil, storage.Object{}, services.Fail(services.ErrNotFound, "File not found")
+	}
+	body, object, err := s.store.Open(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, object, services.Fail(services.ErrNotFound, "File not found")
+	}
+	return body, object, err
+}
+
+// unrecorded describes a file from what the store knows about it.
+func unrecorded(key string, object storage.Object) model.StoredFile {
+	stored := model.StoredFile{
+		Key:         key,
+		Name:        path.Base(key),
+		ContentType: object.ContentType,
+		Size:        object.Size,
+		CreatedAt:   object.ModTime,
+	}
+	if stored.ContentType == "" {
+		stored.ContentType = "application/octet-stream"
+	}
+	return stored
+}
+
+func (s *service) Link(key string) Link {
+	expires := s.now().Add(s.settings.LinkTTL).Truncate(time.Second)
+	stamp := strconv.FormatInt(expires.Unix(), 10)
+	query := url.Values{"expires": {stamp}, "signature": {s.sign(key, stamp)}}
+	return Link{
+		URL:     LinkPrefix + (&url.URL{Path: key}).EscapedPath() + "?" + query.Encode(),
+		Expires: expires,
+	}
+}
+
+func (s *service) Check(key, expires, signature string) error {
+	stamp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || !hmac.Equal([]byte(signature), []byte(s.sign(key, expires))) {
+		return services.Fail(services.ErrForbidden, "Invalid download link")
+	}
+	if s.now().Unix() > stamp {
+		return services.Fail(services.ErrForbidden, "Download link has expired")
+	}
+	return nil
+}
+
+func (s *service) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.settings.SigningKey)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sniff reads the type from the content, ignoring what the client claimed.
+func sniff(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return contentType
+}
+
+func newName() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// cleanName keeps the client's file name for display: its base, without
+// control characters, at most 200 bytes and ending in the sniffed extension.
+func cleanName(name, ext string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, path.Base(strings.ReplaceAll(name, `\`, "/")))
+	name = strings.TrimSpace(strings.TrimSuffix(name, path.Ext(name)))
+	if name == "" || name == "." || name == "/" {
+		name = "upload"
+	}
+	for len(name) > 200 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name + ext
+}
+
+func humanBytes(n int64) string {
+	if n >= 1<<20 && n%(1<<20) == 0 {
+		return fmt.Sprintf("%d MB", n>>20)
+	}
+	if n >= 1<<10 && n%(1<<10) == 0 {
+		return fmt.Sprintf("%d KB", n>>10)
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
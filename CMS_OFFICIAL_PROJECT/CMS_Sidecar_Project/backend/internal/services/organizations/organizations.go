@@ -0,0 +1,203 @@
+// Package organizations manages the members of an organization and their
+// roles.
+package organizations
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"cms_sidecar_backend/internal/model"
+	"cms_sidecar_backend/internal/services"
+	"gorm.io/gorm"
+)
+
+// Service reads and edits organizations. Every edit takes the acting
+// member; only owners and admins may make one.
+type Service interface {
+	// Get loads the organization with its members and their logins.
+	Get(ctx context.Context, orgID uint) (model.Organization, error)
+	// Rename changes the name of actor's organization.
+	Rename(ctx context.Context, actor model.OrganizationMember, name string) (model.Organization, error)
+	// AddMember puts a user who belongs to no organization into actor's.
+	AddMember(ctx context.Context, actor model.OrganizationMember, username, role string) (model.OrganizationMember, error)
+	// SetRole changes a member's role. Only owners grant or take away
+	// ownership, and the last owner keeps it.
+	SetRole(ctx context.Context, actor model.OrganizationMember, userID uint, role string) (model.OrganizationMember, error)
+	// RemoveMember takes a member out of actor's organization. The last
+	// owner cannot be removed.
+	RemoveMember(ctx context.Context, actor model.OrganizationMember, userID uint) error
+}
+
+type service struct {
+	db *gorm.DB
+}
+
+// New builds the organization service on db.
+func New(db *gorm.DB) Service {
+	return &service{db: db}
+}
+
+var roles = map[string]bool{model.MemberRoleOwner: true, model.MemberRoleAdmin: true, model.MemberRoleMember: true}
+
+func canManage(actor model.OrganizationMember) error {
+	if actor.OrganizationID == 0 || (actor.Role != model.MemberRoleOwner && actor.Role != model.MemberRoleAdmin) {
+		return services.Fail(services.ErrForbidden, "Only organization owners and admins can do this")
+	}
+	return nil
+}
+
+func (s *service) Get(ctx context.Context, orgID uint) (model.Organization, error) {
+	var org model.Organization
+	err := s.db.WithContext(ctx).
+		Preload("Members", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, id") }).
+		Preload("Members.User.Profile").
+		First(&org, orgID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return org, services.Fail(services.ErrNotFound, "You do not belong to an organization")
+	}
+	return org, err
+}
+
+func (s *service) Rename(ctx context.Context, actor model.OrganizationMember, name string) (model.Organization, error) {
+	if err := canManage(actor); err != nil {
+		return model.Organization{}, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return model.Organization{}, services.Fail(services.ErrInvalid, "Name is required")
+	}
+	err := s.db.WithContext(ctx).Model(&model.Organization{ID: actor.OrganizationID}).
+		Updates(map[string]interface{}{"name": name, "updated_at": time.Now()}).Error
+	if err != nil {
+		return model.Organization{}, err
+	}
+	return s.Get(ctx, actor.OrganizationID)
+}
+
+func (s *service) AddMember(ctx context.Context, actor model.OrganizationMember, username, role string) (model.OrganizationMember, error) {
+	if err := canManage(actor); err != nil {
+		return model.OrganizationMember{}, err
+	}
+	if role == "" {
+		role = model.MemberRoleMember
+	}
+	if err := s.checkRole(actor, role); err != nil {
+		return model.OrganizationMember{}, err
+	}
+
+	var user model.User
+	err := s.db.WithContext(ctx).Preload("Profile").Where("username = ?", strings.TrimSpace(username)).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.OrganizationMember{}, services.Fail(services.ErrNotFound, "User not found")
+	}
+	if err != nil {
+		return model.OrganizationMember{}, err
+	}
+
+	member := model.OrganizationMember{
+		OrganizationID: actor.OrganizationID,
+		UserID:         user.ID,
+		Role:           role,
+		CreatedAt:      time.Now(),
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.OrganizationMember{}).Where("user_id = ?", user.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return services.Fail(services.ErrConflict, "User already belongs to an organization")
+		}
+		return tx.Create(&member).Error
+	})
+	member.User = &user
+	return member, err
+}
+
+func (s *service) SetRole(ctx context.Context, actor model.OrganizationMember, userID uint, role string) (model.OrganizationMember, error) {
+	if err := canManage(actor); err != nil {
+		return model.OrganizationMember{}, err
+	}
+	if err := s.checkRole(actor, role); err != nil {
+		return model.OrganizationMember{}, err
+	}
+
+	var member model.OrganizationMember
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if member, err = s.member(tx, actor, userID); err != nil {
+			return err
+		}
+		if member.Role == model.MemberRoleOwner && role != model.MemberRoleOwner {
+			if err := s.keepOwner(tx, actor); err != nil {
+				return err
+			}
+		}
+		member.Role = role
+		return tx.Model(&member).Update("role", role).Error
+	})
+	return member, err
+}
+
+func (s *service) RemoveMember(ctx context.Context, actor model.OrganizationMember, userID uint) error {
+	if err := canManage(actor); err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		member, err := s.member(tx, actor, userID)
+		if err != nil {
+			return err
+		}
+		if member.Role == model.MemberRoleOwner {
+			if err := s.keepOwner(tx, actor); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&member).Error
+	})
+}
+
+// checkRole accepts the known roles; only owners hand out ownership.
+func (s *service) checkRole(actor model.OrganizationMember, role string) error {
+	if !roles[role] {
+		return services.Fail(services.ErrInvalid, "Role must be owner, admin or member")
+	}
+	if role == model.MemberRoleOwner && actor.Role != model.MemberRoleOwner {
+		return services.Fail(services.ErrForbidden, "Only owners can make someone an owner")
+	}
+	return nil
+}
+
+// member loads userID's membership in actor's organization. Admins cannot
+// touch owners.
+func (s *service) member(tx *gorm.DB, actor model.OrganizationMember, userID uint) (model.OrganizationMember, error) {
+	var member model.OrganizationMember
+	err := tx.Preload("User.Profile").Where("organization_id = ? AND user_id = ?", actor.OrganizationID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return member, services.Fail(services.ErrNotFound, "Member not found")
+	}
+	if err != nil {
+		return member, err
+	}
+	if member.Role == model.MemberRoleOwner && actor.Role != model.MemberRoleOwner {
+		return member, services.Fail(services.ErrForbidden, "Only owners can change an owner")
+	}
+	return member, nil
+}
+
+// keepOwner fails when the owner about to lose that role is the last one.
+func (s *service) keepOwner(tx *gorm.DB, actor model.OrganizationMember) error {
+	var owners int64
+	err := tx.Model(&model.OrganizationMember{}).
+		Where("organization_id = ? AND role = ?", actor.OrganizationID, model.MemberRoleOwner).
+		Count(&owners).Error
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return services.Fail(services.ErrConflict, "An organization needs at least one owner")
+	}
+	return nil
+}
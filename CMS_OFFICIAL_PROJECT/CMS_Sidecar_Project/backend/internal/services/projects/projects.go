@@ -0,0 +1,89 @@
+// Package projects decides which construction projects a user may see.
+// Django: _accessible_projects(user), with builders seeing every project of
+// their organization.
+package projects
+
+import (
+	"context"
+
+	"cms_sidecar_backend/internal/model"
+	authUtils "cms_sidecar_backend/internal/utilities/auth_page_app"
+	"gorm.io/gorm"
+)
+
+// Service answers project access questions.
+type Service interface {
+	// Scope restricts a construction_project query to what userID may see, so
+	// callers can filter, count or join in SQL.
+	Scope(ctx context.Context, userID uint) (func(*gorm.DB) *gorm.DB, error)
+	// Accessible lists the projects userID may see, by name.
+	Accessible(ctx context.Context, userID uint) ([]model.Project, error)
+	// CanAccess checks a single project with one COUNT.
+	CanAccess(ctx context.Context, userID uint, projectID uint) (bool, error)
+}
+
+type service struct {
+	db *gorm.DB
+}
+
+// New builds the project service on db.
+func New(db *gorm.DB) Service {
+	return &service{db: db}
+}
+
+func (s *service) Scope(ctx context.Context, userID uint) (func(*gorm.DB) *gorm.DB, error) {
+	var user model.User
+	if err := s.db.WithContext(ctx).Preload("Profile").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	userRole := "builder"
+	if user.Profile != nil && user.Profile.UserType != "" {
+		userRole = authUtils.GetUserRole(user.Profile.UserType)
+	}
+
+	switch userRole {
+	case "builder", "organization":
+		// Spelled out as well as enforced by the tenant filter, so callers
+		// without an organization context (cmsctl) get the same answer.
+		orgIDs := s.db.Model(&model.OrganizationMember{}).Select("organization_id").Where("user_id = ?", user.ID)
+		return func(db *gorm.DB) *gorm.DB {
+			return db.Where("construction_project.org_id IN (?)", orgIDs)
+		}, nil
+	case "supervisor":
+		// Resolved as a subquery; no supervisor row simply matches nothing.
+		supervisorIDs := s.db.WithContext(ctx).Model(&model.Supervisor{}).Select("id").Where("supervisor_user_id = ?", user.ID)
+		return func(db *gorm.DB) *gorm.DB {
+			return db.Where("construction_project.project_assigned_supervisor_id IN (?)", supervisorIDs)
+		}, nil
+	default:
+		// Fallback for customer is generally "no access" to management views,
+		// but Django code redirects customer away.
+		return func(db *gorm.DB) *gorm.DB {
+			return db.Where("1 = 0")
+		}, nil
+	}
+}
+
+func (s *service) Accessible(ctx context.Context, userID uint) ([]model.Project, error) {
+	scope, err := s.Scope(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var projects []model.Project
+	if err := s.db.WithContext(ctx).Model(&model.Project{}).Scopes(scope).Order("project_name asc").Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+func (s *service) CanAccess(ctx context.Context, userID uint, projectID uint) (bool, error) {
+	scope, err := s.Scope(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	var count int64
+	err = s.db.WithContext(ctx).Model(&model.Project{}).Scopes(scope).Where("construction_project.id = ?", projectID).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,34 @@
+package receipts
+
+import "testing"
+
+func TestAmounts(t *testing.T) {
+	tests := []struct {
+		amount  float64
+		figures string
+		words   string
+	}{
+		{0, "Rs. 0.00", "Rupees Zero Only"},
+		{0.5, "Rs. 0.50", "Fifty Paise Only"},
+		{0.07, "Rs. 0.07", "Seven Paise Only"},
+		{100000, "Rs. 1,00,000.00", "Rupees One Lakh Only"},
+		{10000000, "Rs. 1,00,00,000.00", "Rupees One Crore Only"},
+		{125000.50, "Rs. 1,25,000.50", "Rupees One Lakh Twenty-Five Thousand and Fifty Paise Only"},
+		{1234567.89, "Rs. 12,34,567.89", "Rupees Twelve Lakh Thirty-Four Thousand Five Hundred Sixty-Seven and Eighty-Nine Paise Only"},
+		{0.995, "Rs. 1.00", "Rupees One Only"},
+		{1.005, "Rs. 1.01", "Rupees One and One Paise Only"},
+		{1234.995, "Rs. 1,235.00", "Rupees One Thousand Two Hundred Thirty-Five Only"},
+		{99999.995, "Rs. 1,00,000.00", "Rupees One Lakh Only"},
+		{0.994, "Rs. 0.99", "Ninety-Nine Paise Only"},
+		{-250.25, "-Rs. 250.25", "Minus Rupees Two Hundred Fifty and Twenty-Five Paise Only"},
+		{-0.001, "Rs. 0.00", "Rupees Zero Only"},
+	}
+	for _, tt := range tests {
+		if got := Figures(tt.amount); got != tt.figures {
+			t.Errorf("Figures(%v) = %q, want %q", tt.amount, got, tt.figures)
+		}
+		if got := Words(tt.amount); got != tt.words {
+			t.Errorf("Words(%v) = %q, want %q", tt.amount, got, tt.words)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+// Package receipts issues numbered PDF receipts for project, flat and plot
+// payments and keeps the letterhead each organization prints on them.
+package receipts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"cms_sidecar_backend/internal/model"
+	"cms_sidecar_backend/internal/services"
+	"cms_sidecar_backend/internal/services/files"
+	"cms_sidecar_backend/internal/services/payments"
+	orgUtils "cms_sidecar_backend/internal/utilities/organization_page_app"
+	utils "cms_sidecar_backend/internal/utilities/payments_page_app"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Service issues receipts and edits letterheads. Letterhead edits take the
+// acting member, who must be an owner or admin.
+type Service interface {
+	// Issue numbers a payment's receipt, renders it and stores the PDF.
+	// A payment gets one receipt: issuing again returns it, re-rendering
+	// the PDF under the same number if storing it failed before. created
+	// says whether this call numbered it.
+	Issue(ctx context.Context, actor model.OrganizationMember, ledger payments.Ledger, paymentID uint, payer string) (receipt model.PaymentReceipt, created bool, err error)
+	// Find returns the receipt issued for a payment.
+	Find(ctx context.Context, ledger payments.Ledger, paymentID uint) (model.PaymentReceipt, error)
+	// Letterhead returns orgID's letterhead, named after the organization
+	// until one is saved.
+	Letterhead(ctx context.Context, orgID uint) (model.Letterhead, error)
+	// SaveLetterhead replaces the text of actor's letterhead, keeping its
+	// logo.
+	SaveLetterhead(ctx context.Context, actor model.OrganizationMember, req orgUtils.LetterheadRequest) (model.Letterhead, error)
+	// SetLogo points actor's letterhead at an uploaded logo.
+	SetLogo(ctx context.Context, actor model.OrganizationMember, key string) (model.Letterhead, error)
+}
+
+type service struct {
+	db    *gorm.DB
+	files files.Service
+}
+
+// New builds the receipt service on db, storing PDFs through files.
+func New(db *gorm.DB, files files.Service) Service {
+	return &service{db: db, files: files}
+}
+
+// errIssued rolls back a numbering that lost the race for a payment.
+var errIssued = errors.New("receipt already issued")
+
+func (s *service) Issue(ctx context.Context, actor model.OrganizationMember, ledger payments.Ledger, paymentID uint, payer string) (model.PaymentReceipt, bool, error) {
+	payment, err := s.payment(ctx, ledger, paymentID)
+	if err != nil {
+		return model.PaymentReceipt{}, false, err
+	}
+	receipt, err := s.issued(ctx, ledger, paymentID)
+	if err != nil {
+		return receipt, false, err
+	}
+	created := false
+	if receipt.ID == 0 {
+		payer = strings.TrimSpace(payer)
+		if payer == "" {
+			payer = payment.payer
+		}
+		if payer == "" {
+			return receipt, false, services.Fail(services.ErrInvalid, "Payer is required")
+		}
+		if utf8.RuneCountInString(payer) > 255 {
+			return receipt, false, services.Fail(services.ErrInvalid, "Payer is too long")
+		}
+		receipt = model.PaymentReceipt{
+			Ledger:         string(ledger),
+			PaymentID:      paymentID,
+			OrganizationID: actor.OrganizationID,
+			Payer:          payer,
+			Amount:         payment.amount,
+			IssuedByID:     actor.UserID,
+			IssuedAt:       time.Now(),
+		}
+		err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			number, err := model.NextOrganizationCode(tx, model.SequenceReceipt, receipt.OrganizationID)
+			if err != nil {
+				return err
+			}
+			receipt.Number = number
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&receipt)
+			if result.Error == nil && result.RowsAffected == 0 {
+				return errIssued
+			}
+			return result.Error
+		})
+		switch {
+		case errors.Is(err, errIssued):
+			if receipt, err = s.issued(ctx, ledger, paymentID); err != nil {
+				return receipt, false, err
+			}
+		case err != nil:
+			return receipt, false, err
+		default:
+			created = true
+		}
+	}
+	if receipt.FileKey != "" {
+		return receipt, created, nil
+	}
+
+	// The PDF is stored outside the numbering transaction: SQLite has one
+	// writer, and the file service records the upload on its own.
+	document, err := s.render(ctx, receipt, payment)
+	if err != nil {
+		return receipt, created, err
+	}
+	stored, err := s.files.Save(ctx, files.Upload{
+		Purpose:        files.IssuedReceipt,
+		Name:           receipt.Number + ".pdf",
+		Body:           bytes.NewReader(document),
+		OrganizationID: receipt.OrganizationID,
+		UploadedByID:   actor.UserID,
+	})
+	if err != nil {
+		return receipt, created, err
+	}
+	result := s.db.WithContext(ctx).Model(&receipt).Where("file_key = ''").Update("file_key", stored.Key)
+	if result.Error != nil {
+		return receipt, created, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Another request stored the PDF first; theirs is the receipt.
+		receipt, err = s.issued(ctx, ledger, paymentID)
+	}
+	return receipt, created, err
+}
+
+func (s *service) Find(ctx context.Context, ledger payments.Ledger, paymentID uint) (model.PaymentReceipt, error) {
+	if _, err := s.payment(ctx, ledger, paymentID); err != nil {
+		return model.PaymentReceipt{}, err
+	}
+	receipt, err := s.issued(ctx, ledger, paymentID)
+	if err == nil && (receipt.ID == 0 || receipt.FileKey == "") {
+		return receipt, services.Fail(services.ErrNotFound, "No receipt has been issued for this payment")
+	}
+	return receipt, err
+}
+
+// issued loads the receipt of a payment, a zero one when there is none.
+func (s *service) issued(ctx context.Context, ledger payments.Ledger, paymentID uint) (model.PaymentReceipt, error) {
+	var receipt model.PaymentReceipt
+	err := s.db.WithContext(ctx).
+		Where(&model.PaymentReceipt{Ledger: string(ledger), PaymentID: paymentID}).
+		Limit(1).Find(&receipt).Error
+	return receipt, err
+}
+
+func (s *service) Letterhead(ctx context.Context, orgID uint) (model.Letterhead, error) {
+	head, err := s.stored(ctx, orgID)
+	if err == nil && head.Name == "" {
+		var org model.Organization
+		if err := s.db.WithContext(ctx).Select("name").Where("id = ?", orgID).Limit(1).Find(&org).Error; err != nil {
+			return head, err
+		}
+		head.Name = org.Name
+	}
+	return head, err
+}
+
+// stored loads the saved letterhead of orgID. An empty name stands for the
+// organization's, so renaming the organization renames its receipts too.
+func (s *service) stored(ctx context.Context, orgID uint) (model.Letterhead, error) {
+	head := model.Letterhead{OrganizationID: orgID}
+	err := s.db.WithContext(ctx).Where("organization_id = ?", orgID).Limit(1).Find(&head).Error
+	return head, err
+}
+
+// letterheadLimits are the column sizes of the letterhead's text fields.
+var letterheadLimits = []struct {
+	field string
+	max   int
+}{{"Name", 255}, {"Address", 1000}, {"Phone", 64}, {"Email", 254}, {"Tax ID", 32}, {"Footer", 1000}}
+
+func (s *service) SaveLetterhead(ctx context.Context, actor model.OrganizationMember, req orgUtils.LetterheadRequest) (model.Letterhead, error) {
+	if err := canManage(actor); err != nil {
+		return model.Letterhead{}, err
+	}
+	head, err := s.stored(ctx, actor.OrganizationID)
+	if err != nil {
+		return head, err
+	}
+	values := []*string{&req.Name, &req.Address, &req.Phone, &req.Email, &req.TaxID, &req.Footer}
+	for i, value := range values {
+		*value = strings.TrimSpace(*value)
+		if utf8.RuneCountInString(*value) > letterheadLimits[i].max {
+			return head, services.Fail(services.ErrInvalid, fmt.Sprintf("%s is longer than %d characters", letterheadLimits[i].field, letterheadLimits[i].max))
+		}
+	}
+	head.Name, head.Address, head.Phone, head.Email, head.TaxID, head.Footer = req.Name, req.Address, req.Phone, req.Email, req.TaxID, req.Footer
+	head.UpdatedAt = time.Now()
+	if err := s.db.WithContext(ctx).Save(&head).Error; err != nil {
+		return head, err
+	}
+	return s.Letterhead(ctx, actor.OrganizationID)
+}
+
+func (s *service) SetLogo(ctx context.Context, actor model.OrganizationMember, key string) (model.Letterhead, error) {
+	if err := canManage(actor); err != nil {
+		return model.Letterhead{}, err
+	}
+	head, err := s.stored(ctx, actor.OrganizationID)
+	if err != nil {
+		return head, err
+	}
+	head.LogoKey = key
+	head.UpdatedAt = time.Now()
+	if err := s.db.WithContext(ctx).Save(&head).Error; err != nil {
+		return head, err
+	}
+	return s.Letterhead(ctx, actor.OrganizationID)
+}
+
+func canManage(actor model.OrganizationMember) error {
+	if actor.OrganizationID == 0 || (actor.Role != model.MemberRoleOwner && actor.Role != model.MemberRoleAdmin) {
+		return services.Fail(services.ErrForbidden, "Only organization owners and admins can do this")
+	}
+	return nil
+}
+
+// details is what a receipt prints about its payment.
+type details struct {
+	project   string
+	unit      string
+	payer     string
+	purpose   string
+	method    string
+	reference string
+	note      string
+	amount    float64
+	date      time.Time
+}
+
+// payment loads a payment of ledger for its receipt. A payment of another
+// organization's project does not exist for the caller.
+func (s *service) payment(ctx context.Context, ledger payments.Ledger, paymentID uint) (details, error) {
+	db := s.db.WithContext(ctx)
+	var d details
+	var projectID, unitID uint
+	var err error
+	switch ledger {
+	case payments.ProjectLedger:
+		var p model.ProjectPayment
+		err = db.Where("id = ?", paymentID).Limit(1).Find(&p).Error
+		projectID = p.ProjectID
+		d.amount, d.date, d.note = p.Amount, p.Date, p.Description
+		d.purpose = label(utils.PaymentTypes, p.Type)
+	case payments.FlatLedger:
+		var p model.FlatPayment
+		err = db.Where("id = ?", paymentID).Limit(1).Find(&p).Error
+		projectID, unitID = p.ProjectID, p.UnitID
+		d.amount, d.date, d.note, d.reference = p.Amount, p.Date, p.Remarks, p.Reference
+		d.purpose, d.method = label(utils.UnitPaymentStages, p.Stage), label(utils.UnitPaymentMethods, p.Method)
+	case payments.PlotLedger:
+		var p model.PlotPayment
+		err = db.Where("id = ?", paymentID).Limit(1).Find(&p).Error
+		projectID, unitID = p.ProjectID, p.UnitID
+		d.amount, d.date, d.note, d.reference = p.Amount, p.Date, p.Remarks, p.Reference
+		d.purpose, d.method = label(utils.UnitPaymentStages, p.Stage), label(utils.UnitPaymentMethods, p.Method)
+	default:
+		return d, fmt.Errorf("unknown payment ledger %q", ledger)
+	}
+	if err != nil {
+		return d, err
+	}
+	if projectID == 0 {
+		return d, services.Fail(services.ErrNotFound, "Payment not found")
+	}
+
+	var project model.Project
+	if err := db.Where("id = ?", projectID).Limit(1).Find(&project).Error; err != nil {
+		return d, err
+	}
+	if project.ID == 0 {
+		return d, services.Fail(services.ErrNotFound, "Payment not found")
+	}
+	d.project = project.ProjectName
+	customerID := project.ProjectAssignedCustomerID
+
+	if unitID != 0 {
+		var unit model.ProjectUnit
+		if err := db.Preload("ProjectUnitBlock").Where("id = ?", unitID).Limit(1).Find(&unit).Error; err != nil {
+			return d, err
+		}
+		d.unit = unitLabel(unit)
+		d.payer = unit.ProjectUnitBuyerName
+		customerID = unit.ProjectUnitBuyerCustomerID
+	}
+	if d.payer == "" && customerID != nil {
+		var customer model.Customer
+		if err := db.Select("customer_name").Where("id = ?", *customerID).Limit(1).Find(&customer).Error; err != nil {
+			return d, err
+		}
+		d.payer = customer.CustomerName
+	}
+	return d, nil
+}
+
+// unitLabel names a unit as "Block A / Unit 101".
+func unitLabel(unit model.ProjectUnit) string {
+	name := unit.ProjectUnitLabel
+	if name == "" && unit.ID != 0 {
+		name = fmt.Sprintf("%d", unit.ProjectUnitNumber)
+	}
+	if name == "" {
+		return ""
+	}
+	if block := unit.ProjectUnitBlock.ProjectBlockName; block != "" {
+		return block + " / Unit " + name
+	}
+	return "Unit " + name
+}
+
+// label shows a choice by its display name, or as stored when it is not
+// one of the choices.
+func label(choices map[string]string, value string) string {
+	if name, ok := choices[value]; ok {
+		return name
+	}
+	return value
+}
+
+// logo reads the JPEG thumbnail of the letterhead's logo. A logo that can
+// no longer be read is left off rather than failing the receipt.
+func (s *service) logo(ctx context.Context, key string) []byte {
+	if key == "" {
+		return nil
+	}
+	stored, err := s.files.Describe(ctx, key)
+	if err != nil || stored.ThumbnailKey == "" {
+		return nil
+	}
+	body, _, err := s.files.Open(ctx, stored.ThumbnailKey)
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil
+	}
+	return data
+}
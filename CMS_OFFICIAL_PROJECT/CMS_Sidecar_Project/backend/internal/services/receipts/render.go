@@ -0,0 +1,145 @@
+package receipts
+
+import (
+	"context"
+	"strings"
+
+	"cms_sidecar_backend/internal/model"
+	"cms_sidecar_backend/internal/pdf"
+)
+
+// Layout of the A4 receipt, in points.
+const (
+	margin      = 50.0
+	right       = pdf.A4Width - margin
+	valueColumn = 190.0
+	logoHeight  = 56.0
+	footerTop   = 700.0
+)
+
+// render lays the receipt out on one A4 page under the organization's
+// letterhead.
+func (s *service) render(ctx context.Context, receipt model.PaymentReceipt, payment details) ([]byte, error) {
+	head, err := s.Letterhead(ctx, receipt.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	doc := pdf.New()
+	doc.Title = "Receipt " + receipt.Number
+	doc.Author = head.Name
+	doc.Created = receipt.IssuedAt
+	page := doc.AddPage(pdf.A4Width, pdf.A4Height)
+
+	// Letterhead: the logo on the left, the organization beside it.
+	y, x := margin, margin
+	if data := s.logo(ctx, head.LogoKey); data != nil {
+		if img, err := doc.JPEG(data); err == nil {
+			width := logoHeight * float64(img.Width()) / float64(img.Height())
+			page.Image(img, margin, y, width, logoHeight)
+			x = margin + width + 14
+		}
+	}
+	textWidth := right - x
+	y += 16
+	for _, line := range pdf.Wrap(pdf.HelveticaBold, 18, head.Name, textWidth) {
+		page.Text(x, y, pdf.HelveticaBold, 18, line)
+		y += 20
+	}
+	page.Gray(0.3)
+	for _, line := range pdf.Wrap(pdf.Helvetica, 9, head.Address, textWidth) {
+		page.Text(x, y, pdf.Helvetica, 9, line)
+		y += 12
+	}
+	if contact := join(" | ", prefixed("Phone: ", head.Phone), prefixed("Email: ", head.Email)); contact != "" {
+		page.Text(x, y, pdf.Helvetica, 9, contact)
+		y += 12
+	}
+	if head.TaxID != "" {
+		page.Text(x, y, pdf.Helvetica, 9, "GSTIN: "+head.TaxID)
+		y += 12
+	}
+	page.Gray(0)
+	y = max(y, margin+logoHeight) + 10
+	page.Line(margin, y, right, y, 1)
+
+	y += 34
+	page.TextCenter(pdf.A4Width/2, y, pdf.HelveticaBold, 16, "PAYMENT RECEIPT")
+	y += 30
+	page.Text(margin, y, pdf.HelveticaBold, 10, "Receipt No. "+receipt.Number)
+	page.TextRight(right, y, pdf.Helvetica, 10, "Date: "+receipt.IssuedAt.Format("02 Jan 2006"))
+	y += 12
+	page.Line(margin, y, right, y, 0.5)
+	y += 24
+
+	date := ""
+	if !payment.date.IsZero() {
+		date = payment.date.Format("02 Jan 2006")
+	}
+	rows := []struct{ name, value string }{
+		{"Received from", receipt.Payer},
+		{"Project", payment.project},
+		{"Block / Unit", payment.unit},
+		{"Payment for", payment.purpose},
+		{"Amount", Figures(receipt.Amount)},
+		{"Amount in words", Words(receipt.Amount)},
+		{"Payment method", payment.method},
+		{"Reference", payment.reference},
+		{"Payment date", date},
+		{"Remarks", payment.note},
+	}
+	for _, row := range rows {
+		if strings.TrimSpace(row.value) == "" {
+			continue
+		}
+		page.Text(margin, y, pdf.HelveticaBold, 10, row.name)
+		font, size := pdf.Helvetica, 10.0
+		if row.name == "Amount" {
+			font, size = pdf.HelveticaBold, 12
+		}
+		for _, line := range pdf.Wrap(font, size, row.value, right-valueColumn) {
+			page.Text(valueColumn, y, font, size, line)
+			y += 15
+		}
+		y += 7
+	}
+
+	// Signature block and footer at the foot of the page.
+	y = max(y+30, footerTop-90)
+	page.TextRight(right, y, pdf.Helvetica, 10, "For "+head.Name)
+	y += 44
+	page.Line(right-150, y, right, y, 0.5)
+	y += 14
+	page.TextRight(right, y, pdf.Helvetica, 9, "Authorised signatory")
+
+	y = max(y+30, footerTop)
+	page.Gray(0.3)
+	for _, line := range pdf.Wrap(pdf.Helvetica, 9, head.Footer, right-margin) {
+		if line != "" {
+			page.TextCenter(pdf.A4Width/2, y, pdf.Helvetica, 9, line)
+		}
+		y += 12
+	}
+	page.Line(margin, pdf.A4Height-margin-14, right, pdf.A4Height-margin-14, 0.5)
+	page.TextCenter(pdf.A4Width/2, pdf.A4Height-margin, pdf.Helvetica, 8,
+		"This is a computer-generated receipt. Subject to realisation of cheque or transfer.")
+	page.Gray(0)
+	return doc.Bytes()
+}
+
+func prefixed(prefix, value string) string {
+	if value == "" {
+		return ""
+	}
+	return prefix + value
+}
+
+// join joins the non-empty parts with sep.
+func join(sep string, parts ...string) string {
+	kept := parts[:0]
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, sep)
+}
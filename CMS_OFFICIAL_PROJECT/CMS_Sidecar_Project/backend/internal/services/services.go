@@ -0,0 +1,53 @@
+// Package services holds what the domain services share: the error kinds
+// handlers translate into HTTP statuses and the optimistic-locking save.
+// The services themselves live in one subpackage per domain and depend only
+// on GORM, so handlers and cmsctl call the same business rules.
+package services
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Error kinds a service failure wraps.
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrInvalid   = errors.New("invalid input")
+	ErrConflict  = errors.New("conflict")
+	ErrForbidden = errors.New("forbidden")
+	// ErrTooLarge and ErrUnsupported reject uploads by size and by type.
+	ErrTooLarge    = errors.New("too large")
+	ErrUnsupported = errors.New("unsupported media type")
+)
+
+// Failure is a rule violation whose message is safe to show the caller.
+type Failure struct {
+	Kind    error
+	Message string
+}
+
+func (f *Failure) Error() string { return f.Message }
+
+func (f *Failure) Unwrap() error { return f.Kind }
+
+// Fail reports a failure of kind with a message meant for the caller.
+func Fail(kind error, message string) error {
+	return &Failure{Kind: kind, Message: message}
+}
+
+// SaveVersioned writes every column of value only while the stored version is
+// still expected. The caller bumps the version field on value beforehand;
+// false means another writer got there first.
+func SaveVersioned(tx *gorm.DB, value interface{}, versionColumn string, expected uint) (bool, error) {
+	result := tx.Model(value).
+		Where(versionColumn+" = ?", expected).
+		Select("*").
+		Omit(clause.Associations).
+		Updates(value)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
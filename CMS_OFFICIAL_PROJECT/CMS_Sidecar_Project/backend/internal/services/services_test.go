@@ -0,0 +1,48 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type versioned struct {
+	ID      uint `gorm:"primaryKey"`
+	Name    string
+	Version uint
+}
+
+// TestSaveVersioned checks that of two writers that loaded the same version
+// only the first saves, and the second leaves the row alone.
+func TestSaveVersioned(t *testing.T) {
+	database, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "versions.sqlite3")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.AutoMigrate(&versioned{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Create(&versioned{ID: 1, Name: "loaded", Version: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	first := versioned{ID: 1, Name: "first", Version: 2}
+	second := versioned{ID: 1, Name: "second", Version: 2}
+	if saved, err := SaveVersioned(database, &first, "version", 1); err != nil || !saved {
+		t.Fatalf("first save: saved %v, %v; want saved", saved, err)
+	}
+	if saved, err := SaveVersioned(database, &second, "version", 1); err != nil || saved {
+		t.Fatalf("second save from the same version: saved %v, %v; want not saved", saved, err)
+	}
+
+	var stored versioned
+	if err := database.First(&stored, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "first" || stored.Version != 2 {
+		t.Errorf("row is %+v, want the first writer's name at version 2", stored)
+	}
+}
@@ -0,0 +1,170 @@
+// Package sessions issues the bearer tokens handed out at login, checks
+// them on every request and keeps track of the device each one is used on.
+package sessions
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"cms_sidecar_backend/internal/model"
+	"cms_sidecar_backend/internal/services"
+	"gorm.io/gorm"
+)
+
+// TokenPrefix starts every session token.
+const TokenPrefix = "cms_s_"
+
+// CSRFToken is what a browser signed in with the session cookie for token
+// must echo in X-CSRF-Token on writes. It is derived from the token, so a
+// site that can plant cookies still cannot make up a matching pair.
+func CSRFToken(token string) string {
+	return services.HashToken("csrf:" + token)
+}
+
+// seenEvery is how stale last_seen_at may get before a request writes it
+// back, so reads do not turn into a write each.
+const seenEvery = time.Minute
+
+// Device describes where a login comes from.
+type Device struct {
+	Name      string
+	Platform  string
+	UserAgent string
+	IP        string
+}
+
+// Service starts, checks, lists and revokes sessions.
+type Service interface {
+	// Start opens a session for userID on device and returns its token,
+	// which is not stored anywhere.
+	Start(ctx context.Context, userID uint, device Device) (string, model.Session, error)
+	// Authenticate returns the live session token belongs to and notes that
+	// it was just used from ip.
+	Authenticate(ctx context.Context, token, ip string) (model.Session, error)
+	// List returns userID's live sessions, most recently used first.
+	List(ctx context.Context, userID uint) ([]model.Session, error)
+	// Revoke ends one of userID's sessions.
+	Revoke(ctx context.Context, userID, sessionID uint) error
+	// RevokeUser ends every live session of userID but keep (0 keeps none)
+	// and returns how many it ended.
+	RevokeUser(ctx context.Context, userID, keep uint) (int64, error)
+}
+
+type service struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+// New builds the session service on db; sessions last ttl.
+func New(db *gorm.DB, ttl time.Duration) Service {
+	return &service{db: db, ttl: ttl}
+}
+
+func (s *service) Start(ctx context.Context, userID uint, device Device) (string, model.Session, error) {
+	token, hash, err := services.NewToken(TokenPrefix)
+	if err != nil {
+		return "", model.Session{}, err
+	}
+	now := time.Now()
+	session := model.Session{
+		UserID:     userID,
+		TokenHash:  hash,
+		DeviceName: truncate(strings.TrimSpace(device.Name), 120),
+		Platform:   platform(device.Platform, device.UserAgent),
+		UserAgent:  truncate(device.UserAgent, 255),
+		IP:         device.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.ttl),
+	}
+	if err := s.db.WithContext(ctx).Create(&session).Error; err != nil {
+		return "", model.Session{}, err
+	}
+	return token, session, nil
+}
+
+func (s *service) Authenticate(ctx context.Context, token, ip string) (model.Session, error) {
+	var session model.Session
+	now := time.Now()
+	found := s.db.WithContext(ctx).
+		Where("token_hash = ? AND revoked_at IS NULL AND expires_at > ?", services.HashToken(token), now).
+		Limit(1).Find(&session)
+	if found.Error != nil {
+		return session, found.Error
+	}
+	if found.RowsAffected == 0 {
+		return session, services.Fail(services.ErrNotFound, "Session expired or signed out")
+	}
+	if now.Sub(session.LastSeenAt) >= seenEvery || session.IP != ip {
+		session.LastSeenAt, session.IP = now, ip
+		err := s.db.WithContext(ctx).Model(&session).
+			UpdateColumns(map[string]interface{}{"last_seen_at": now, "ip": ip}).Error
+		if err != nil {
+			return session, err
+		}
+	}
+	return session, nil
+}
+
+func (s *service) List(ctx context.Context, userID uint) ([]model.Session, error) {
+	var sessions []model.Session
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC, id DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (s *service) Revoke(ctx context.Context, userID, sessionID uint) error {
+	result := s.db.WithContext(ctx).Model(&model.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return services.Fail(services.ErrNotFound, "Session not found")
+	}
+	return nil
+}
+
+func (s *service) RevokeUser(ctx context.Context, userID, keep uint) (int64, error) {
+	result := s.db.WithContext(ctx).Model(&model.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keep).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// platform takes the platform the client reported or, failing that, guesses
+// it from the User-Agent: the Flutter web build runs in a browser, the
+// desktop build talks through dart:io.
+func platform(reported, userAgent string) string {
+	switch strings.ToLower(strings.TrimSpace(reported)) {
+	case "desktop", "windows", "macos", "linux":
+		return model.PlatformDesktop
+	case "web":
+		return model.PlatformWeb
+	case "mobile", "android", "ios":
+		return model.PlatformMobile
+	}
+	switch {
+	case strings.HasPrefix(userAgent, "Mozilla/"):
+		return model.PlatformWeb
+	case strings.HasPrefix(userAgent, "Dart/"):
+		return model.PlatformDesktop
+	}
+	return model.PlatformUnknown
+}
+
+// truncate cuts value to at most size bytes without splitting a character.
+func truncate(value string, size int) string {
+	if len(value) <= size {
+		return value
+	}
+	for size > 0 && !utf8.RuneStart(value[size]) {
+		size--
+	}
+	return value[:size]
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// NewToken returns a random secret starting with prefix and the hash to
+// store for it. The secret is shown to the caller once and never kept.
+func NewToken(prefix string) (token string, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token = prefix + base64.RawURLEncoding.EncodeToString(raw)
+	return token, HashToken(token), nil
+}
+
+// HashToken is the stored form of a secret made by NewToken.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Disk keeps files in a directory tree, the layout Django's MEDIA_ROOT uses.
+type Disk struct {
+	root string
+}
+
+// NewDisk stores files under root, which is created on the first Put.
+func NewDisk(root string) *Disk {
+	return &Disk{root: root}
+}
+
+func (d *Disk) path(key string) (string, error) {
+	if err := CheckKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(d.root, filepath.FromSlash(key)), nil
+}
+
+// Put writes to a temporary file next to the target and renames it into
+// place, so readers never see half a file.
+func (d *Disk) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	target, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, io.LimitReader(body, size)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+func (d *Disk) Open(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	target, err := d.path(key)
+	if err != nil {
+		return nil, Object{}, err
+	}
+	file, err := os.Open(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, Object{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Object{}, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, Object{}, err
+	}
+	if info.IsDir() {
+		file.Close()
+		return nil, Object{}, ErrNotFound
+	}
+	return file, Object{
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		ModTime:     info.ModTime(),
+	}, nil
+}
+
+func (d *Disk) Delete(ctx context.Context, key string) error {
+	target, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config locates a bucket on an S3-compatible service. Endpoint is the
+// service's base URL, such as https://s3.eu-west-1.amazonaws.com or
+// http://localhost:9000 for a local MinIO; requests use path-style URLs.
+type S3Config struct {
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+}
+
+// S3 stores files as objects in one bucket, signing requests with AWS
+// Signature Version 4.
+type S3 struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+// NewS3 checks cfg and builds the store. Region defaults to us-east-1.
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("S3 storage needs CMS_S3_ENDPOINT, CMS_S3_BUCKET, CMS_S3_ACCESS_KEY and CMS_S3_SECRET_KEY")
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("CMS_S3_ENDPOINT %q is not an http(s) URL", cfg.Endpoint)
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3{
+		endpoint:  endpoint,
+		bucket:    cfg.Bucket,
+		region:    region,
+		accessKey: cfg.AccessKey,
+		secretKey: cfg.SecretKey,
+		client:    &http.Client{Timeout: time.Minute},
+	}, nil
+}
+
+// Put reads the body into memory to sign its hash; uploads are capped well
+// below what that costs.
+func (s *S3) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	payload, err := io.ReadAll(io.LimitReader(body, size))
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, payload, header)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3) Open(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, http.Header{})
+	if err != nil {
+		return nil, Object{}, err
+	}
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return resp.Body, Object{
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		ModTime:     modTime,
+	}, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, http.Header{})
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do sends a signed request for key and turns error statuses into errors;
+// 404 is ErrNotFound.
+func (s *S3) do(ctx context.Context, method, key string, payload []byte, header http.Header) (*http.Response, error) {
+	if err := CheckKey(key); err != nil {
+		return nil, err
+	}
+	target := *s.endpoint
+	target.Path = strings.TrimSuffix(target.Path, "/") + "/" + s.bucket + "/" + key
+	target.RawPath = escapePath(target.Path)
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	s.sign(req, payload, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return nil, fmt.Errorf("storage: S3 %s %s: %s: %s", method, key, resp.Status, strings.TrimSpace(string(detail)))
+}
+
+// sign adds the Signature Version 4 Authorization header. The signed
+// headers are Host, Content-Type, Range and every x-amz-* header.
+func (s *S3) sign(req *http.Request, payload []byte, now time.Time) {
+	stamp := now.UTC().Format("20060102T150405Z")
+	day := stamp[:8]
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || lower == "range" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + s.region + "/s3/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + sha256Hex([]byte(canonical))
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), day)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, escape(name, false)+"="+escape(value, false))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// escapePath percent-encodes everything but unreserved characters and the
+// slashes between segments, as Signature Version 4 expects.
+func escapePath(p string) string {
+	return escape(p, true)
+}
+
+func escape(s string, keepSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && keepSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,332 @@
+// Package summary maintains cms_project_summary, a per-project projection of
+// unit status counts, collected payments, expenses by category and stock
+// value, so list and dashboard views read one row per project instead of
+// re-aggregating raw rows.
+//
+// Like the search index, the table is kept current by SQLite triggers on the
+// source tables, inside the writing transaction whichever backend wrote it.
+// A unit, payment, expense or stock write adds or removes that row's share
+// of its project's row rather than recounting the project.
+package summary
+
+import (
+	"fmt"
+	"strings"
+
+	"cms_sidecar_backend/internal/model"
+	"gorm.io/gorm"
+)
+
+const table = "cms_project_summary"
+
+// amountSource is one table whose amounts roll up into a summary column.
+type amountSource struct {
+	column        string // summary column
+	table         string
+	projectColumn string
+	amount        string
+}
+
+var amountSources = []amountSource{
+	{"collected_amount", "project_payments", "project_payment_project_id", "project_payment_amount"},
+	{"collected_amount", "flat_payments", "flat_payment_project_id", "flat_payment_amount"},
+	{"collected_amount", "plot_payments", "plot_payment_project_id", "plot_payment_amount"},
+	{"manpower_expense", "construction_manpowerexpense", "manpower_expense_project_id", "manpower_expense_total_amount"},
+	{"material_expense", "construction_materialexpense", "material_expense_project_id", "material_expense_total_amount"},
+	{"general_expense", "construction_generalexpense", "general_expense_project_id", "general_expense_amount"},
+	{"departmental_expense", "construction_departmentalexpense", "departmental_expense_project_id", "departmental_expense_amount"},
+	{"administration_expense", "construction_administrationexpense", "administration_expense_project_id", "administration_expense_amount"},
+}
+
+const (
+	unitTable  = "construction_projectunit"
+	blockTable = "construction_projectblock"
+	stockTable = "stock_management_page_app_stockbalance"
+)
+
+// schema records which source tables exist; missing ones count as zero so a
+// partially migrated database still works.
+type schema map[string]bool
+
+func loadSchema(db *gorm.DB) schema {
+	tables := []string{unitTable, blockTable, stockTable}
+	for _, source := range amountSources {
+		tables = append(tables, source.table)
+	}
+	s := schema{}
+	for _, name := range tables {
+		s[name] = db.Migrator().HasTable(name)
+	}
+	return s
+}
+
+// unitCount counts the units of project p matching condition (SQL over the
+// alias u).
+func (s schema) unitCount(condition string) string {
+	if !s[unitTable] || !s[blockTable] {
+		return "0"
+	}
+	return "(SELECT COUNT(*) FROM " + unitTable + " u JOIN " + blockTable + " b ON b.id = u.project_unit_block_id " +
+		"WHERE b.project_block_project_id = p.id" + condition + ")"
+}
+
+// unitStatuses are the status columns of the summary with the condition a
+// unit status expression meets to count towards each.
+var unitStatuses = []struct{ column, condition string }{
+	{"sold_units", "LOWER({s}) = 'sold'"},
+	{"booked_units", "LOWER({s}) = 'booked'"},
+	{"hold_units", "LOWER({s}) = 'hold'"},
+	{"available_units", "LOWER(COALESCE({s}, '')) NOT IN ('sold', 'booked', 'hold')"},
+}
+
+// stockValue values the remaining stock of the project project (an SQL
+// expression) at the project's average purchase price of each item from its
+// material expenses.
+func (s schema) stockValue(project string) string {
+	if !s[stockTable] {
+		return "0"
+	}
+	return "COALESCE((SELECT SUM((sb.stock_total_allocated - sb.stock_used) * " + s.stockPrice("sb") + ") FROM " + stockTable +
+		" sb WHERE sb.stock_project_id = " + project + "), 0)"
+}
+
+// stockPrice is the average purchase price of the item of stock row row.
+func (s schema) stockPrice(row string) string {
+	if !s["construction_materialexpense"] {
+		return "0"
+	}
+	return "COALESCE((SELECT SUM(me.material_expense_total_amount) / NULLIF(SUM(me.material_expense_quantity), 0) " +
+		"FROM construction_materialexpense me WHERE me.material_expense_project_id = " + row + ".stock_project_id " +
+		"AND me.material_expense_item_id = " + row + ".stock_material_item_id), 0)"
+}
+
+// refreshSQL recomputes the rows of the projects selected by where (SQL over
+// the alias p).
+func (s schema) refreshSQL(where string) string {
+	counts := []string{s.unitCount("")}
+	for _, status := range unitStatuses {
+		counts = append(counts, s.unitCount(" AND "+strings.ReplaceAll(status.condition, "{s}", "u.project_unit_status")))
+	}
+
+	amounts := map[string][]string{}
+	var columns []string
+	for _, source := range amountSources {
+		if _, seen := amounts[source.column]; !seen {
+			columns = append(columns, source.column)
+			amounts[source.column] = nil
+		}
+		if s[source.table] {
+			amounts[source.column] = append(amounts[source.column], fmt.Sprintf(
+				"COALESCE((SELECT SUM(%s) FROM %s WHERE %s = p.id), 0)", source.amount, source.table, source.projectColumn))
+		}
+	}
+	values := make([]string, len(columns))
+	for i, column := range columns {
+		values[i] = "0"
+		if len(amounts[column]) > 0 {
+			values[i] = strings.Join(amounts[column], " + ")
+		}
+	}
+
+	return fmt.Sprintf(
+		"INSERT OR REPLACE INTO %s (project_id, total_units, sold_units, booked_units, hold_units, available_units, %s, stock_value, updated_at) "+
+			"SELECT p.id, %s, %s, %s, CURRENT_TIMESTAMP FROM construction_project p WHERE %s",
+		table, strings.Join(columns, ", "), strings.Join(counts, ", "), strings.Join(values, ", "), s.stockValue("p.id"), where,
+	)
+}
+
+// adjustSQL adds (op "+") or removes (op "-") the given column contributions
+// to the summary row of project.
+func adjustSQL(project, op string, contributions [][2]string) string {
+	sets := make([]string, 0, len(contributions)+1)
+	for _, c := range contributions {
+		sets = append(sets, fmt.Sprintf("%[1]s = %[1]s %[2]s %[3]s", c[0], op, c[1]))
+	}
+	sets = append(sets, "updated_at = CURRENT_TIMESTAMP")
+	return fmt.Sprintf("UPDATE %s SET %s WHERE project_id = %s", table, strings.Join(sets, ", "), project)
+}
+
+// trigger is one source table and how a row of it changes its project's
+// summary. project names the project of a row and delta adds or removes the
+// row's contribution; {r} stands for NEW or OLD in both. columns lists the
+// columns whose updates matter. Tables without delta recount the affected
+// projects instead.
+type trigger struct {
+	name    string
+	table   string
+	project string
+	columns []string
+	delta   func(row, op string) []string
+}
+
+func (s schema) triggers() []trigger {
+	var list []trigger
+	if s[unitTable] && s[blockTable] {
+		unitProject := "(SELECT project_block_project_id FROM " + blockTable + " WHERE id = {r}.project_unit_block_id)"
+		list = append(list, trigger{"unit", unitTable, unitProject, []string{"project_unit_status", "project_unit_block_id"},
+			func(row, op string) []string {
+				contributions := [][2]string{{"total_units", "1"}}
+				for _, status := range unitStatuses {
+					condition := strings.ReplaceAll(status.condition, "{s}", row+".project_unit_status")
+					contributions = append(contributions, [2]string{status.column, "(CASE WHEN " + condition + " THEN 1 ELSE 0 END)"})
+				}
+				return []string{adjustSQL(strings.ReplaceAll(unitProject, "{r}", row), op, contributions)}
+			}})
+	}
+	if s[blockTable] {
+		// Blocks only move or disappear with their units, rarely: those
+		// writes recount the projects involved.
+		list = append(list, trigger{"block", blockTable, "{r}.project_block_project_id", []string{"project_block_project_id"}, nil})
+	}
+	if s[stockTable] {
+		list = append(list, trigger{"stock", stockTable, "{r}.stock_project_id",
+			[]string{"stock_project_id", "stock_material_item_id", "stock_total_allocated", "stock_used"},
+			func(row, op string) []string {
+				value := "(" + row + ".stock_total_allocated - " + row + ".stock_used) * " + s.stockPrice(row)
+				return []string{adjustSQL(row+".stock_project_id", op, [][2]string{{"stock_value", value}})}
+			}})
+	}
+	for _, source := range amountSources {
+		if !s[source.table] {
+			continue
+		}
+		source := source
+		t := trigger{source.table, source.table, "{r}." + source.projectColumn, []string{source.projectColumn, source.amount},
+			func(row, op string) []string {
+				return []string{adjustSQL(row+"."+source.projectColumn, op, [][2]string{{source.column, "COALESCE(" + row + "." + source.amount + ", 0)"}})}
+			}}
+		if source.table == "construction_materialexpense" && s[stockTable] {
+			// Material purchases set the price stock is valued at, so the
+			// project's stock value is recomputed along with the expense.
+			t.columns = append(t.columns, "material_expense_item_id", "material_expense_quantity")
+			expense := t.delta
+			t.delta = func(row, op string) []string {
+				project := row + "." + source.projectColumn
+				return append(expense(row, op), fmt.Sprintf("UPDATE %s SET stock_value = %s WHERE project_id = %s", table, s.stockValue(project), project))
+			}
+		}
+		list = append(list, t)
+	}
+	return list
+}
+
+func (t trigger) projectOf(row string) string {
+	return strings.ReplaceAll(t.project, "{r}", row)
+}
+
+// statements returns the trigger bodies for an insert, an update and a
+// delete on t's table. Recounted tables need none for inserts: a new block
+// has no units yet.
+func (t trigger) statements(s schema) (insert, update, remove []string) {
+	if t.delta == nil {
+		return nil,
+			[]string{s.refreshSQL("p.id IN (" + t.projectOf("OLD") + ", " + t.projectOf("NEW") + ")")},
+			[]string{s.refreshSQL("p.id = " + t.projectOf("OLD"))}
+	}
+	return t.delta("NEW", "+"), append(t.delta("OLD", "-"), t.delta("NEW", "+")...), t.delta("OLD", "-")
+}
+
+// Ensure creates the summary table and (re)creates its triggers for the
+// tables that exist now. A freshly created table is filled from the current
+// rows.
+func Ensure(db *gorm.DB) error {
+	if !db.Migrator().HasTable("construction_project") {
+		return nil
+	}
+	created := !db.Migrator().HasTable(table)
+	if err := db.AutoMigrate(&model.ProjectSummary{}); err != nil {
+		return fmt.Errorf("failed to migrate project summary: %w", err)
+	}
+
+	s := loadSchema(db)
+	var statements []string
+	// Triggers are rebuilt on every start so their bodies follow the tables
+	// that exist; a Django migration that adds one is picked up on restart.
+	var existing []string
+	if err := db.Raw("SELECT name FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'cms_summary_%'").Scan(&existing).Error; err != nil {
+		return err
+	}
+	for _, name := range existing {
+		statements = append(statements, "DROP TRIGGER IF EXISTS "+name)
+	}
+	statements = append(statements,
+		"CREATE TRIGGER cms_summary_project_ai AFTER INSERT ON construction_project BEGIN "+s.refreshSQL("p.id = NEW.id")+"; END",
+		"CREATE TRIGGER cms_summary_project_ad AFTER DELETE ON construction_project BEGIN DELETE FROM "+table+" WHERE project_id = OLD.id; END",
+	)
+	body := func(stmts []string) string { return strings.Join(stmts, "; ") + ";" }
+	for _, t := range s.triggers() {
+		name := "cms_summary_" + t.name
+		insert, update, remove := t.statements(s)
+		if len(insert) > 0 {
+			statements = append(statements, fmt.Sprintf("CREATE TRIGGER %s_ai AFTER INSERT ON %s BEGIN %s END", name, t.table, body(insert)))
+		}
+		statements = append(statements,
+			fmt.Sprintf("CREATE TRIGGER %s_au AFTER UPDATE OF %s ON %s BEGIN %s END", name, strings.Join(t.columns, ", "), t.table, body(update)),
+			fmt.Sprintf("CREATE TRIGGER %s_ad AFTER DELETE ON %s BEGIN %s END", name, t.table, body(remove)),
+		)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to prepare project summary triggers: %w", err)
+	}
+	if created {
+		_, err = Rebuild(db)
+	}
+	return err
+}
+
+// Rebuild recomputes every summary row (or only those of projectIDs) and
+// returns the projects whose stored row had drifted from the source tables.
+func Rebuild(db *gorm.DB, projectIDs ...uint) ([]uint, error) {
+	var drifted []uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		scope := func() *gorm.DB {
+			query := tx.Model(&model.ProjectSummary{})
+			if len(projectIDs) > 0 {
+				query = query.Where("project_id IN ?", projectIDs)
+			}
+			return query
+		}
+
+		var before []model.ProjectSummary
+		if err := scope().Find(&before).Error; err != nil {
+			return err
+		}
+		stored := make(map[uint]model.ProjectSummary, len(before))
+		for _, row := range before {
+			stored[row.ProjectID] = row
+		}
+
+		where := "1 = 1"
+		var args []interface{}
+		if len(projectIDs) > 0 {
+			where = "p.id IN ?"
+			args = append(args, projectIDs)
+		} else if err := tx.Exec("DELETE FROM " + table + " WHERE project_id NOT IN (SELECT id FROM construction_project)").Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(loadSchema(tx).refreshSQL(where), args...).Error; err != nil {
+			return err
+		}
+
+		var after []model.ProjectSummary
+		if err := scope().Order("project_id").Find(&after).Error; err != nil {
+			return err
+		}
+		for _, row := range after {
+			if old, ok := stored[row.ProjectID]; !ok || !old.SameTotals(row) {
+				drifted = append(drifted, row.ProjectID)
+			}
+		}
+		return nil
+	})
+	return drifted, err
+}
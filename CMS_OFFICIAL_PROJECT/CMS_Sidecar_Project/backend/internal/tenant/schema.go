@@ -0,0 +1,168 @@
+package tenant
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"cms_sidecar_backend/internal/model"
+	authUtils "cms_sidecar_backend/internal/utilities/auth_page_app"
+	"gorm.io/gorm"
+)
+
+// defaultOrg receives rows nothing else attributes: the oldest organization.
+const defaultOrg = "(SELECT MIN(id) FROM cms_organization)"
+
+// owner says how a row without org_id, from before organizations existed or
+// written by Django, finds its organization. The expression is SQL over the
+// row placeholder {r}; rows it leaves NULL go to the default organization.
+type owner struct {
+	table string
+	org   string
+}
+
+func memberOrg(userColumn string) string {
+	return "(SELECT organization_id FROM cms_organization_member WHERE user_id = {r}." + userColumn + ")"
+}
+
+// owners is ordered so batches look up projects that are already attributed.
+var owners = []owner{
+	{"construction_project", memberOrg("project_owner_id")},
+	{"construction_vendor", memberOrg("vendor_created_by_id")},
+	{"construction_supervisor", memberOrg("supervisor_created_by_id")},
+	{"construction_attendancebatch", "(SELECT org_id FROM construction_project WHERE id = {r}.project_id)"},
+	{"construction_customer", ""},
+	{"construction_channelpartner", ""},
+	{"construction_materialitem", ""},
+}
+
+func (o owner) orgExpr(alias string) string {
+	if o.org == "" {
+		return defaultOrg
+	}
+	return "COALESCE(" + strings.ReplaceAll(o.org, "{r}", alias) + ", " + defaultOrg + ")"
+}
+
+// supervisorMembership makes a supervisor's login a member of the
+// supervisor's organization.
+const supervisorMembership = "INSERT OR IGNORE INTO cms_organization_member (organization_id, user_id, role, created_at) " +
+	"SELECT %[1]s.org_id, %[1]s.supervisor_user_id, '" + model.MemberRoleMember + "', CURRENT_TIMESTAMP FROM %[2]s " +
+	"WHERE %[1]s.org_id IS NOT NULL AND %[1]s.supervisor_user_id IS NOT NULL"
+
+// Ensure gives every builder an organization, attributes existing rows and
+// installs the triggers that attribute rows Django inserts later. It expects
+// the org_id columns to exist already.
+func Ensure(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&model.User{}) {
+		return nil
+	}
+	var users []model.User
+	err := db.Preload("Profile").
+		Where("id NOT IN (?)", db.Model(&model.OrganizationMember{}).Select("user_id")).
+		Order("id").
+		Find(&users).Error
+	if err != nil {
+		return fmt.Errorf("failed to list users without an organization: %w", err)
+	}
+	for _, user := range users {
+		if !isBuilder(user) {
+			continue
+		}
+		if _, err := Provision(db, user); err != nil {
+			return err
+		}
+	}
+
+	var statements []string
+	for _, o := range owners {
+		if !db.Migrator().HasTable(o.table) {
+			continue
+		}
+		statements = append(statements,
+			fmt.Sprintf("UPDATE %s SET org_id = %s WHERE org_id IS NULL", o.table, o.orgExpr(o.table)),
+			fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS cms_tenant_%[1]s_ai AFTER INSERT ON %[1]s WHEN NEW.org_id IS NULL BEGIN "+
+				"UPDATE %[1]s SET org_id = %[2]s WHERE id = NEW.id; END", o.table, o.orgExpr("NEW")),
+		)
+	}
+	if db.Migrator().HasTable("construction_supervisor") {
+		statements = append(statements,
+			fmt.Sprintf(supervisorMembership, "s", "construction_supervisor s"),
+			"CREATE TRIGGER IF NOT EXISTS cms_tenant_supervisor_member_ai AFTER INSERT ON construction_supervisor BEGIN "+
+				fmt.Sprintf(supervisorMembership, "NEW", "(SELECT 1)")+"; END",
+			"CREATE TRIGGER IF NOT EXISTS cms_tenant_supervisor_member_au AFTER UPDATE OF org_id, supervisor_user_id ON construction_supervisor BEGIN "+
+				fmt.Sprintf(supervisorMembership, "NEW", "(SELECT 1)")+"; END",
+		)
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to attribute rows to organizations: %w", err)
+	}
+	return nil
+}
+
+// isBuilder mirrors the login rule: users without a profile are builders,
+// and the old "organization" user type counts as one.
+func isBuilder(user model.User) bool {
+	role := ""
+	if user.Profile != nil {
+		role = user.Profile.UserType
+	}
+	return authUtils.IsBuilderRole(authUtils.GetUserRole(role))
+}
+
+// Provision creates an organization named after user and makes user its
+// owner.
+func Provision(db *gorm.DB, user model.User) (model.Organization, error) {
+	name := user.Username
+	if user.Profile != nil && user.Profile.DisplayName != "" {
+		name = user.Profile.DisplayName
+	}
+	org := model.Organization{Name: name}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.OrganizationMember{
+			OrganizationID: org.ID,
+			UserID:         user.ID,
+			Role:           model.MemberRoleOwner,
+			CreatedAt:      time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return org, fmt.Errorf("failed to create organization for user %d: %w", user.ID, err)
+	}
+	return org, nil
+}
+
+// Resolve returns the membership of userID. A builder who has none yet,
+// e.g. one registered through Django, is given an organization of their
+// own; anyone else gets a zero membership.
+func Resolve(db *gorm.DB, userID uint) (model.OrganizationMember, error) {
+	var member model.OrganizationMember
+	found := db.Where("user_id = ?", userID).Limit(1).Find(&member)
+	if found.Error != nil || found.RowsAffected > 0 {
+		return member, found.Error
+	}
+
+	var user model.User
+	found = db.Preload("Profile").Where("id = ?", userID).Limit(1).Find(&user)
+	if found.Error != nil {
+		return member, found.Error
+	}
+	if found.RowsAffected == 0 || !isBuilder(user) {
+		return model.OrganizationMember{}, nil
+	}
+	org, err := Provision(db, user)
+	if err != nil {
+		return member, err
+	}
+	return model.OrganizationMember{OrganizationID: org.ID, UserID: userID, Role: model.MemberRoleOwner}, nil
+}
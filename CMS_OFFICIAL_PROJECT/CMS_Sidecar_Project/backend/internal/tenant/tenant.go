@@ -0,0 +1,206 @@
+// Package tenant scopes data to organizations. A request context carries
+// the caller's organization; GORM callbacks installed by Enforce add
+// "org_id = ?" to every query, update and delete on a tenant table run with
+// that context and stamp org_id on every insert. Tables owned through a
+// project are filtered by their parent row instead. Contexts without an
+// organization (cmsctl, startup) are not filtered.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Column holds the owning organization on every tenant table.
+const Column = "org_id"
+
+// Tables are the tenant tables. The search index carries its rows'
+// organization too, so search results are filtered the same way.
+var Tables = map[string]bool{
+	"construction_project":         true,
+	"construction_vendor":          true,
+	"construction_supervisor":      true,
+	"construction_customer":        true,
+	"construction_channelpartner":  true,
+	"construction_materialitem":    true,
+	"construction_attendancebatch": true,
+	"cms_search_index":             true,
+}
+
+// Parent names the column of a child table that points at its owning row,
+// and the table that row lives in.
+type Parent struct {
+	Column string
+	Table  string
+}
+
+// Children are the tables owned through a project or an attendance batch:
+// blocks, units, presets, summaries, payments, expenses, stock, batch
+// members and their attendance records. They carry no org_id; their queries,
+// updates and deletes are restricted to rows whose parent chain ends at a
+// project of the organization. Inserts are not checked here; handlers check
+// the caller may access the project first.
+var Children = map[string]Parent{
+	"construction_projectblock":              {"project_block_project_id", "construction_project"},
+	"construction_projectunit":               {"project_unit_block_id", "construction_projectblock"},
+	"construction_projectpreset":             {"project_preset_project_id", "construction_project"},
+	"cms_project_summary":                    {"project_id", "construction_project"},
+	"flat_payments":                          {"flat_payment_project_id", "construction_project"},
+	"plot_payments":                          {"plot_payment_project_id", "construction_project"},
+	"project_payments":                       {"project_payment_project_id", "construction_project"},
+	"construction_manpowerexpense":           {"manpower_expense_project_id", "construction_project"},
+	"construction_materialexpense":           {"material_expense_project_id", "construction_project"},
+	"construction_generalexpense":            {"general_expense_project_id", "construction_project"},
+	"construction_departmentalexpense":       {"departmental_expense_project_id", "construction_project"},
+	"construction_administrationexpense":     {"administration_expense_project_id", "construction_project"},
+	"stock_management_page_app_stockbalance": {"stock_project_id", "construction_project"},
+	"construction_attendancemember":          {"batch_id", "construction_attendancebatch"},
+	"construction_attendancerecord":          {"member_id", "construction_attendancemember"},
+}
+
+// owned returns a subquery selecting the ids of table's rows that belong to
+// the organization bound to its single placeholder.
+func owned(table string) string {
+	if parent, ok := Children[table]; ok {
+		return "SELECT id FROM " + table + " WHERE " + parent.Column + " IN (" + owned(parent.Table) + ")"
+	}
+	return "SELECT id FROM " + table + " WHERE " + Column + " = ?"
+}
+
+// ErrNoOrganization is returned when a caller outside any organization tries
+// to create tenant data.
+var ErrNoOrganization = errors.New("tenant: caller belongs to no organization")
+
+type contextKey struct{}
+
+// WithOrganization scopes the queries run with the returned context to
+// orgID. Zero stands for a caller without an organization: reads match
+// nothing and inserts fail with ErrNoOrganization.
+func WithOrganization(ctx context.Context, orgID uint) context.Context {
+	return context.WithValue(ctx, contextKey{}, orgID)
+}
+
+// Organization returns the organization ctx is scoped to; ok is false for
+// unscoped contexts.
+func Organization(ctx context.Context) (orgID uint, ok bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	orgID, ok = ctx.Value(contextKey{}).(uint)
+	return orgID, ok
+}
+
+// Enforce installs the tenant callbacks on db. Raw SQL is not rewritten;
+// code running raw statements against tenant tables filters by org_id
+// itself.
+func Enforce(db *gorm.DB) error {
+	callbacks := db.Callback()
+	registrations := []error{
+		callbacks.Create().Before("gorm:create").Register("cms:tenant", stamp),
+		callbacks.Query().Before("gorm:query").Register("cms:tenant", filter),
+		callbacks.Row().Before("gorm:row").Register("cms:tenant", filter),
+		callbacks.Update().Before("gorm:update").Register("cms:tenant", filterWrite),
+		callbacks.Delete().Before("gorm:delete").Register("cms:tenant", filterWrite),
+	}
+	for _, err := range registrations {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scoped returns the organization of a statement on a tenant table built by
+// GORM (not raw SQL).
+func scoped(db *gorm.DB) (uint, bool) {
+	stmt := db.Statement
+	_, child := Children[stmt.Table]
+	if !(Tables[stmt.Table] || child) || stmt.SQL.Len() > 0 {
+		return 0, false
+	}
+	return Organization(stmt.Context)
+}
+
+func filter(db *gorm.DB) {
+	orgID, ok := scoped(db)
+	if !ok {
+		return
+	}
+	table := db.Statement.Table
+	var condition clause.Expression = clause.Eq{Column: clause.Column{Table: table, Name: Column}, Value: orgID}
+	if parent, ok := Children[table]; ok {
+		condition = clause.Expr{
+			SQL:  "? IN (" + owned(parent.Table) + ")",
+			Vars: []interface{}{clause.Column{Table: table, Name: parent.Column}, orgID},
+		}
+	}
+	if orgID == 0 {
+		condition = clause.Expr{SQL: "1 = 0"}
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{condition}})
+}
+
+// filterWrite scopes updates and deletes. One with neither conditions nor a
+// primary key is left alone: the organization condition would turn it into
+// a write to every row of the organization instead of GORM's
+// ErrMissingWhereClause.
+func filterWrite(db *gorm.DB) {
+	if !unconditioned(db) {
+		filter(db)
+	}
+}
+
+func unconditioned(db *gorm.DB) bool {
+	stmt := db.Statement
+	if _, ok := stmt.Clauses["WHERE"]; ok || db.AllowGlobalUpdate {
+		return false
+	}
+	if stmt.Schema == nil || stmt.Schema.PrioritizedPrimaryField == nil {
+		return true
+	}
+	value := reflect.Indirect(stmt.ReflectValue)
+	switch value.Kind() {
+	case reflect.Struct:
+		_, zero := stmt.Schema.PrioritizedPrimaryField.ValueOf(stmt.Context, value)
+		return zero
+	case reflect.Slice, reflect.Array:
+		return value.Len() == 0
+	}
+	return true
+}
+
+// stamp sets org_id on every row being inserted, whatever the caller put
+// there.
+func stamp(db *gorm.DB) {
+	orgID, ok := scoped(db)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+	field := db.Statement.Schema.LookUpField(Column)
+	if field == nil {
+		return
+	}
+	if orgID == 0 {
+		db.AddError(ErrNoOrganization)
+		return
+	}
+	set := func(row reflect.Value) {
+		value := orgID
+		if err := field.Set(db.Statement.Context, row, &value); err != nil {
+			db.AddError(err)
+		}
+	}
+	rows := reflect.Indirect(db.Statement.ReflectValue)
+	switch rows.Kind() {
+	case reflect.Struct:
+		set(rows)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rows.Len(); i++ {
+			set(reflect.Indirect(rows.Index(i)))
+		}
+	}
+}
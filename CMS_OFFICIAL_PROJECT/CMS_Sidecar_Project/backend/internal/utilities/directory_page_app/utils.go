@@ -51,3 +51,10 @@ type RegenerateCredentialsRequest struct {
 	Type string `json:"type"`
 	ID   uint   `json:"id"`
 }
+
+// Credentials are the fresh login details returned by regenerate_credentials.
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
@@ -0,0 +1,16 @@
+package files_page_app
+
+import "time"
+
+// FileResponse describes a stored file with download links that stop
+// working at ExpiresAt; ask for the file again for fresh ones. ThumbnailURL
+// is set for images.
+type FileResponse struct {
+	Key          string    `json:"key"`
+	Name         string    `json:"name"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
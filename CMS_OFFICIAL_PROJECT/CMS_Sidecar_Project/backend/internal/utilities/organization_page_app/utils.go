@@ -0,0 +1,150 @@
+package organization_page_app
+
+import "time"
+
+// OrganizationResponse is the caller's organization, the caller's role in
+// it and its members.
+type OrganizationResponse struct {
+	ID      uint             `json:"id"`
+	Name    string           `json:"name"`
+	Role    string           `json:"role"`
+	Members []MemberResponse `json:"members"`
+}
+
+// MemberResponse is one member with their login.
+type MemberResponse struct {
+	UserID   uint      `json:"user_id"`
+	Username string    `json:"username"`
+	UserType string    `json:"user_type"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// UpdateOrganizationRequest renames the organization.
+type UpdateOrganizationRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// AddMemberRequest adds an existing user who belongs to no organization.
+// Role defaults to member.
+type AddMemberRequest struct {
+	Username string `json:"username" binding:"required"`
+	Role     string `json:"role"`
+}
+
+// UpdateMemberRequest changes a member's role.
+type UpdateMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// UserResponse is a member's account as owners manage it.
+type UserResponse struct {
+	UserID     uint      `json:"user_id"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	Phone      string    `json:"phone"`
+	UserType   string    `json:"user_type"`
+	Role       string    `json:"role"`
+	IsActive   bool      `json:"is_active"`
+	DateJoined time.Time `json:"date_joined"`
+}
+
+// UpdateUserRequest changes a member's user type (builder or supervisor).
+type UpdateUserRequest struct {
+	UserType string `json:"user_type" binding:"required"`
+}
+
+// InviteRequest invites someone by email or phone. UserType defaults to
+// supervisor, Role to member and ExpiresInHours to 72 (at most 720).
+type InviteRequest struct {
+	Email          string `json:"email"`
+	Phone          string `json:"phone"`
+	UserType       string `json:"user_type"`
+	Role           string `json:"role"`
+	ExpiresInHours int    `json:"expires_in_hours"`
+}
+
+// InviteResponse is one invite. Token is only filled in when the invite is
+// created; Status is pending, accepted, revoked or expired.
+type InviteResponse struct {
+	ID          uint      `json:"id"`
+	Email       string    `json:"email"`
+	Phone       string    `json:"phone"`
+	UserType    string    `json:"user_type"`
+	Role        string    `json:"role"`
+	Status      string    `json:"status"`
+	InvitedByID uint      `json:"invited_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Token       string    `json:"token,omitempty"`
+}
+
+// CreateAPIKeyRequest issues an API key. Scopes are "<resource>:read",
+// "<resource>:write" or an admin scope such as "directory:admin"; AllowedIPs takes addresses and CIDR ranges and, when
+// empty, allows any. ExpiresAt is optional.
+type CreateAPIKeyRequest struct {
+	Name       string     `json:"name" binding:"required"`
+	Scopes     []string   `json:"scopes" binding:"required"`
+	AllowedIPs []string   `json:"allowed_ips"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+// APIKeyResponse is one API key. Key is only filled in when the key is
+// created; Status is active, revoked or expired.
+type APIKeyResponse struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	Scopes      []string   `json:"scopes"`
+	AllowedIPs  []string   `json:"allowed_ips"`
+	Status      string     `json:"status"`
+	CreatedByID uint       `json:"created_by_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `json:"last_used_ip"`
+	Key         string     `json:"key,omitempty"`
+}
+
+// AuditEntryResponse is one audit log entry. Changes maps each changed
+// column to its "from" and "to" values; creates have only "to", deletes
+// only "from".
+type AuditEntryResponse struct {
+	ID         uint                              `json:"id"`
+	OccurredAt time.Time                         `json:"occurred_at"`
+	UserID     uint                              `json:"user_id"`
+	APIKeyID   uint                              `json:"api_key_id"`
+	SessionID  uint                              `json:"session_id"`
+	IP         string                            `json:"ip"`
+	RequestID  string                            `json:"request_id"`
+	Route      string                            `json:"route"`
+	Action     string                            `json:"action"`
+	Entity     string                            `json:"entity"`
+	EntityID   string                            `json:"entity_id"`
+	Changes    map[string]map[string]interface{} `json:"changes"`
+	Hash       string                            `json:"hash"`
+}
+
+// LetterheadRequest replaces what receipts print about the organization.
+// Name defaults to the organization's name.
+type LetterheadRequest struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Phone   string `json:"phone"`
+	Email   string `json:"email"`
+	TaxID   string `json:"tax_id"`
+	Footer  string `json:"footer"`
+}
+
+// LetterheadResponse is the letterhead with a signed link to its logo, if
+// one was uploaded.
+type LetterheadResponse struct {
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	Phone     string    `json:"phone"`
+	Email     string    `json:"email"`
+	TaxID     string    `json:"tax_id"`
+	Footer    string    `json:"footer"`
+	LogoURL   string    `json:"logo_url"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
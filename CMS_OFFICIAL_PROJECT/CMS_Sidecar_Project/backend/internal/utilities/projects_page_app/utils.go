@@ -45,3 +45,48 @@ type UpdateProjectRequest struct {
 	CreateProjectRequest // Embed for now, fields are similar
 	// Add partial update fields if needed distinct from create
 }
+
+// DeletionDependency counts one kind of record that depends on a block or project.
+// Blocking dependencies carry sales, money or inventory history and are only
+// removed when the caller explicitly confirms a cascade.
+type DeletionDependency struct {
+	Entity   string `json:"entity"`
+	Table    string `json:"table"`
+	Count    int64  `json:"count"`
+	Blocking bool   `json:"blocking"`
+}
+
+// DeletionPlan is the report returned before (or instead of) deleting a block or project.
+type DeletionPlan struct {
+	Target       string               `json:"target"`
+	TargetID     uint                 `json:"target_id"`
+	Dependencies []DeletionDependency `json:"dependencies"`
+	Safe         bool                 `json:"safe"`
+}
+
+// SummaryTotals adds up project summaries for dashboard cards.
+type SummaryTotals struct {
+	TotalUnits      int     `json:"total_units"`
+	SoldUnits       int     `json:"sold_units"`
+	BookedUnits     int     `json:"booked_units"`
+	HoldUnits       int     `json:"hold_units"`
+	AvailableUnits  int     `json:"available_units"`
+	CollectedAmount float64 `json:"collected_amount"`
+	TotalExpense    float64 `json:"total_expense"`
+	StockValue      float64 `json:"stock_value"`
+}
+
+// MultiFlatProjectStats is one row of the multi-flat projects list.
+type MultiFlatProjectStats struct {
+	ID             uint   `json:"id"`
+	ProjectCode    string `json:"project_code"`
+	ProjectName    string `json:"project_name"`
+	ProjectStatus  string `json:"project_status"`
+	ProjectBudget  string `json:"project_budget"`
+	BlockCount     uint   `json:"project_block_count"`
+	TotalUnits     int    `json:"total_units"`
+	SoldUnits      int    `json:"sold_units"`
+	BookedUnits    int    `json:"booked_units"`
+	HoldUnits      int    `json:"hold_units"`
+	AvailableUnits int    `json:"available_units"`
+}
@@ -27,6 +27,7 @@ type VendorResponse struct {
 	BusinessAddress   string `json:"business_address"`
 	PaymentPreference string `json:"payment_method_preference"`
 	BankAccount       string `json:"bank_account_number"`
+	Version           uint   `json:"version"`
 }
 
 type VendorChoice struct {
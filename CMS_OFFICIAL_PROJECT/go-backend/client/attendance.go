@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// StatsOptions narrows AttendanceStats. From and To are YYYY-MM-DD; Batch
+// and Project are ids; TZ is an IANA zone for the day boundaries.
+type StatsOptions struct {
+	From, To, Batch, Project, TZ string
+}
+
+// AttendanceStats is the aggregate behind the attendance charts.
+type AttendanceStats struct {
+	Stats        map[string]int `json:"stats"`
+	DailyChart   []ChartEntry   `json:"daily_chart"`
+	MonthlyChart []ChartEntry   `json:"monthly_chart"`
+	YearlyChart  []ChartEntry   `json:"yearly_chart"`
+	StatusChart  []ChartEntry   `json:"status_chart"`
+	Range        StatsRange     `json:"range"`
+}
+
+// AttendanceStats aggregates attendance on the server.
+func (c *Client) AttendanceStats(ctx context.Context, opts StatsOptions) (AttendanceStats, error) {
+	q := url.Values{}
+	for key, value := range map[string]string{"from": opts.From, "to": opts.To, "batch": opts.Batch, "project": opts.Project, "tz": opts.TZ} {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	var stats AttendanceStats
+	_, err := c.get(ctx, v2Path("attendance", "stats"), q, &stats)
+	return stats, err
+}
+
+// ListAttendanceRecords returns one page of records. Filters: status, mode,
+// batch, member_id, date_from, date_to.
+func (c *Client) ListAttendanceRecords(ctx context.Context, opts ListOptions) ([]AttendanceRecord, Page, error) {
+	var records []AttendanceRecord
+	page, err := c.list(ctx, v2Path("attendance", "records"), opts, &records)
+	return records, page, err
+}
+
+// MarkAttendance records attendance for batch members; pass IdempotencyKey
+// so a retry does not mark anyone twice.
+func (c *Client) MarkAttendance(ctx context.Context, req AttendanceRecordRequest, opts ...CallOption) error {
+	return c.write(ctx, http.MethodPost, v2Path("attendance", "records"), req, nil, opts...)
+}
+
+// ListAttendanceBatches returns every batch.
+func (c *Client) ListAttendanceBatches(ctx context.Context) ([]AttendanceBatch, error) {
+	var batches []AttendanceBatch
+	_, err := c.get(ctx, v2Path("attendance", "batches"), nil, &batches)
+	return batches, err
+}
+
+// SaveAttendanceBatch creates a batch, or adds members to the one with the
+// same name.
+func (c *Client) SaveAttendanceBatch(ctx context.Context, req AttendanceBatchRequest) (AttendanceBatch, error) {
+	var batch AttendanceBatch
+	err := c.write(ctx, http.MethodPost, v2Path("attendance", "batches"), req, &batch)
+	return batch, err
+}
+
+// ListAttendanceMembers returns the members of a batch.
+func (c *Client) ListAttendanceMembers(ctx context.Context, batchID uint) ([]AttendanceMember, error) {
+	var members []AttendanceMember
+	_, err := c.get(ctx, v2Path("attendance", "batches", batchID, "members"), nil, &members)
+	return members, err
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"sync"
+)
+
+// TokenSource supplies the bearer token sent with each request.
+type TokenSource interface {
+	// Token returns the current token.
+	Token(ctx context.Context) (string, error)
+	// Refresh is called after a 401 and returns the token to retry with.
+	Refresh(ctx context.Context) (string, error)
+}
+
+// StaticToken is a token that never changes; a 401 is returned as is.
+type StaticToken string
+
+func (t StaticToken) Token(context.Context) (string, error)   { return string(t), nil }
+func (t StaticToken) Refresh(context.Context) (string, error) { return string(t), nil }
+
+// RefreshingToken caches a token and asks fetch for a new one the first
+// time it is needed and after every 401. Concurrent callers share one fetch.
+type RefreshingToken struct {
+	fetch func(ctx context.Context) (string, error)
+
+	mu    sync.Mutex
+	token string
+}
+
+// NewRefreshingToken starts with initial (which may be empty) and calls
+// fetch to replace it.
+func NewRefreshingToken(initial string, fetch func(ctx context.Context) (string, error)) *RefreshingToken {
+	return &RefreshingToken{fetch: fetch, token: initial}
+}
+
+func (t *RefreshingToken) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token == "" {
+		return t.refreshLocked(ctx)
+	}
+	return t.token, nil
+}
+
+func (t *RefreshingToken) Refresh(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.refreshLocked(ctx)
+}
+
+func (t *RefreshingToken) refreshLocked(ctx context.Context) (string, error) {
+	token, err := t.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	t.token = token
+	return token, nil
+}
@@ -0,0 +1,212 @@
+// Package client is a typed Go client for the CMS /api/v2 surface. It
+// unwraps the success/data/message envelope, turns failures into *Error,
+// follows pagination cursors and refreshes bearer tokens on 401.
+//
+//	c := client.New("http://localhost:8080", client.WithToken(client.StaticToken(token)))
+//	projects, err := c.ListProjects(ctx)
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls one CMS server. It is safe for concurrent use.
+type Client struct {
+	baseURL string
+	http    *http.Client
+	tokens  TokenSource
+	agent   string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient replaces http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithToken sends a bearer token from ts with every request.
+func WithToken(ts TokenSource) Option {
+	return func(c *Client) { c.tokens = ts }
+}
+
+// WithUserAgent sets the User-Agent header.
+func WithUserAgent(agent string) Option {
+	return func(c *Client) { c.agent = agent }
+}
+
+// New builds a client for the server at baseURL (scheme and host, without
+// /api/v2).
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{baseURL: strings.TrimRight(baseURL, "/"), http: http.DefaultClient, agent: "cms-go-client"}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CallOption sets per-call headers or captures response headers.
+type CallOption func(*call)
+
+type call struct {
+	header http.Header
+	etag   *string
+}
+
+// IfMatch guards an update with the ETag of the version being edited; the
+// server answers ErrConflict when someone saved in between.
+func IfMatch(etag string) CallOption {
+	return func(c *call) { c.header.Set("If-Match", etag) }
+}
+
+// IdempotencyKey makes a create safe to retry: the server replays the first
+// response for the same key and payload.
+func IdempotencyKey(key string) CallOption {
+	return func(c *call) { c.header.Set("Idempotency-Key", key) }
+}
+
+// CaptureETag stores the response's ETag in dst, for a later IfMatch.
+func CaptureETag(dst *string) CallOption {
+	return func(c *call) { c.etag = dst }
+}
+
+// envelope is the body every /api endpoint answers with.
+type envelope struct {
+	Success    bool            `json:"success"`
+	Data       json.RawMessage `json:"data"`
+	Message    string          `json:"message"`
+	Pagination *Page           `json:"pagination"`
+}
+
+// do sends one request and decodes the envelope's data into out (when not
+// nil). A 401 refreshes the token once and retries.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}, opts ...CallOption) (*Page, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("client: encode %s %s: %w", method, path, err)
+		}
+	}
+	cl := call{header: make(http.Header)}
+	for _, opt := range opts {
+		opt(&cl)
+	}
+
+	resp, err := c.send(ctx, method, path, query, payload, cl.header, false)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && c.tokens != nil {
+		resp.Body.Close()
+		resp, err = c.send(ctx, method, path, query, payload, cl.header, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if cl.etag != nil {
+		*cl.etag = resp.Header.Get("ETag")
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("client: read %s %s: %w", method, path, err)
+	}
+
+	var env envelope
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &env); err != nil {
+			if resp.StatusCode >= 300 {
+				return nil, newError(resp, string(raw))
+			}
+			return nil, fmt.Errorf("client: decode %s %s: %w", method, path, err)
+		}
+	}
+	if resp.StatusCode >= 300 || (len(raw) > 0 && !env.Success) {
+		return nil, newError(resp, env.Message)
+	}
+	if out != nil && len(env.Data) > 0 && string(env.Data) != "null" {
+		if err := json.Unmarshal(env.Data, out); err != nil {
+			return nil, fmt.Errorf("client: decode %s %s: %w", method, path, err)
+		}
+	}
+	return env.Pagination, nil
+}
+
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, payload []byte, header http.Header, refresh bool) (*http.Response, error) {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.agent)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.tokens != nil {
+		token, err := c.token(ctx, refresh)
+		if err != nil {
+			return nil, err
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("client: %s %s: %w", method, path, err)
+	}
+	return resp, nil
+}
+
+func (c *Client) token(ctx context.Context, refresh bool) (string, error) {
+	if refresh {
+		token, err := c.tokens.Refresh(ctx)
+		if err != nil {
+			return "", fmt.Errorf("client: refresh token: %w", err)
+		}
+		return token, nil
+	}
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("client: token: %w", err)
+	}
+	return token, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, out interface{}, opts ...CallOption) (*Page, error) {
+	return c.do(ctx, http.MethodGet, path, query, nil, out, opts...)
+}
+
+func (c *Client) write(ctx context.Context, method, path string, body, out interface{}, opts ...CallOption) error {
+	_, err := c.do(ctx, method, path, nil, body, out, opts...)
+	return err
+}
+
+// v2Path joins /api/v2 and escaped segments.
+func v2Path(segments ...interface{}) string {
+	var b strings.Builder
+	b.WriteString("/api/v2")
+	for _, segment := range segments {
+		b.WriteByte('/')
+		b.WriteString(url.PathEscape(fmt.Sprint(segment)))
+	}
+	return b.String()
+}
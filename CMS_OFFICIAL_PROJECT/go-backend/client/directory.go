@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// ListVendors returns the user's vendors.
+func (c *Client) ListVendors(ctx context.Context) ([]Vendor, error) {
+	var data struct {
+		Vendors []Vendor `json:"vendors"`
+	}
+	_, err := c.get(ctx, v2Path("vendors"), nil, &data)
+	return data.Vendors, err
+}
+
+type vendorResult struct {
+	Vendor Vendor `json:"vendor"`
+}
+
+// GetVendor loads one vendor.
+func (c *Client) GetVendor(ctx context.Context, id uint, opts ...CallOption) (Vendor, error) {
+	var data vendorResult
+	_, err := c.get(ctx, v2Path("vendors", id), nil, &data, opts...)
+	return data.Vendor, err
+}
+
+// CreateVendor adds a vendor.
+func (c *Client) CreateVendor(ctx context.Context, req CreateVendorRequest) (Vendor, error) {
+	var data vendorResult
+	err := c.write(ctx, http.MethodPost, v2Path("vendors"), req, &data)
+	return data.Vendor, err
+}
+
+// UpdateVendor replaces a vendor's details.
+func (c *Client) UpdateVendor(ctx context.Context, id uint, req CreateVendorRequest, opts ...CallOption) (Vendor, error) {
+	var data vendorResult
+	err := c.write(ctx, http.MethodPut, v2Path("vendors", id), req, &data, opts...)
+	return data.Vendor, err
+}
+
+// DeleteVendor removes a vendor.
+func (c *Client) DeleteVendor(ctx context.Context, id uint) error {
+	return c.write(ctx, http.MethodDelete, v2Path("vendors", id), nil, nil)
+}
+
+// ListSupervisors returns the user's supervisors with their projects.
+func (c *Client) ListSupervisors(ctx context.Context) ([]Supervisor, error) {
+	var data struct {
+		Supervisors []Supervisor `json:"supervisors"`
+	}
+	_, err := c.get(ctx, v2Path("supervisors"), nil, &data)
+	return data.Supervisors, err
+}
+
+type supervisorResult struct {
+	Supervisor Supervisor `json:"supervisor"`
+}
+
+// GetSupervisor loads one supervisor.
+func (c *Client) GetSupervisor(ctx context.Context, id uint, opts ...CallOption) (Supervisor, error) {
+	var data supervisorResult
+	_, err := c.get(ctx, v2Path("supervisors", id), nil, &data, opts...)
+	return data.Supervisor, err
+}
+
+// CreateSupervisor adds a supervisor and assigns their projects.
+func (c *Client) CreateSupervisor(ctx context.Context, req CreateSupervisorRequest) (Supervisor, error) {
+	var data supervisorResult
+	err := c.write(ctx, http.MethodPost, v2Path("supervisors"), req, &data)
+	return data.Supervisor, err
+}
+
+// UpdateSupervisor edits a supervisor, their projects and page access.
+func (c *Client) UpdateSupervisor(ctx context.Context, id uint, req UpdateSupervisorRequest, opts ...CallOption) (Supervisor, error) {
+	var data supervisorResult
+	err := c.write(ctx, http.MethodPut, v2Path("supervisors", id), req, &data, opts...)
+	return data.Supervisor, err
+}
+
+// DeleteSupervisor removes a supervisor and unassigns their projects.
+func (c *Client) DeleteSupervisor(ctx context.Context, id uint) error {
+	return c.write(ctx, http.MethodDelete, v2Path("supervisors", id), nil, nil)
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Sentinels for errors.Is; every *Error matches the one for its status.
+var (
+	ErrInvalid      = errors.New("invalid request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrUnavailable  = errors.New("server busy")
+)
+
+// Error is a request the server refused. Message is the envelope's message,
+// safe to show to a user.
+type Error struct {
+	StatusCode int
+	Message    string
+	// ETag is the current version when an update lost a race (409).
+	ETag string
+	// RetryAfter is set when the server asked the caller to back off.
+	RetryAfter time.Duration
+}
+
+func newError(resp *http.Response, message string) *Error {
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+	e := &Error{StatusCode: resp.StatusCode, Message: message, ETag: resp.Header.Get("ETag")}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return e
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("cms api: %d %s", e.StatusCode, e.Message)
+}
+
+// Is maps the status code onto the sentinel errors.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrInvalid:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict || e.StatusCode == http.StatusPreconditionFailed
+	case ErrUnavailable:
+		return e.StatusCode == http.StatusServiceUnavailable || e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/url"
+	"strconv"
+)
+
+// Page is the pagination block of list responses.
+type Page struct {
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+	PageSize   int    `json:"page_size"`
+	Total      int64  `json:"total"`
+	Sort       string `json:"sort"`
+}
+
+// ListOptions are the cursor, page size, sort and filters of list
+// endpoints. Filters hold the endpoint's own parameters (project_id,
+// status, search, date_from, ...).
+type ListOptions struct {
+	Cursor   string
+	PageSize int
+	Sort     string
+	Filters  map[string]string
+}
+
+func (o ListOptions) query() url.Values {
+	q := url.Values{}
+	if o.Cursor != "" {
+		q.Set("cursor", o.Cursor)
+	}
+	if o.PageSize > 0 {
+		q.Set("page_size", strconv.Itoa(o.PageSize))
+	}
+	if o.Sort != "" {
+		q.Set("sort", o.Sort)
+	}
+	for key, value := range o.Filters {
+		q.Set(key, value)
+	}
+	return q
+}
+
+// All walks every page of a list method, starting from opts:
+//
+//	for unit, err := range client.All(ctx, client.ListOptions{}, c.ListUnits) { ... }
+//
+// Iteration stops after the first error, which is yielded.
+func All[T any](ctx context.Context, opts ListOptions, list func(context.Context, ListOptions) ([]T, Page, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			items, page, err := list(ctx, opts)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if !page.HasMore || page.NextCursor == "" {
+				return
+			}
+			opts.Cursor = page.NextCursor
+		}
+	}
+}
+
+// list fetches one page into out.
+func (c *Client) list(ctx context.Context, path string, opts ListOptions, out interface{}) (Page, error) {
+	page, err := c.get(ctx, path, opts.query(), out)
+	if err != nil || page == nil {
+		return Page{}, err
+	}
+	return *page, nil
+}
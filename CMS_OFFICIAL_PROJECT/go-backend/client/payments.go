@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// ListProjectPayments returns one page of project payments. Filters:
+// project_id, type, date_from, date_to.
+func (c *Client) ListProjectPayments(ctx context.Context, opts ListOptions) ([]ProjectPayment, Page, error) {
+	var payments []ProjectPayment
+	page, err := c.list(ctx, v2Path("payments", "projects"), opts, &payments)
+	return payments, page, err
+}
+
+// CreateProjectPayment records a project payment; pass IdempotencyKey so a
+// retry after a timeout does not record it twice.
+func (c *Client) CreateProjectPayment(ctx context.Context, req CreateProjectPaymentRequest, opts ...CallOption) (ProjectPayment, error) {
+	var payment ProjectPayment
+	err := c.write(ctx, http.MethodPost, v2Path("payments", "projects"), req, &payment, opts...)
+	return payment, err
+}
+
+// ListFlatPayments returns one page of flat payments.
+func (c *Client) ListFlatPayments(ctx context.Context, opts ListOptions) ([]FlatPayment, Page, error) {
+	var data struct {
+		Payments []FlatPayment `json:"payments"`
+	}
+	page, err := c.list(ctx, v2Path("payments", "flats"), opts, &data)
+	return data.Payments, page, err
+}
+
+// CreateFlatPayment records a payment against a flat of the project.
+func (c *Client) CreateFlatPayment(ctx context.Context, req CreateUnitPaymentRequest, opts ...CallOption) (FlatPayment, error) {
+	var payment FlatPayment
+	err := c.write(ctx, http.MethodPost, v2Path("payments", "flats"), req, &payment, opts...)
+	return payment, err
+}
+
+// ListPlotPayments returns one page of plot payments.
+func (c *Client) ListPlotPayments(ctx context.Context, opts ListOptions) ([]PlotPayment, Page, error) {
+	var data struct {
+		Payments []PlotPayment `json:"payments"`
+	}
+	page, err := c.list(ctx, v2Path("payments", "plots"), opts, &data)
+	return data.Payments, page, err
+}
+
+// CreatePlotPayment records a payment against a plot of the project.
+func (c *Client) CreatePlotPayment(ctx context.Context, req CreateUnitPaymentRequest, opts ...CallOption) (PlotPayment, error) {
+	var payment PlotPayment
+	err := c.write(ctx, http.MethodPost, v2Path("payments", "plots"), req, &payment, opts...)
+	return payment, err
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ListProjects returns the projects the user can access, with blocks and
+// units.
+func (c *Client) ListProjects(ctx context.Context) ([]Project, error) {
+	var projects []Project
+	_, err := c.get(ctx, v2Path("projects"), nil, &projects)
+	return projects, err
+}
+
+// GetProject loads one project; pass CaptureETag to edit it afterwards.
+func (c *Client) GetProject(ctx context.Context, id uint, opts ...CallOption) (Project, error) {
+	var project Project
+	_, err := c.get(ctx, v2Path("projects", id), nil, &project, opts...)
+	return project, err
+}
+
+// CreateProject creates a project.
+func (c *Client) CreateProject(ctx context.Context, req CreateProjectRequest) (Project, error) {
+	var project Project
+	err := c.write(ctx, http.MethodPost, v2Path("projects"), req, &project)
+	return project, err
+}
+
+// UpdateProject saves a project edit; pass IfMatch to guard against lost
+// updates.
+func (c *Client) UpdateProject(ctx context.Context, id uint, req UpdateProjectRequest, opts ...CallOption) (Project, error) {
+	var project Project
+	err := c.write(ctx, http.MethodPut, v2Path("projects", id), req, &project, opts...)
+	return project, err
+}
+
+// DeleteProject deletes a project. Projects with sales, money or stock
+// history are refused with ErrConflict unless cascade is set.
+func (c *Client) DeleteProject(ctx context.Context, id uint, cascade bool) error {
+	_, err := c.do(ctx, http.MethodDelete, v2Path("projects", id), cascadeQuery(cascade), nil, nil)
+	return err
+}
+
+// ProjectDeletionPlan reports what DeleteProject would remove.
+func (c *Client) ProjectDeletionPlan(ctx context.Context, id uint) (DeletionPlan, error) {
+	var plan DeletionPlan
+	_, err := c.get(ctx, v2Path("projects", id, "deletion-plan"), nil, &plan)
+	return plan, err
+}
+
+// ListMultiFlatProjects returns multi-flat projects with their unit counts.
+func (c *Client) ListMultiFlatProjects(ctx context.Context) ([]MultiFlatProject, error) {
+	var data struct {
+		Projects []MultiFlatProject `json:"projects"`
+	}
+	_, err := c.get(ctx, v2Path("multi-flat", "projects"), nil, &data)
+	return data.Projects, err
+}
+
+func cascadeQuery(cascade bool) url.Values {
+	if !cascade {
+		return nil
+	}
+	return url.Values{"cascade": {"true"}}
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+type blockResult struct {
+	Block        Block `json:"block"`
+	CreatedUnits int   `json:"created_units"`
+}
+
+// CreateBlock adds a block to a multi-flat project and returns it with the
+// number of units generated.
+func (c *Client) CreateBlock(ctx context.Context, projectCode string, req CreateBlockRequest) (Block, int, error) {
+	var data blockResult
+	err := c.write(ctx, http.MethodPost, v2Path("multi-flat", "projects", projectCode, "blocks"), req, &data)
+	return data.Block, data.CreatedUnits, err
+}
+
+// GetBlock loads a block with its units.
+func (c *Client) GetBlock(ctx context.Context, id uint, opts ...CallOption) (Block, error) {
+	var block Block
+	_, err := c.get(ctx, v2Path("multi-flat", "blocks", id), nil, &block, opts...)
+	return block, err
+}
+
+// UpdateBlock edits a block; floor and unit counts only grow. It returns the
+// block and the number of units added.
+func (c *Client) UpdateBlock(ctx context.Context, id uint, req UpdateBlockRequest, opts ...CallOption) (Block, int, error) {
+	var data blockResult
+	err := c.write(ctx, http.MethodPatch, v2Path("multi-flat", "blocks", id), req, &data, opts...)
+	return data.Block, data.CreatedUnits, err
+}
+
+// DeleteBlock deletes a block and reports what was removed. Blocks with
+// sales history are refused with ErrConflict unless cascade is set.
+func (c *Client) DeleteBlock(ctx context.Context, id uint, cascade bool) (DeletionPlan, error) {
+	var plan DeletionPlan
+	_, err := c.do(ctx, http.MethodDelete, v2Path("multi-flat", "blocks", id), cascadeQuery(cascade), nil, &plan)
+	return plan, err
+}
+
+// ListUnits returns one page of units for the CRM. Filters: project_id,
+// block_id, status, crm_stage, search, date_from, date_to.
+func (c *Client) ListUnits(ctx context.Context, opts ListOptions) ([]UnitSummary, Page, error) {
+	var data struct {
+		Units []UnitSummary `json:"units"`
+	}
+	page, err := c.list(ctx, v2Path("multi-flat", "units"), opts, &data)
+	return data.Units, page, err
+}
+
+// GetUnit loads one unit.
+func (c *Client) GetUnit(ctx context.Context, id uint, opts ...CallOption) (Unit, error) {
+	var unit Unit
+	_, err := c.get(ctx, v2Path("multi-flat", "units", id), nil, &unit, opts...)
+	return unit, err
+}
+
+// UpdateUnit changes a unit's status, price or buyer.
+func (c *Client) UpdateUnit(ctx context.Context, id uint, req UpdateUnitRequest, opts ...CallOption) (Unit, error) {
+	var unit Unit
+	err := c.write(ctx, http.MethodPatch, v2Path("multi-flat", "units", id), req, &unit, opts...)
+	return unit, err
+}
@@ -0,0 +1,23 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// StockBalances lists every material with its balance on the project.
+func (c *Client) StockBalances(ctx context.Context, projectID uint) ([]StockItem, error) {
+	var data struct {
+		Stock []StockItem `json:"stock"`
+	}
+	_, err := c.get(ctx, v2Path("projects", projectID, "stock"), nil, &data)
+	return data.Stock, err
+}
+
+// UpdateStock sets the allocation and usage of one material; usage is
+// capped at the allocation.
+func (c *Client) UpdateStock(ctx context.Context, projectID uint, req UpdateStockRequest) (StockBalance, error) {
+	var balance StockBalance
+	err := c.write(ctx, http.MethodPatch, v2Path("projects", projectID, "stock"), req, &balance)
+	return balance, err
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"github.com/quickgeo/cms-official-go/internal/model"
+	attendanceUtils "github.com/quickgeo/cms-official-go/internal/utilities/attendance_page_app"
+	paymentUtils "github.com/quickgeo/cms-official-go/internal/utilities/payments_page_app"
+	projectUtils "github.com/quickgeo/cms-official-go/internal/utilities/projects_page_app"
+	salesUtils "github.com/quickgeo/cms-official-go/internal/utilities/sales_page_app"
+	stockUtils "github.com/quickgeo/cms-official-go/internal/utilities/stock_management_page_app"
+	supUtils "github.com/quickgeo/cms-official-go/internal/utilities/supervisor_page_app"
+	vendorUtils "github.com/quickgeo/cms-official-go/internal/utilities/vendor_page_app"
+)
+
+// The client uses the server's own request and response types, so a field
+// renamed on one side cannot silently drift on the other.
+type (
+	Project              = model.Project
+	CreateProjectRequest = projectUtils.CreateProjectRequest
+	UpdateProjectRequest = projectUtils.UpdateProjectRequest
+	DeletionPlan         = projectUtils.DeletionPlan
+	MultiFlatProject     = projectUtils.MultiFlatProjectStats
+
+	Block              = model.ProjectBlock
+	CreateBlockRequest = salesUtils.CreateBlockRequest
+	UpdateBlockRequest = salesUtils.UpdateBlockRequest
+	Unit               = model.ProjectUnit
+	UnitSummary        = salesUtils.UnitResponse
+	UpdateUnitRequest  = salesUtils.UpdateUnitRequest
+
+	ProjectPayment              = model.ProjectPayment
+	FlatPayment                 = model.FlatPayment
+	PlotPayment                 = model.PlotPayment
+	CreateProjectPaymentRequest = paymentUtils.CreateProjectPaymentRequest
+	CreateUnitPaymentRequest    = paymentUtils.CreateUnitPaymentRequest
+
+	AttendanceRecord        = model.AttendanceRecord
+	AttendanceBatch         = model.AttendanceBatch
+	AttendanceMember        = model.AttendanceMember
+	AttendanceRecordRequest = attendanceUtils.AttendanceRecordRequest
+	AttendanceBatchRequest  = attendanceUtils.AttendanceBatchRequest
+	ChartEntry              = attendanceUtils.ChartEntry
+	StatsRange              = attendanceUtils.StatsRange
+
+	StockItem          = stockUtils.StockItem
+	StockBalance       = model.StockBalance
+	UpdateStockRequest = stockUtils.UpdateStockRequest
+
+	Vendor                  = vendorUtils.VendorResponse
+	CreateVendorRequest     = vendorUtils.CreateVendorRequest
+	Supervisor              = supUtils.SupervisorResponse
+	CreateSupervisorRequest = supUtils.CreateSupervisorRequest
+	UpdateSupervisorRequest = supUtils.UpdateSupervisorRequest
+)
@@ -0,0 +1,269 @@
+// Command cmsclient is an example CLI built on the client package.
+//
+//	cmsclient [-url URL] [-token TOKEN] <command> [flags]
+//
+// The server URL and token default to CMS_API_URL and CMS_API_TOKEN.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/quickgeo/cms-official-go/client"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, c *client.Client, out *tabwriter.Writer, args []string) error
+}
+
+var commands = []command{
+	{"projects", "list accessible projects", runProjects},
+	{"units", "list units (-project, -status, -search, -all)", runUnits},
+	{"set-unit-status", "change a unit's status (-unit, -status)", runSetUnitStatus},
+	{"payments", "list payments (-kind project|flat|plot, -project)", runPayments},
+	{"attendance-stats", "attendance totals (-from, -to, -project)", runAttendanceStats},
+	{"stock", "stock balances of a project (-project)", runStock},
+	{"vendors", "list vendors", runVendors},
+	{"supervisors", "list supervisors", runSupervisors},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: cmsclient [-url URL] [-token TOKEN] <command> [flags]")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-18s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+func main() {
+	flags := flag.NewFlagSet("cmsclient", flag.ExitOnError)
+	flags.Usage = usage
+	baseURL := flags.String("url", envOr("CMS_API_URL", "http://localhost:8080"), "server URL")
+	token := flags.String("token", os.Getenv("CMS_API_TOKEN"), "bearer token")
+	flags.Parse(os.Args[1:])
+	if flags.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	var opts []client.Option
+	if *token != "" {
+		opts = append(opts, client.WithToken(client.StaticToken(*token)))
+	}
+	c := client.New(*baseURL, opts...)
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	name := flags.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(context.Background(), c, out, flags.Args()[1:])
+		out.Flush()
+		if err != nil {
+			var apiErr *client.Error
+			if errors.As(err, &apiErr) {
+				fmt.Fprintf(os.Stderr, "cmsclient %s: server said %d: %s\n", name, apiErr.StatusCode, apiErr.Message)
+			} else {
+				fmt.Fprintf(os.Stderr, "cmsclient %s: %v\n", name, err)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+	usage()
+	os.Exit(2)
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func runProjects(ctx context.Context, c *client.Client, out *tabwriter.Writer, args []string) error {
+	projects, err := c.ListProjects(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "ID\tCODE\tNAME\tSTATUS\tCONFIGURATION")
+	for _, p := range projects {
+		fmt.Fprintf(out, "%d\t%s\t%s\t%s\t%s\n", p.ID, p.ProjectCode, p.ProjectName, p.ProjectStatus, p.ProjectFlatConfiguration)
+	}
+	return nil
+}
+
+func runUnits(ctx context.Context, c *client.Client, out *tabwriter.Writer, args []string) error {
+	flags := flag.NewFlagSet("units", flag.ExitOnError)
+	project := flags.String("project", "", "project id")
+	status := flags.String("status", "", "unit status")
+	search := flags.String("search", "", "free-text search")
+	all := flags.Bool("all", false, "follow cursors through every page")
+	flags.Parse(args)
+
+	opts := client.ListOptions{Filters: map[string]string{}}
+	for key, value := range map[string]string{"project_id": *project, "status": *status, "search": *search} {
+		if value != "" {
+			opts.Filters[key] = value
+		}
+	}
+
+	fmt.Fprintln(out, "ID\tPROJECT\tUNIT\tBHK\tSTATUS\tBUYER")
+	show := func(u client.UnitSummary) {
+		fmt.Fprintf(out, "%d\t%s\t%s\t%s\t%s\t%s\n", u.ID, u.ProjectCode, u.ProjectUnitLabel, u.ProjectUnitBHKConfiguration, u.ProjectUnitStatus, u.ProjectUnitBuyerName)
+	}
+	if *all {
+		for unit, err := range client.All(ctx, opts, c.ListUnits) {
+			if err != nil {
+				return err
+			}
+			show(unit)
+		}
+		return nil
+	}
+	units, page, err := c.ListUnits(ctx, opts)
+	if err != nil {
+		return err
+	}
+	for _, unit := range units {
+		show(unit)
+	}
+	if page.HasMore {
+		fmt.Fprintf(out, "... %d of %d shown, use -all for the rest\n", len(units), page.Total)
+	}
+	return nil
+}
+
+// runSetUnitStatus shows the read-modify-write pattern: the ETag of the
+// read guards the write, so a concurrent edit is reported, not overwritten.
+func runSetUnitStatus(ctx context.Context, c *client.Client, out *tabwriter.Writer, args []string) error {
+	flags := flag.NewFlagSet("set-unit-status", flag.ExitOnError)
+	unitID := flags.Uint("unit", 0, "unit id")
+	status := flags.String("status", "", "new status (available, booked, hold, sold)")
+	flags.Parse(args)
+	if *unitID == 0 || *status == "" {
+		return errors.New("-unit and -status are required")
+	}
+
+	var etag string
+	if _, err := c.GetUnit(ctx, *unitID, client.CaptureETag(&etag)); err != nil {
+		return err
+	}
+	unit, err := c.UpdateUnit(ctx, *unitID, client.UpdateUnitRequest{Status: *status}, client.IfMatch(etag))
+	if errors.Is(err, client.ErrConflict) {
+		return errors.New("the unit was changed by someone else; reload and try again")
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "unit %d (%s) is now %s\n", unit.ID, unit.ProjectUnitLabel, unit.ProjectUnitStatus)
+	return nil
+}
+
+func runPayments(ctx context.Context, c *client.Client, out *tabwriter.Writer, args []string) error {
+	flags := flag.NewFlagSet("payments", flag.ExitOnError)
+	kind := flags.String("kind", "project", "project, flat or plot")
+	project := flags.String("project", "", "project id")
+	flags.Parse(args)
+
+	opts := client.ListOptions{Filters: map[string]string{}}
+	if *project != "" {
+		opts.Filters["project_id"] = *project
+	}
+
+	fmt.Fprintln(out, "ID\tPROJECT\tDATE\tAMOUNT")
+	switch *kind {
+	case "project":
+		for p, err := range client.All(ctx, opts, c.ListProjectPayments) {
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "%d\t%d\t%s\t%.2f\n", p.ID, p.ProjectID, p.Date.Format("2006-01-02"), p.Amount)
+		}
+	case "flat":
+		for p, err := range client.All(ctx, opts, c.ListFlatPayments) {
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "%d\t%d\t%s\t%.2f\n", p.ID, p.ProjectID, p.Date.Format("2006-01-02"), p.Amount)
+		}
+	case "plot":
+		for p, err := range client.All(ctx, opts, c.ListPlotPayments) {
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "%d\t%d\t%s\t%.2f\n", p.ID, p.ProjectID, p.Date.Format("2006-01-02"), p.Amount)
+		}
+	default:
+		return fmt.Errorf("unknown -kind %q", *kind)
+	}
+	return nil
+}
+
+func runAttendanceStats(ctx context.Context, c *client.Client, out *tabwriter.Writer, args []string) error {
+	flags := flag.NewFlagSet("attendance-stats", flag.ExitOnError)
+	var opts client.StatsOptions
+	flags.StringVar(&opts.From, "from", "", "first day, YYYY-MM-DD")
+	flags.StringVar(&opts.To, "to", "", "last day, YYYY-MM-DD")
+	flags.StringVar(&opts.Project, "project", "", "project id")
+	flags.Parse(args)
+
+	stats, err := c.AttendanceStats(ctx, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "range\t%s .. %s\n", stats.Range.From, stats.Range.To)
+	for _, entry := range stats.StatusChart {
+		fmt.Fprintf(out, "%s\t%d\n", entry.Label, entry.Total)
+	}
+	return nil
+}
+
+func runStock(ctx context.Context, c *client.Client, out *tabwriter.Writer, args []string) error {
+	flags := flag.NewFlagSet("stock", flag.ExitOnError)
+	project := flags.Uint("project", 0, "project id")
+	flags.Parse(args)
+	if *project == 0 {
+		return errors.New("-project is required")
+	}
+
+	items, err := c.StockBalances(ctx, *project)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "MATERIAL\tALLOCATED\tUSED\tREMAINING")
+	for _, item := range items {
+		fmt.Fprintf(out, "%s\t%v\t%v\t%v\n", item.MaterialDisplayName, item.TotalAllocated, item.Used, item.Remaining)
+	}
+	return nil
+}
+
+func runVendors(ctx context.Context, c *client.Client, out *tabwriter.Writer, args []string) error {
+	vendors, err := c.ListVendors(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "ID\tCODE\tCOMPANY\tPHONE")
+	for _, v := range vendors {
+		fmt.Fprintf(out, "%d\t%s\t%s\t%s\n", v.ID, v.Code, v.CompanyName, v.PrimaryPhone)
+	}
+	return nil
+}
+
+func runSupervisors(ctx context.Context, c *client.Client, out *tabwriter.Writer, args []string) error {
+	supervisors, err := c.ListSupervisors(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "ID\tCODE\tNAME\tPHONE\tPROJECTS")
+	for _, s := range supervisors {
+		fmt.Fprintf(out, "%d\t%s\t%s\t%s\t%v\n", s.ID, s.Code, s.Name, s.PrimaryPhone, s.AssignedProjectIDs)
+	}
+	return nil
+}
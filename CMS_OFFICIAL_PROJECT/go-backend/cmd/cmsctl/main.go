@@ -0,0 +1,54 @@
+// Command cmsctl bundles maintenance tasks for the Go service.
+//
+//	cmsctl query-budget        check that list endpoints run a fixed number of queries
+//	cmsctl rebuild-summaries   recompute project summaries and report drift
+//	cmsctl generate-units      create missing units of multi-flat blocks
+//	cmsctl openapi             print the API document or check it covers every route
+//	cmsctl verify-audit        check the audit log's hash chain
+//	cmsctl check-storage       put, read and delete a file in the configured storage
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"query-budget", "check that list endpoints run a fixed number of queries", runQueryBudget},
+	{"rebuild-summaries", "recompute project summaries and report drift", runRebuildSummaries},
+	{"generate-units", "create missing units of multi-flat blocks", runGenerateUnits},
+	{"openapi", "print the API document or check it covers every route", runOpenAPI},
+	{"verify-audit", "check the audit log's hash chain", runVerifyAudit},
+	{"check-storage", "put, read and delete a file in the configured storage", runCheckStorage},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: cmsctl <command> [flags]")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "cmsctl %s: %v\n", cmd.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	usage()
+	os.Exit(2)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quickgeo/cms-official-go/internal/db"
+	"github.com/quickgeo/cms-official-go/internal/handlers"
+	"github.com/quickgeo/cms-official-go/internal/model"
+	"github.com/quickgeo/cms-official-go/internal/tenant"
+	"gorm.io/gorm"
+)
+
+// budgetEndpoint is one request whose query count must not grow with the data.
+type budgetEndpoint struct {
+	name string
+	path string
+}
+
+var budgetEndpoints = []budgetEndpoint{
+	{"multi-flat projects", "/api/v2/multi-flat/projects"},
+	{"crm units", "/api/v2/multi-flat/units?page_size=200"},
+	{"project detail", "/api/v2/projects/1"},
+}
+
+// runQueryBudget seeds throwaway databases of growing size, calls each
+// endpoint against them and fails when any endpoint's query count changes
+// with the number of projects or units.
+func runQueryBudget(args []string) error {
+	flags := flag.NewFlagSet("query-budget", flag.ExitOnError)
+	sizes := flags.String("projects", "1,5,25", "comma-separated project counts to seed")
+	units := flags.Int("units", 40, "units per project")
+	flags.Parse(args)
+
+	var projectCounts []int
+	for _, field := range strings.Split(*sizes, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid project count %q", field)
+		}
+		projectCounts = append(projectCounts, n)
+	}
+
+	dir, err := os.MkdirTemp("", "cmsctl-budget-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	// The requests carry no token; they act as the seeded owner.
+	os.Setenv("CMS_DEV_USER_ID", "1")
+	counts, err := measureQueries(dir, projectCounts, *units)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-22s", "endpoint \\ projects")
+	for _, projects := range projectCounts {
+		fmt.Printf("%8d", projects)
+	}
+	fmt.Println()
+	for i, ep := range budgetEndpoints {
+		fmt.Printf("%-22s", ep.name)
+		for _, n := range counts[i] {
+			fmt.Printf("%8d", n)
+		}
+		fmt.Println()
+	}
+	if failed := growingEndpoints(counts); len(failed) > 0 {
+		return fmt.Errorf("query count grows with data for: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// measureQueries seeds one database in dir per project count and returns, for
+// each budget endpoint, the statements a request ran against each of them.
+// The caller must act as the seeded owner, user 1.
+func measureQueries(dir string, projectCounts []int, units int) ([][]uint64, error) {
+	gin.SetMode(gin.ReleaseMode)
+	counts := make([][]uint64, len(budgetEndpoints))
+	for _, projects := range projectCounts {
+		database, err := seedBudgetDB(filepath.Join(dir, fmt.Sprintf("budget-%d.sqlite3", projects)), projects, units)
+		if err != nil {
+			return nil, err
+		}
+		h := handlers.New(database, nil)
+		router := gin.New()
+		if err := h.Register(router); err != nil {
+			return nil, err
+		}
+		for i, ep := range budgetEndpoints {
+			before := db.QueryCount()
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, ep.path, nil))
+			if recorder.Code != http.StatusOK {
+				return nil, fmt.Errorf("%s: status %d: %s", ep.name, recorder.Code, recorder.Body.String())
+			}
+			counts[i] = append(counts[i], db.QueryCount()-before)
+		}
+		if sqlDB, err := database.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+	return counts, nil
+}
+
+// growingEndpoints names the budget endpoints whose query count differs
+// between the seeded sizes.
+func growingEndpoints(counts [][]uint64) []string {
+	var failed []string
+	for i, ep := range budgetEndpoints {
+		for _, n := range counts[i][1:] {
+			if n != counts[i][0] {
+				failed = append(failed, ep.name)
+				break
+			}
+		}
+	}
+	return failed
+}
+
+// seedBudgetDB creates the Django tables the endpoints read and fills them
+// with multi-flat projects of two blocks each, every other unit sold.
+func seedBudgetDB(path string, projects, unitsPerProject int) (*gorm.DB, error) {
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		return nil, err
+	}
+	database, err := db.Connect(path)
+	if err != nil {
+		return nil, err
+	}
+	err = database.AutoMigrate(&model.User{}, &model.Profile{}, &model.Project{}, &model.ProjectBlock{},
+		&model.ProjectUnit{}, &model.Supervisor{}, &model.Customer{}, &model.ChannelPartner{}, &model.Vendor{})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.EnsureSchema(database); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	ownerID := uint(1)
+	err = database.Transaction(func(tx *gorm.DB) error {
+		user := model.User{ID: 1, Username: "budget", IsActive: true, DateJoined: now}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		// Projects pick up the owner's organization through the tenant
+		// triggers.
+		if _, err := tenant.Provision(tx, user); err != nil {
+			return err
+		}
+		for p := 1; p <= projects; p++ {
+			project := model.Project{
+				ProjectName:              fmt.Sprintf("Budget Tower %d", p),
+				ProjectCode:              fmt.Sprintf("BT%03d", p),
+				ProjectFlatConfiguration: "multi_flat",
+				ProjectOwnerID:           &ownerID,
+			}
+			if err := tx.Create(&project).Error; err != nil {
+				return err
+			}
+			var units []model.ProjectUnit
+			for b := 1; b <= 2; b++ {
+				block := model.ProjectBlock{ProjectBlockProjectID: project.ID, ProjectBlockName: fmt.Sprintf("Block %d", b), ProjectBlockSequence: uint(b)}
+				if err := tx.Create(&block).Error; err != nil {
+					return err
+				}
+				for u := 1; u <= unitsPerProject/2; u++ {
+					status := "available"
+					if u%2 == 0 {
+						status = "sold"
+					}
+					units = append(units, model.ProjectUnit{
+						ProjectUnitBlockID: block.ID,
+						ProjectUnitNumber:  uint(u),
+						ProjectUnitLabel:   fmt.Sprintf("%s-%d", block.ProjectBlockName, u),
+						ProjectUnitStatus:  status,
+					})
+				}
+			}
+			if len(units) > 0 {
+				if err := tx.CreateInBatches(&units, 100).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tenant.Enforce(database); err != nil {
+		return nil, err
+	}
+	return database, db.CountQueries(database)
+}
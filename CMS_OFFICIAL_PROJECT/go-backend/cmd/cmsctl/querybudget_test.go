@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+// budgetCeilings caps the statements each budget endpoint may run, so a new
+// per-request query shows up here even when it does not grow with the data.
+var budgetCeilings = map[string]uint64{
+	"multi-flat projects": 7,
+	"crm units":           8,
+	"project detail":      7,
+}
+
+// TestQueryBudget fails when an endpoint's query count grows with the number
+// of projects and units, or rises above its ceiling.
+func TestQueryBudget(t *testing.T) {
+	t.Setenv("CMS_DEV_USER_ID", "1")
+	sizes := []int{1, 5}
+	counts, err := measureQueries(t.TempDir(), sizes, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, ep := range budgetEndpoints {
+		for _, n := range counts[i][1:] {
+			if n != counts[i][0] {
+				t.Errorf("%s: query count grows with data: %v for %v projects", ep.name, counts[i], sizes)
+				break
+			}
+		}
+		ceiling, ok := budgetCeilings[ep.name]
+		if !ok {
+			t.Errorf("%s has no ceiling in budgetCeilings", ep.name)
+			continue
+		}
+		if counts[i][0] > ceiling {
+			t.Errorf("%s: %d queries, budget is %d", ep.name, counts[i][0], ceiling)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/quickgeo/cms-official-go/internal/storage"
+)
+
+// runCheckStorage writes, reads back and deletes a small object in the store
+// the server would use, so a bucket's endpoint, credentials and permissions
+// can be checked before uploads depend on them.
+func runCheckStorage(args []string) error {
+	flags := flag.NewFlagSet("check-storage", flag.ExitOnError)
+	path := flags.String("db", databasePath(), "SQLite database; disk storage defaults to media/ next to it")
+	flags.Parse(args)
+
+	store, err := storage.FromEnv(filepath.Join(filepath.Dir(*path), "media"))
+	if err != nil {
+		return err
+	}
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	key := "cmsctl/check-" + hex.EncodeToString(raw) + ".txt"
+	payload := []byte("cmsctl storage check\n")
+
+	ctx := context.Background()
+	if err := store.Put(ctx, key, bytes.NewReader(payload), int64(len(payload)), "text/plain"); err != nil {
+		return fmt.Errorf("put %s: %w", key, err)
+	}
+	body, _, err := store.Open(ctx, key)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", key, err)
+	}
+	got, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return fmt.Errorf("read %s: %w", key, err)
+	}
+	if !bytes.Equal(got, payload) {
+		return fmt.Errorf("read %s: got %d bytes back, wrote %d", key, len(got), len(payload))
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		return fmt.Errorf("delete %s: %w", key, err)
+	}
+	if _, _, err := store.Open(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("%s still readable after delete: %v", key, err)
+	}
+	fmt.Println("storage ok: put, read and delete succeeded")
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/quickgeo/cms-official-go/internal/db"
+	"github.com/quickgeo/cms-official-go/internal/summary"
+)
+
+// databasePath mirrors the server's default database location.
+func databasePath() string {
+	if path := os.Getenv("CMS_SQLITE_PATH"); path != "" {
+		return path
+	}
+	return "../backend/db.sqlite3"
+}
+
+// runRebuildSummaries recomputes cms_project_summary from the source tables
+// and lists the projects whose stored row was out of date.
+func runRebuildSummaries(args []string) error {
+	flags := flag.NewFlagSet("rebuild-summaries", flag.ExitOnError)
+	path := flags.String("db", databasePath(), "SQLite database (defaults to CMS_SQLITE_PATH)")
+	projects := flags.String("projects", "", "comma-separated project ids (all when empty)")
+	flags.Parse(args)
+
+	var projectIDs []uint
+	for _, field := range strings.Split(*projects, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid project id %q", field)
+		}
+		projectIDs = append(projectIDs, uint(id))
+	}
+
+	database, err := db.Connect(*path)
+	if err != nil {
+		return err
+	}
+	if err := db.EnsureSchema(database); err != nil {
+		return err
+	}
+	drifted, err := summary.Rebuild(database, projectIDs...)
+	if err != nil {
+		return err
+	}
+
+	if len(drifted) == 0 {
+		fmt.Println("project summaries were up to date")
+		return nil
+	}
+	ids := make([]string, len(drifted))
+	for i, id := range drifted {
+		ids[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	fmt.Printf("repaired %d project summaries: %s\n", len(drifted), strings.Join(ids, ", "))
+	return nil
+}
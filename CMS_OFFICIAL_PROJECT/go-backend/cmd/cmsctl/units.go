@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/quickgeo/cms-official-go/internal/db"
+	"github.com/quickgeo/cms-official-go/internal/model"
+	salesService "github.com/quickgeo/cms-official-go/internal/services/sales"
+)
+
+// runGenerateUnits fills in the missing floor/unit slots of multi-flat
+// blocks from their stored layout, using the same rules as the block API.
+func runGenerateUnits(args []string) error {
+	flags := flag.NewFlagSet("generate-units", flag.ExitOnError)
+	path := flags.String("db", databasePath(), "SQLite database (defaults to CMS_SQLITE_PATH)")
+	project := flags.String("project", "", "project code whose blocks are filled in")
+	blockID := flags.Uint("block", 0, "single block id (instead of -project)")
+	flags.Parse(args)
+
+	if (*project == "") == (*blockID == 0) {
+		return errors.New("give exactly one of -project or -block")
+	}
+
+	database, err := db.Connect(*path)
+	if err != nil {
+		return err
+	}
+	if err := db.EnsureSchema(database); err != nil {
+		return err
+	}
+
+	var blocks []model.ProjectBlock
+	query := database.Order("project_block_sequence asc")
+	if *blockID != 0 {
+		query = query.Where("id = ?", *blockID)
+	} else {
+		query = query.Joins("JOIN construction_project ON construction_project.id = construction_projectblock.project_block_project_id").
+			Where("construction_project.project_code = ?", *project)
+	}
+	if err := query.Find(&blocks).Error; err != nil {
+		return err
+	}
+	if len(blocks) == 0 {
+		return errors.New("no matching blocks")
+	}
+
+	sales := salesService.New(database)
+	total := 0
+	for i := range blocks {
+		created, err := sales.GenerateMissingUnits(context.Background(), &blocks[i], nil)
+		if err != nil {
+			return fmt.Errorf("block %d: %w", blocks[i].ID, err)
+		}
+		fmt.Printf("%s: %d unit(s) created\n", blocks[i].ProjectBlockName, created)
+		total += created
+	}
+	fmt.Printf("%d unit(s) created across %d block(s)\n", total, len(blocks))
+	return nil
+}
@@ -20,7 +20,7 @@ func Connect(path string) (*gorm.DB, error) {
 		return nil, fmt.Errorf("sqlite file not found at %q: %w", absPath, err)
 	}
 
-	db, err := gorm.Open(sqlite.Open(absPath), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(DSN(absPath)), &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
 	}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+var queryCount atomic.Uint64
+
+// CountQueries makes every statement run through db bump QueryCount. It is
+// meant for development and the query-budget check, not for production.
+func CountQueries(db *gorm.DB) error {
+	count := func(*gorm.DB) { queryCount.Add(1) }
+	callbacks := db.Callback()
+	registrations := []error{
+		callbacks.Create().After("gorm:create").Register("cms:count_queries", count),
+		callbacks.Query().After("gorm:query").Register("cms:count_queries", count),
+		callbacks.Update().After("gorm:update").Register("cms:count_queries", count),
+		callbacks.Delete().After("gorm:delete").Register("cms:count_queries", count),
+		callbacks.Row().After("gorm:row").Register("cms:count_queries", count),
+		callbacks.Raw().After("gorm:raw").Register("cms:count_queries", count),
+	}
+	for _, err := range registrations {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// QueryCount is the number of statements run since CountQueries was enabled.
+func QueryCount() uint64 {
+	return queryCount.Load()
+}
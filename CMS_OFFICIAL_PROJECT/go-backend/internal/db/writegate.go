@@ -0,0 +1,228 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// writeGate lets one transaction at a time write in this process, so
+// concurrent handlers queue here instead of contending for the SQLite lock.
+var writeGate = make(chan struct{}, 1)
+
+// defaultWriteWait bounds how long a transaction queues for the write slot.
+const defaultWriteWait = 15 * time.Second
+
+func writeWait() time.Duration {
+	if value, err := strconv.Atoi(os.Getenv("CMS_WRITE_WAIT_SECONDS")); err == nil && value > 0 {
+		return time.Duration(value) * time.Second
+	}
+	return defaultWriteWait
+}
+
+// ErrWriteBusy is returned when a transaction gave up waiting for the write
+// slot.
+var ErrWriteBusy = errors.New("db: timed out waiting for the write slot")
+
+// WriteStats are the counters behind the write-lock metrics.
+type WriteStats struct {
+	Acquired   uint64        // write slots granted
+	Waiting    int64         // requests queued right now
+	Timeouts   uint64        // requests that gave up waiting
+	WaitTime   time.Duration // total time spent queued
+	HeldTime   time.Duration // total time slots were held
+	BusyErrors uint64        // statements that failed with SQLITE_BUSY/LOCKED
+}
+
+var (
+	writesAcquired atomic.Uint64
+	writesWaiting  atomic.Int64
+	writeTimeouts  atomic.Uint64
+	writeWaitNanos atomic.Int64
+	writeHeldNanos atomic.Int64
+	busyErrors     atomic.Uint64
+)
+
+// AcquireWrite waits for the write slot until ctx is done. The returned
+// release must be called once the writes are finished.
+func AcquireWrite(ctx context.Context) (func(), error) {
+	start := time.Now()
+	writesWaiting.Add(1)
+	defer writesWaiting.Add(-1)
+
+	select {
+	case writeGate <- struct{}{}:
+	case <-ctx.Done():
+		writeTimeouts.Add(1)
+		writeWaitNanos.Add(int64(time.Since(start)))
+		return nil, ctx.Err()
+	}
+
+	waited := time.Since(start)
+	writesAcquired.Add(1)
+	writeWaitNanos.Add(int64(waited))
+	held := time.Now()
+	return func() {
+		writeHeldNanos.Add(int64(time.Since(held)))
+		<-writeGate
+	}, nil
+}
+
+// GateWrites makes db's transactions take the write slot: BEGIN waits for
+// it and COMMIT or ROLLBACK gives it back, and a statement run outside a
+// transaction holds it while it executes. Only the transaction holds the
+// slot, not the request around it, so reading the body, uploading files or
+// rendering PDFs never blocks other writers. A transaction that waits longer
+// than CMS_WRITE_WAIT_SECONDS (default 15) or until its context ends fails
+// with ErrWriteBusy. Plain reads never wait; WAL mode lets them run during a
+// write.
+func GateWrites(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	db.ConnPool = &gatedPool{DB: sqlDB, wait: writeWait()}
+	db.Statement.ConnPool = db.ConnPool
+	return nil
+}
+
+// gatedPool is the connection pool GateWrites installs.
+type gatedPool struct {
+	*sql.DB
+	wait time.Duration
+}
+
+func (p *gatedPool) acquire(ctx context.Context) (func(), error) {
+	waitCtx, cancel := context.WithTimeout(ctx, p.wait)
+	defer cancel()
+	release, err := AcquireWrite(waitCtx)
+	if err != nil {
+		if timedOut, ok := ctx.Value(writeWaitKey{}).(*atomic.Bool); ok {
+			timedOut.Store(true)
+		}
+		return nil, ErrWriteBusy
+	}
+	return release, nil
+}
+
+// BeginTx starts a transaction holding the write slot.
+func (p *gatedPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	release, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := p.DB.BeginTx(ctx, opts)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &gatedTx{Tx: tx, pool: p, release: release}, nil
+}
+
+// ExecContext runs a statement outside a transaction holding the write slot.
+func (p *gatedPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	release, err := p.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return p.DB.ExecContext(ctx, query, args...)
+}
+
+func (p *gatedPool) GetDBConn() (*sql.DB, error) {
+	return p.DB, nil
+}
+
+// gatedTx gives the write slot back when the transaction ends.
+type gatedTx struct {
+	*sql.Tx
+	pool    *gatedPool
+	release func()
+	once    sync.Once
+}
+
+func (t *gatedTx) Commit() error {
+	defer t.done()
+	return t.Tx.Commit()
+}
+
+func (t *gatedTx) Rollback() error {
+	defer t.done()
+	return t.Tx.Rollback()
+}
+
+func (t *gatedTx) done() {
+	t.once.Do(t.release)
+}
+
+func (t *gatedTx) GetDBConn() (*sql.DB, error) {
+	return t.pool.DB, nil
+}
+
+type writeWaitKey struct{}
+
+// WatchWriteWaits returns a context whose transactions report a timed-out
+// wait for the write slot to WriteWaitTimedOut.
+func WatchWriteWaits(ctx context.Context) context.Context {
+	return context.WithValue(ctx, writeWaitKey{}, new(atomic.Bool))
+}
+
+// WriteWaitTimedOut reports whether a transaction run with ctx, a context
+// from WatchWriteWaits, gave up waiting for the write slot.
+func WriteWaitTimedOut(ctx context.Context) bool {
+	timedOut, ok := ctx.Value(writeWaitKey{}).(*atomic.Bool)
+	return ok && timedOut.Load()
+}
+
+// IsBusy reports whether err is SQLite refusing a lock.
+func IsBusy(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := err.Error()
+	return strings.Contains(message, "SQLITE_BUSY") || strings.Contains(message, "SQLITE_LOCKED") ||
+		strings.Contains(message, "database is locked") || strings.Contains(message, "database table is locked")
+}
+
+// TrackBusy counts statements that fail with a lock error.
+func TrackBusy(db *gorm.DB) error {
+	track := func(tx *gorm.DB) {
+		if IsBusy(tx.Error) {
+			busyErrors.Add(1)
+		}
+	}
+	callbacks := db.Callback()
+	registrations := []error{
+		callbacks.Create().After("gorm:commit_or_rollback_transaction").Register("cms:track_busy", track),
+		callbacks.Update().After("gorm:commit_or_rollback_transaction").Register("cms:track_busy", track),
+		callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register("cms:track_busy", track),
+		callbacks.Query().After("gorm:query").Register("cms:track_busy", track),
+		callbacks.Raw().After("gorm:raw").Register("cms:track_busy", track),
+	}
+	for _, err := range registrations {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CurrentWriteStats returns a snapshot of the write-lock counters.
+func CurrentWriteStats() WriteStats {
+	return WriteStats{
+		Acquired:   writesAcquired.Load(),
+		Waiting:    writesWaiting.Load(),
+		Timeouts:   writeTimeouts.Load(),
+		WaitTime:   time.Duration(writeWaitNanos.Load()),
+		HeldTime:   time.Duration(writeHeldNanos.Load()),
+		BusyErrors: busyErrors.Load(),
+	}
+}
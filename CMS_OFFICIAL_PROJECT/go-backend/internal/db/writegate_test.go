@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// TestGateWrites checks a transaction holds the write slot until it ends,
+// that a writer queued behind it gives up with ErrWriteBusy and that reads
+// never wait.
+func TestGateWrites(t *testing.T) {
+	t.Setenv("CMS_WRITE_WAIT_SECONDS", "1")
+	path := filepath.Join(t.TempDir(), "gate.sqlite3")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	database, err := Connect(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := GateWrites(database); err != nil {
+		t.Fatal(err)
+	}
+	if sqlDB, err := database.DB(); err != nil {
+		t.Fatalf("DB after GateWrites: %v", err)
+	} else {
+		defer sqlDB.Close()
+	}
+	if err := database.Exec("CREATE TABLE note (id INTEGER PRIMARY KEY, body TEXT)").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tx := database.Begin()
+	if tx.Error != nil {
+		t.Fatal(tx.Error)
+	}
+	if err := tx.Exec("INSERT INTO note (body) VALUES ('held')").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var count int64
+	if err := database.Table("note").Count(&count).Error; err != nil {
+		t.Errorf("read while a transaction holds the slot: %v", err)
+	}
+	ctx := WatchWriteWaits(context.Background())
+	err = database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Exec("INSERT INTO note (body) VALUES ('queued')").Error
+	})
+	if !errors.Is(err, ErrWriteBusy) {
+		t.Errorf("write queued behind an open transaction returned %v, want ErrWriteBusy", err)
+	}
+	if !WriteWaitTimedOut(ctx) {
+		t.Error("WriteWaitTimedOut is false after the wait timed out")
+	}
+	if err := database.Exec("INSERT INTO note (body) VALUES ('autocommit')").Error; !errors.Is(err, ErrWriteBusy) {
+		t.Errorf("statement outside a transaction returned %v, want ErrWriteBusy", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		t.Fatal(err)
+	}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		return tx.Exec("INSERT INTO note (body) VALUES ('after')").Error
+	})
+	if err != nil {
+		t.Errorf("write after the transaction committed: %v", err)
+	}
+	if err := database.Table("note").Count(&count).Error; err != nil || count != 2 {
+		t.Errorf("note holds %d rows (%v), want 2", count, err)
+	}
+}
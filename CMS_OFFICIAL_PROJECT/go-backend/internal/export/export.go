@@ -0,0 +1,176 @@
+// Package export writes list results as CSV or XLSX spreadsheets. Rows go
+// straight to the output as they are written, so an export never holds more
+// than the batch of rows being read.
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is a spreadsheet file format.
+type Format string
+
+const (
+	CSV  Format = "csv"
+	XLSX Format = "xlsx"
+)
+
+// ErrFormat rejects a format other than csv or xlsx.
+var ErrFormat = errors.New("format must be csv or xlsx")
+
+// ParseFormat reads the format query parameter.
+func ParseFormat(raw string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(raw))) {
+	case CSV:
+		return CSV, nil
+	case XLSX:
+		return XLSX, nil
+	}
+	return "", ErrFormat
+}
+
+// ContentType is the media type files of f are served as.
+func (f Format) ContentType() string {
+	if f == XLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Writer writes one spreadsheet. Cells may be strings, integers, floats,
+// bools, times, pointers to those or nil for an empty cell; times without a
+// clock part are written as dates.
+type Writer interface {
+	// Row writes one row after the header.
+	Row(cells []interface{}) error
+	// Close finishes the file. A file that is not closed is incomplete.
+	Close() error
+}
+
+// New starts a spreadsheet of format on w with a header row.
+func New(w io.Writer, format Format, header []string) (Writer, error) {
+	switch format {
+	case CSV:
+		return newCSV(w, header)
+	case XLSX:
+		return newXLSX(w, header)
+	}
+	return nil, ErrFormat
+}
+
+// csvWriter writes RFC 4180 CSV behind a UTF-8 byte order mark, which is
+// how Excel recognises UTF-8.
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSV(w io.Writer, header []string) (*csvWriter, error) {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return nil, err
+	}
+	cw := &csvWriter{w: csv.NewWriter(w)}
+	if err := cw.w.Write(header); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) Row(cells []interface{}) error {
+	cw.record = cw.record[:0]
+	for _, cell := range cells {
+		text, numeric := cellText(cell)
+		if !numeric {
+			text = defuse(text)
+		}
+		cw.record = append(cw.record, text)
+	}
+	return cw.w.Write(cw.record)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// defuse stops spreadsheet programs from running a text cell as a formula.
+func defuse(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// cellText writes a cell as text and says whether it is a number.
+func cellText(cell interface{}) (string, bool) {
+	switch v := deref(cell).(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, false
+	case bool:
+		return strconv.FormatBool(v), false
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case time.Time:
+		if v.IsZero() {
+			return "", false
+		}
+		if isDate(v) {
+			return v.Format("2006-01-02"), false
+		}
+		return v.Format("2006-01-02 15:04:05"), false
+	}
+	return fmt.Sprint(cell), false
+}
+
+// deref unwraps the pointers nullable columns are read into.
+func deref(cell interface{}) interface{} {
+	switch v := cell.(type) {
+	case *string:
+		if v != nil {
+			return *v
+		}
+	case *int:
+		if v != nil {
+			return *v
+		}
+	case *int64:
+		if v != nil {
+			return *v
+		}
+	case *uint:
+		if v != nil {
+			return *v
+		}
+	case *float64:
+		if v != nil {
+			return *v
+		}
+	case *time.Time:
+		if v != nil {
+			return *v
+		}
+	default:
+		return cell
+	}
+	return nil
+}
+
+func isDate(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
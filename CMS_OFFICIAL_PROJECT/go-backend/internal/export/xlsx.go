@@ -0,0 +1,146 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+// The workbook parts besides the sheet never change. Cell styles: 1 is a
+// date, 2 a date and time, 3 the bold header.
+const (
+	contentTypesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`
+	rootRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	workbookXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Export" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	workbookRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`
+	stylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><numFmts count="2"><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/><numFmt numFmtId="165" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts><fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="4"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs></styleSheet>`
+	sheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews><sheetData>`
+	sheetEnd = `</sheetData></worksheet>`
+)
+
+const (
+	dateStyle     = 1
+	dateTimeStyle = 2
+	headerStyle   = 3
+)
+
+// excelEpoch is day zero of Excel's date serials, chosen so that serials
+// after February 1900 come out right despite Excel's leap year bug.
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// xlsxWriter streams a one-sheet workbook. Strings are written inline
+// rather than into a shared string table, which would have to be complete
+// before the sheet.
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	row   int
+}
+
+func newXLSX(w io.Writer, header []string) (*xlsxWriter, error) {
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", rootRelsXML},
+		{"xl/workbook.xml", workbookXML},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+		{"xl/styles.xml", stylesXML},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	xw := &xlsxWriter{zip: zw, sheet: bufio.NewWriter(f)}
+	xw.sheet.WriteString(sheetStart)
+
+	cells := make([]interface{}, len(header))
+	for i, name := range header {
+		cells[i] = name
+	}
+	return xw, xw.write(cells, headerStyle)
+}
+
+func (xw *xlsxWriter) Row(cells []interface{}) error {
+	return xw.write(cells, 0)
+}
+
+func (xw *xlsxWriter) write(cells []interface{}, style int) error {
+	xw.row++
+	row := strconv.Itoa(xw.row)
+	b := xw.sheet
+	b.WriteString(`<row r="` + row + `">`)
+	for i, cell := range cells {
+		ref := column(i) + row
+		switch v := deref(cell).(type) {
+		case nil:
+			continue
+		case time.Time:
+			if v.IsZero() {
+				continue
+			}
+			cellStyle := dateTimeStyle
+			if isDate(v) {
+				cellStyle = dateStyle
+			}
+			wall := time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), 0, time.UTC)
+			serial := wall.Sub(excelEpoch).Hours() / 24
+			b.WriteString(`<c r="` + ref + `" s="` + strconv.Itoa(cellStyle) + `"><v>` + strconv.FormatFloat(serial, 'f', -1, 64) + `</v></c>`)
+		case bool:
+			value := "0"
+			if v {
+				value = "1"
+			}
+			b.WriteString(`<c r="` + ref + `" t="b"><v>` + value + `</v></c>`)
+		default:
+			text, numeric := cellText(v)
+			if numeric {
+				b.WriteString(`<c r="` + ref + `"><v>` + text + `</v></c>`)
+				continue
+			}
+			b.WriteString(`<c r="` + ref + `" t="inlineStr"`)
+			if style != 0 {
+				b.WriteString(` s="` + strconv.Itoa(style) + `"`)
+			}
+			b.WriteString(`><is><t xml:space="preserve">`)
+			xml.EscapeText(b, []byte(text))
+			b.WriteString(`</t></is></c>`)
+		}
+	}
+	_, err := b.WriteString(`</row>`)
+	return err
+}
+
+func (xw *xlsxWriter) Close() error {
+	xw.sheet.WriteString(sheetEnd)
+	if err := xw.sheet.Flush(); err != nil {
+		return err
+	}
+	return xw.zip.Close()
+}
+
+// column names the i-th column, counting from zero: A … Z, AA, AB and so on.
+func column(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
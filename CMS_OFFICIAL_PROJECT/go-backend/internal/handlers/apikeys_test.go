@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// apiKey issues a key with scopes for the signed-in owner and returns its
+// bearer header pair.
+func (s *testServer) apiKey(owner []string, scopes ...string) []string {
+	s.t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{"name": "script", "scopes": scopes})
+	w := s.do(http.MethodPost, "/api/v2/organization/api-keys", string(body), owner...)
+	if w.Code != http.StatusCreated {
+		s.t.Fatalf("create API key %v: %d %s", scopes, w.Code, w.Body)
+	}
+	var resp struct {
+		Data struct {
+			APIKey struct {
+				Key string `json:"key"`
+			} `json:"api_key"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Data.APIKey.Key == "" {
+		s.t.Fatalf("create API key returned no key: %s", w.Body)
+	}
+	return []string{"Authorization", "Bearer " + resp.Data.APIKey.Key}
+}
+
+func TestSearchFollowsKeyScopes(t *testing.T) {
+	s := newTestServer(t)
+	owner, _ := s.signUp("owner")
+	orgID := s.organization("owner")
+	s.exec(`INSERT INTO construction_customer (customer_name, customer_primary_phone_number, org_id) VALUES ('Sharma Residency', '9811111111', ?)`, orgID)
+	s.exec(`INSERT INTO construction_vendor (vendor_company_name, vendor_first_name, vendor_primary_phone_number, org_id) VALUES ('Sharma Steel', 'Anil', '9822222222', ?)`, orgID)
+
+	searchKey := s.apiKey(owner, "search:read")
+	directoryKey := s.apiKey(owner, "search:read", "directory:read")
+	leadsKey := s.apiKey(owner, "search:read", "leads:read", "directory:read")
+
+	tests := []struct {
+		name   string
+		path   string
+		auth   []string
+		status int
+		types  []string
+	}{
+		{"person sees every type", "/api/v2/search?q=sharma", owner, http.StatusOK, []string{"customer", "vendor"}},
+		{"search scope alone finds nothing", "/api/v2/search?q=sharma", searchKey, http.StatusOK, nil},
+		{"directory key misses leads", "/api/v2/search?q=sharma", directoryKey, http.StatusOK, []string{"vendor"}},
+		{"leads key sees leads", "/api/v2/search?q=sharma", leadsKey, http.StatusOK, []string{"customer", "vendor"}},
+		{"asking for leads without the scope", "/api/v2/search?q=sharma&types=customer", directoryKey, http.StatusForbidden, nil},
+		{"asking for a granted type", "/api/v2/search?q=sharma&types=vendor", directoryKey, http.StatusOK, []string{"vendor"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodGet, tt.path, "", tt.auth...)
+			if w.Code != tt.status {
+				t.Fatalf("GET %s: %d %s, want %d", tt.path, w.Code, w.Body, tt.status)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var resp struct {
+				Data struct {
+					Results []struct {
+						Type string `json:"type"`
+					} `json:"results"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			var types []string
+			for _, result := range resp.Data.Results {
+				types = append(types, result.Type)
+			}
+			sort.Strings(types)
+			if strings.Join(types, ",") != strings.Join(tt.types, ",") {
+				t.Errorf("GET %s returned %v, want %v", tt.path, types, tt.types)
+			}
+		})
+	}
+}
+
+func TestRegenerateCredentialsNeedsAdminScope(t *testing.T) {
+	s := newTestServer(t)
+	owner, _ := s.signUp("owner")
+
+	writeKey := s.apiKey(owner, "directory:read", "directory:write")
+	if w := s.do(http.MethodPost, "/api/v2/directory/credentials", `{"type":"vendor","id":1}`, writeKey...); w.Code != http.StatusForbidden ||
+		!strings.Contains(w.Body.String(), "directory:admin") {
+		t.Errorf("directory:write key regenerating credentials: %d %s, want 403 naming directory:admin", w.Code, w.Body)
+	}
+	if w := s.do(http.MethodPost, "/api/v2/vendors", `{"company_name":"Acme","first_name":"Ravi","primary_phone_number":"9800000000"}`, writeKey...); w.Code != http.StatusCreated {
+		t.Errorf("directory:write key creating a vendor: %d %s, want 201", w.Code, w.Body)
+	}
+
+	adminKey := s.apiKey(owner, "directory:admin")
+	if w := s.do(http.MethodPost, "/api/v2/directory/credentials", "not json", adminKey...); w.Code != http.StatusBadRequest {
+		t.Errorf("directory:admin key regenerating credentials: %d %s, want it past the scope check", w.Code, w.Body)
+	}
+
+	if w := s.do(http.MethodPost, "/api/v2/organization/api-keys", `{"name":"x","scopes":["payments:admin"]}`, owner...); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown admin scope: %d %s, want 400", w.Code, w.Body)
+	}
+}
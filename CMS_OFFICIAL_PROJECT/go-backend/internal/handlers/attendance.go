@@ -9,58 +9,235 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/quickgeo/cms-official-go/internal/listquery"
 	"github.com/quickgeo/cms-official-go/internal/model"
 	"github.com/quickgeo/cms-official-go/internal/responses"
 	utils "github.com/quickgeo/cms-official-go/internal/utilities/attendance_page_app"
 	"gorm.io/gorm"
 )
 
+// attendanceDay returns the SQL bucket key (YYYY-MM-DD) of a record in loc,
+// with its arguments. Django stores plain dates, which are kept as they are;
+// timestamps written by this service carry an offset and are shifted by the
+// offset loc had at that instant, one CASE branch per DST period between
+// start and end. Records outside those periods only need to fall outside
+// the range, which a day's error cannot change.
+func attendanceDay(loc *time.Location, start, end time.Time) (string, []interface{}) {
+	if start.IsZero() {
+		start = end
+	}
+	var sql strings.Builder
+	var args []interface{}
+	sql.WriteString("(CASE WHEN length(attendance_date) = 10 THEN attendance_date")
+	at, until := start.AddDate(0, 0, -1), end.AddDate(0, 0, 2)
+	for {
+		change, ok := utils.NextOffsetChange(loc, at, until)
+		if !ok {
+			break
+		}
+		sql.WriteString(" WHEN datetime(attendance_date) < ? THEN date(attendance_date, ?)")
+		args = append(args, change.UTC().Format("2006-01-02 15:04:05"), utils.UTCOffsetModifier(loc, at))
+		at = change
+	}
+	sql.WriteString(" ELSE date(attendance_date, ?) END)")
+	args = append(args, utils.UTCOffsetModifier(loc, at))
+	return sql.String(), args
+}
+
+// attendanceBucket is one row of a GROUP BY over the stats query.
+type attendanceBucket struct {
+	Bucket string
+	Status string
+	Mode   string
+	Total  int
+}
+
+// attendanceStatsScope applies the batch and project filters. Batches may be
+// given by id or name; records carry the batch name and the member id.
+func (h *Handler) attendanceStatsScope(c *gin.Context) (func(*gorm.DB) *gorm.DB, error) {
+	var batchIDs []uint
+	var batchNames []string
+	if raw := strings.TrimSpace(c.Query("batch")); raw != "" {
+		for _, value := range strings.Split(raw, ",") {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			if id, err := strconv.ParseUint(value, 10, 64); err == nil {
+				batchIDs = append(batchIDs, uint(id))
+			} else {
+				batchNames = append(batchNames, value)
+			}
+		}
+	}
+
+	var projectID uint64
+	if raw := strings.TrimSpace(c.Query("project")); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid project")
+		}
+		projectID = id
+	}
+
+	// inBatches keeps records of the batches matched by where.
+	inBatches := func(db *gorm.DB, where func(*gorm.DB) *gorm.DB) *gorm.DB {
+		batches := func(column string) *gorm.DB {
+			return where(h.dbFor(c).Model(&model.AttendanceBatch{}).Select(column))
+		}
+		members := h.dbFor(c).Model(&model.AttendanceMember{}).Select("id").Where("batch_id IN (?)", batches("id"))
+		return db.Where(h.dbFor(c).Where("attendance_batch IN (?)", batches("name")).Or("member_id IN (?)", members))
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		if len(batchIDs) > 0 || len(batchNames) > 0 {
+			db = inBatches(db, func(q *gorm.DB) *gorm.DB {
+				switch {
+				case len(batchNames) == 0:
+					return q.Where("id IN ?", batchIDs)
+				case len(batchIDs) == 0:
+					return q.Where("name IN ?", batchNames)
+				}
+				return q.Where("id IN ? OR name IN ?", batchIDs, batchNames)
+			})
+		}
+		if projectID > 0 {
+			db = inBatches(db, func(q *gorm.DB) *gorm.DB { return q.Where("project_id = ?", projectID) })
+		}
+		return db
+	}, nil
+}
+
+// getAttendanceStats aggregates attendance in SQL. Optional filters: from/to
+// (YYYY-MM-DD, inclusive), batch (ids or names, comma separated), project and
+// tz (IANA zone used for day buckets, the server zone by default). The charts
+// end at "to", or today when it is not given.
 func (h *Handler) getAttendanceStats(c *gin.Context) {
-	var records []model.AttendanceRecord
-	if err := h.db.Order("attendance_date desc").Find(&records).Error; err != nil {
+	loc := time.Local
+	if tz := strings.TrimSpace(c.Query("tz")); tz != "" {
+		parsed, err := time.LoadLocation(tz)
+		if err != nil {
+			responses.JSON(c, http.StatusBadRequest, false, nil, "invalid tz")
+			return
+		}
+		loc = parsed
+	}
+	now := time.Now().In(loc)
+
+	parseDay := func(name string) (time.Time, bool, bool) {
+		raw := strings.TrimSpace(c.Query(name))
+		if raw == "" {
+			return time.Time{}, false, true
+		}
+		day, err := time.ParseInLocation("2006-01-02", raw, loc)
+		return day, err == nil, err == nil
+	}
+	from, hasFrom, ok := parseDay("from")
+	if !ok {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "from must be YYYY-MM-DD")
+		return
+	}
+	today := utils.DateOnly(now)
+	to, hasTo, ok := parseDay("to")
+	if !ok {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "to must be YYYY-MM-DD")
+		return
+	}
+	if !hasTo {
+		to = today
+	}
+	if hasFrom && from.After(to) {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "from must not be after to")
+		return
+	}
+
+	scope, err := h.attendanceStatsScope(c)
+	if err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, err.Error())
+		return
+	}
+
+	// between restricts rows to [start, end] by local day, and returns the
+	// day's SQL for bucketing. The plain text comparison is a wide,
+	// index-friendly pre-filter; the day check is exact.
+	between := func(start, end time.Time) (*gorm.DB, string, []interface{}) {
+		if hasFrom && from.After(start) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		day, args := attendanceDay(loc, start, end)
+		query := h.dbFor(c).Model(&model.AttendanceRecord{}).Scopes(scope).
+			Where("attendance_date >= ? AND attendance_date < ?", start.AddDate(0, 0, -1).Format("2006-01-02"), end.AddDate(0, 0, 2).Format("2006-01-02")).
+			Where(day+" BETWEEN ? AND ?", append(append([]interface{}{}, args...), start.Format("2006-01-02"), end.Format("2006-01-02"))...)
+		return query, day, args
+	}
+	bucketed := func(start, end time.Time, length int, extra string) ([]attendanceBucket, error) {
+		query, day, args := between(start, end)
+		columns := fmt.Sprintf("substr(%s, 1, %d)", day, length) + " AS bucket, COUNT(*) AS total"
+		group := "bucket"
+		if extra != "" {
+			columns += ", " + extra
+			group += ", " + extra
+		}
+		var rows []attendanceBucket
+		err := query.Select(columns, args...).Group(group).Scan(&rows).Error
+		return rows, err
+	}
+
+	dailyDates := utils.GetLast7Days(to)
+	monthRange := utils.GetLast6Months(to)
+	yearRange := utils.GetLast3Years(to)
+	fail := func() {
 		responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to load attendance stats")
+	}
+
+	daily, err := bucketed(dailyDates[0], to, 10, "status, mode")
+	if err != nil {
+		fail()
+		return
+	}
+	monthly, err := bucketed(monthRange[0], to, 7, "")
+	if err != nil {
+		fail()
+		return
+	}
+	yearly, err := bucketed(time.Date(yearRange[0], 1, 1, 0, 0, 0, 0, loc), to, 4, "")
+	if err != nil {
+		fail()
+		return
+	}
+	var statuses []attendanceBucket
+	all, _, _ := between(time.Time{}, to)
+	err = all.Select("status, COUNT(*) AS total").Group("status").Order("status").Scan(&statuses).Error
+	if err != nil {
+		fail()
 		return
 	}
 
-	now := time.Now()
-	today := utils.DateOnly(now)
 	stats := map[string]int{
 		"total_today":    0,
 		"present_today":  0,
 		"remote_today":   0,
 		"on_leave_today": 0,
 	}
-
-	dailyDates := utils.GetLast7Days(today)
+	todayKey := today.Format("2006-01-02")
 	dailyTotals := make(map[string]int)
-
-	monthRange := utils.GetLast6Months(today)
-	monthlyTotals := make(map[string]int)
-
-	yearRange := utils.GetLast3Years(today)
-	yearlyTotals := make(map[int]int)
-	statusTotals := make(map[string]int)
-
-	for _, rec := range records {
-		recDate := utils.DateOnly(rec.AttendanceDate)
-		dateKey := recDate.Format("2006-01-02")
-		dailyTotals[dateKey]++
-		if recDate.Equal(today) {
-			stats["total_today"]++
-			if rec.Status == "present" {
-				stats["present_today"]++
-			}
-			if rec.Mode == "remote" {
-				stats["remote_today"]++
-			}
-			if rec.Status == "leave" {
-				stats["on_leave_today"]++
-			}
+	for _, row := range daily {
+		dailyTotals[row.Bucket] += row.Total
+		if row.Bucket != todayKey {
+			continue
+		}
+		stats["total_today"] += row.Total
+		if row.Status == "present" {
+			stats["present_today"] += row.Total
+		}
+		if row.Mode == "remote" {
+			stats["remote_today"] += row.Total
+		}
+		if row.Status == "leave" {
+			stats["on_leave_today"] += row.Total
 		}
-		monthKey := recDate.Format("2006-01")
-		monthlyTotals[monthKey]++
-		yearlyTotals[recDate.Year()]++
-		statusTotals[rec.Status]++
 	}
 
 	dailyChart := make([]utils.ChartEntry, len(dailyDates))
@@ -71,6 +248,10 @@ func (h *Handler) getAttendanceStats(c *gin.Context) {
 		}
 	}
 
+	monthlyTotals := make(map[string]int)
+	for _, row := range monthly {
+		monthlyTotals[row.Bucket] = row.Total
+	}
 	monthlyChart := make([]utils.ChartEntry, len(monthRange))
 	for i, m := range monthRange {
 		monthlyChart[i] = utils.ChartEntry{
@@ -80,50 +261,94 @@ func (h *Handler) getAttendanceStats(c *gin.Context) {
 	}
 
 	statusLabel := utils.AttendanceStatusMap
-	statusChart := make([]utils.ChartEntry, 0, len(statusTotals))
-	for status, total := range statusTotals {
+	statusChart := make([]utils.ChartEntry, 0, len(statuses))
+	for _, row := range statuses {
 		statusChart = append(statusChart, utils.ChartEntry{
-			Label: statusLabel[status],
-			Total: total,
+			Label: statusLabel[row.Status],
+			Total: row.Total,
 		})
 	}
 
+	yearlyTotals := make(map[string]int)
+	for _, row := range yearly {
+		yearlyTotals[row.Bucket] = row.Total
+	}
 	yearlyChart := make([]utils.ChartEntry, len(yearRange))
 	for i, year := range yearRange {
 		yearlyChart[i] = utils.ChartEntry{
 			Label: strconv.Itoa(year),
-			Total: yearlyTotals[year],
+			Total: yearlyTotals[strconv.Itoa(year)],
 		}
 	}
 
+	statsRange := utils.StatsRange{To: to.Format("2006-01-02"), Timezone: loc.String()}
+	if hasFrom {
+		statsRange.From = from.Format("2006-01-02")
+	}
 	responses.JSON(c, http.StatusOK, true, gin.H{
 		"stats":         stats,
 		"daily_chart":   dailyChart,
 		"monthly_chart": monthlyChart,
 		"yearly_chart":  yearlyChart,
 		"status_chart":  statusChart,
+		"range":         statsRange,
 	}, "attendance stats loaded")
 }
 
+// getAttendanceChoices serves the status and mode labels used by the forms.
+func (h *Handler) getAttendanceChoices(c *gin.Context) {
+	h.serveReference(c, "attendance-choices", nil, "attendance choices loaded", "failed to load attendance choices", func() (interface{}, error) {
+		return gin.H{
+			"statuses": utils.AttendanceStatusMap,
+			"modes":    utils.AttendanceModeMap,
+		}, nil
+	})
+}
+
+var attendanceRecordListSpec = listquery.Spec{
+	Table:       "construction_attendancerecord",
+	Sorts:       map[string]string{"date": "attendance_date", "created_at": "created_at", "name": "attendee_name"},
+	DefaultSort: "-date",
+	Filters: map[string]string{
+		"status":    "status",
+		"mode":      "mode",
+		"batch":     "attendance_batch",
+		"member_id": "member_id",
+	},
+	DateColumn:      "attendance_date",
+	DefaultPageSize: 150,
+}
+
+var attendanceRecordColumns = []column[model.AttendanceRecord]{
+	{"Date", func(r model.AttendanceRecord) interface{} { return r.AttendanceDate }},
+	{"Code", func(r model.AttendanceRecord) interface{} { return r.AttendanceCode }},
+	{"Name", func(r model.AttendanceRecord) interface{} { return r.AttendeeName }},
+	{"Role", func(r model.AttendanceRecord) interface{} { return r.AttendeeRole }},
+	{"Batch", func(r model.AttendanceRecord) interface{} { return r.AttendanceBatch }},
+	{"Status", func(r model.AttendanceRecord) interface{} { return choiceLabel(utils.AttendanceStatusMap, r.Status) }},
+	{"Mode", func(r model.AttendanceRecord) interface{} { return choiceLabel(utils.AttendanceModeMap, r.Mode) }},
+	{"Check in", func(r model.AttendanceRecord) interface{} { return r.CheckInTime }},
+	{"Check out", func(r model.AttendanceRecord) interface{} { return r.CheckOutTime }},
+	{"Notes", func(r model.AttendanceRecord) interface{} { return r.WorkNotes }},
+}
+
 func (h *Handler) listAttendanceRecords(c *gin.Context) {
-	limit := 150
-	if q := c.Query("limit"); q != "" {
-		if val, err := strconv.Atoi(q); err == nil && val > 0 {
-			limit = val
-		}
+	query := h.dbFor(c).Preload("Member")
+	if exporting(c) {
+		exportList(c, attendanceRecordListSpec, query, "attendance", attendanceRecordColumns)
+		return
 	}
-
 	var records []model.AttendanceRecord
-	if err := h.db.Preload("Member").Order("attendance_date desc, created_at desc").Limit(limit).Find(&records).Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to load attendance records")
+	page, ok := findPage(c, attendanceRecordListSpec, query, &records, "failed to load attendance records")
+	if !ok {
 		return
 	}
-	responses.JSON(c, http.StatusOK, true, records, "attendance records loaded")
+	responses.Page(c, http.StatusOK, records, "attendance records loaded", page)
 }
 
 func (h *Handler) listAttendanceBatches(c *gin.Context) {
 	var batches []model.AttendanceBatch
-	if err := h.db.Order("name asc").Find(&batches).Error; err != nil {
+	if err := h.dbFor(c).Order("name asc").Find(&batches).Error; err != nil {
 		responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to load attendance batches")
 		return
 	}
@@ -143,7 +368,7 @@ func (h *Handler) listAttendanceMembers(c *gin.Context) {
 	}
 
 	var members []model.AttendanceMember
-	if err := h.db.Where("batch_id = ? AND is_active = ?", batchID, true).Order("name asc").Find(&members).Error; err != nil {
+	if err := h.dbFor(c).Where("batch_id = ? AND is_active = ?", batchID, true).Order("name asc").Find(&members).Error; err != nil {
 		responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to load batch members")
 		return
 	}
@@ -157,86 +382,9 @@ func (h *Handler) createAttendanceBatch(c *gin.Context) {
 		return
 	}
 
-	name := strings.TrimSpace(req.Name)
-	if name == "" {
-		responses.JSON(c, http.StatusBadRequest, false, nil, "batch name is required")
-		return
-	}
-	role := strings.TrimSpace(req.Role)
-	if role == "" {
-		role = "staff"
-	}
-
-	tx := h.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			panic(r)
-		}
-	}()
-
-	var batch model.AttendanceBatch
-	err := tx.Where("name = ?", name).First(&batch).Error
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		tx.Rollback()
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to load batch")
-		return
-	}
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		batch = model.AttendanceBatch{
-			Name:        name,
-			Description: strings.TrimSpace(req.Description),
-			CreatedAt:   time.Now(),
-		}
-		if err := tx.Create(&batch).Error; err != nil {
-			tx.Rollback()
-			responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to create batch")
-			return
-		}
-	} else if batch.Description == "" && strings.TrimSpace(req.Description) != "" {
-		batch.Description = strings.TrimSpace(req.Description)
-		if err := tx.Model(&batch).Update("description", batch.Description).Error; err != nil {
-			tx.Rollback()
-			responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to update batch description")
-			return
-		}
-	}
-
-	created := 0
-	names := make(map[string]struct{})
-	for _, raw := range req.Members {
-		if raw == "" {
-			continue
-		}
-		name := strings.TrimSpace(raw)
-		if name == "" {
-			continue
-		}
-		if _, ok := names[strings.ToLower(name)]; ok {
-			continue
-		}
-		names[strings.ToLower(name)] = struct{}{}
-		var member model.AttendanceMember
-		if err := tx.Where("batch_id = ? AND name = ?", batch.ID, name).First(&member).Error; err == nil {
-			continue
-		}
-		member = model.AttendanceMember{
-			BatchID:   batch.ID,
-			Name:      name,
-			Role:      role,
-			IsActive:  true,
-			CreatedAt: time.Now(),
-		}
-		if err := tx.Create(&member).Error; err != nil {
-			tx.Rollback()
-			responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to create member")
-			return
-		}
-		created++
-	}
-
-	if err := tx.Commit().Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to persist batch")
+	batch, created, err := h.attendance.SaveBatch(c.Request.Context(), req)
+	if err != nil {
+		serviceFailure(c, err, "failed to save batch")
 		return
 	}
 
@@ -254,89 +402,9 @@ func (h *Handler) createAttendanceRecords(c *gin.Context) {
 		return
 	}
 
-	if len(req.PresentMemberIDs) == 0 {
-		responses.JSON(c, http.StatusBadRequest, false, nil, "select at least one present member")
-		return
-	}
-
-	checkIn, err := utils.ParseAttendanceTime(req.CheckIn)
-	if err != nil || checkIn == nil {
-		responses.JSON(c, http.StatusBadRequest, false, nil, "check-in time is required (HH:MM)")
-		return
-	}
-	checkOut, err := utils.ParseAttendanceTime(req.CheckOut)
-	if err != nil || checkOut == nil {
-		responses.JSON(c, http.StatusBadRequest, false, nil, "check-out time is required (HH:MM)")
-		return
-	}
-
-	var batch model.AttendanceBatch
-	if err := h.db.First(&batch, req.BatchID).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			responses.JSON(c, http.StatusBadRequest, false, nil, "batch not found")
-			return
-		}
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to load batch")
-		return
-	}
-
-	var members []model.AttendanceMember
-	if err := h.db.Where("batch_id = ? AND is_active = ?", batch.ID, true).Find(&members).Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to load batch members")
-		return
-	}
-
-	presentMap := make(map[uint]struct{}, len(req.PresentMemberIDs))
-	for _, id := range req.PresentMemberIDs {
-		presentMap[id] = struct{}{}
-	}
-
-	attendanceDate := utils.DateOnly(time.Now())
-	tx := h.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-			panic(r)
-		}
-	}()
-
-	created := 0
-	for _, member := range members {
-		_, isPresent := presentMap[member.ID]
-		status := "absent"
-		mode := "onsite"
-		if isPresent {
-			status = "present"
-			mode = model.NormalizeAttendanceMode(req.Mode)
-		}
-		record := model.AttendanceRecord{
-			AttendeeName:    member.Name,
-			AttendeeRole:    member.Role,
-			AttendanceDate:  attendanceDate,
-			Status:          status,
-			Mode:            mode,
-			CheckInTime:     nil,
-			CheckOutTime:    nil,
-			WorkNotes:       "",
-			CreatedAt:       time.Now(),
-			AttendanceBatch: batch.Name,
-			MemberID:        &member.ID,
-		}
-		if isPresent {
-			record.CheckInTime = checkIn
-			record.CheckOutTime = checkOut
-			record.WorkNotes = strings.TrimSpace(req.Notes)
-		}
-		if err := tx.Create(&record).Error; err != nil {
-			tx.Rollback()
-			responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to save attendance")
-			return
-		}
-		created++
-	}
-
-	if err := tx.Commit().Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to persist attendance")
+	created, err := h.attendance.MarkAttendance(c.Request.Context(), req)
+	if err != nil {
+		serviceFailure(c, err, "failed to save attendance")
 		return
 	}
 	responses.JSON(c, http.StatusOK, true, nil, fmt.Sprintf("Marked attendance for %d member(s).", created))
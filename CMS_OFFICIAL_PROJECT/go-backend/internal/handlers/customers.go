@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quickgeo/cms-official-go/internal/listquery"
+	"github.com/quickgeo/cms-official-go/internal/model"
+	"github.com/quickgeo/cms-official-go/internal/responses"
+	"github.com/quickgeo/cms-official-go/internal/search"
+)
+
+// customersListSpec pages the customer catalog 200 at a time; it used to
+// return every row.
+var customersListSpec = listquery.Spec{
+	Table:           "construction_customer",
+	Sorts:           map[string]string{"created_at": "customer_created_at", "code": "customer_code", "name": "customer_name"},
+	DefaultSort:     "-created_at",
+	DefaultPageSize: 200,
+}
+
+// customerColumns are shared by the customer and CRM customer exports; they
+// leave out the password hash and bank details.
+var customerColumns = []column[model.Customer]{
+	{"Code", func(r model.Customer) interface{} { return r.CustomerCode }},
+	{"Name", func(r model.Customer) interface{} { return r.CustomerName }},
+	{"Company", func(r model.Customer) interface{} { return r.CustomerCompanyName }},
+	{"Phone", func(r model.Customer) interface{} { return r.CustomerPrimaryPhoneNumber }},
+	{"Secondary phone", func(r model.Customer) interface{} { return r.CustomerSecondaryPhoneNumber }},
+	{"Email", func(r model.Customer) interface{} { return r.CustomerEmail }},
+	{"Address", func(r model.Customer) interface{} { return r.CustomerAddress }},
+	{"Job title", func(r model.Customer) interface{} { return r.CustomerJobTitle }},
+	{"Contact preference", func(r model.Customer) interface{} { return r.CustomerContactPreference }},
+	{"Created", func(r model.Customer) interface{} { return r.CustomerCreatedAt }},
+}
+
+func (h *Handler) listCustomers(c *gin.Context) {
+	query := h.dbFor(c).Model(&model.Customer{})
+	if ids, ok := search.MatchIDs(h.dbFor(c), search.KindCustomer, c.Query("search")); ok {
+		query = query.Where("id IN (?)", ids)
+	}
+	if exporting(c) {
+		exportList(c, customersListSpec, query, "customers", customerColumns)
+		return
+	}
+
+	var customers []model.Customer
+	page, ok := findPage(c, customersListSpec, query, &customers, "failed to load customers")
+	if !ok {
+		return
+	}
+	responses.Page(c, http.StatusOK, customers, "customers loaded", page)
+}
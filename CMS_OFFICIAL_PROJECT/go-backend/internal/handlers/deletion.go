@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"github.com/quickgeo/cms-official-go/internal/model"
+	projectUtils "github.com/quickgeo/cms-official-go/internal/utilities/projects_page_app"
+	"gorm.io/gorm"
+)
+
+// dependencyQuery describes one dependent record set of a block or project.
+// Blocking sets hold sales, money or inventory history; the rest (generated
+// units, presets) are removed together with their parent without confirmation.
+type dependencyQuery struct {
+	entity   string
+	table    string
+	blocking bool
+	scope    func(tx *gorm.DB) *gorm.DB
+}
+
+// unitHasCRMData matches units that carry buyer details entered by sales.
+const unitHasCRMData = "(COALESCE(project_unit_buyer_name, '') <> '' OR COALESCE(project_unit_buyer_phone, '') <> '' " +
+	"OR COALESCE(project_unit_buyer_email, '') <> '' OR project_unit_buyer_customer_id IS NOT NULL " +
+	"OR project_unit_buyer_channel_partner_id IS NOT NULL)"
+
+// blockDependencies mirrors the records hanging off construction_projectblock.
+func blockDependencies(blockID uint) []dependencyQuery {
+	unitIDs := func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&model.ProjectUnit{}).Select("id").Where("project_unit_block_id = ?", blockID)
+	}
+	units := func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&model.ProjectUnit{}).Where("project_unit_block_id = ?", blockID)
+	}
+
+	return []dependencyQuery{
+		{entity: "units", table: "construction_projectunit", scope: units},
+		{entity: "sold_units", table: "construction_projectunit", blocking: true, scope: func(tx *gorm.DB) *gorm.DB {
+			return units(tx).Where("LOWER(project_unit_status) = ?", "sold")
+		}},
+		{entity: "reserved_units", table: "construction_projectunit", blocking: true, scope: func(tx *gorm.DB) *gorm.DB {
+			return units(tx).Where("LOWER(project_unit_status) IN ?", []string{"booked", "hold"})
+		}},
+		{entity: "crm_units", table: "construction_projectunit", blocking: true, scope: func(tx *gorm.DB) *gorm.DB {
+			return units(tx).Where(unitHasCRMData)
+		}},
+		{entity: "flat_payments", table: "flat_payments", blocking: true, scope: func(tx *gorm.DB) *gorm.DB {
+			return tx.Model(&model.FlatPayment{}).Where("flat_payment_unit_id IN (?)", unitIDs(tx))
+		}},
+		{entity: "plot_payments", table: "plot_payments", blocking: true, scope: func(tx *gorm.DB) *gorm.DB {
+			return tx.Model(&model.PlotPayment{}).Where("plot_payment_unit_id IN (?)", unitIDs(tx))
+		}},
+	}
+}
+
+// projectDependencies mirrors the records hanging off construction_project.
+func projectDependencies(projectID uint) []dependencyQuery {
+	blockIDs := func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&model.ProjectBlock{}).Select("id").Where("project_block_project_id = ?", projectID)
+	}
+	units := func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&model.ProjectUnit{}).Where("project_unit_block_id IN (?)", blockIDs(tx))
+	}
+	byProject := func(value interface{}, column string) func(tx *gorm.DB) *gorm.DB {
+		return func(tx *gorm.DB) *gorm.DB {
+			return tx.Model(value).Where(column+" = ?", projectID)
+		}
+	}
+
+	return []dependencyQuery{
+		{entity: "blocks", table: "construction_projectblock", scope: byProject(&model.ProjectBlock{}, "project_block_project_id")},
+		{entity: "units", table: "construction_projectunit", scope: units},
+		{entity: "sold_units", table: "construction_projectunit", blocking: true, scope: func(tx *gorm.DB) *gorm.DB {
+			return units(tx).Where("LOWER(project_unit_status) = ?", "sold")
+		}},
+		{entity: "reserved_units", table: "construction_projectunit", blocking: true, scope: func(tx *gorm.DB) *gorm.DB {
+			return units(tx).Where("LOWER(project_unit_status) IN ?", []string{"booked", "hold"})
+		}},
+		{entity: "crm_units", table: "construction_projectunit", blocking: true, scope: func(tx *gorm.DB) *gorm.DB {
+			return units(tx).Where(unitHasCRMData)
+		}},
+		{entity: "presets", table: "construction_projectpreset", scope: byProject(&model.ProjectPreset{}, "project_preset_project_id")},
+		{entity: "project_payments", table: "project_payments", blocking: true, scope: byProject(&model.ProjectPayment{}, "project_payment_project_id")},
+		{entity: "flat_payments", table: "flat_payments", blocking: true, scope: byProject(&model.FlatPayment{}, "flat_payment_project_id")},
+		{entity: "plot_payments", table: "plot_payments", blocking: true, scope: byProject(&model.PlotPayment{}, "plot_payment_project_id")},
+		{entity: "stock_balances", table: "stock_management_page_app_stockbalance", blocking: true, scope: byProject(&model.StockBalance{}, "stock_project_id")},
+		{entity: "manpower_expenses", table: "construction_manpowerexpense", blocking: true, scope: byProject(&model.ManpowerExpense{}, "manpower_expense_project_id")},
+		{entity: "material_expenses", table: "construction_materialexpense", blocking: true, scope: byProject(&model.MaterialExpense{}, "material_expense_project_id")},
+		{entity: "general_expenses", table: "construction_generalexpense", blocking: true, scope: byProject(&model.GeneralExpense{}, "general_expense_project_id")},
+		{entity: "departmental_expenses", table: "construction_departmentalexpense", blocking: true, scope: byProject(&model.DepartmentalExpense{}, "departmental_expense_project_id")},
+		{entity: "administration_expenses", table: "construction_administrationexpense", blocking: true, scope: byProject(&model.AdministrationExpense{}, "administration_expense_project_id")},
+	}
+}
+
+// buildDeletionPlan counts every dependency and marks the plan unsafe when a
+// blocking dependency still has rows.
+func buildDeletionPlan(tx *gorm.DB, target string, targetID uint, deps []dependencyQuery) (projectUtils.DeletionPlan, error) {
+	plan := projectUtils.DeletionPlan{
+		Target:       target,
+		TargetID:     targetID,
+		Dependencies: make([]projectUtils.DeletionDependency, 0, len(deps)),
+		Safe:         true,
+	}
+
+	for _, dep := range deps {
+		var count int64
+		if err := dep.scope(tx).Count(&count).Error; err != nil {
+			return plan, err
+		}
+		plan.Dependencies = append(plan.Dependencies, projectUtils.DeletionDependency{
+			Entity:   dep.entity,
+			Table:    dep.table,
+			Count:    count,
+			Blocking: dep.blocking,
+		})
+		if dep.blocking && count > 0 {
+			plan.Safe = false
+		}
+	}
+	return plan, nil
+}
+
+// planBlockDeletion reports what deleting a block would remove.
+func (h *Handler) planBlockDeletion(tx *gorm.DB, blockID uint) (projectUtils.DeletionPlan, error) {
+	return buildDeletionPlan(tx, "block", blockID, blockDependencies(blockID))
+}
+
+// planProjectDeletion reports what deleting a project would remove.
+func (h *Handler) planProjectDeletion(tx *gorm.DB, projectID uint) (projectUtils.DeletionPlan, error) {
+	return buildDeletionPlan(tx, "project", projectID, projectDependencies(projectID))
+}
+
+// cascadeDeleteBlock removes a block with its units and unit payments.
+// Callers must run it inside a transaction.
+func cascadeDeleteBlock(tx *gorm.DB, blockID uint) error {
+	unitIDs := func() *gorm.DB {
+		return tx.Model(&model.ProjectUnit{}).Select("id").Where("project_unit_block_id = ?", blockID)
+	}
+
+	if err := tx.Where("flat_payment_unit_id IN (?)", unitIDs()).Delete(&model.FlatPayment{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("plot_payment_unit_id IN (?)", unitIDs()).Delete(&model.PlotPayment{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("project_unit_block_id = ?", blockID).Delete(&model.ProjectUnit{}).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&model.ProjectBlock{}, blockID).Error
+}
+
+// cascadeDeleteProject removes a project with every dependent record.
+// Callers must run it inside a transaction.
+func cascadeDeleteProject(tx *gorm.DB, projectID uint) error {
+	dependents := []struct {
+		value  interface{}
+		column string
+	}{
+		{&model.ProjectPayment{}, "project_payment_project_id"},
+		{&model.FlatPayment{}, "flat_payment_project_id"},
+		{&model.PlotPayment{}, "plot_payment_project_id"},
+		{&model.StockBalance{}, "stock_project_id"},
+		{&model.ManpowerExpense{}, "manpower_expense_project_id"},
+		{&model.MaterialExpense{}, "material_expense_project_id"},
+		{&model.GeneralExpense{}, "general_expense_project_id"},
+		{&model.DepartmentalExpense{}, "departmental_expense_project_id"},
+		{&model.AdministrationExpense{}, "administration_expense_project_id"},
+		{&model.ProjectPreset{}, "project_preset_project_id"},
+	}
+	for _, dep := range dependents {
+		if err := tx.Where(dep.column+" = ?", projectID).Delete(dep.value).Error; err != nil {
+			return err
+		}
+	}
+
+	var blockIDs []uint
+	if err := tx.Model(&model.ProjectBlock{}).Where("project_block_project_id = ?", projectID).Pluck("id", &blockIDs).Error; err != nil {
+		return err
+	}
+	for _, blockID := range blockIDs {
+		if err := cascadeDeleteBlock(tx, blockID); err != nil {
+			return err
+		}
+	}
+	return tx.Delete(&model.Project{}, projectID).Error
+}
+
+// cascadeConfirmed reports whether the caller explicitly asked for a cascade
+// delete (?cascade=true).
+func cascadeConfirmed(value string) bool {
+	switch value {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
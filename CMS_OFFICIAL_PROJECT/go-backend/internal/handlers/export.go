@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quickgeo/cms-official-go/internal/export"
+	"github.com/quickgeo/cms-official-go/internal/listquery"
+	"github.com/quickgeo/cms-official-go/internal/responses"
+	"gorm.io/gorm"
+)
+
+// column is one spreadsheet column of an exported list of T.
+type column[T any] struct {
+	name  string
+	value func(T) interface{}
+}
+
+// exporting reports whether the request asks for a spreadsheet rather than
+// JSON; format=json and no format mean JSON.
+func exporting(c *gin.Context) bool {
+	format := c.Query("format")
+	return format != "" && format != "json"
+}
+
+// exportList answers with every row of query that the request's filters,
+// date range and sort select, as a CSV or XLSX file named after name. Rows
+// are read and written one batch at a time, so large lists stream.
+//
+// Errors before the first row is written get the usual JSON answer. A read
+// that fails later can only cut the file short; XLSX files then fail to
+// open instead of looking complete.
+func exportList[T any](c *gin.Context, spec listquery.Spec, query *gorm.DB, name string, columns []column[T]) {
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, err.Error())
+		return
+	}
+	lq, err := listquery.Parse(c, spec)
+	if err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, err.Error())
+		return
+	}
+
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.name
+	}
+	cells := make([]interface{}, len(columns))
+	var rows []T
+	var sheet export.Writer
+	err = lq.Each(query, &rows, func() error {
+		if sheet == nil {
+			started, err := startExport(c, format, name, header)
+			if err != nil {
+				return err
+			}
+			sheet = started
+		}
+		for _, row := range rows {
+			for i, col := range columns {
+				cells[i] = col.value(row)
+			}
+			if err := sheet.Row(cells); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if sheet == nil && err == nil {
+		sheet, err = startExport(c, format, name, header)
+	}
+	if err != nil {
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to export list")
+			return
+		}
+		c.Error(err)
+		return
+	}
+	if err := sheet.Close(); err != nil {
+		c.Error(err)
+	}
+}
+
+// choiceLabel shows a stored choice by its display name, falling back to the
+// stored value for choices that have since been retired.
+func choiceLabel(choices map[string]string, value string) string {
+	if name, ok := choices[value]; ok {
+		return name
+	}
+	return value
+}
+
+// startExport sends the headers of a download and its header row.
+func startExport(c *gin.Context, format export.Format, name string, header []string) (export.Writer, error) {
+	filename := name + "-" + time.Now().Format("2006-01-02") + "." + string(format)
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	return export.New(c.Writer, format, header)
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quickgeo/cms-official-go/internal/model"
+	"github.com/quickgeo/cms-official-go/internal/responses"
+	fileService "github.com/quickgeo/cms-official-go/internal/services/files"
+	paymentService "github.com/quickgeo/cms-official-go/internal/services/payments"
+	utils "github.com/quickgeo/cms-official-go/internal/utilities/files_page_app"
+)
+
+const (
+	// defaultMaxUploadBytes caps uploaded files unless CMS_MAX_UPLOAD_BYTES
+	// says otherwise.
+	defaultMaxUploadBytes = 10 << 20
+	// multipartOverhead is room for the form fields and boundaries around an
+	// uploaded file.
+	multipartOverhead = 64 << 10
+	// defaultFileLinkTTL is how long download links work unless
+	// CMS_FILE_LINK_TTL_MINUTES says otherwise.
+	defaultFileLinkTTL = 15 * time.Minute
+)
+
+func maxUploadBytes() int64 {
+	if value, err := strconv.ParseInt(os.Getenv("CMS_MAX_UPLOAD_BYTES"), 10, 64); err == nil && value > 0 {
+		return value
+	}
+	return defaultMaxUploadBytes
+}
+
+func fileLinkTTL() time.Duration {
+	if value, err := strconv.Atoi(os.Getenv("CMS_FILE_LINK_TTL_MINUTES")); err == nil && value > 0 {
+		return time.Duration(value) * time.Minute
+	}
+	return defaultFileLinkTTL
+}
+
+// fileSigningKey signs download links. Without CMS_FILE_SIGNING_KEY each
+// process picks its own, so links only work on the process that made them.
+func fileSigningKey() []byte {
+	if key := os.Getenv("CMS_FILE_SIGNING_KEY"); key != "" {
+		return []byte(key)
+	}
+	return fileService.RandomKey()
+}
+
+func (h *Handler) getProjectPaymentDocument(c *gin.Context) {
+	h.servePaymentFile(c, paymentService.ProjectLedger)
+}
+
+func (h *Handler) uploadProjectPaymentDocument(c *gin.Context) {
+	h.attachPaymentFile(c, paymentService.ProjectLedger, fileService.PaymentDocument)
+}
+
+func (h *Handler) getFlatPaymentReceipt(c *gin.Context) {
+	h.servePaymentFile(c, paymentService.FlatLedger)
+}
+
+func (h *Handler) uploadFlatPaymentReceipt(c *gin.Context) {
+	h.attachPaymentFile(c, paymentService.FlatLedger, fileService.PaymentReceipt)
+}
+
+func (h *Handler) getPlotPaymentReceipt(c *gin.Context) {
+	h.servePaymentFile(c, paymentService.PlotLedger)
+}
+
+func (h *Handler) uploadPlotPaymentReceipt(c *gin.Context) {
+	h.attachPaymentFile(c, paymentService.PlotLedger, fileService.PaymentReceipt)
+}
+
+// servePaymentFile describes the file on a payment with fresh links.
+func (h *Handler) servePaymentFile(c *gin.Context, ledger paymentService.Ledger) {
+	paymentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payment id")
+		return
+	}
+	path, err := h.payments.Attachment(c.Request.Context(), ledger, uint(paymentID))
+	if err != nil {
+		serviceFailure(c, err, "Failed to load payment")
+		return
+	}
+	if path == "" {
+		responses.JSON(c, http.StatusNotFound, false, nil, "Payment has no file")
+		return
+	}
+	h.describeFile(c, path)
+}
+
+// attachPaymentFile stores the upload and points the payment at it. The
+// file it replaces stays in storage.
+func (h *Handler) attachPaymentFile(c *gin.Context, ledger paymentService.Ledger, purpose fileService.Purpose) {
+	paymentID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payment id")
+		return
+	}
+	if _, err := h.payments.Attachment(c.Request.Context(), ledger, uint(paymentID)); err != nil {
+		serviceFailure(c, err, "Failed to load payment")
+		return
+	}
+	stored, ok := h.saveUpload(c, purpose)
+	if !ok {
+		return
+	}
+	if err := h.payments.Attach(c.Request.Context(), ledger, uint(paymentID), stored.Key); err != nil {
+		serviceFailure(c, err, "Failed to attach file")
+		return
+	}
+	responses.JSON(c, http.StatusCreated, true, h.fileResponse(stored), "file uploaded")
+}
+
+// getAvatar describes the caller's avatar with fresh links.
+func (h *Handler) getAvatar(c *gin.Context) {
+	user, ok := h.profileUser(c)
+	if !ok {
+		return
+	}
+	if user.Profile.Avatar == "" {
+		responses.JSON(c, http.StatusNotFound, false, nil, "No avatar uploaded")
+		return
+	}
+	h.describeFile(c, user.Profile.Avatar)
+}
+
+// uploadAvatar replaces the caller's avatar.
+func (h *Handler) uploadAvatar(c *gin.Context) {
+	user, ok := h.profileUser(c)
+	if !ok {
+		return
+	}
+	stored, ok := h.saveUpload(c, fileService.Avatar)
+	if !ok {
+		return
+	}
+	err := h.dbFor(c).Model(user.Profile).Update("profile_avatar", stored.Key).Error
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to update avatar")
+		return
+	}
+	responses.JSON(c, http.StatusCreated, true, h.fileResponse(stored), "avatar uploaded")
+}
+
+// saveUpload stores the multipart "file" field for purpose.
+func (h *Handler) saveUpload(c *gin.Context, purpose fileService.Purpose) (model.StoredFile, bool) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			bodyTooLarge(c, tooLarge.Limit)
+			return model.StoredFile{}, false
+		}
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Send the file as multipart form field \"file\"")
+		return model.StoredFile{}, false
+	}
+	file, err := header.Open()
+	if err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Could not read uploaded file")
+		return model.StoredFile{}, false
+	}
+	defer file.Close()
+
+	stored, err := h.files.Save(c.Request.Context(), fileService.Upload{
+		Purpose:        purpose,
+		Name:           header.Filename,
+		Body:           file,
+		OrganizationID: currentOrganization(c),
+		UploadedByID:   currentUser(c),
+	})
+	if err != nil {
+		serviceFailure(c, err, "Failed to store file")
+		return stored, false
+	}
+	return stored, true
+}
+
+func (h *Handler) describeFile(c *gin.Context, key string) {
+	stored, err := h.files.Describe(c.Request.Context(), key)
+	if err != nil {
+		serviceFailure(c, err, "Failed to load file")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, h.fileResponse(stored), "file loaded")
+}
+
+func (h *Handler) fileResponse(stored model.StoredFile) utils.FileResponse {
+	link := h.files.Link(stored.Key)
+	response := utils.FileResponse{
+		Key:         stored.Key,
+		Name:        stored.Name,
+		ContentType: stored.ContentType,
+		Size:        stored.Size,
+		URL:         link.URL,
+		ExpiresAt:   link.Expires,
+	}
+	if stored.ThumbnailKey != "" {
+		response.ThumbnailURL = h.files.Link(stored.ThumbnailKey).URL
+	}
+	return response
+}
+
+// downloadFile serves a file through a signed link. The link is the
+// authorization: it was handed to a caller allowed to see the file and
+// works until it expires.
+func (h *Handler) downloadFile(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if err := h.files.Check(key, c.Query("expires"), c.Query("signature")); err != nil {
+		serviceFailure(c, err, "Invalid download link")
+		return
+	}
+	body, stored, err := h.files.Open(c.Request.Context(), key)
+	if err != nil {
+		serviceFailure(c, err, "Failed to load file")
+		return
+	}
+	defer body.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(stored.ContentType, "image/") {
+		disposition = "inline"
+	}
+	expires, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+	maxAge := max(expires-time.Now().Unix(), 0)
+	c.DataFromReader(http.StatusOK, stored.Size, stored.ContentType, body, map[string]string{
+		"Content-Disposition": mime.FormatMediaType(disposition, map[string]string{"filename": stored.Name}),
+		"Cache-Control":       "private, max-age=" + strconv.FormatInt(maxAge, 10),
+	})
+}
@@ -1,49 +1,180 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
-	"strconv"
+	"os"
+	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/quickgeo/cms-official-go/internal/model"
+	"github.com/quickgeo/cms-official-go/internal/refdata"
 	"github.com/quickgeo/cms-official-go/internal/responses"
+	"github.com/quickgeo/cms-official-go/internal/services"
+	apiKeyService "github.com/quickgeo/cms-official-go/internal/services/apikeys"
+	attendanceService "github.com/quickgeo/cms-official-go/internal/services/attendance"
+	directoryService "github.com/quickgeo/cms-official-go/internal/services/directory"
+	fileService "github.com/quickgeo/cms-official-go/internal/services/files"
+	organizationService "github.com/quickgeo/cms-official-go/internal/services/organizations"
+	paymentService "github.com/quickgeo/cms-official-go/internal/services/payments"
+	projectService "github.com/quickgeo/cms-official-go/internal/services/projects"
+	receiptService "github.com/quickgeo/cms-official-go/internal/services/receipts"
+	salesService "github.com/quickgeo/cms-official-go/internal/services/sales"
+	sessionService "github.com/quickgeo/cms-official-go/internal/services/sessions"
+	stockService "github.com/quickgeo/cms-official-go/internal/services/stock"
+	userService "github.com/quickgeo/cms-official-go/internal/services/users"
+	"github.com/quickgeo/cms-official-go/internal/storage"
+	"github.com/quickgeo/cms-official-go/internal/tenant"
 	"gorm.io/gorm"
 )
 
-// Handler wires the Gin routes with the storage layer.
+// Handler wires the Gin routes with the storage layer and the domain services.
 type Handler struct {
-	db *gorm.DB
+	db         *gorm.DB
+	refs       *refdata.Cache
+	projects   projectService.Service
+	sales      salesService.Service
+	payments   paymentService.Service
+	attendance attendanceService.Service
+	stock      stockService.Service
+	directory  directoryService.Service
+
+	organizations organizationService.Service
+	sessions      sessionService.Service
+	users         userService.Service
+	apiKeys       apiKeyService.Service
+	files         fileService.Service
+	receipts      receiptService.Service
+
+	specOnce sync.Once
+	spec     []byte
+	specErr  error
 }
 
-// New builds a handler with an attached database connection.
-func New(db *gorm.DB) *Handler {
-	return &Handler{db: db}
+// New builds a handler with an attached database connection and the store
+// uploads go to; without a store uploads fail.
+func New(db *gorm.DB, store storage.Store) *Handler {
+	projects := projectService.New(db)
+	sessions := sessionService.New(db, sessionTTL())
+	files := fileService.New(db, store, fileService.Settings{
+		MaxBytes:   maxUploadBytes(),
+		LinkTTL:    fileLinkTTL(),
+		SigningKey: fileSigningKey(),
+	})
+	return &Handler{
+		db:         db,
+		refs:       refdata.New(db),
+		projects:   projects,
+		sales:      salesService.New(db),
+		payments:   paymentService.New(db, projects),
+		attendance: attendanceService.New(db),
+		stock:      stockService.New(db),
+		directory:  directoryService.New(db),
+
+		organizations: organizationService.New(db),
+		sessions:      sessions,
+		users:         userService.New(db, sessions),
+		apiKeys:       apiKeyService.New(db),
+		files:         files,
+		receipts:      receiptService.New(db, files),
+	}
 }
 
-// Register sets up the routes that mimic the old /api/v1 surface.
-func (h *Handler) Register(router *gin.Engine) {
-	v1 := router.Group("/api/v1")
+// serviceFailure answers a service error: rule violations keep their own
+// message and status, anything else is a 500 with fallback.
+func serviceFailure(c *gin.Context, err error, fallback string) {
+	status := http.StatusInternalServerError
+	message := fallback
+	var failure *services.Failure
+	if errors.As(err, &failure) {
+		message = failure.Message
+	}
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrInvalid):
+		status = http.StatusBadRequest
+	case errors.Is(err, services.ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, services.ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, services.ErrTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrUnsupported):
+		status = http.StatusUnsupportedMediaType
+	case errors.Is(err, tenant.ErrNoOrganization):
+		status = http.StatusForbidden
+		message = "You do not belong to an organization"
+	}
+	responses.JSON(c, status, false, nil, message)
+}
 
-	v1.GET("/index", h.IndexView)
+// Routes lists the deprecated /api/v1 surface, /api/v2, the API docs and
+// signed file downloads.
+// Each v1 route carries the v2 path serving the same handler, when there is
+// one.
+func (h *Handler) Routes() []Route {
+	v1 := newRouteTable("/api/v1")
+	h.registerV1(v1)
+	v2 := newRouteTable("/api/v2")
+	h.registerV2(v2)
+	linkSuccessors(*v1.routes, *v2.routes)
+	meta := newRouteTable("/api")
+	h.registerMeta(meta)
+	routes := append(*v1.routes, *v2.routes...)
+	return append(routes, *meta.routes...)
+}
 
-	v1.GET("/projects", h.listProjects)
-	v1.GET("/projects/:id", h.getProject)
+// Register checks Routes for clashes and installs them on router. v1 and v2
+// requests are scoped to the caller's organization first, then charged to
+// their rate limit budget. v1 responses
+// carry Deprecation, Link and, when CMS_V1_SUNSET is set, Sunset headers.
+func (h *Handler) Register(router gin.IRoutes) error {
+	routes := h.Routes()
+	if err := checkRoutes(routes); err != nil {
+		return err
+	}
+	sunset := os.Getenv("CMS_V1_SUNSET")
+	identify := h.identify()
+	limit := h.rateLimit()
+	for _, route := range routes {
+		chain := route.Handlers
+		if strings.HasPrefix(route.Path, "/api/v1/") || strings.HasPrefix(route.Path, "/api/v2/") {
+			if limit != nil {
+				chain = append([]gin.HandlerFunc{limit(route)}, chain...)
+			}
+			chain = append([]gin.HandlerFunc{identify(route)}, chain...)
+		}
+		if strings.HasPrefix(route.Path, "/api/v1/") {
+			chain = append([]gin.HandlerFunc{deprecated(route.Successor, sunset)}, chain...)
+		}
+		router.Handle(route.Method, route.Path, chain...)
+	}
+	return nil
+}
+
+// registerV1 keeps the old /api/v1 paths working. Where the old table
+// registered two handlers for one path, the full resource handler won.
+func (h *Handler) registerV1(v1 routeTable) {
+	v1.GET("/index", h.IndexView)
+	v1.GET("/search", h.SearchAPI)
 
 	v1.GET("/customers", h.listCustomers)
-	v1.GET("/vendors", h.listVendors)
-	v1.GET("/supervisors", h.listSupervisors)
 	v1.GET("/channel-partners", h.listChannelPartners)
 	v1.GET("/material-items", h.listMaterialItems)
 
-	attendance := v1.Group("/attendance")
+	attendance := v1.group("/attendance")
 	attendance.GET("/stats", h.getAttendanceStats)
+	attendance.GET("/choices", h.getAttendanceChoices)
 	attendance.GET("/records", h.listAttendanceRecords)
-	attendance.POST("/records", h.createAttendanceRecords)
+	attendance.POST("/records", h.Idempotent(), h.createAttendanceRecords)
 	attendance.GET("/batches", h.listAttendanceBatches)
 	attendance.GET("/batches/:id/members", h.listAttendanceMembers)
 	attendance.POST("/batches", h.createAttendanceBatch)
 
-	expenses := v1.Group("/expenses")
+	expenses := v1.group("/expenses")
 	expenses.GET("/labor-work-types", h.ListLaborWorkTypes)
 	expenses.GET("/manpower", h.ListManpowerExpenses)
 	expenses.GET("/material", h.ListMaterialExpenses)
@@ -52,170 +183,112 @@ func (h *Handler) Register(router *gin.Engine) {
 	expenses.GET("/administration", h.ListAdministrationExpenses)
 
 	// Auth Routes
-	auth := v1.Group("/auth")
+	auth := v1.group("/auth")
 	auth.POST("/login", h.LoginView)
 	auth.POST("/register", h.RegisterView)
 	auth.POST("/logout", h.LogoutView)
 
 	// CRM Routes
-	crm := v1.Group("/crm")
+	crm := v1.group("/crm")
 	crm.GET("/projects", h.CRMProjectsList)
 	crm.GET("/customers", h.CRMCustomers)
+	crm.POST("/customers", h.CreateCustomerAPI)
 	crm.GET("/channel-partners", h.CRMChannelPartners)
+	crm.POST("/channel-partners", h.CreateChannelPartnerAPI)
 	crm.GET("/kanban", h.KanbanBoardAPI)
 	crm.PATCH("/kanban/stage/:unit_id", h.KanbanUpdateStageAPI)
+	crm.PATCH("/kanban/:unit_id", h.CRMKanbanUpdateAPI)
 
 	// Dashboard Routes
-	dashboard := v1.Group("/dashboard")
+	dashboard := v1.group("/dashboard")
 	dashboard.GET("/overview", h.DashboardView)
 
 	// Directory Routes
-	directory := v1.Group("/directory")
+	directory := v1.group("/directory")
 	directory.GET("/vendors/list", h.VendorListAPI)
 	directory.POST("/credentials/regenerate", h.RegenerateCredentialsAPI)
 
 	// Insights Routes
-	insights := v1.Group("/insights")
+	insights := v1.group("/insights")
 	insights.GET("/overview", h.InsightsView)
 
-	// Payments Routes (New)
-	payments := v1.Group("/payments")
+	// Payments Routes
+	payments := v1.group("/payments")
 	payments.GET("/list-projects", h.PaymentsProjectsList) // for dropdowns
-	payments.GET("/projects", h.ProjectPaymentsAPI)
-	payments.POST("/projects", h.ProjectPaymentsAPI)
-	payments.GET("/flats", h.FlatPaymentsAPI)
-	payments.POST("/flats", h.FlatPaymentsAPI)
-	payments.GET("/plots", h.PlotPaymentsAPI)
-	payments.POST("/plots", h.PlotPaymentsAPI)
+	payments.GET("/choices", h.PaymentChoicesAPI)
+	payments.GET("/projects", h.ListProjectPaymentsAPI)
+	payments.POST("/projects", h.Idempotent(), h.CreateProjectPaymentAPI)
+	payments.GET("/flats", h.ListFlatPaymentsAPI)
+	payments.POST("/flats", h.Idempotent(), h.CreateFlatPaymentAPI)
+	payments.GET("/plots", h.ListPlotPaymentsAPI)
+	payments.POST("/plots", h.Idempotent(), h.CreatePlotPaymentAPI)
 
 	// Profile Routes
-	profile := v1.Group("/profile")
+	profile := v1.group("/profile")
 	profile.GET("/me", h.ProfileView)
-	profile.POST("/me", h.ProfileView)
+	profile.POST("/me", h.UpdateProfileView)
 
-	// Projects Routes (New)
-	projects := v1.Group("/projects")
-	projects.GET("", h.ProjectsAPI)
-	projects.POST("", h.ProjectsAPI)
+	// Projects Routes
+	projects := v1.group("/projects")
+	projects.GET("", h.ListProjectsAPI)
+	projects.POST("", h.CreateProjectAPI)
 	projects.GET("/:id", h.ProjectDetailAPI)
-	projects.PUT("/:id", h.ProjectDetailAPI)
-	projects.DELETE("/:id", h.ProjectDetailAPI)
+	projects.PUT("/:id", h.UpdateProjectAPI)
+	projects.DELETE("/:id", h.DeleteProjectAPI)
+	projects.GET("/:id/deletion-plan", h.ProjectDeletionPlanAPI)
 
 	projects.GET("/multi-flat", h.MultiFlatProjectsAPI)
 	projects.GET("/multi-flat-grid/:code", h.MultiFlatProjectGridAPI)
 	projects.GET("/multi-flat-presets/:code", h.MultiFlatPresetsAPI)
-	projects.POST("/multi-flat-presets/:code", h.MultiFlatPresetsAPI)
+	projects.POST("/multi-flat-presets/:code", h.UpdateMultiFlatPresetsAPI)
 
-	// CRM Routes (Specific to Projects App features)
-	crmProj := v1.Group("/crm")
-	crmProj.GET("/kanban", h.CRMKanbanBoardAPI)
-	crmProj.PATCH("/kanban/:unit_id", h.CRMKanbanUpdateAPI)
-	crmProj.GET("/customers", h.CrmCustomersAPI)
-	crmProj.GET("/channel-partners", h.CrmChannelPartnersAPI)
-
-	// Sales Routes (New)
-	sales := v1.Group("/sales")
+	// Sales Routes
+	sales := v1.group("/sales")
 	sales.POST("/multi-flat/projects/:code/blocks", h.CreateMultiFlatBlockAPI)
+	sales.GET("/multi-flat/blocks/:block_id", h.MultiFlatBlockAPI)
 	sales.PATCH("/multi-flat/blocks/:block_id", h.UpdateMultiFlatBlockAPI)
-	sales.DELETE("/multi-flat/blocks/:block_id", h.UpdateMultiFlatBlockAPI)
+	sales.DELETE("/multi-flat/blocks/:block_id", h.DeleteMultiFlatBlockAPI)
+	sales.GET("/multi-flat/blocks/:block_id/deletion-plan", h.MultiFlatBlockDeletionPlanAPI)
+	sales.GET("/multi-flat/units/:unit_id", h.MultiFlatUnitAPI)
 	sales.PATCH("/multi-flat/units/:unit_id", h.UpdateMultiFlatUnitAPI)
 	sales.GET("/multi-flat/crm/units", h.MultiFlatCRMUnitsAPI)
 
 	// Stock Management
-	v1.GET("/stock", h.StockManagementAPI)
-	v1.POST("/stock", h.StockManagementAPI)
+	v1.GET("/stock", h.StockBalancesAPI)
+	v1.POST("/stock", h.UpdateStockAPI)
 
 	// Supervisor Routes
-	supervisors := v1.Group("/supervisors")
-	supervisors.GET("", h.SupervisorCollectionAPI)
-	supervisors.POST("", h.SupervisorCollectionAPI)
-	supervisors.PUT("/:id", h.SupervisorDetailAPI)
-	supervisors.PATCH("/:id", h.SupervisorDetailAPI)
-	supervisors.DELETE("/:id", h.SupervisorDetailAPI)
+	supervisors := v1.group("/supervisors")
+	supervisors.GET("", h.ListSupervisorsAPI)
+	supervisors.POST("", h.CreateSupervisorAPI)
+	supervisors.GET("/:id", h.SupervisorDetailAPI)
+	supervisors.PUT("/:id", h.UpdateSupervisorAPI)
+	supervisors.PATCH("/:id", h.UpdateSupervisorAPI)
+	supervisors.DELETE("/:id", h.DeleteSupervisorAPI)
 
 	// Track Finances
 	v1.GET("/track-finances", h.TrackFinancesView)
 
 	// Vendor Routes
-	v1.GET("/vendors", h.VendorCollectionAPI)
-	v1.POST("/vendors", h.VendorCollectionAPI)
-	v1.PUT("/vendors/:id", h.VendorDetailAPI)
-	v1.PATCH("/vendors/:id", h.VendorDetailAPI)
-	v1.DELETE("/vendors/:id", h.VendorDetailAPI)
+	v1.GET("/vendors", h.ListVendorsAPI)
+	v1.POST("/vendors", h.CreateVendorAPI)
+	v1.GET("/vendors/:id", h.VendorDetailAPI)
+	v1.PUT("/vendors/:id", h.UpdateVendorAPI)
+	v1.PATCH("/vendors/:id", h.UpdateVendorAPI)
+	v1.DELETE("/vendors/:id", h.DeleteVendorAPI)
 	v1.GET("/vendor-choices", h.VendorChoicesAPI)
 }
 
-func (h *Handler) listProjects(c *gin.Context) {
-	var projects []model.Project
-	if err := h.db.Preload("Blocks.Units").Order("project_created_at desc").Find(&projects).Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to load projects")
-		return
-	}
-	responses.JSON(c, http.StatusOK, true, projects, "projects loaded")
-}
-
-func (h *Handler) getProject(c *gin.Context) {
-	idParam := c.Param("id")
-	projectID, err := strconv.ParseUint(idParam, 10, 64)
-	if err != nil {
-		responses.JSON(c, http.StatusBadRequest, false, nil, "invalid project id")
-		return
-	}
-
-	var project model.Project
-	if err := h.db.Preload("Blocks.Units").First(&project, projectID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			responses.JSON(c, http.StatusNotFound, false, nil, "project not found")
-			return
-		}
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to load project")
-		return
-	}
-
-	responses.JSON(c, http.StatusOK, true, project, "project loaded")
-}
-
-func (h *Handler) listCustomers(c *gin.Context) {
-	var customers []model.Customer
-	if err := h.db.Order("customer_created_at desc").Find(&customers).Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to load customers")
-		return
-	}
-	responses.JSON(c, http.StatusOK, true, customers, "customers loaded")
-}
-
-func (h *Handler) listVendors(c *gin.Context) {
-	var vendors []model.Vendor
-	if err := h.db.Order("vendor_created_at desc").Find(&vendors).Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to load vendors")
-		return
-	}
-	responses.JSON(c, http.StatusOK, true, vendors, "vendors loaded")
-}
-
-func (h *Handler) listSupervisors(c *gin.Context) {
-	var supervisors []model.Supervisor
-	if err := h.db.Order("supervisor_created_at desc").Find(&supervisors).Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to load supervisors")
-		return
-	}
-	responses.JSON(c, http.StatusOK, true, supervisors, "supervisors loaded")
-}
-
-func (h *Handler) listChannelPartners(c *gin.Context) {
-	var partners []model.ChannelPartner
-	if err := h.db.Order("channel_partner_created_at desc").Find(&partners).Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to load channel partners")
-		return
-	}
-	responses.JSON(c, http.StatusOK, true, partners, "channel partners loaded")
+func (h *Handler) listMaterialItems(c *gin.Context) {
+	h.serveReference(c, fmt.Sprintf("material-items:%d", currentOrganization(c)), []string{refdata.MaterialItems}, "material items loaded", "failed to load material items", func() (interface{}, error) {
+		var items []model.MaterialItem
+		err := h.dbFor(c).Order("material_item_display_name asc").Find(&items).Error
+		return items, err
+	})
 }
 
-func (h *Handler) listMaterialItems(c *gin.Context) {
-	var items []model.MaterialItem
-	if err := h.db.Order("material_item_display_name asc").Find(&items).Error; err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "failed to load material items")
-		return
-	}
-	responses.JSON(c, http.StatusOK, true, items, "material items loaded")
+// dbFor runs queries with the request's context, so the tenant filter scopes
+// them to the caller's organization.
+func (h *Handler) dbFor(c *gin.Context) *gorm.DB {
+	return h.db.WithContext(c.Request.Context())
 }
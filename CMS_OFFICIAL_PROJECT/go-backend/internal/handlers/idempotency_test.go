@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// idempotentRoute mounts handler behind Idempotent at POST /things and
+// counts how often it runs.
+func idempotentRoute(t *testing.T, handler func(c *gin.Context, call int64)) (*testServer, *atomic.Int64) {
+	s := newTestServer(t)
+	calls := new(atomic.Int64)
+	s.router.POST("/things", New(s.db, nil).Idempotent(), func(c *gin.Context) {
+		handler(c, calls.Add(1))
+	})
+	return s, calls
+}
+
+func created(c *gin.Context, call int64) {
+	c.JSON(http.StatusCreated, gin.H{"call": call})
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	s, calls := idempotentRoute(t, created)
+	first := s.do(http.MethodPost, "/things", `{"amount":10}`, idempotencyHeader, "k-1")
+	retry := s.do(http.MethodPost, "/things", `{"amount":10}`, idempotencyHeader, "k-1")
+	if first.Code != http.StatusCreated || retry.Code != http.StatusCreated {
+		t.Fatalf("answered %d then %d, want 201 twice", first.Code, retry.Code)
+	}
+	if retry.Body.String() != first.Body.String() || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry answered %q (replayed %q), want the stored %q", retry.Body, retry.Header().Get("Idempotent-Replayed"), first.Body)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+
+	other := s.do(http.MethodPost, "/things", `{"amount":10}`, idempotencyHeader, "k-2")
+	if other.Code != http.StatusCreated || calls.Load() != 2 {
+		t.Errorf("a new key answered %d after %d calls, want a fresh 201", other.Code, calls.Load())
+	}
+}
+
+func TestIdempotentDifferentBody(t *testing.T) {
+	s, calls := idempotentRoute(t, created)
+	s.do(http.MethodPost, "/things", `{"amount":10}`, idempotencyHeader, "k-1")
+	w := s.do(http.MethodPost, "/things", `{"amount":99}`, idempotencyHeader, "k-1")
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("same key with another body answered %d, want 422: %s", w.Code, w.Body)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+}
+
+func TestIdempotentConcurrent(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	s, calls := idempotentRoute(t, func(c *gin.Context, call int64) {
+		if call == 1 {
+			close(entered)
+			<-release
+		}
+		created(c, call)
+	})
+
+	done := make(chan int)
+	go func() {
+		done <- s.do(http.MethodPost, "/things", `{"amount":10}`, idempotencyHeader, "k-1").Code
+	}()
+	<-entered
+	overlap := s.do(http.MethodPost, "/things", `{"amount":10}`, idempotencyHeader, "k-1")
+	close(release)
+	if code := <-done; code != http.StatusCreated {
+		t.Fatalf("first request answered %d, want 201", code)
+	}
+	if overlap.Code != http.StatusConflict {
+		t.Errorf("overlapping retry answered %d, want 409: %s", overlap.Code, overlap.Body)
+	}
+
+	after := s.do(http.MethodPost, "/things", `{"amount":10}`, idempotencyHeader, "k-1")
+	if after.Code != http.StatusCreated || after.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry after the first finished answered %d (replayed %q), want the stored 201", after.Code, after.Header().Get("Idempotent-Replayed"))
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+}
+
+func TestIdempotentFailureNotCached(t *testing.T) {
+	s, calls := idempotentRoute(t, func(c *gin.Context, call int64) {
+		if call == 1 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "try again"})
+			return
+		}
+		created(c, call)
+	})
+	failed := s.do(http.MethodPost, "/things", `{"amount":10}`, idempotencyHeader, "k-1")
+	retry := s.do(http.MethodPost, "/things", `{"amount":10}`, idempotencyHeader, "k-1")
+	if failed.Code != http.StatusInternalServerError {
+		t.Fatalf("first attempt answered %d, want 500", failed.Code)
+	}
+	if retry.Code != http.StatusCreated || retry.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry after a failure answered %d (replayed %q), want a fresh 201", retry.Code, retry.Header().Get("Idempotent-Replayed"))
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want 2", n)
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quickgeo/cms-official-go/internal/listquery"
+	"github.com/quickgeo/cms-official-go/internal/responses"
+	"gorm.io/gorm"
+)
+
+// findPage loads one page of query into dest using the list parameters of
+// the request. On failure it has already answered and returns false.
+func findPage(c *gin.Context, spec listquery.Spec, query *gorm.DB, dest interface{}, failure string) (responses.Pagination, bool) {
+	lq, err := listquery.Parse(c, spec)
+	if err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, err.Error())
+		return responses.Pagination{}, false
+	}
+	page, err := lq.Find(query, dest)
+	if errors.Is(err, listquery.ErrStaleCursor) {
+		responses.JSON(c, http.StatusBadRequest, false, nil, err.Error())
+		return page, false
+	}
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, failure)
+		return page, false
+	}
+	return page, true
+}
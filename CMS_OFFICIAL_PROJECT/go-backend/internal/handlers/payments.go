@@ -3,63 +3,20 @@ package handlers
 import (
 	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/quickgeo/cms-official-go/internal/listquery"
 	"github.com/quickgeo/cms-official-go/internal/model"
 	"github.com/quickgeo/cms-official-go/internal/responses"
-	authUtils "github.com/quickgeo/cms-official-go/internal/utilities/auth_page_app"
 	utils "github.com/quickgeo/cms-official-go/internal/utilities/payments_page_app"
 )
 
-// Helper: Accessible Projects Logic
-// In Django: _accessible_projects(user)
-// Mirrors the same logic implemented in Dashboard but possibly reused here.
-func (h *Handler) getAccessibleProjects(userID uint) ([]model.Project, error) {
-	var user model.User
-	if err := h.db.Preload("Profile").First(&user, userID).Error; err != nil {
-		return nil, err
-	}
-
-	userRole := "builder"
-	if user.Profile != nil && user.Profile.UserType != "" {
-		userRole = authUtils.GetUserRole(user.Profile.UserType)
-	}
-
-	var projects []model.Project
-	query := h.db.Model(&model.Project{})
-
-	switch userRole {
-	case "builder", "organization":
-		query = query.Where("project_owner_id = ?", user.ID)
-	case "supervisor":
-		var supervisor model.Supervisor
-		if err := h.db.Where("supervisor_user_id = ?", user.ID).First(&supervisor).Error; err == nil {
-			query = query.Where("project_assigned_supervisor_id = ?", supervisor.ID)
-		} else {
-			query = query.Where("1 = 0")
-		}
-	default:
-		// Fallback for customer is generally "no access" to management views,
-		// but Django code redirects customer away.
-		// If specific logic needed for other roles, implement here.
-		// For now returning empty if unknown role.
-		return nil, nil // API typically handles unauthorized separately
-	}
-
-	if err := query.Order("project_name asc").Find(&projects).Error; err != nil {
-		return nil, err
-	}
-	return projects, nil
-}
-
 // PaymentsProjectsList returns accessible projects for dropdowns.
 func (h *Handler) PaymentsProjectsList(c *gin.Context) {
 	// Mock auth
-	userID := uint(1)
-	projects, err := h.getAccessibleProjects(userID)
+	userID := currentUser(c)
+	projects, err := h.projects.Accessible(c.Request.Context(), userID)
 	if err != nil {
 		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load projects")
 		return
@@ -78,12 +35,23 @@ func (h *Handler) PaymentsProjectsList(c *gin.Context) {
 	responses.JSON(c, http.StatusOK, true, payload, "Projects loaded")
 }
 
+// PaymentChoicesAPI serves the payment type, stage and method labels.
+func (h *Handler) PaymentChoicesAPI(c *gin.Context) {
+	h.serveReference(c, "payment-choices", nil, "Choices loaded", "Failed to load choices", func() (interface{}, error) {
+		return gin.H{
+			"payment_types":   utils.PaymentTypes,
+			"payment_stages":  utils.UnitPaymentStages,
+			"payment_methods": utils.UnitPaymentMethods,
+		}, nil
+	})
+}
+
 // Helper: Units Map Logic
 // Django: _build_units_map(projects, allowed_layouts)
-func (h *Handler) buildUnitsMap(layoutTypes []string) map[string][]map[string]interface{} {
+func (h *Handler) buildUnitsMap(c *gin.Context, layoutTypes []string) map[string][]map[string]interface{} {
 	// 1. Get Accessible Projects
-	userID := uint(1)
-	projects, _ := h.getAccessibleProjects(userID)
+	userID := currentUser(c)
+	projects, _ := h.projects.Accessible(c.Request.Context(), userID)
 
 	// 2. Filter by layout
 	var projectIDs []uint
@@ -107,7 +75,7 @@ func (h *Handler) buildUnitsMap(layoutTypes []string) map[string][]map[string]in
 	// 3. Fetch Units
 	var units []model.ProjectUnit
 	// Joins Block -> Project
-	err := h.db.Joins("JOIN construction_projectblock ON construction_projectblock.id = construction_projectunit.project_unit_block_id").
+	err := h.dbFor(c).Joins("JOIN construction_projectblock ON construction_projectblock.id = construction_projectunit.project_unit_block_id").
 		Joins("JOIN construction_project ON construction_project.id = construction_projectblock.project_block_project_id").
 		Where("construction_project.id IN ?", projectIDs).
 		Preload("ProjectUnitBlock"). // Need block name
@@ -127,7 +95,7 @@ func (h *Handler) buildUnitsMap(layoutTypes []string) map[string][]map[string]in
 
 	// Fetch blocks manually to be safe on relations
 	var blocks []model.ProjectBlock
-	h.db.Where("project_block_project_id IN ?", projectIDs).Find(&blocks)
+	h.dbFor(c).Where("project_block_project_id IN ?", projectIDs).Find(&blocks)
 	blockMap := make(map[uint]model.ProjectBlock)
 	for _, b := range blocks {
 		blockMap[b.ID] = b
@@ -160,185 +128,218 @@ func (h *Handler) buildUnitsMap(layoutTypes []string) map[string][]map[string]in
 	return mapped
 }
 
-// ProjectPaymentsAPI handles GET (list) and POST (create) project payments.
-func (h *Handler) ProjectPaymentsAPI(c *gin.Context) {
-	switch c.Request.Method {
-	case "GET":
-		userID := uint(1)
-		projects, err := h.getAccessibleProjects(userID)
-		if err != nil {
-			responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load projects")
-			return
-		}
-		projectIDs := []uint{}
-		for _, p := range projects {
-			projectIDs = append(projectIDs, p.ID)
-		}
+var projectPaymentListSpec = listquery.Spec{
+	Table:       "project_payments",
+	Sorts:       map[string]string{"date": "project_payment_date", "amount": "project_payment_amount", "created_at": "project_payment_created_at"},
+	DefaultSort: "-date",
+	Filters:     map[string]string{"project_id": "project_payment_project_id", "type": "project_payment_type"},
+	DateColumn:  "project_payment_date",
+}
 
-		var payments []model.ProjectPayment
-		if err := h.db.Preload("Project").Where("project_payment_project_id IN ?", projectIDs).Order("project_payment_date desc, id desc").Limit(50).Find(&payments).Error; err != nil {
-			responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load payments")
-			return
-		}
-		responses.JSON(c, http.StatusOK, true, payments, "Payments loaded")
+// unitName names a unit by its label, or by its number when it has none.
+func unitName(u model.ProjectUnit) string {
+	if u.ProjectUnitLabel != "" {
+		return u.ProjectUnitLabel
+	}
+	if u.ID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("#%d", u.ProjectUnitNumber)
+}
 
-	case "POST":
-		var req utils.CreateProjectPaymentRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
-			return
-		}
+var projectPaymentColumns = []column[model.ProjectPayment]{
+	{"Date", func(p model.ProjectPayment) interface{} { return p.Date }},
+	{"Project", func(p model.ProjectPayment) interface{} { return p.Project.ProjectName }},
+	{"Type", func(p model.ProjectPayment) interface{} { return choiceLabel(utils.PaymentTypes, p.Type) }},
+	{"Amount", func(p model.ProjectPayment) interface{} { return p.Amount }},
+	{"Description", func(p model.ProjectPayment) interface{} { return p.Description }},
+}
 
-		if req.Date.IsZero() {
-			req.Date = time.Now()
-		}
+// ListProjectPaymentsAPI lists payments recorded against accessible projects,
+// or exports them as a spreadsheet.
+func (h *Handler) ListProjectPaymentsAPI(c *gin.Context) {
+	userID := currentUser(c)
+	projects, err := h.projects.Accessible(c.Request.Context(), userID)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load projects")
+		return
+	}
+	projectIDs := []uint{}
+	for _, p := range projects {
+		projectIDs = append(projectIDs, p.ID)
+	}
 
-		payment := model.ProjectPayment{
-			ProjectID:   req.ProjectID,
-			Amount:      req.Amount,
-			Type:        req.Type,
-			Date:        req.Date,
-			Description: req.Description,
-			CreatedAt:   time.Now(),
-		}
+	query := h.dbFor(c).Preload("Project").Where("project_payment_project_id IN ?", projectIDs)
+	if exporting(c) {
+		exportList(c, projectPaymentListSpec, query, "project-payments", projectPaymentColumns)
+		return
+	}
+	var payments []model.ProjectPayment
+	page, ok := findPage(c, projectPaymentListSpec, query, &payments, "Failed to load payments")
+	if !ok {
+		return
+	}
+	responses.Page(c, http.StatusOK, payments, "Payments loaded", page)
+}
 
-		if err := h.db.Create(&payment).Error; err != nil {
-			responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to save payment")
-			return
-		}
-		responses.JSON(c, http.StatusOK, true, payment, "Payment saved")
+// CreateProjectPaymentAPI records a project payment.
+func (h *Handler) CreateProjectPaymentAPI(c *gin.Context) {
+	var req utils.CreateProjectPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
+		return
+	}
+
+	if !h.canAccessProject(c, req.ProjectID) {
+		return
+	}
+	payment, err := h.payments.CreateProjectPayment(c.Request.Context(), req)
+	if err != nil {
+		serviceFailure(c, err, "Failed to save payment")
+		return
 	}
+	responses.JSON(c, http.StatusOK, true, payment, "Payment saved")
 }
 
-// FlatPaymentsAPI handles GET/POST for flat payments.
-func (h *Handler) FlatPaymentsAPI(c *gin.Context) {
-	switch c.Request.Method {
-	case "GET":
-		// Return List + Units Lookup Map
-		// Query param project_id filter optional
-
-		// 1. Map units (for dropdowns)
-		unitsMap := h.buildUnitsMap([]string{"single_flat", "multi_flat"})
-
-		// 2. List Payments
-		userID := uint(1)
-		projects, _ := h.getAccessibleProjects(userID)
-		projectIDs := []uint{}
-		for _, p := range projects {
-			// Check if flat project
-			conf := strings.ToLower(p.ProjectFlatConfiguration)
-			if strings.Contains(conf, "flat") {
-				projectIDs = append(projectIDs, p.ID)
-			}
-		}
+var flatPaymentListSpec = listquery.Spec{
+	Table:       "flat_payments",
+	Sorts:       map[string]string{"date": "flat_payment_date", "amount": "flat_payment_amount", "created_at": "flat_payment_created_at"},
+	DefaultSort: "-date",
+	Filters: map[string]string{
+		"project_id": "flat_payment_project_id",
+		"unit_id":    "flat_payment_unit_id",
+		"stage":      "flat_payment_stage",
+		"method":     "flat_payment_method",
+	},
+	DateColumn: "flat_payment_date",
+}
 
-		query := h.db.Preload("Project").Preload("Unit").Where("flat_payment_project_id IN ?", projectIDs)
+var flatPaymentColumns = []column[model.FlatPayment]{
+	{"Date", func(p model.FlatPayment) interface{} { return p.Date }},
+	{"Project", func(p model.FlatPayment) interface{} { return p.Project.ProjectName }},
+	{"Unit", func(p model.FlatPayment) interface{} { return unitName(p.Unit) }},
+	{"Amount", func(p model.FlatPayment) interface{} { return p.Amount }},
+	{"Stage", func(p model.FlatPayment) interface{} { return choiceLabel(utils.UnitPaymentStages, p.Stage) }},
+	{"Method", func(p model.FlatPayment) interface{} { return choiceLabel(utils.UnitPaymentMethods, p.Method) }},
+	{"Reference", func(p model.FlatPayment) interface{} { return p.Reference }},
+	{"Remarks", func(p model.FlatPayment) interface{} { return p.Remarks }},
+}
 
-		filterID := c.Query("project_id")
-		if filterID != "" {
-			if id, err := strconv.ParseUint(filterID, 10, 64); err == nil {
-				query = query.Where("flat_payment_project_id = ?", id)
-			}
-		}
+// ListFlatPaymentsAPI lists flat payments with the units lookup map, or exports
+// them as a spreadsheet.
+func (h *Handler) ListFlatPaymentsAPI(c *gin.Context) {
+	// Return List + Units Lookup Map
+	// Query param project_id filter optional
 
-		var payments []model.FlatPayment
-		query.Order("flat_payment_date desc, id desc").Limit(50).Find(&payments)
+	// 1. List Payments; exports skip the units map
+	userID := currentUser(c)
+	projectIDs, _ := h.payments.FlatProjectIDs(c.Request.Context(), userID)
 
-		responses.JSON(c, http.StatusOK, true, gin.H{
-			"payments":  payments,
-			"units_map": unitsMap,
-		}, "Flat payments loaded")
+	query := h.dbFor(c).Preload("Project").Preload("Unit").Where("flat_payment_project_id IN ?", projectIDs)
+	if exporting(c) {
+		exportList(c, flatPaymentListSpec, query, "flat-payments", flatPaymentColumns)
+		return
+	}
 
-	case "POST":
-		var req utils.CreateUnitPaymentRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
-			return
-		}
+	// 2. Map units (for dropdowns)
+	unitsMap := h.buildUnitsMap(c, []string{"single_flat", "multi_flat"})
 
-		if req.Date.IsZero() {
-			req.Date = time.Now()
-		}
+	var payments []model.FlatPayment
+	page, ok := findPage(c, flatPaymentListSpec, query, &payments, "Failed to load flat payments")
+	if !ok {
+		return
+	}
 
-		payment := model.FlatPayment{
-			ProjectID: req.ProjectID,
-			UnitID:    req.UnitID, // Validation that Unit belongs to Project usually needed
-			Amount:    req.Amount,
-			Stage:     req.Stage,
-			Method:    req.Method,
-			Reference: req.Reference,
-			Remarks:   req.Remarks,
-			Date:      req.Date,
-			CreatedAt: time.Now(),
-		}
+	responses.Page(c, http.StatusOK, gin.H{
+		"payments":  payments,
+		"units_map": unitsMap,
+	}, "Flat payments loaded", page)
+}
 
-		if err := h.db.Create(&payment).Error; err != nil {
-			responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to save flat payment")
-			return
-		}
-		responses.JSON(c, http.StatusOK, true, payment, "Payment saved")
+// CreateFlatPaymentAPI records a payment against a flat.
+func (h *Handler) CreateFlatPaymentAPI(c *gin.Context) {
+	var req utils.CreateUnitPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
+		return
+	}
+
+	if !h.canAccessProject(c, req.ProjectID) {
+		return
+	}
+	payment, err := h.payments.CreateFlatPayment(c.Request.Context(), req)
+	if err != nil {
+		serviceFailure(c, err, "Failed to save flat payment")
+		return
 	}
+	responses.JSON(c, http.StatusOK, true, payment, "Payment saved")
 }
 
-// PlotPaymentsAPI handles GET/POST for plot payments.
-func (h *Handler) PlotPaymentsAPI(c *gin.Context) {
-	switch c.Request.Method {
-	case "GET":
-		unitsMap := h.buildUnitsMap([]string{"multi_plot"})
-
-		userID := uint(1)
-		projects, _ := h.getAccessibleProjects(userID)
-		projectIDs := []uint{}
-		for _, p := range projects {
-			if p.ProjectFlatConfiguration == "multi_plot" {
-				projectIDs = append(projectIDs, p.ID)
-			}
-		}
+var plotPaymentListSpec = listquery.Spec{
+	Table:       "plot_payments",
+	Sorts:       map[string]string{"date": "plot_payment_date", "amount": "plot_payment_amount", "created_at": "plot_payment_created_at"},
+	DefaultSort: "-date",
+	Filters: map[string]string{
+		"project_id": "plot_payment_project_id",
+		"unit_id":    "plot_payment_unit_id",
+		"stage":      "plot_payment_stage",
+		"method":     "plot_payment_method",
+	},
+	DateColumn: "plot_payment_date",
+}
 
-		query := h.db.Preload("Project").Preload("Unit").Where("plot_payment_project_id IN ?", projectIDs)
+var plotPaymentColumns = []column[model.PlotPayment]{
+	{"Date", func(p model.PlotPayment) interface{} { return p.Date }},
+	{"Project", func(p model.PlotPayment) interface{} { return p.Project.ProjectName }},
+	{"Unit", func(p model.PlotPayment) interface{} { return unitName(p.Unit) }},
+	{"Amount", func(p model.PlotPayment) interface{} { return p.Amount }},
+	{"Stage", func(p model.PlotPayment) interface{} { return choiceLabel(utils.UnitPaymentStages, p.Stage) }},
+	{"Method", func(p model.PlotPayment) interface{} { return choiceLabel(utils.UnitPaymentMethods, p.Method) }},
+	{"Reference", func(p model.PlotPayment) interface{} { return p.Reference }},
+	{"Remarks", func(p model.PlotPayment) interface{} { return p.Remarks }},
+}
 
-		filterID := c.Query("project_id")
-		if filterID != "" {
-			if id, err := strconv.ParseUint(filterID, 10, 64); err == nil {
-				query = query.Where("plot_payment_project_id = ?", id)
-			}
-		}
+// ListPlotPaymentsAPI lists plot payments with the units lookup map, or exports
+// them as a spreadsheet.
+func (h *Handler) ListPlotPaymentsAPI(c *gin.Context) {
+	userID := currentUser(c)
+	projectIDs, _ := h.payments.PlotProjectIDs(c.Request.Context(), userID)
 
-		var payments []model.PlotPayment
-		query.Order("plot_payment_date desc, id desc").Limit(50).Find(&payments)
+	query := h.dbFor(c).Preload("Project").Preload("Unit").Where("plot_payment_project_id IN ?", projectIDs)
+	if exporting(c) {
+		exportList(c, plotPaymentListSpec, query, "plot-payments", plotPaymentColumns)
+		return
+	}
 
-		responses.JSON(c, http.StatusOK, true, gin.H{
-			"payments":  payments,
-			"units_map": unitsMap,
-		}, "Plot payments loaded")
+	unitsMap := h.buildUnitsMap(c, []string{"multi_plot"})
 
-	case "POST":
-		var req utils.CreateUnitPaymentRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
-			return
-		}
+	var payments []model.PlotPayment
+	page, ok := findPage(c, plotPaymentListSpec, query, &payments, "Failed to load plot payments")
+	if !ok {
+		return
+	}
 
-		if req.Date.IsZero() {
-			req.Date = time.Now()
-		}
+	responses.Page(c, http.StatusOK, gin.H{
+		"payments":  payments,
+		"units_map": unitsMap,
+	}, "Plot payments loaded", page)
+}
 
-		payment := model.PlotPayment{
-			ProjectID: req.ProjectID,
-			UnitID:    req.UnitID,
-			Amount:    req.Amount,
-			Stage:     req.Stage,
-			Method:    req.Method,
-			Reference: req.Reference,
-			Remarks:   req.Remarks,
-			Date:      req.Date,
-			CreatedAt: time.Now(),
-		}
+// CreatePlotPaymentAPI records a payment against a plot.
+func (h *Handler) CreatePlotPaymentAPI(c *gin.Context) {
+	var req utils.CreateUnitPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
+		return
+	}
 
-		if err := h.db.Create(&payment).Error; err != nil {
-			responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to save plot payment")
-			return
-		}
-		responses.JSON(c, http.StatusOK, true, payment, "Payment saved")
+	if !h.canAccessProject(c, req.ProjectID) {
+		return
+	}
+	payment, err := h.payments.CreatePlotPayment(c.Request.Context(), req)
+	if err != nil {
+		serviceFailure(c, err, "Failed to save plot payment")
+		return
 	}
+	responses.JSON(c, http.StatusOK, true, payment, "Payment saved")
 }
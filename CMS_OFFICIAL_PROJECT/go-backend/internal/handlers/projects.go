@@ -9,16 +9,19 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/quickgeo/cms-official-go/internal/model"
+	"github.com/quickgeo/cms-official-go/internal/refdata"
 	"github.com/quickgeo/cms-official-go/internal/responses"
+	"github.com/quickgeo/cms-official-go/internal/services"
 	utils "github.com/quickgeo/cms-official-go/internal/utilities/projects_page_app"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // ensureDefaultMaterialItems ensures basic material items exist.
 // Mirrored from _ensure_default_material_items
-func (h *Handler) ensureDefaultMaterialItems() {
+func (h *Handler) ensureDefaultMaterialItems(c *gin.Context) {
 	var count int64
-	h.db.Model(&model.MaterialItem{}).Count(&count)
+	h.dbFor(c).Model(&model.MaterialItem{}).Count(&count)
 	if count > 0 {
 		return
 	}
@@ -30,13 +33,13 @@ func (h *Handler) ensureDefaultMaterialItems() {
 		{MaterialItemName: "sand", MaterialItemDisplayName: "Sand"},
 		// ... add others if strict parity needed, or rely on existing DB
 	}
-	h.db.Create(&items)
+	h.dbFor(c).Create(&items)
 }
 
 // ensureDefaultLaborWorkTypes ensures basic work types exist.
-func (h *Handler) ensureDefaultLaborWorkTypes() {
+func (h *Handler) ensureDefaultLaborWorkTypes(c *gin.Context) {
 	var count int64
-	h.db.Model(&model.LaborWorkType{}).Count(&count)
+	h.dbFor(c).Model(&model.LaborWorkType{}).Count(&count)
 	if count > 0 {
 		return
 	}
@@ -46,237 +49,276 @@ func (h *Handler) ensureDefaultLaborWorkTypes() {
 		{LaborWorkTypeName: "water_sanitary", LaborWorkTypeDescription: "Water and sanitary"},
 		// ...
 	}
-	h.db.Create(&items)
+	h.dbFor(c).Create(&items)
 }
 
-// ProjectsAPI handles CRUD for Projects.
-func (h *Handler) ProjectsAPI(c *gin.Context) {
-	// Auth ID (Mock)
-	userID := uint(1)
+// ListProjectsAPI lists the projects the user may access.
+func (h *Handler) ListProjectsAPI(c *gin.Context) {
+	userID := currentUser(c)
+	projects, err := h.projects.Accessible(c.Request.Context(), userID)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load projects")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, projects, "Projects loaded")
+}
 
-	switch c.Request.Method {
-	case "GET":
-		projects, err := h.getAccessibleProjects(userID)
-		if err != nil {
-			responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load projects")
-			return
-		}
-		responses.JSON(c, http.StatusOK, true, projects, "Projects loaded")
+// CreateProjectAPI creates a project owned by the user.
+func (h *Handler) CreateProjectAPI(c *gin.Context) {
+	userID := currentUser(c)
 
-	case "POST":
-		var req utils.CreateProjectRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
-			return
-		}
+	var req utils.CreateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
+		return
+	}
 
-		// Validation: Code usage
-		var count int64
-		h.db.Model(&model.Project{}).Where("project_code = ?", req.ProjectCode).Count(&count)
-		if count > 0 {
-			responses.JSON(c, http.StatusBadRequest, false, nil, "Project code already exists")
-			return
-		}
+	// Validation: Code usage
+	var count int64
+	h.dbFor(c).Model(&model.Project{}).Where("project_code = ?", req.ProjectCode).Count(&count)
+	if count > 0 {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Project code already exists")
+		return
+	}
 
-		project := model.Project{
-			ProjectOwnerID:              &userID, // Assume creator is owner
-			ProjectCode:                 req.ProjectCode,
-			ProjectName:                 req.ProjectName,
-			ProjectLandAreaSquareFeet:   req.ProjectLandAreaSquareFeet,
-			ProjectConstructionType:     req.ProjectConstructionType,
-			ProjectFlatConfiguration:    req.ProjectFlatConfiguration,
-			ProjectBlockCount:           req.ProjectBlockCount,
-			ProjectLandAddress:          req.ProjectLandAddress,
-			ProjectBudget:               req.ProjectBudget,
-			ProjectDurationMonths:       req.ProjectDurationMonths,
-			ProjectStatus:               req.ProjectStatus,
-			ProjectAssignedSupervisorID: req.ProjectAssignedSupervisorID,
-			ProjectAssignedCustomerID:   req.ProjectAssignedCustomerID,
-			ProjectCreatedAt:            time.Now(),
-		}
-		if project.ProjectStatus == "" {
-			project.ProjectStatus = "Active"
-		}
+	project := model.Project{
+		ProjectOwnerID:              &userID, // Assume creator is owner
+		ProjectCode:                 req.ProjectCode,
+		ProjectName:                 req.ProjectName,
+		ProjectLandAreaSquareFeet:   req.ProjectLandAreaSquareFeet,
+		ProjectConstructionType:     req.ProjectConstructionType,
+		ProjectFlatConfiguration:    req.ProjectFlatConfiguration,
+		ProjectBlockCount:           req.ProjectBlockCount,
+		ProjectLandAddress:          req.ProjectLandAddress,
+		ProjectBudget:               req.ProjectBudget,
+		ProjectDurationMonths:       req.ProjectDurationMonths,
+		ProjectStatus:               req.ProjectStatus,
+		ProjectAssignedSupervisorID: req.ProjectAssignedSupervisorID,
+		ProjectAssignedCustomerID:   req.ProjectAssignedCustomerID,
+		ProjectCreatedAt:            time.Now(),
+	}
+	if project.ProjectStatus == "" {
+		project.ProjectStatus = "Active"
+	}
 
-		if err := h.db.Create(&project).Error; err != nil {
-			responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to create project")
-			return
-		}
+	if err := h.dbFor(c).Create(&project).Error; err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to create project")
+		return
+	}
 
-		// Ensure defaults
-		h.ensureDefaultMaterialItems()
-		h.ensureDefaultLaborWorkTypes()
+	// Ensure defaults
+	h.ensureDefaultMaterialItems(c)
+	h.ensureDefaultLaborWorkTypes(c)
 
-		responses.JSON(c, http.StatusCreated, true, project, "Project created")
-	}
+	responses.JSON(c, http.StatusCreated, true, project, "Project created")
 }
 
-// ProjectDetailAPI handles GET/PUT/DELETE for a single project.
-func (h *Handler) ProjectDetailAPI(c *gin.Context) {
+// loadProject resolves the :id project for the user, answering 404 or 403
+// itself when it cannot.
+func (h *Handler) loadProject(c *gin.Context) (model.Project, bool) {
 	idStr := c.Param("id")
 	id, _ := strconv.ParseUint(idStr, 10, 64)
-	userID := uint(1) // Mock
+	userID := currentUser(c)
 
 	var project model.Project
-	if err := h.db.First(&project, id).Error; err != nil {
+	if err := h.dbFor(c).First(&project, id).Error; err != nil {
 		responses.JSON(c, http.StatusNotFound, false, nil, "Project not found")
-		return
+		return project, false
 	}
 
 	// Access Check (Simplified for parity)
-	// getAccessibleProjects returns list, we can check if ID in list
-	accessible, _ := h.getAccessibleProjects(userID)
-	found := false
-	for _, p := range accessible {
-		if p.ID == uint(id) {
-			found = true
-			break
-		}
-	}
-	if !found {
+	if found, _ := h.projects.CanAccess(c.Request.Context(), userID, project.ID); !found {
 		responses.JSON(c, http.StatusForbidden, false, nil, "Access denied")
+		return project, false
+	}
+	return project, true
+}
+
+// canAccessProject checks the caller may access projectID before a handler
+// reads or writes its blocks, units, payments, expenses or stock. It answers
+// 404 when they may not, so other organizations' projects stay invisible.
+func (h *Handler) canAccessProject(c *gin.Context, projectID uint) bool {
+	allowed, err := h.projects.CanAccess(c.Request.Context(), currentUser(c), projectID)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Error checking access")
+		return false
+	}
+	if !allowed {
+		responses.JSON(c, http.StatusNotFound, false, nil, "Project not found")
+		return false
+	}
+	return true
+}
+
+// ProjectDetailAPI returns a single project with its ETag.
+func (h *Handler) ProjectDetailAPI(c *gin.Context) {
+	project, ok := h.loadProject(c)
+	if !ok {
 		return
 	}
+	c.Header("ETag", entityETag("project", project.ID, project.ProjectVersion))
+	responses.JSON(c, http.StatusOK, true, project, "Project loaded")
+}
 
-	switch c.Request.Method {
-	case "GET":
-		responses.JSON(c, http.StatusOK, true, project, "Project loaded")
+// UpdateProjectAPI saves a project edit guarded by If-Match.
+func (h *Handler) UpdateProjectAPI(c *gin.Context) {
+	project, ok := h.loadProject(c)
+	if !ok {
+		return
+	}
+	etag := entityETag("project", project.ID, project.ProjectVersion)
+	if ifMatchFails(c, etag) {
+		preconditionFailed(c, etag, project)
+		return
+	}
 
-	case "PUT", "PATCH":
-		var req utils.UpdateProjectRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
-			return
-		}
+	var req utils.UpdateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
+		return
+	}
 
-		// On Hold Check
-		if project.ProjectStatus == "On Hold" && req.ProjectStatus == "On Hold" {
-			// If trying to change immutable fields while On Hold
-			// Simplified: Allow only status change if On Hold
-			// For now, mirroring strictly requires complex checks.
-			// We will skip complex immutable check for MVP unless requested.
-		}
+	// On Hold Check
+	if project.ProjectStatus == "On Hold" && req.ProjectStatus == "On Hold" {
+		// If trying to change immutable fields while On Hold
+		// Simplified: Allow only status change if On Hold
+		// For now, mirroring strictly requires complex checks.
+		// We will skip complex immutable check for MVP unless requested.
+	}
 
-		project.ProjectName = req.ProjectName
-		project.ProjectStatus = req.ProjectStatus
-		project.ProjectBudget = req.ProjectBudget
-		// ... Update other fields
+	project.ProjectName = req.ProjectName
+	project.ProjectStatus = req.ProjectStatus
+	project.ProjectBudget = req.ProjectBudget
+	// ... Update other fields
 
-		if err := h.db.Save(&project).Error; err != nil {
-			responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to update")
-			return
+	expected := project.ProjectVersion
+	project.ProjectVersion++
+	project.ProjectUpdatedAt = time.Now()
+	saved, err := services.SaveVersioned(h.dbFor(c), &project, "project_version", expected)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to update")
+		return
+	}
+	if !saved {
+		var current model.Project
+		h.dbFor(c).First(&current, project.ID)
+		versionConflict(c, entityETag("project", current.ID, current.ProjectVersion), current)
+		return
+	}
+	c.Header("ETag", entityETag("project", project.ID, project.ProjectVersion))
+	responses.JSON(c, http.StatusOK, true, project, "Project updated")
+}
+
+// DeleteProjectAPI deletes a project with its dependants.
+func (h *Handler) DeleteProjectAPI(c *gin.Context) {
+	project, ok := h.loadProject(c)
+	if !ok {
+		return
+	}
+
+	// Payments, expenses, stock and sold units block the delete unless the
+	// caller confirms the cascade explicitly.
+	var plan utils.DeletionPlan
+	refused := false
+	err := h.dbFor(c).Transaction(func(tx *gorm.DB) error {
+		var err error
+		plan, err = h.planProjectDeletion(tx, project.ID)
+		if err != nil {
+			return err
 		}
-		responses.JSON(c, http.StatusOK, true, project, "Project updated")
-
-	case "DELETE":
-		// Check for expenses/payments
-		// We used Preload/Joins in model relations? No, need to query.
-		// relationships added in model/project_models.go just now.
-
-		// Manually check counts
-		var expC, payC int64
-		// h.db.Model(&project).Association("ManpowerExpenses").Count()
-		// Gorm Association count is best
-
-		h.db.Model(&model.ManpowerExpense{}).Where("manpower_expense_project_id = ?", project.ID).Count(&expC)
-		if expC > 0 {
-			responses.JSON(c, http.StatusBadRequest, false, nil, "Cannot delete project with existing expenses")
-			return
+		if !plan.Safe && !cascadeConfirmed(c.Query("cascade")) {
+			refused = true
+			return nil
 		}
+		return cascadeDeleteProject(tx, project.ID)
+	})
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to delete")
+		return
+	}
+	if refused {
+		responses.JSON(c, http.StatusConflict, false, plan, "Cannot delete project with existing payments, expenses, stock or sales; retry with cascade=true to delete them too")
+		return
+	}
+	responses.JSON(c, http.StatusNoContent, true, nil, "Project deleted")
+}
 
-		h.db.Model(&model.ProjectPayment{}).Where("project_payment_project_id = ?", project.ID).Count(&payC)
-		if payC > 0 {
-			responses.JSON(c, http.StatusBadRequest, false, nil, "Cannot delete project with existing payments")
-			return
-		}
+// ProjectDeletionPlanAPI reports every record a project delete would remove.
+func (h *Handler) ProjectDeletionPlanAPI(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid project id")
+		return
+	}
 
-		if err := h.db.Delete(&project).Error; err != nil {
-			responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to delete")
-			return
-		}
-		responses.JSON(c, http.StatusNoContent, true, nil, "Project deleted")
+	var project model.Project
+	if err := h.dbFor(c).First(&project, id).Error; err != nil {
+		responses.JSON(c, http.StatusNotFound, false, nil, "Project not found")
+		return
+	}
+
+	plan, err := h.planProjectDeletion(h.dbFor(c), project.ID)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to build deletion plan")
+		return
 	}
+	responses.JSON(c, http.StatusOK, true, plan, "Deletion plan loaded")
 }
 
 // MultiFlatProjectsAPI mirrors multi_flat_projects
 func (h *Handler) MultiFlatProjectsAPI(c *gin.Context) {
-	userID := uint(1)
-	projects, _ := h.getAccessibleProjects(userID)
-
-	filterType := strings.ToLower(c.Query("type"))
-	var filtered []model.Project
+	userID := currentUser(c)
+	scope, err := h.projects.Scope(c.Request.Context(), userID)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load projects")
+		return
+	}
 
-	switch filterType {
+	configurations := []string{"multi_flat"}
+	switch strings.ToLower(c.Query("type")) {
 	case "plot":
-		for _, p := range projects {
-			if strings.ToLower(p.ProjectFlatConfiguration) == "multi_plot" {
-				filtered = append(filtered, p)
-			}
-		}
+		configurations = []string{"multi_plot"}
 	case "all":
-		for _, p := range projects {
-			conf := strings.ToLower(p.ProjectFlatConfiguration)
-			if conf == "multi_flat" || conf == "multi_plot" {
-				filtered = append(filtered, p)
-			}
-		}
-	default:
-		for _, p := range projects {
-			if strings.ToLower(p.ProjectFlatConfiguration) == "multi_flat" {
-				filtered = append(filtered, p)
-			}
-		}
+		configurations = []string{"multi_flat", "multi_plot"}
 	}
 
-	// Calculate counts (unit status counts)
-	type ProjectStats struct {
-		ID uint `json:"id"`
-		// ... all fields from Django API
-		ProjectCode    string `json:"project_code"`
-		ProjectName    string `json:"project_name"`
-		ProjectStatus  string `json:"project_status"`
-		ProjectBudget  string `json:"project_budget"`
-		BlockCount     uint   `json:"project_block_count"`
-		TotalUnits     int    `json:"total_units"`
-		SoldUnits      int    `json:"sold_units"`
-		BookedUnits    int    `json:"booked_units"`
-		HoldUnits      int    `json:"hold_units"`
-		AvailableUnits int    `json:"available_units"`
-	}
-
-	var results []ProjectStats
-
-	for _, p := range filtered {
-		var units []model.ProjectUnit
-		// Join through blocks
-		h.db.Table("construction_projectunit").
-			Joins("JOIN construction_projectblock ON construction_projectblock.id = construction_projectunit.project_unit_block_id").
-			Where("construction_projectblock.project_block_project_id = ?", p.ID).
-			Find(&units)
-
-		stats := ProjectStats{
-			ID:            p.ID,
-			ProjectCode:   p.ProjectCode,
-			ProjectName:   p.ProjectName,
-			ProjectStatus: p.ProjectStatus,
-			ProjectBudget: fmt.Sprintf("%.2f", p.ProjectBudget),
-			BlockCount:    p.ProjectBlockCount,
-			TotalUnits:    len(units),
-		}
+	var filtered []model.Project
+	if err := h.dbFor(c).Model(&model.Project{}).Scopes(scope).
+		Where("LOWER(project_flat_configuration) IN ?", configurations).
+		Order("project_name asc").
+		Find(&filtered).Error; err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load projects")
+		return
+	}
 
-		for _, u := range units {
-			switch strings.ToLower(u.ProjectUnitStatus) {
-			case "sold":
-				stats.SoldUnits++
-			case "booked":
-				stats.BookedUnits++
-			case "hold":
-				stats.HoldUnits++
-			default:
-				stats.AvailableUnits++
-			}
+	projectIDs := make([]uint, len(filtered))
+	for i, p := range filtered {
+		projectIDs[i] = p.ID
+	}
+
+	// Unit counts come from the maintained project summaries.
+	summaries, err := h.projectSummaries(c, projectIDs)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to count units")
+		return
+	}
+
+	results := make([]utils.MultiFlatProjectStats, len(filtered))
+	for i, p := range filtered {
+		summary := summaries[p.ID]
+		results[i] = utils.MultiFlatProjectStats{
+			ID:             p.ID,
+			ProjectCode:    p.ProjectCode,
+			ProjectName:    p.ProjectName,
+			ProjectStatus:  p.ProjectStatus,
+			ProjectBudget:  fmt.Sprintf("%.2f", p.ProjectBudget),
+			BlockCount:     p.ProjectBlockCount,
+			TotalUnits:     summary.TotalUnits,
+			SoldUnits:      summary.SoldUnits,
+			BookedUnits:    summary.BookedUnits,
+			HoldUnits:      summary.HoldUnits,
+			AvailableUnits: summary.AvailableUnits,
 		}
-		results = append(results, stats)
 	}
 
 	responses.JSON(c, http.StatusOK, true, gin.H{"projects": results}, "Projects loaded")
@@ -284,18 +326,15 @@ func (h *Handler) MultiFlatProjectsAPI(c *gin.Context) {
 
 // MultiFlatProjectGridAPI mirrors multi_flat_project_grid
 func (h *Handler) MultiFlatProjectGridAPI(c *gin.Context) {
-	code := c.Param("code") // Project Code or ID? Django uses code in URL but ID in some internal calls. Assuming code.
-	// Actually Django URL `path('multi-flat-grid/<str:project_code>/', ...)` uses code.
-
-	var project model.Project
-	if err := h.db.Where("project_code = ?", code).First(&project).Error; err != nil {
-		responses.JSON(c, http.StatusNotFound, false, nil, "Project not found")
+	// Django URL `path('multi-flat-grid/<str:project_code>/', ...)` uses code.
+	project, ok := h.projectByCode(c)
+	if !ok {
 		return
 	}
 
 	// Fetch Blocks & Units
 	var blocks []model.ProjectBlock
-	h.db.Preload("Units").Where("project_block_project_id = ?", project.ID).
+	h.dbFor(c).Preload("Units").Where("project_block_project_id = ?", project.ID).
 		Order("project_block_sequence asc, project_block_name asc").
 		Find(&blocks)
 
@@ -359,73 +398,8 @@ func (h *Handler) MultiFlatProjectGridAPI(c *gin.Context) {
 	}, "Grid loaded")
 }
 
-// CRMKanbanBoardAPI mirrors crm_kanban_board
-func (h *Handler) CRMKanbanBoardAPI(c *gin.Context) {
-	projectID := c.Query("project_id")
-	if projectID == "" {
-		responses.JSON(c, http.StatusBadRequest, false, nil, "project_id required")
-		return
-	}
-
-	// Fetch units and map to stages
-	// Stage logic: "visitor" (default), "active" (paid > 0 or booked), "completed" (sold or paid full)
-
-	var units []model.ProjectUnit
-	// Join Block -> Project
-	h.db.Joins("JOIN construction_projectblock ON construction_projectblock.id = construction_projectunit.project_unit_block_id").
-		Where("construction_projectblock.project_block_project_id = ?", projectID).
-		Preload("ProjectUnitBlock"). // Need block name
-		Find(&units)
-
-	columns := []map[string]interface{}{
-		{"key": "visitor", "label": "Visitor", "cards": []interface{}{}},
-		{"key": "active", "label": "Active", "cards": []interface{}{}},
-		{"key": "completed", "label": "Completed", "cards": []interface{}{}},
-	}
-
-	for _, u := range units {
-		stage := "visitor"
-		if u.ProjectUnitCRMStage != "" {
-			stage = u.ProjectUnitCRMStage
-		} else {
-			// Auto-derive
-			if strings.ToLower(u.ProjectUnitStatus) == "sold" {
-				stage = "completed"
-			} else if strings.ToLower(u.ProjectUnitStatus) == "booked" {
-				stage = "active"
-			}
-		}
-
-		// Validation check
-		if stage != "visitor" && stage != "active" && stage != "completed" {
-			stage = "visitor"
-		}
-
-		card := map[string]interface{}{
-			"id":         u.ID,
-			"unit_label": u.ProjectUnitLabel,
-			"buyer_name": u.ProjectUnitBuyerName,
-			"status":     u.ProjectUnitStatus,
-			"stage":      stage,
-		}
-
-		// Append to correct column
-		for i, col := range columns {
-			if col["key"] == stage {
-				cards := col["cards"].([]interface{})
-				cards = append(cards, card)
-				columns[i]["cards"] = cards
-			}
-		}
-	}
-
-	responses.JSON(c, http.StatusOK, true, gin.H{"columns": columns}, "Kanban loaded")
-}
-
 // CRMKanbanUpdateAPI handles drag-drop updates
 func (h *Handler) CRMKanbanUpdateAPI(c *gin.Context) {
-	unitID := c.Param("unit_id")
-
 	var req struct {
 		Stage string `json:"stage"`
 	}
@@ -434,69 +408,84 @@ func (h *Handler) CRMKanbanUpdateAPI(c *gin.Context) {
 		return
 	}
 
-	var unit model.ProjectUnit
-	if err := h.db.First(&unit, unitID).Error; err != nil {
-		responses.JSON(c, http.StatusNotFound, false, nil, "Unit not found")
+	unit, ok := h.loadUnit(c)
+	if !ok {
+		return
+	}
+	etag := entityETag("unit", unit.ID, unit.ProjectUnitVersion)
+	if ifMatchFails(c, etag) {
+		preconditionFailed(c, etag, unit)
 		return
 	}
 
 	unit.ProjectUnitCRMStage = strings.ToLower(req.Stage)
-	h.db.Save(&unit)
+	if !h.saveUnit(c, &unit, "Failed to update stage") {
+		return
+	}
+	c.Header("ETag", entityETag("unit", unit.ID, unit.ProjectUnitVersion))
 	responses.JSON(c, http.StatusOK, true, unit, "Stage updated")
 }
 
-// MultiFlatPresetsAPI mirrors multi_flat_presets
-func (h *Handler) MultiFlatPresetsAPI(c *gin.Context) {
-	code := c.Param("code")
+// projectByCode resolves the :code project, answering 404 when it is missing
+// or the caller may not access it.
+func (h *Handler) projectByCode(c *gin.Context) (model.Project, bool) {
 	var project model.Project
-	if err := h.db.Where("project_code = ?", code).First(&project).Error; err != nil {
+	if err := h.dbFor(c).Where("project_code = ?", c.Param("code")).First(&project).Error; err != nil {
 		responses.JSON(c, http.StatusNotFound, false, nil, "Project not found")
-		return
+		return project, false
 	}
+	return project, h.canAccessProject(c, project.ID)
+}
 
+// loadPreset returns the project's preset, creating an empty one on first use.
+func (h *Handler) loadPreset(c *gin.Context, projectID uint) (model.ProjectPreset, error) {
 	var preset model.ProjectPreset
-	// Try to find preset
-	err := h.db.Where("project_preset_project_id = ?", project.ID).First(&preset).Error
+	err := h.dbFor(c).Where("project_preset_project_id = ?", projectID).First(&preset).Error
 	if err != nil {
-		// Create if not exist
 		preset = model.ProjectPreset{
-			ProjectPresetProjectID: project.ID,
+			ProjectPresetProjectID: projectID,
 			ProjectPresetUpdatedAt: time.Now(),
 		}
-		h.db.Create(&preset)
-	}
-
-	switch c.Request.Method {
-	case "GET":
-		responses.JSON(c, http.StatusOK, true, preset, "Presets loaded")
-	case "POST":
-		// Update logic
-		// Simplified: accept JSON payload directly into JSON fields if structure matches
-		var req model.ProjectPreset
-		if err := c.ShouldBindJSON(&req); err == nil {
-			preset.ProjectPresetBHKOptions = req.ProjectPresetBHKOptions
-			preset.ProjectPresetFacingOptions = req.ProjectPresetFacingOptions
-			preset.ProjectPresetAreaOptions = req.ProjectPresetAreaOptions
-			preset.ProjectPresetUpdatedAt = time.Now()
-			h.db.Save(&preset)
-		}
-		responses.JSON(c, http.StatusOK, true, preset, "Presets updated")
+		err = h.dbFor(c).Create(&preset).Error
 	}
+	return preset, err
 }
 
-// CrmCustomersAPI list customers
-func (h *Handler) CrmCustomersAPI(c *gin.Context) {
-	var customers []model.Customer
-	h.db.Limit(100).Find(&customers) // Pagination needed ideally
-	responses.JSON(c, http.StatusOK, true, gin.H{"customers": customers}, "Customers loaded")
+// MultiFlatPresetsAPI mirrors multi_flat_presets
+func (h *Handler) MultiFlatPresetsAPI(c *gin.Context) {
+	project, ok := h.projectByCode(c)
+	if !ok {
+		return
+	}
+	key := fmt.Sprintf("presets:%d", project.ID)
+	h.serveReference(c, key, []string{refdata.ProjectPresets}, "Presets loaded", "Failed to load presets", func() (interface{}, error) {
+		return h.loadPreset(c, project.ID)
+	})
 }
 
-// CrmChannelPartnersAPI list partners
-func (h *Handler) CrmChannelPartnersAPI(c *gin.Context) {
-	var partners []model.ChannelPartner
-	h.db.Limit(100).Find(&partners)
-	responses.JSON(c, http.StatusOK, true, gin.H{"channel_partners": partners}, "Partners loaded")
+// UpdateMultiFlatPresetsAPI replaces the BHK, facing and area options.
+func (h *Handler) UpdateMultiFlatPresetsAPI(c *gin.Context) {
+	project, ok := h.projectByCode(c)
+	if !ok {
+		return
+	}
+	preset, err := h.loadPreset(c, project.ID)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load presets")
+		return
+	}
+	// Update logic
+	// Simplified: accept JSON payload directly into JSON fields if structure matches
+	var req model.ProjectPreset
+	if err := c.ShouldBindJSON(&req); err == nil {
+		preset.ProjectPresetBHKOptions = req.ProjectPresetBHKOptions
+		preset.ProjectPresetFacingOptions = req.ProjectPresetFacingOptions
+		preset.ProjectPresetAreaOptions = req.ProjectPresetAreaOptions
+		preset.ProjectPresetUpdatedAt = time.Now()
+		h.dbFor(c).Save(&preset)
+	}
+	responses.JSON(c, http.StatusOK, true, preset, "Presets updated")
 }
 
-// Ensure ensureDefaultMaterialItems is not dead code (it's called in ProjectsAPI)
+// Ensure ensureDefaultMaterialItems is not dead code (it's called in CreateProjectAPI)
 var _ = datatypes.JSON{}
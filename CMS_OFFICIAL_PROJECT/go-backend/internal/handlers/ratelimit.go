@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quickgeo/cms-official-go/internal/responses"
+)
+
+// defaultRatePerMinute is each client's shared budget unless
+// CMS_RATE_LIMIT_PER_MINUTE says otherwise; 0 turns rate limiting off.
+const defaultRatePerMinute = 600
+
+// rateLimited counts requests answered 429, for /metrics.
+var rateLimited atomic.Uint64
+
+func ratePerMinute() int {
+	if value, err := strconv.Atoi(os.Getenv("CMS_RATE_LIMIT_PER_MINUTE")); err == nil && value >= 0 {
+		return value
+	}
+	return defaultRatePerMinute
+}
+
+// exportBudget is shared by the list exports, which read whole tables.
+var exportBudget = budget{name: "exports", perMinute: 10, burst: 5}
+
+// budget is a token bucket: perMinute tokens trickle back in, up to burst.
+type budget struct {
+	name      string
+	perMinute float64
+	burst     float64
+}
+
+// rateBudgets gives the expensive handlers and the sign-in routes a budget
+// of their own; every other route draws on the client's default budget.
+// v1 and v2 routes serving the same handler share one bucket.
+func (h *Handler) rateBudgets() map[string]budget {
+	budgets := make(map[string]budget)
+	set := func(b budget, handlers ...gin.HandlerFunc) {
+		for _, handler := range handlers {
+			budgets[Route{Handlers: []gin.HandlerFunc{handler}}.handlerName()] = b
+		}
+	}
+	set(budget{name: "sign-in", perMinute: 10, burst: 5}, h.LoginView, h.RegisterView, h.AcceptInviteView)
+	// Loads every project with its blocks and units.
+	set(budget{name: "projects", perMinute: 60, burst: 10}, h.ListProjectsAPI)
+	set(budget{name: "attendance-stats", perMinute: 60, burst: 10}, h.getAttendanceStats)
+	set(budget{name: "search", perMinute: 120, burst: 20}, h.SearchAPI, h.CRMCustomers, h.CRMChannelPartners)
+	// Stores the file and decodes images for a thumbnail.
+	set(budget{name: "uploads", perMinute: 30, burst: 10}, h.uploadProjectPaymentDocument, h.uploadFlatPaymentReceipt,
+		h.uploadPlotPaymentReceipt, h.uploadAvatar, h.uploadLetterheadLogo)
+	// Renders and stores a PDF.
+	set(budget{name: "receipts", perMinute: 30, burst: 10}, h.issueProjectPaymentReceipt, h.issueFlatPaymentReceipt,
+		h.issuePlotPaymentReceipt)
+	return budgets
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+	// refill is how long an empty bucket takes to fill up again.
+	refill time.Duration
+}
+
+// rateLimiter keeps one bucket per client and budget in memory.
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// take spends a token from key's bucket, returning how long to wait when
+// there is none.
+func (l *rateLimiter) take(key string, b budget, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	perSecond := b.perMinute / 60
+	current, ok := l.buckets[key]
+	if !ok {
+		current = &bucket{tokens: b.burst, at: now, refill: time.Duration(b.burst / perSecond * float64(time.Second))}
+		l.buckets[key] = current
+	}
+	current.tokens = math.Min(b.burst, current.tokens+now.Sub(current.at).Seconds()*perSecond)
+	current.at = now
+	if current.tokens >= 1 {
+		current.tokens--
+		return 0
+	}
+	return time.Duration((1 - current.tokens) / perSecond * float64(time.Second))
+}
+
+// sweep drops, once a minute, the buckets that have filled up again; a new
+// bucket starts full, so forgetting them changes nothing.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	l.swept = now
+	for key, current := range l.buckets {
+		if now.Sub(current.at) >= current.refill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// rateClient names who a request is charged to: the API key, the signed-in
+// user or, for everyone else, the client IP. That IP comes from a forwarded
+// header only behind a trusted proxy (TrustProxies), so a caller cannot get
+// a fresh bucket by sending a new X-Forwarded-For.
+func rateClient(c *gin.Context) string {
+	if keyID := c.GetUint(apiKeyKey); keyID != 0 {
+		return "key:" + strconv.FormatUint(uint64(keyID), 10)
+	}
+	if c.GetUint(sessionKey) != 0 {
+		return "user:" + strconv.FormatUint(uint64(currentUser(c)), 10)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// rateLimit returns the limiter for each route, or nil when rate limiting is
+// off. It runs after identify so requests are charged to their caller. A
+// request over budget is answered 429 with Retry-After. Spreadsheet exports
+// of the lists documented as exportable draw on exportBudget instead.
+func (h *Handler) rateLimit() func(Route) gin.HandlerFunc {
+	perMinute := ratePerMinute()
+	if perMinute == 0 {
+		return nil
+	}
+	fallback := budget{name: "default", perMinute: float64(perMinute), burst: float64(max(perMinute/5, 1))}
+	budgets := h.rateBudgets()
+	specs := h.apiSpecs()
+	limiter := &rateLimiter{buckets: make(map[string]*bucket)}
+	return func(route Route) gin.HandlerFunc {
+		b, ok := budgets[route.handlerName()]
+		if !ok {
+			b = fallback
+		}
+		exportable := specs[route.handlerName()].Export
+		return func(c *gin.Context) {
+			spend := b
+			if exportable && exporting(c) {
+				spend = exportBudget
+			}
+			wait := limiter.take(spend.name+" "+rateClient(c), spend, time.Now())
+			if wait > 0 {
+				rateLimited.Add(1)
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				responses.JSON(c, http.StatusTooManyRequests, false, nil, "Too many requests; try again in a moment")
+				c.Abort()
+				return
+			}
+			c.Next()
+		}
+	}
+}
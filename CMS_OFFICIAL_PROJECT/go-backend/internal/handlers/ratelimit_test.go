@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterTake(t *testing.T) {
+	// One token a second, three at most.
+	b := budget{name: "test", perMinute: 60, burst: 3}
+	start := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	limiter := &rateLimiter{buckets: make(map[string]*bucket), swept: start}
+
+	steps := []struct {
+		name string
+		key  string
+		at   time.Duration
+		want time.Duration
+	}{
+		{"burst 1", "ip:a", 0, 0},
+		{"burst 2", "ip:a", 0, 0},
+		{"burst 3", "ip:a", 0, 0},
+		{"burst exhausted", "ip:a", 0, time.Second},
+		{"other key has its own bucket", "ip:b", 0, 0},
+		{"half a token back", "ip:a", 500 * time.Millisecond, 500 * time.Millisecond},
+		{"one and a half tokens back", "ip:a", 1500 * time.Millisecond, 0},
+		{"half a token left", "ip:a", 1500 * time.Millisecond, 500 * time.Millisecond},
+		{"refilled after the interval", "ip:a", 10 * time.Second, 0},
+		{"refill stops at burst 2", "ip:a", 10 * time.Second, 0},
+		{"refill stops at burst 3", "ip:a", 10 * time.Second, 0},
+		{"empty again", "ip:a", 10 * time.Second, time.Second},
+		{"other key still has tokens", "ip:b", 10 * time.Second, 0},
+	}
+	for _, step := range steps {
+		if got := limiter.take(step.key, b, start.Add(step.at)); got != step.want {
+			t.Errorf("%s: take(%s) at +%s waits %s, want %s", step.name, step.key, step.at, got, step.want)
+		}
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	b := budget{name: "test", perMinute: 60, burst: 3}
+	start := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	limiter := &rateLimiter{buckets: make(map[string]*bucket), swept: start}
+
+	limiter.take("ip:idle", b, start)
+	limiter.take("ip:busy", b, start.Add(59*time.Second))
+	limiter.take("ip:busy", b, start.Add(time.Minute))
+	if _, ok := limiter.buckets["ip:idle"]; ok {
+		t.Error("a bucket that has filled up again is kept after a minute")
+	}
+	if _, ok := limiter.buckets["ip:busy"]; !ok {
+		t.Error("a bucket still refilling was dropped")
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Route is one endpoint of the API surface.
+type Route struct {
+	Method   string
+	Path     string
+	Handlers []gin.HandlerFunc
+	// Successor is the v2 path that replaces a deprecated v1 route.
+	Successor string
+}
+
+// handlerName identifies the final handler so v1 routes can be matched to
+// the v2 route serving the same handler.
+func (r Route) handlerName() string {
+	if len(r.Handlers) == 0 {
+		return ""
+	}
+	return runtime.FuncForPC(reflect.ValueOf(r.Handlers[len(r.Handlers)-1]).Pointer()).Name()
+}
+
+// routeTable collects routes before they reach Gin, so clashes are reported
+// as one startup error instead of Gin's panic on the first one.
+type routeTable struct {
+	prefix string
+	routes *[]Route
+}
+
+func newRouteTable(prefix string) routeTable {
+	return routeTable{prefix: prefix, routes: &[]Route{}}
+}
+
+func (t routeTable) group(path string) routeTable {
+	return routeTable{prefix: t.prefix + path, routes: t.routes}
+}
+
+func (t routeTable) handle(method, path string, handlers ...gin.HandlerFunc) {
+	*t.routes = append(*t.routes, Route{Method: method, Path: t.prefix + path, Handlers: handlers})
+}
+
+func (t routeTable) GET(path string, handlers ...gin.HandlerFunc) {
+	t.handle(http.MethodGet, path, handlers...)
+}
+
+func (t routeTable) POST(path string, handlers ...gin.HandlerFunc) {
+	t.handle(http.MethodPost, path, handlers...)
+}
+
+func (t routeTable) PUT(path string, handlers ...gin.HandlerFunc) {
+	t.handle(http.MethodPut, path, handlers...)
+}
+
+func (t routeTable) PATCH(path string, handlers ...gin.HandlerFunc) {
+	t.handle(http.MethodPatch, path, handlers...)
+}
+
+func (t routeTable) DELETE(path string, handlers ...gin.HandlerFunc) {
+	t.handle(http.MethodDelete, path, handlers...)
+}
+
+// checkRoutes reports every route registered twice for the same method and
+// every path parameter whose name clashes with another route's parameter at
+// the same position, both of which Gin refuses at startup.
+func checkRoutes(routes []Route) error {
+	var problems []string
+	seen := make(map[string]string)
+	params := make(map[string]string)
+	for _, route := range routes {
+		segments := strings.Split(strings.Trim(route.Path, "/"), "/")
+		shape := make([]string, len(segments))
+		for i, segment := range segments {
+			shape[i] = segment
+			if segment == "" || (segment[0] != ':' && segment[0] != '*') {
+				continue
+			}
+			shape[i] = segment[:1]
+			position := route.Method + " /" + strings.Join(shape[:i+1], "/")
+			if other, ok := params[position]; !ok {
+				params[position] = segment
+			} else if other != segment {
+				problems = append(problems, fmt.Sprintf("%s %s: parameter %s clashes with %s", route.Method, route.Path, segment, other))
+			}
+		}
+
+		key := route.Method + " /" + strings.Join(shape, "/")
+		if other, ok := seen[key]; ok {
+			problems = append(problems, fmt.Sprintf("%s %s: already registered as %s", route.Method, route.Path, other))
+			continue
+		}
+		seen[key] = route.Path
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("conflicting routes:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// linkSuccessors points each v1 route at the v2 route serving the same
+// handler with the same method.
+func linkSuccessors(v1, v2 []Route) {
+	successors := make(map[string]string)
+	for _, route := range v2 {
+		key := route.Method + " " + route.handlerName()
+		if _, ok := successors[key]; !ok {
+			successors[key] = route.Path
+		}
+	}
+	for i := range v1 {
+		v1[i].Successor = successors[v1[i].Method+" "+v1[i].handlerName()]
+	}
+}
+
+// deprecated marks a v1 response with the Deprecation header, the Sunset
+// date when one is configured, and a Link to the v2 successor with the path
+// parameters filled in (the v2 root when the successor needs parameters the
+// v1 path does not have).
+func deprecated(successor string, sunset string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		if sunset != "" {
+			c.Header("Sunset", sunset)
+		}
+		target := successor
+		for _, param := range c.Params {
+			target = strings.Replace(target, ":"+param.Key, param.Value, 1)
+		}
+		if target == "" || strings.Contains(target, "/:") {
+			target = "/api/v2"
+		}
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, target))
+		c.Next()
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quickgeo/cms-official-go/internal/responses"
+	"github.com/quickgeo/cms-official-go/internal/search"
+)
+
+// searchKinds maps each result type to the resource an API key must be able
+// to read to see it.
+var searchKinds = map[string]string{
+	search.KindCustomer:       "leads",
+	search.KindUnit:           "projects",
+	search.KindVendor:         "directory",
+	search.KindChannelPartner: "leads",
+	search.KindProject:        "projects",
+}
+
+// SearchAPI answers the global search box: /search?q=...&types=customer,unit&limit=20.
+// Words match as prefixes, numbers also match phone prefixes, and a query
+// with no hits is retried once with the closest indexed spellings. An API
+// key only searches the types its read scopes cover.
+func (h *Handler) SearchAPI(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Missing q")
+		return
+	}
+
+	var kinds []string
+	if raw := c.Query("types"); raw != "" {
+		for _, kind := range strings.Split(raw, ",") {
+			kind = strings.TrimSpace(kind)
+			resource, ok := searchKinds[kind]
+			if !ok {
+				responses.JSON(c, http.StatusBadRequest, false, nil, "Unknown type: "+kind)
+				return
+			}
+			if !keyAllows(c, resource+":read") {
+				responses.JSON(c, http.StatusForbidden, false, nil, "This API key does not have the "+resource+":read scope")
+				return
+			}
+			kinds = append(kinds, kind)
+		}
+	} else if _, isKey := c.Get(apiKeyScopesKey); isKey {
+		for kind, resource := range searchKinds {
+			if keyAllows(c, resource+":read") {
+				kinds = append(kinds, kind)
+			}
+		}
+		if len(kinds) == 0 {
+			responses.JSON(c, http.StatusOK, true, search.Response{Results: []search.Result{}}, "Search results")
+			return
+		}
+		sort.Strings(kinds)
+	}
+
+	limit := 20
+	if raw := c.Query("limit"); raw != "" {
+		if val, err := strconv.Atoi(raw); err == nil && val > 0 {
+			limit = min(val, 100)
+		}
+	}
+
+	result, err := search.Query(h.dbFor(c), text, kinds, limit)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Search failed")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, result, "Search results")
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"github.com/quickgeo/cms-official-go/internal/responses"
+	sessionService "github.com/quickgeo/cms-official-go/internal/services/sessions"
+)
+
+const (
+	// sessionCookie carries the session token for the web build. It is
+	// HttpOnly; scripts never see it.
+	sessionCookie = "cms_session"
+	// csrfCookie holds the value cookie-authenticated writes echo in
+	// csrfHeader.
+	csrfCookie = "cms_csrf"
+	csrfHeader = "X-CSRF-Token"
+)
+
+// apiPolicy is the Content-Security-Policy of every JSON response: nothing
+// may load and nothing may frame it.
+const apiPolicy = "default-src 'none'; frame-ancestors 'none'"
+
+// hstsPolicy is sent over HTTPS only; browsers ignore it on plain HTTP.
+const hstsPolicy = "max-age=31536000; includeSubDomains"
+
+// defaultOrigins serve the Flutter web build and the docs during
+// development, whatever port they run on.
+var defaultOrigins = []string{"http://localhost", "http://localhost:*", "http://127.0.0.1", "http://127.0.0.1:*"}
+
+// behindHTTPS reports CMS_HTTPS=1, set when a proxy in front terminates TLS.
+func behindHTTPS() bool {
+	return os.Getenv("CMS_HTTPS") == "1"
+}
+
+func secureRequest(c *gin.Context) bool {
+	return c.Request.TLS != nil || behindHTTPS()
+}
+
+// TrustProxies lets c.ClientIP() read X-Forwarded-For and X-Real-IP only
+// from the proxies in CMS_TRUSTED_PROXIES, a comma-separated list of
+// addresses or CIDR ranges. Unset, no proxy is trusted and the client IP is
+// the address of the connection, so callers cannot pick their own to pass an
+// API key's allowlist or dodge a rate limit.
+func TrustProxies(router *gin.Engine) error {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("CMS_TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		return fmt.Errorf("CMS_TRUSTED_PROXIES: %w", err)
+	}
+	return nil
+}
+
+// corsOrigins reads the comma-separated CMS_CORS_ORIGINS. An entry is an
+// origin such as https://app.example.com, with at most one * standing for a
+// port or a subdomain.
+func corsOrigins() ([]string, error) {
+	raw := strings.TrimSpace(os.Getenv("CMS_CORS_ORIGINS"))
+	if raw == "" {
+		return defaultOrigins, nil
+	}
+	var origins []string
+	for _, origin := range strings.Split(raw, ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin == "" {
+			continue
+		}
+		if origin == "*" {
+			return nil, fmt.Errorf("CMS_CORS_ORIGINS cannot be *; list the origins allowed to send credentials")
+		}
+		parsed, err := url.Parse(strings.Replace(origin, "*", "1", 1))
+		if err != nil || strings.Count(origin, "*") > 1 || (parsed.Scheme != "http" && parsed.Scheme != "https") ||
+			parsed.Host == "" || parsed.Path != "" || parsed.RawQuery != "" || parsed.User != nil {
+			return nil, fmt.Errorf("CMS_CORS_ORIGINS: %q is not an origin like https://app.example.com", origin)
+		}
+		origins = append(origins, origin)
+	}
+	if len(origins) == 0 {
+		return defaultOrigins, nil
+	}
+	return origins, nil
+}
+
+// CORS lets browsers on the allowed origins (CMS_CORS_ORIGINS, localhost by
+// default) call the API with credentials. Other origins get no CORS headers,
+// so browsers keep their responses from the calling page.
+func CORS() (gin.HandlerFunc, error) {
+	origins, err := corsOrigins()
+	if err != nil {
+		return nil, err
+	}
+	config := cors.DefaultConfig()
+	config.AllowOrigins = origins
+	config.AllowWildcard = true
+	config.AllowCredentials = true
+	config.AddAllowHeaders("Authorization", csrfHeader, requestIDHeader, idempotencyHeader, "If-Match", "If-None-Match", "If-Modified-Since")
+	config.AddExposeHeaders(requestIDHeader, "ETag", "Last-Modified", "Retry-After", "Idempotent-Replayed", "Deprecation", "Sunset", "Link", "X-Query-Count")
+	return cors.New(config), nil
+}
+
+// SecurityHeaders sets the standard browser protections on every response.
+// Strict-Transport-Security goes out when the request came over TLS or
+// CMS_HTTPS=1 says a proxy terminated it.
+func SecurityHeaders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("Referrer-Policy", "no-referrer")
+		header.Set("Content-Security-Policy", apiPolicy)
+		if secureRequest(c) {
+			header.Set("Strict-Transport-Security", hstsPolicy)
+		}
+		c.Next()
+	}
+}
+
+// setSessionCookies signs the browser in alongside the token in the body:
+// the session in an HttpOnly cookie, its CSRF value in one scripts can read.
+func setSessionCookies(c *gin.Context, token string, expires time.Time) {
+	maxAge := int(time.Until(expires).Seconds())
+	writeCookie(c, sessionCookie, token, expires, maxAge, true)
+	writeCookie(c, csrfCookie, sessionService.CSRFToken(token), expires, maxAge, false)
+}
+
+// clearSessionCookies signs the browser out.
+func clearSessionCookies(c *gin.Context) {
+	writeCookie(c, sessionCookie, "", time.Unix(0, 0), -1, true)
+	writeCookie(c, csrfCookie, "", time.Unix(0, 0), -1, false)
+}
+
+func writeCookie(c *gin.Context, name, value string, expires time.Time, maxAge int, httpOnly bool) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		MaxAge:   maxAge,
+		HttpOnly: httpOnly,
+		Secure:   secureRequest(c),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// cookieToken is the session token from the cookie, unless the route signs
+// people in, where a leftover cookie must not get in the way.
+func cookieToken(c *gin.Context) string {
+	for _, path := range []string{"/auth/login", "/auth/register", "/auth/accept-invite"} {
+		if strings.HasSuffix(c.FullPath(), path) {
+			return ""
+		}
+	}
+	token, err := c.Cookie(sessionCookie)
+	if err != nil {
+		return ""
+	}
+	return token
+}
+
+// checkCSRF enforces the double submit on cookie-authenticated writes: the
+// X-CSRF-Token header must match both the cms_csrf cookie and the session.
+// Another site can make the browser send the cookies but cannot read them.
+func checkCSRF(c *gin.Context, token string) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	sent := c.GetHeader(csrfHeader)
+	cookie, _ := c.Cookie(csrfCookie)
+	if sent != "" &&
+		subtle.ConstantTimeCompare([]byte(sent), []byte(cookie)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(sent), []byte(sessionService.CSRFToken(token))) == 1 {
+		return true
+	}
+	responses.JSON(c, http.StatusForbidden, false, nil, "CSRF token missing or invalid; send the cms_csrf cookie back in "+csrfHeader)
+	return false
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quickgeo/cms-official-go/internal/db"
+	"github.com/quickgeo/cms-official-go/internal/model"
+	"github.com/quickgeo/cms-official-go/internal/tenant"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testServer is the API on a throwaway database with the Django tables the
+// handlers read, organization scoping on, as main wires it.
+type testServer struct {
+	t      *testing.T
+	db     *gorm.DB
+	router *gin.Engine
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	gin.SetMode(gin.ReleaseMode)
+	path := filepath.Join(t.TempDir(), "cms.sqlite3")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	database, err := db.Connect(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.Logger = logger.Discard
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	err = database.AutoMigrate(&model.User{}, &model.Profile{}, &model.Project{}, &model.ProjectBlock{},
+		&model.ProjectUnit{}, &model.ProjectPreset{}, &model.ProjectPayment{}, &model.FlatPayment{}, &model.PlotPayment{},
+		&model.Vendor{}, &model.Supervisor{}, &model.Customer{}, &model.ChannelPartner{}, &model.MaterialItem{},
+		&model.GeneralExpense{}, &model.AttendanceBatch{}, &model.AttendanceMember{}, &model.AttendanceRecord{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.EnsureSchema(database); err != nil {
+		t.Fatal(err)
+	}
+	if err := tenant.Enforce(database); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.GateWrites(database); err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.Use(BusyWrites())
+	if err := New(database, nil).Register(router); err != nil {
+		t.Fatal(err)
+	}
+	return &testServer{t: t, db: database, router: router}
+}
+
+// do serves one request; headers are name, value pairs.
+func (s *testServer) do(method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	recorder := httptest.NewRecorder()
+	s.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// signUp registers a builder, which opens a new organization, and logs in.
+// It returns the bearer header pair and the login response for its cookies.
+func (s *testServer) signUp(username string) ([]string, *httptest.ResponseRecorder) {
+	s.t.Helper()
+	credentials := `{"username":"` + username + `","password":"Passw0rd!x"`
+	if w := s.do(http.MethodPost, "/api/v2/auth/register", credentials+`,"password_confirm":"Passw0rd!x","user_type":"builder"}`); w.Code >= 300 {
+		s.t.Fatalf("register %s: %d %s", username, w.Code, w.Body)
+	}
+	login := s.do(http.MethodPost, "/api/v2/auth/login", credentials+"}")
+	if login.Code != http.StatusOK {
+		s.t.Fatalf("login %s: %d %s", username, login.Code, login.Body)
+	}
+	var resp struct {
+		Data struct {
+			Token string `json:"token"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(login.Body.Bytes(), &resp); err != nil || resp.Data.Token == "" {
+		s.t.Fatalf("login %s returned no token: %s", username, login.Body)
+	}
+	return []string{"Authorization", "Bearer " + resp.Data.Token}, login
+}
+
+// organization returns the organization username belongs to.
+func (s *testServer) organization(username string) uint {
+	s.t.Helper()
+	var orgID uint
+	err := s.db.Raw(`SELECT m.organization_id FROM cms_organization_member m
+		JOIN auth_user u ON u.id = m.user_id WHERE u.username = ?`, username).Scan(&orgID).Error
+	if err != nil || orgID == 0 {
+		s.t.Fatalf("no organization for %s: %v", username, err)
+	}
+	return orgID
+}
+
+// exec runs seed SQL, failing the test on error.
+func (s *testServer) exec(sql string, args ...interface{}) {
+	s.t.Helper()
+	if err := s.db.Exec(sql, args...).Error; err != nil {
+		s.t.Fatalf("%s: %v", sql, err)
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quickgeo/cms-official-go/internal/model"
+	"github.com/quickgeo/cms-official-go/internal/responses"
+	sessionService "github.com/quickgeo/cms-official-go/internal/services/sessions"
+	utils "github.com/quickgeo/cms-official-go/internal/utilities/auth_page_app"
+)
+
+// startSession opens a session for userID on the device making the request.
+func (h *Handler) startSession(c *gin.Context, userID uint, deviceName, platform string) (string, model.Session, error) {
+	return h.sessions.Start(c.Request.Context(), userID, sessionService.Device{
+		Name:      deviceName,
+		Platform:  platform,
+		UserAgent: c.GetHeader("User-Agent"),
+		IP:        c.ClientIP(),
+	})
+}
+
+func sessionResponses(c *gin.Context, sessions []model.Session) []utils.SessionResponse {
+	current := c.GetUint(sessionKey)
+	payload := []utils.SessionResponse{}
+	for _, s := range sessions {
+		payload = append(payload, utils.SessionResponse{
+			ID:         s.ID,
+			DeviceName: s.DeviceName,
+			Platform:   s.Platform,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    s.ID == current,
+		})
+	}
+	return payload
+}
+
+// sessionParam parses :id, answering 400 when it is not a number.
+func sessionParam(c *gin.Context) (uint, bool) {
+	sessionID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid session id")
+		return 0, false
+	}
+	return uint(sessionID), true
+}
+
+// listSessions lists the caller's signed-in devices.
+func (h *Handler) listSessions(c *gin.Context) {
+	sessions, err := h.sessions.List(c.Request.Context(), currentUser(c))
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load sessions")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"sessions": sessionResponses(c, sessions)}, "Sessions loaded")
+}
+
+// revokeSession signs the caller out on one device.
+func (h *Handler) revokeSession(c *gin.Context) {
+	sessionID, ok := sessionParam(c)
+	if !ok {
+		return
+	}
+	if err := h.sessions.Revoke(c.Request.Context(), currentUser(c), sessionID); err != nil {
+		serviceFailure(c, err, "Failed to revoke session")
+		return
+	}
+	responses.JSON(c, http.StatusNoContent, true, nil, "Session revoked")
+}
+
+// revokeSessions signs the caller out everywhere; ?keep_current=true spares
+// the session the request was made with.
+func (h *Handler) revokeSessions(c *gin.Context) {
+	keep := uint(0)
+	if c.Query("keep_current") == "true" {
+		keep = c.GetUint(sessionKey)
+	}
+	revoked, err := h.sessions.RevokeUser(c.Request.Context(), currentUser(c), keep)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to revoke sessions")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"revoked": revoked}, "Sessions revoked")
+}
+
+// listUserSessions lists a member's signed-in devices (owners only).
+func (h *Handler) listUserSessions(c *gin.Context) {
+	userID, ok := userParam(c)
+	if !ok {
+		return
+	}
+	sessions, err := h.users.Sessions(c.Request.Context(), currentMember(c), userID)
+	if err != nil {
+		serviceFailure(c, err, "Failed to load sessions")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"sessions": sessionResponses(c, sessions)}, "Sessions loaded")
+}
+
+// signOutUserSession forces a member's device to sign out, e.g. a lost
+// supervisor phone or laptop (owners only).
+func (h *Handler) signOutUserSession(c *gin.Context) {
+	userID, ok := userParam(c)
+	if !ok {
+		return
+	}
+	sessionID, ok := sessionParam(c)
+	if !ok {
+		return
+	}
+	if _, err := h.users.SignOut(c.Request.Context(), currentMember(c), userID, sessionID); err != nil {
+		serviceFailure(c, err, "Failed to sign out device")
+		return
+	}
+	responses.JSON(c, http.StatusNoContent, true, nil, "Device signed out")
+}
+
+// signOutUser signs a member out on every device (owners only).
+func (h *Handler) signOutUser(c *gin.Context) {
+	userID, ok := userParam(c)
+	if !ok {
+		return
+	}
+	revoked, err := h.users.SignOut(c.Request.Context(), currentMember(c), userID, 0)
+	if err != nil {
+		serviceFailure(c, err, "Failed to sign out user")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"revoked": revoked}, "User signed out")
+}
@@ -1,135 +1,68 @@
 package handlers
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/quickgeo/cms-official-go/internal/model"
 	"github.com/quickgeo/cms-official-go/internal/responses"
 	stockUtils "github.com/quickgeo/cms-official-go/internal/utilities/stock_management_page_app"
 )
 
-// StockManagementAPI handles listing and updating stock.
-func (h *Handler) StockManagementAPI(c *gin.Context) {
-	projectID := c.Query("project_id")
+// stockProject resolves the project from the :id path parameter (v2) or the
+// project_id query parameter (v1) and checks the user may access it.
+func (h *Handler) stockProject(c *gin.Context) (uint, bool) {
+	projectID := c.Param("id")
+	if projectID == "" {
+		projectID = c.Query("project_id")
+	}
 	if projectID == "" {
 		responses.JSON(c, http.StatusBadRequest, false, nil, "Project ID required")
-		return
+		return 0, false
 	}
-
-	// Access Check
-	pID, _ := strconv.Atoi(projectID)
-	_, err := h.getAccessibleProjects(1) // Mock User 1
-	// Ideally check if pID is in accessible list. Skipping redundant check for brevity.
-	_ = pID
+	pID, err := strconv.ParseUint(projectID, 10, 64)
 	if err != nil {
-		responses.JSON(c, http.StatusInternalServerError, false, nil, "Error checking access")
-		return
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid project ID")
+		return 0, false
 	}
 
-	switch c.Request.Method {
-	case "GET":
-		// List all materials with their stock balances
-		var materials []model.MaterialItem
-		h.db.Find(&materials)
-
-		var balances []model.StockBalance
-		h.db.Where("stock_project_id = ?", projectID).Find(&balances)
-
-		balanceMap := make(map[uint]model.StockBalance)
-		for _, b := range balances {
-			balanceMap[b.StockMaterialItemID] = b
-		}
-
-		type StockItemResponse struct {
-			MaterialItemID      uint    `json:"material_item_id"`
-			MaterialName        string  `json:"material_name"`
-			MaterialDisplayName string  `json:"material_display_name"`
-			TotalAllocated      float64 `json:"total_allocated"`
-			Used                float64 `json:"used"`
-			Remaining           float64 `json:"remaining"`
-			Notes               string  `json:"notes"`
-		}
-
-		payload := []StockItemResponse{}
-		for _, m := range materials {
-			allocated := 0.0
-			used := 0.0
-			notes := ""
-
-			if b, ok := balanceMap[m.ID]; ok {
-				allocated = b.StockTotalAllocated
-				used = b.StockUsed
-				notes = b.StockNotes
-			}
-
-			rem := allocated - used
-			if rem < 0 {
-				rem = 0
-			}
-
-			payload = append(payload, StockItemResponse{
-				MaterialItemID:      m.ID,
-				MaterialName:        m.MaterialItemName,
-				MaterialDisplayName: m.MaterialItemDisplayName,
-				TotalAllocated:      allocated,
-				Used:                used,
-				Remaining:           rem,
-				Notes:               notes,
-			})
-		}
-
-		responses.JSON(c, http.StatusOK, true, gin.H{"stock": payload}, "Stock loaded")
-
-	case "POST":
-		var req stockUtils.UpdateStockRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
-			return
-		}
-
-		var balance model.StockBalance
-		err := h.db.Where("stock_project_id = ? AND stock_material_item_id = ?", projectID, req.MaterialItemID).First(&balance).Error
-
-		if err != nil {
-			// Create new
-			pIDUint := uint(pID)
-			balance = model.StockBalance{
-				StockProjectID:      pIDUint,
-				StockMaterialItemID: req.MaterialItemID,
-				StockCreatedAt:      time.Now(),
-			}
-		}
+	if !h.canAccessProject(c, uint(pID)) {
+		return 0, false
+	}
+	return uint(pID), true
+}
 
-		// Update fields
-		if req.TotalAllocated != nil {
-			v, _ := strconv.ParseFloat(fmt.Sprintf("%v", req.TotalAllocated), 64)
-			balance.StockTotalAllocated = v
-		}
-		if req.Used != nil {
-			v, _ := strconv.ParseFloat(fmt.Sprintf("%v", req.Used), 64)
-			balance.StockUsed = v
-		}
-		if req.Notes != "" {
-			balance.StockNotes = req.Notes
-		}
-		balance.StockUpdatedAt = time.Now()
+// StockBalancesAPI lists every material with its balance on the project.
+func (h *Handler) StockBalancesAPI(c *gin.Context) {
+	projectID, ok := h.stockProject(c)
+	if !ok {
+		return
+	}
+	items, err := h.stock.Balances(c.Request.Context(), projectID)
+	if err != nil {
+		serviceFailure(c, err, "Failed to load stock")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"stock": items}, "Stock loaded")
+}
 
-		// Logic from model.py: if used > allocated, cap it?
-		// "if self.stock_used > self.stock_total_allocated: self.stock_used = self.stock_total_allocated"
-		if balance.StockUsed > balance.StockTotalAllocated {
-			balance.StockUsed = balance.StockTotalAllocated
-		}
+// UpdateStockAPI creates or changes one material balance on the project.
+func (h *Handler) UpdateStockAPI(c *gin.Context) {
+	projectID, ok := h.stockProject(c)
+	if !ok {
+		return
+	}
 
-		if balance.ID == 0 {
-			h.db.Create(&balance)
-		} else {
-			h.db.Save(&balance)
-		}
+	var req stockUtils.UpdateStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
+		return
+	}
 
-		responses.JSON(c, http.StatusOK, true, balance, "Stock updated")
+	balance, err := h.stock.Update(c.Request.Context(), projectID, req)
+	if err != nil {
+		serviceFailure(c, err, "Failed to update stock")
+		return
 	}
+	responses.JSON(c, http.StatusOK, true, balance, "Stock updated")
 }
@@ -4,29 +4,17 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/quickgeo/cms-official-go/internal/model"
 	"github.com/quickgeo/cms-official-go/internal/responses"
+	"github.com/quickgeo/cms-official-go/internal/services"
 	supUtils "github.com/quickgeo/cms-official-go/internal/utilities/supervisor_page_app"
 )
 
 // -- Helpers --
 
-func generateSupervisorCode(h *Handler) string {
-	var last model.Supervisor
-	h.db.Order("supervisor_code desc").First(&last)
-
-	if last.SupervisorCode != "" && strings.HasPrefix(last.SupervisorCode, "SUP-") {
-		numPart := strings.TrimPrefix(last.SupervisorCode, "SUP-")
-		n, _ := strconv.Atoi(numPart)
-		return fmt.Sprintf("SUP-%04d", n+1)
-	}
-	return "SUP-0001"
-}
-
 // In-process mock for JSON page access store. Real impl should use DB or proper File Store service.
 // Simulating the JSON file logic from Django by just using a dummy map for now, or DB field if added.
 // Django uses `supervisor_pages.json`.
@@ -52,104 +40,124 @@ func savePageAccess(supID uint, access supUtils.PageAccess) {
 
 // Start Handler
 
-// SupervisorCollectionAPI handles List and Create
-func (h *Handler) SupervisorCollectionAPI(c *gin.Context) {
-	// Access: Owner only? Or staff.
-	userID := uint(1) // Mock
-
-	switch c.Request.Method {
-	case "GET":
-		var sups []model.Supervisor
-		h.db.Preload("AssignedProjects").Where("supervisor_created_by_id = ?", userID).Find(&sups)
-
-		var payload []supUtils.SupervisorResponse
-		for _, s := range sups {
-			pIDs := []uint{}
-			for _, p := range s.AssignedProjects {
-				// Only if owner matches
-				// If query already filtered by created_by, assumed accessible.
-				// Wait, Supervisor -> AssignedProjects relation in GORM is simple.
-				// We need to filter those projects where owner is self.
-				if p.ProjectOwnerID != nil && *p.ProjectOwnerID == userID {
-					pIDs = append(pIDs, p.ID)
-				}
-			}
-			payload = append(payload, supUtils.SupervisorResponse{
-				ID:                 s.ID,
-				Code:               s.SupervisorCode,
-				Name:               s.SupervisorName,
-				PrimaryPhone:       s.SupervisorPrimaryPhone,
-				SecondaryPhone:     s.SupervisorSecondaryPhone,
-				Email:              s.SupervisorEmail,
-				Address:            s.SupervisorAddress,
-				AssignedProjectIDs: pIDs,
-				PageAccess:         getPageAccess(s.ID),
-			})
+// ListSupervisorsAPI lists the organization's supervisors with their assignments.
+func (h *Handler) ListSupervisorsAPI(c *gin.Context) {
+	var sups []model.Supervisor
+	// Supervisors and their assigned projects are both scoped to the
+	// organization by the tenant filter.
+	h.dbFor(c).Preload("AssignedProjects").Find(&sups)
+
+	var payload []supUtils.SupervisorResponse
+	for _, s := range sups {
+		pIDs := []uint{}
+		for _, p := range s.AssignedProjects {
+			pIDs = append(pIDs, p.ID)
 		}
-		responses.JSON(c, http.StatusOK, true, gin.H{"supervisors": payload}, "List loaded")
+		payload = append(payload, supUtils.SupervisorResponse{
+			ID:                 s.ID,
+			Code:               s.SupervisorCode,
+			Name:               s.SupervisorName,
+			PrimaryPhone:       s.SupervisorPrimaryPhone,
+			SecondaryPhone:     s.SupervisorSecondaryPhone,
+			Email:              s.SupervisorEmail,
+			Address:            s.SupervisorAddress,
+			AssignedProjectIDs: pIDs,
+			PageAccess:         getPageAccess(s.ID),
+		})
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"supervisors": payload}, "List loaded")
+}
 
-	case "POST":
-		var req supUtils.CreateSupervisorRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
-			return
-		}
+// CreateSupervisorAPI adds a supervisor and assigns their projects.
+func (h *Handler) CreateSupervisorAPI(c *gin.Context) {
+	userID := currentUser(c)
 
-		code := generateSupervisorCode(h)
-		sup := model.Supervisor{
-			SupervisorCreatedByID:    &userID,
-			SupervisorCode:           code,
-			SupervisorName:           req.Name,
-			SupervisorPrimaryPhone:   req.PrimaryPhoneNumber,
-			SupervisorSecondaryPhone: req.SecondaryPhone,
-			SupervisorEmail:          req.Email,
-			SupervisorAddress:        req.Address,
-			SupervisorCreatedAt:      time.Now(),
-		}
+	var req supUtils.CreateSupervisorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
+		return
+	}
 
-		if err := h.db.Create(&sup).Error; err != nil {
-			responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to create")
-			return
-		}
+	sup := model.Supervisor{
+		SupervisorCreatedByID:    &userID,
+		SupervisorName:           req.Name,
+		SupervisorPrimaryPhone:   req.PrimaryPhoneNumber,
+		SupervisorSecondaryPhone: req.SecondaryPhone,
+		SupervisorEmail:          req.Email,
+		SupervisorAddress:        req.Address,
+		SupervisorCreatedAt:      time.Now(),
+	}
 
-		// Assignments
-		if len(req.AssignedProjectIDs) > 0 {
-			h.updateAssignments(userID, &sup, req.AssignedProjectIDs)
-		}
+	if err := h.dbFor(c).Create(&sup).Error; err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to create")
+		return
+	}
 
-		resp := supUtils.SupervisorResponse{
-			ID: sup.ID, Code: sup.SupervisorCode, Name: sup.SupervisorName,
-			PrimaryPhone: sup.SupervisorPrimaryPhone, AssignedProjectIDs: req.AssignedProjectIDs,
-			PageAccess: getPageAccess(sup.ID),
-		}
-		responses.JSON(c, http.StatusCreated, true, map[string]interface{}{"supervisor": resp}, "Created")
+	// Assignments
+	if len(req.AssignedProjectIDs) > 0 {
+		h.updateAssignments(c, &sup, req.AssignedProjectIDs)
+	}
+
+	resp := supUtils.SupervisorResponse{
+		ID: sup.ID, Code: sup.SupervisorCode, Name: sup.SupervisorName,
+		PrimaryPhone: sup.SupervisorPrimaryPhone, AssignedProjectIDs: req.AssignedProjectIDs,
+		PageAccess: getPageAccess(sup.ID), Version: sup.SupervisorVersion,
 	}
+	responses.JSON(c, http.StatusCreated, true, map[string]interface{}{"supervisor": resp}, "Created")
 }
 
-// SupervisorDetailAPI handles Update/Delete
-func (h *Handler) SupervisorDetailAPI(c *gin.Context) {
-	idStr := c.Param("id")
-	supID, _ := strconv.Atoi(idStr)
-	userID := uint(1)
+// loadSupervisor resolves the organization's :id supervisor, answering 404 when it
+// is missing.
+func (h *Handler) loadSupervisor(c *gin.Context) (model.Supervisor, bool) {
+	supID, _ := strconv.Atoi(c.Param("id"))
 
 	var sup model.Supervisor
-	if err := h.db.Where("id = ? AND supervisor_created_by_id = ?", supID, userID).First(&sup).Error; err != nil {
+	if err := h.dbFor(c).Where("id = ?", supID).First(&sup).Error; err != nil {
 		responses.JSON(c, http.StatusNotFound, false, nil, "Supervisor not found")
+		return sup, false
+	}
+	return sup, true
+}
+
+// SupervisorDetailAPI returns one supervisor with its ETag.
+func (h *Handler) SupervisorDetailAPI(c *gin.Context) {
+	sup, ok := h.loadSupervisor(c)
+	if !ok {
 		return
 	}
+	c.Header("ETag", entityETag("supervisor", sup.ID, sup.SupervisorVersion))
+	responses.JSON(c, http.StatusOK, true, map[string]interface{}{"supervisor": h.supervisorResponse(c, sup)}, "Supervisor loaded")
+}
+
+// DeleteSupervisorAPI removes a supervisor and unassigns their projects.
+func (h *Handler) DeleteSupervisorAPI(c *gin.Context) {
+	sup, ok := h.loadSupervisor(c)
+	if !ok {
+		return
+	}
+
+	// Unassign projects
+	h.dbFor(c).Model(&model.Project{}).Where("project_assigned_supervisor_id = ?", sup.ID).
+		Update("project_assigned_supervisor_id", nil)
 
-	if c.Request.Method == "DELETE" {
-		// Unassign projects
-		h.db.Model(&model.Project{}).Where("project_assigned_supervisor_id = ?", sup.ID).
-			Update("project_assigned_supervisor_id", nil)
+	h.dbFor(c).Delete(&sup)
+	delete(MockPageAccessStore, c.Param("id"))
+	responses.JSON(c, http.StatusNoContent, true, nil, "Deleted")
+}
+
+// UpdateSupervisorAPI saves a supervisor edit guarded by If-Match.
+func (h *Handler) UpdateSupervisorAPI(c *gin.Context) {
+	sup, ok := h.loadSupervisor(c)
+	if !ok {
+		return
+	}
 
-		h.db.Delete(&sup)
-		delete(MockPageAccessStore, idStr)
-		responses.JSON(c, http.StatusNoContent, true, nil, "Deleted")
+	etag := entityETag("supervisor", sup.ID, sup.SupervisorVersion)
+	if ifMatchFails(c, etag) {
+		preconditionFailed(c, etag, map[string]interface{}{"supervisor": h.supervisorResponse(c, sup)})
 		return
 	}
 
-	// PUT/PATCH
 	var req supUtils.UpdateSupervisorRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
@@ -167,47 +175,63 @@ func (h *Handler) SupervisorDetailAPI(c *gin.Context) {
 	sup.SupervisorEmail = req.Email
 	sup.SupervisorAddress = req.Address
 
-	h.db.Save(&sup)
+	expected := sup.SupervisorVersion
+	sup.SupervisorVersion++
+	sup.SupervisorUpdatedAt = time.Now()
+	saved, err := services.SaveVersioned(h.dbFor(c), &sup, "supervisor_version", expected)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to update supervisor")
+		return
+	}
+	if !saved {
+		var current model.Supervisor
+		h.dbFor(c).First(&current, sup.ID)
+		versionConflict(c, entityETag("supervisor", current.ID, current.SupervisorVersion), map[string]interface{}{"supervisor": h.supervisorResponse(c, current)})
+		return
+	}
 
 	if req.AssignedProjectIDs != nil {
-		h.updateAssignments(userID, &sup, req.AssignedProjectIDs)
+		h.updateAssignments(c, &sup, req.AssignedProjectIDs)
 	}
 
 	if req.PageAccess != nil {
 		savePageAccess(sup.ID, *req.PageAccess)
 	}
 
-	// Fetch fresh assignments
+	c.Header("ETag", entityETag("supervisor", sup.ID, sup.SupervisorVersion))
+	responses.JSON(c, http.StatusOK, true, map[string]interface{}{"supervisor": h.supervisorResponse(c, sup)}, "Updated")
+}
+
+// supervisorResponse serializes a supervisor with fresh project assignments.
+func (h *Handler) supervisorResponse(c *gin.Context, sup model.Supervisor) supUtils.SupervisorResponse {
 	var pIDs []uint
-	h.db.Model(&model.Project{}).Where("project_assigned_supervisor_id = ?", sup.ID).Pluck("id", &pIDs)
+	h.dbFor(c).Model(&model.Project{}).Where("project_assigned_supervisor_id = ?", sup.ID).Pluck("id", &pIDs)
 
-	resp := supUtils.SupervisorResponse{
+	return supUtils.SupervisorResponse{
 		ID: sup.ID, Code: sup.SupervisorCode, Name: sup.SupervisorName,
 		PrimaryPhone: sup.SupervisorPrimaryPhone, SecondaryPhone: sup.SupervisorSecondaryPhone,
 		Email: sup.SupervisorEmail, Address: sup.SupervisorAddress,
 		AssignedProjectIDs: pIDs,
 		PageAccess:         getPageAccess(sup.ID),
+		Version:            sup.SupervisorVersion,
 	}
-	responses.JSON(c, http.StatusOK, true, map[string]interface{}{"supervisor": resp}, "Updated")
 }
 
 // updateAssignments helper
-func (h *Handler) updateAssignments(userID uint, sup *model.Supervisor, newIDs []uint) {
-	// Clear old assignments (where owner is user)
-	// Excluding those in new list?
-	// Logic: set supervisor_id = NULL for projects owned by user AND currently assigned to SUP AND NOT in newIDs
-	// Then set supervisor_id = SUP for projects owned by user AND in newIDs
+func (h *Handler) updateAssignments(c *gin.Context, sup *model.Supervisor, newIDs []uint) {
+	// Logic: set supervisor_id = NULL for the organization's projects currently assigned to SUP AND NOT in newIDs
+	// Then set supervisor_id = SUP for the organization's projects in newIDs
 
 	// 1. Unassign
-	h.db.Model(&model.Project{}).
-		Where("project_owner_id = ? AND project_assigned_supervisor_id = ?", userID, sup.ID).
+	h.dbFor(c).Model(&model.Project{}).
+		Where("project_assigned_supervisor_id = ?", sup.ID).
 		Where("id NOT IN ?", newIDs).
 		Update("project_assigned_supervisor_id", nil)
 
 	// 2. Assign
 	if len(newIDs) > 0 {
-		h.db.Model(&model.Project{}).
-			Where("project_owner_id = ? AND id IN ?", userID, newIDs).
+		h.dbFor(c).Model(&model.Project{}).
+			Where("id IN ?", newIDs).
 			Update("project_assigned_supervisor_id", sup.ID)
 	}
 }
@@ -7,20 +7,46 @@ import (
 	"github.com/quickgeo/cms-official-go/internal/responses"
 )
 
-// TrackFinancesView - simple access check, returns 200 OK.
-// In Django this renders a template. In Go backend API, it likely just confirms access or returns config.
-// Frontend handles UI.
+// TrackFinancesView - access check plus the per-project finance summaries
+// (collected amount, expenses by category, stock value).
+// In Django this renders a template; the frontend loads the detail lists via other APIs.
 func (h *Handler) TrackFinancesView(c *gin.Context) {
 	// Simple role check
 	// user := c.MustGet("user").(model.User) -- if middleware
 	// We'll trust middleware or mock:
-	userID := uint(1) // mock
-	_, err := h.getAccessibleProjects(userID)
+	userID := currentUser(c)
+	projects, err := h.projects.Accessible(c.Request.Context(), userID)
 	if err != nil {
 		responses.JSON(c, http.StatusInternalServerError, false, nil, "Error checking access")
 		return
 	}
 
-	// Just return success, frontend loads remaining data via other APIs (Project, Expenses, Payments)
-	responses.JSON(c, http.StatusOK, true, gin.H{"message": "Access granted"}, "Welcome to Track Finances")
+	projectIDs := make([]uint, len(projects))
+	for i, p := range projects {
+		projectIDs[i] = p.ID
+	}
+	summaries, err := h.projectSummaries(c, projectIDs)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to load project summaries")
+		return
+	}
+
+	finances := make([]gin.H, len(projects))
+	for i, p := range projects {
+		summary := summaries[p.ID]
+		summary.ProjectID = p.ID
+		finances[i] = gin.H{
+			"project_id":    p.ID,
+			"project_name":  p.ProjectName,
+			"project_code":  p.ProjectCode,
+			"summary":       summary,
+			"total_expense": summary.TotalExpense(),
+		}
+	}
+
+	responses.JSON(c, http.StatusOK, true, gin.H{
+		"message":  "Access granted",
+		"projects": finances,
+		"totals":   summaryTotals(summaries),
+	}, "Welcome to Track Finances")
 }
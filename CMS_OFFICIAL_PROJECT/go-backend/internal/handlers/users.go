@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quickgeo/cms-official-go/internal/model"
+	"github.com/quickgeo/cms-official-go/internal/responses"
+	utils "github.com/quickgeo/cms-official-go/internal/utilities/organization_page_app"
+)
+
+func userResponse(member model.OrganizationMember) utils.UserResponse {
+	resp := utils.UserResponse{UserID: member.UserID, Role: member.Role, UserType: "builder"}
+	if user := member.User; user != nil {
+		resp.Username = user.Username
+		resp.Email = user.Email
+		resp.IsActive = user.IsActive
+		resp.DateJoined = user.DateJoined
+		if user.Profile != nil {
+			resp.Phone = user.Profile.PhoneNumber
+			if user.Profile.UserType != "" {
+				resp.UserType = user.Profile.UserType
+			}
+		}
+	}
+	return resp
+}
+
+func inviteResponse(invite model.Invite, token string) utils.InviteResponse {
+	status := "pending"
+	switch {
+	case invite.AcceptedAt != nil:
+		status = "accepted"
+	case invite.RevokedAt != nil:
+		status = "revoked"
+	case !invite.ExpiresAt.After(time.Now()):
+		status = "expired"
+	}
+	return utils.InviteResponse{
+		ID:          invite.ID,
+		Email:       invite.Email,
+		Phone:       invite.Phone,
+		UserType:    invite.UserType,
+		Role:        invite.Role,
+		Status:      status,
+		InvitedByID: invite.InvitedByID,
+		CreatedAt:   invite.CreatedAt,
+		ExpiresAt:   invite.ExpiresAt,
+		Token:       token,
+	}
+}
+
+// userParam parses :user_id, answering 400 when it is not a number.
+func userParam(c *gin.Context) (uint, bool) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid user id")
+		return 0, false
+	}
+	return uint(userID), true
+}
+
+// listUsers lists the organization's accounts; ?active=true|false filters.
+func (h *Handler) listUsers(c *gin.Context) {
+	var active *bool
+	if raw := c.Query("active"); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			responses.JSON(c, http.StatusBadRequest, false, nil, "active must be true or false")
+			return
+		}
+		active = &value
+	}
+	members, err := h.users.List(c.Request.Context(), currentMember(c), active)
+	if err != nil {
+		serviceFailure(c, err, "Failed to load users")
+		return
+	}
+	payload := []utils.UserResponse{}
+	for _, member := range members {
+		payload = append(payload, userResponse(member))
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"users": payload}, "Users loaded")
+}
+
+// updateUser changes a member's user type.
+func (h *Handler) updateUser(c *gin.Context) {
+	userID, ok := userParam(c)
+	if !ok {
+		return
+	}
+	var req utils.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "User type is required")
+		return
+	}
+	member, err := h.users.SetUserType(c.Request.Context(), currentMember(c), userID, req.UserType)
+	if err != nil {
+		serviceFailure(c, err, "Failed to update user")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"user": userResponse(member)}, "User updated")
+}
+
+// deactivateUser blocks a member's login and signs them out everywhere.
+func (h *Handler) deactivateUser(c *gin.Context) {
+	userID, ok := userParam(c)
+	if !ok {
+		return
+	}
+	member, err := h.users.Deactivate(c.Request.Context(), currentMember(c), userID)
+	if err != nil {
+		serviceFailure(c, err, "Failed to deactivate user")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"user": userResponse(member)}, "User deactivated")
+}
+
+// reactivateUser lets a deactivated member log in again.
+func (h *Handler) reactivateUser(c *gin.Context) {
+	userID, ok := userParam(c)
+	if !ok {
+		return
+	}
+	member, err := h.users.Reactivate(c.Request.Context(), currentMember(c), userID)
+	if err != nil {
+		serviceFailure(c, err, "Failed to reactivate user")
+		return
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"user": userResponse(member)}, "User reactivated")
+}
+
+// listInvites lists the organization's invites.
+func (h *Handler) listInvites(c *gin.Context) {
+	invites, err := h.users.Invites(c.Request.Context(), currentMember(c))
+	if err != nil {
+		serviceFailure(c, err, "Failed to load invites")
+		return
+	}
+	payload := []utils.InviteResponse{}
+	for _, invite := range invites {
+		payload = append(payload, inviteResponse(invite, ""))
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"invites": payload}, "Invites loaded")
+}
+
+// createInvite invites someone by email or phone. The token in the response
+// is the only copy; pass it on to the invitee.
+func (h *Handler) createInvite(c *gin.Context) {
+	var req utils.InviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
+		return
+	}
+	invite, token, err := h.users.Invite(c.Request.Context(), currentMember(c), req)
+	if err != nil {
+		serviceFailure(c, err, "Failed to create invite")
+		return
+	}
+	responses.JSON(c, http.StatusCreated, true, gin.H{"invite": inviteResponse(invite, token)}, "Invite created")
+}
+
+// revokeInvite withdraws a pending invite.
+func (h *Handler) revokeInvite(c *gin.Context) {
+	inviteID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid invite id")
+		return
+	}
+	if err := h.users.RevokeInvite(c.Request.Context(), currentMember(c), uint(inviteID)); err != nil {
+		serviceFailure(c, err, "Failed to revoke invite")
+		return
+	}
+	responses.JSON(c, http.StatusNoContent, true, nil, "Invite revoked")
+}
@@ -3,29 +3,18 @@ package handlers
 import (
 	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/quickgeo/cms-official-go/internal/listquery"
 	"github.com/quickgeo/cms-official-go/internal/model"
+	"github.com/quickgeo/cms-official-go/internal/refdata"
 	"github.com/quickgeo/cms-official-go/internal/responses"
+	"github.com/quickgeo/cms-official-go/internal/services"
 	vendorUtils "github.com/quickgeo/cms-official-go/internal/utilities/vendor_page_app"
 )
 
-// Helper
-func generateVendorCode(h *Handler) string {
-	var last model.Vendor
-	h.db.Order("vendor_code desc").First(&last)
-
-	if last.VendorCode != "" && strings.HasPrefix(last.VendorCode, "VND-") {
-		numPart := strings.TrimPrefix(last.VendorCode, "VND-")
-		n, _ := strconv.Atoi(numPart)
-		return fmt.Sprintf("VND-%04d", n+1)
-	}
-	return "VND-0001"
-}
-
 func serializeVendor(v model.Vendor) vendorUtils.VendorResponse {
 	return vendorUtils.VendorResponse{
 		ID:                v.ID,
@@ -39,101 +28,152 @@ func serializeVendor(v model.Vendor) vendorUtils.VendorResponse {
 		BusinessAddress:   v.VendorBusinessAddress,
 		PaymentPreference: v.VendorPaymentPreference,
 		BankAccount:       v.VendorBankAccountNumber,
+		Version:           v.VendorVersion,
 	}
 }
 
-// VendorCollectionAPI
-func (h *Handler) VendorCollectionAPI(c *gin.Context) {
-	userID := uint(1) // Mock
+// vendorExportSpec orders vendor exports; the vendor list itself is not
+// paged.
+var vendorExportSpec = listquery.Spec{
+	Table:       "construction_vendor",
+	Sorts:       map[string]string{"name": "vendor_company_name", "code": "vendor_code", "created_at": "vendor_created_at"},
+	DefaultSort: "name",
+}
 
-	switch c.Request.Method {
-	case "GET":
-		var vendors []model.Vendor
-		query := h.db.Order("vendor_company_name, vendor_first_name, vendor_last_name")
+var vendorColumns = []column[model.Vendor]{
+	{"Code", func(v model.Vendor) interface{} { return v.VendorCode }},
+	{"Company", func(v model.Vendor) interface{} { return v.VendorCompanyName }},
+	{"First name", func(v model.Vendor) interface{} { return v.VendorFirstName }},
+	{"Last name", func(v model.Vendor) interface{} { return v.VendorLastName }},
+	{"Phone", func(v model.Vendor) interface{} { return v.VendorPrimaryPhone }},
+	{"Secondary phone", func(v model.Vendor) interface{} { return v.VendorSecondaryPhone }},
+	{"Email", func(v model.Vendor) interface{} { return v.VendorEmail }},
+	{"Business address", func(v model.Vendor) interface{} { return v.VendorBusinessAddress }},
+	{"Payment preference", func(v model.Vendor) interface{} { return v.VendorPaymentPreference }},
+	{"Created", func(v model.Vendor) interface{} { return v.VendorCreatedAt }},
+}
 
-		// If not staff, filter by created_by (simplified logic mirroring Django)
-		// Assuming user is staff for now or implementing filter:
-		// if !user.IsStaff { query = query.Where("vendor_created_by_id = ?", userID) }
-		query.Where("vendor_created_by_id = ?", userID).Find(&vendors)
+// ListVendorsAPI lists the organization's vendors, or exports them as a
+// spreadsheet.
+func (h *Handler) ListVendorsAPI(c *gin.Context) {
+	if exporting(c) {
+		exportList(c, vendorExportSpec, h.dbFor(c), "vendors", vendorColumns)
+		return
+	}
+	var vendors []model.Vendor
+	query := h.dbFor(c).Order("vendor_company_name, vendor_first_name, vendor_last_name")
 
-		payload := []vendorUtils.VendorResponse{}
-		for _, v := range vendors {
-			payload = append(payload, serializeVendor(v))
-		}
-		responses.JSON(c, http.StatusOK, true, gin.H{"vendors": payload}, "Vendors loaded")
+	// Vendors are shared across the organization; the tenant filter scopes
+	// the query.
+	query.Find(&vendors)
 
-	case "POST":
-		var req vendorUtils.CreateVendorRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
-			return
-		}
+	payload := []vendorUtils.VendorResponse{}
+	for _, v := range vendors {
+		payload = append(payload, serializeVendor(v))
+	}
+	responses.JSON(c, http.StatusOK, true, gin.H{"vendors": payload}, "Vendors loaded")
+}
 
-		if req.CompanyName == "" || req.FirstName == "" || req.PrimaryPhone == "" {
-			responses.JSON(c, http.StatusBadRequest, false, nil, "Company, First Name, Phone required")
-			return
-		}
+// CreateVendorAPI adds a vendor; online payment needs a PIN.
+func (h *Handler) CreateVendorAPI(c *gin.Context) {
+	userID := currentUser(c)
 
-		pref := "offline"
-		if req.PaymentPreference != "" {
-			pref = strings.ToLower(req.PaymentPreference)
-		}
+	var req vendorUtils.CreateVendorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
+		return
+	}
 
-		pinHash := ""
-		bankAcc := ""
-		if pref == "online" {
-			if req.PhonePIN == "" {
-				responses.JSON(c, http.StatusBadRequest, false, nil, "PIN required for online")
-				return
-			}
-			pinHash = req.PhonePIN // In real app, hash this!
-		} else {
-			bankAcc = req.BankAccount
-		}
+	if req.CompanyName == "" || req.FirstName == "" || req.PrimaryPhone == "" {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Company, First Name, Phone required")
+		return
+	}
 
-		vendor := model.Vendor{
-			VendorCreatedByID:          &userID,
-			VendorCode:                 generateVendorCode(h),
-			VendorCompanyName:          req.CompanyName,
-			VendorFirstName:            req.FirstName,
-			VendorLastName:             req.LastName,
-			VendorPrimaryPhone:         req.PrimaryPhone,
-			VendorSecondaryPhone:       req.SecondaryPhone,
-			VendorEmail:                req.Email,
-			VendorBusinessAddress:      req.BusinessAddress,
-			VendorPaymentPreference:    pref,
-			VendorBankAccountNumber:    bankAcc,
-			VendorOnlinePaymentPINHash: pinHash,
-			VendorCreatedAt:            time.Now(),
-		}
+	pref := "offline"
+	if req.PaymentPreference != "" {
+		pref = strings.ToLower(req.PaymentPreference)
+	}
 
-		if err := h.db.Create(&vendor).Error; err != nil {
-			responses.JSON(c, http.StatusBadRequest, false, nil, "Failed to create vendor")
+	pinHash := ""
+	bankAcc := ""
+	if pref == "online" {
+		if req.PhonePIN == "" {
+			responses.JSON(c, http.StatusBadRequest, false, nil, "PIN required for online")
 			return
 		}
+		pinHash = req.PhonePIN // In real app, hash this!
+	} else {
+		bankAcc = req.BankAccount
+	}
 
-		responses.JSON(c, http.StatusCreated, true, map[string]interface{}{"vendor": serializeVendor(vendor)}, "Vendor created")
+	vendor := model.Vendor{
+		VendorCreatedByID:          &userID,
+		VendorCompanyName:          req.CompanyName,
+		VendorFirstName:            req.FirstName,
+		VendorLastName:             req.LastName,
+		VendorPrimaryPhone:         req.PrimaryPhone,
+		VendorSecondaryPhone:       req.SecondaryPhone,
+		VendorEmail:                req.Email,
+		VendorBusinessAddress:      req.BusinessAddress,
+		VendorPaymentPreference:    pref,
+		VendorBankAccountNumber:    bankAcc,
+		VendorOnlinePaymentPINHash: pinHash,
+		VendorCreatedAt:            time.Now(),
+	}
+
+	if err := h.dbFor(c).Create(&vendor).Error; err != nil {
+		responses.JSON(c, http.StatusBadRequest, false, nil, "Failed to create vendor")
+		return
 	}
+
+	responses.JSON(c, http.StatusCreated, true, map[string]interface{}{"vendor": serializeVendor(vendor)}, "Vendor created")
 }
 
-// VendorDetailAPI
-func (h *Handler) VendorDetailAPI(c *gin.Context) {
+// loadVendor resolves the organization's :id vendor, answering 404 when it is missing.
+func (h *Handler) loadVendor(c *gin.Context) (model.Vendor, bool) {
 	idStr := c.Param("id")
-	userID := uint(1) // Mock
 
 	var vendor model.Vendor
-	if err := h.db.Where("id = ? AND vendor_created_by_id = ?", idStr, userID).First(&vendor).Error; err != nil {
+	if err := h.dbFor(c).Where("id = ?", idStr).First(&vendor).Error; err != nil {
 		responses.JSON(c, http.StatusNotFound, false, nil, "Vendor not found")
+		return vendor, false
+	}
+	return vendor, true
+}
+
+// VendorDetailAPI returns one vendor with its ETag.
+func (h *Handler) VendorDetailAPI(c *gin.Context) {
+	vendor, ok := h.loadVendor(c)
+	if !ok {
+		return
+	}
+	c.Header("ETag", entityETag("vendor", vendor.ID, vendor.VendorVersion))
+	responses.JSON(c, http.StatusOK, true, map[string]interface{}{"vendor": serializeVendor(vendor)}, "Vendor loaded")
+}
+
+// DeleteVendorAPI removes a vendor.
+func (h *Handler) DeleteVendorAPI(c *gin.Context) {
+	vendor, ok := h.loadVendor(c)
+	if !ok {
 		return
 	}
+	h.dbFor(c).Delete(&vendor)
+	responses.JSON(c, http.StatusNoContent, true, nil, "Deleted")
+}
 
-	if c.Request.Method == "DELETE" {
-		h.db.Delete(&vendor)
-		responses.JSON(c, http.StatusNoContent, true, nil, "Deleted")
+// UpdateVendorAPI saves a vendor edit guarded by If-Match.
+func (h *Handler) UpdateVendorAPI(c *gin.Context) {
+	vendor, ok := h.loadVendor(c)
+	if !ok {
+		return
+	}
+
+	etag := entityETag("vendor", vendor.ID, vendor.VendorVersion)
+	if ifMatchFails(c, etag) {
+		preconditionFailed(c, etag, map[string]interface{}{"vendor": serializeVendor(vendor)})
 		return
 	}
 
-	// UPDATE
 	var req vendorUtils.CreateVendorRequest // Reusing create struct as it has same fields
 	if err := c.ShouldBindJSON(&req); err != nil {
 		responses.JSON(c, http.StatusBadRequest, false, nil, "Invalid payload")
@@ -171,29 +211,48 @@ func (h *Handler) VendorDetailAPI(c *gin.Context) {
 		}
 	}
 
-	h.db.Save(&vendor)
+	expected := vendor.VendorVersion
+	vendor.VendorVersion++
+	vendor.VendorUpdatedAt = time.Now()
+	saved, err := services.SaveVersioned(h.dbFor(c), &vendor, "vendor_version", expected)
+	if err != nil {
+		responses.JSON(c, http.StatusInternalServerError, false, nil, "Failed to update vendor")
+		return
+	}
+	if !saved {
+		var current model.Vendor
+		h.dbFor(c).First(&current, vendor.ID)
+		versionConflict(c, entityETag("vendor", current.ID, current.VendorVersion), map[string]interface{}{"vendor": serializeVendor(current)})
+		return
+	}
+
+	c.Header("ETag", entityETag("vendor", vendor.ID, vendor.VendorVersion))
 	responses.JSON(c, http.StatusOK, true, map[string]interface{}{"vendor": serializeVendor(vendor)}, "Vendor updated")
 }
 
 // VendorChoicesAPI
 func (h *Handler) VendorChoicesAPI(c *gin.Context) {
-	userID := uint(1)
-	var vendors []model.Vendor
-	h.db.Where("vendor_created_by_id = ?", userID).Order("vendor_company_name").Find(&vendors)
-
-	payload := []vendorUtils.VendorChoice{}
-	for _, v := range vendors {
-		name := v.VendorCompanyName
-		if name == "" {
-			name = fmt.Sprintf("%s %s", v.VendorFirstName, v.VendorLastName)
+	key := fmt.Sprintf("vendor-choices:%d", currentOrganization(c))
+	h.serveReference(c, key, []string{refdata.Vendors}, "Choices loaded", "Failed to load vendors", func() (interface{}, error) {
+		var vendors []model.Vendor
+		if err := h.dbFor(c).Order("vendor_company_name").Find(&vendors).Error; err != nil {
+			return nil, err
 		}
 
-		payload = append(payload, vendorUtils.VendorChoice{
-			ID:          v.ID,
-			Name:        name,
-			DisplayName: name,
-			Code:        v.VendorCode,
-		})
-	}
-	responses.JSON(c, http.StatusOK, true, gin.H{"vendors": payload}, "Choices loaded")
+		payload := []vendorUtils.VendorChoice{}
+		for _, v := range vendors {
+			name := v.VendorCompanyName
+			if name == "" {
+				name = fmt.Sprintf("%s %s", v.VendorFirstName, v.VendorLastName)
+			}
+
+			payload = append(payload, vendorUtils.VendorChoice{
+				ID:          v.ID,
+				Name:        name,
+				DisplayName: name,
+				Code:        v.VendorCode,
+			})
+		}
+		return gin.H{"vendors": payload}, nil
+	})
 }
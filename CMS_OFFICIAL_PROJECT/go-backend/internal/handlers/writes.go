@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quickgeo/cms-official-go/internal/db"
+)
+
+// BusyWrites answers 503 with Retry-After, instead of a server error, when
+// the request's transaction gave up waiting for the write slot (see
+// db.GateWrites). Nothing is queued here; only the transaction holds the
+// slot.
+func BusyWrites() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := db.WatchWriteWaits(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		c.Writer = &busyWriter{ResponseWriter: c.Writer, ctx: ctx}
+		c.Next()
+	}
+}
+
+// busyWriter turns a server error into 503 once a write wait timed out.
+type busyWriter struct {
+	gin.ResponseWriter
+	ctx context.Context
+}
+
+func (w *busyWriter) WriteHeader(code int) {
+	if code >= http.StatusInternalServerError && db.WriteWaitTimedOut(w.ctx) {
+		w.Header().Set("Retry-After", "1")
+		code = http.StatusServiceUnavailable
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// ListenMetrics binds CMS_METRICS_ADDR (e.g. "127.0.0.1:9090") for Metrics,
+// a listener of its own so the numbers stay off the public API port. serve
+// answers on it until the process exits; it is nil when CMS_METRICS_ADDR is
+// unset, and then the metrics are not served at all.
+func ListenMetrics() (serve func() error, err error) {
+	addr := strings.TrimSpace(os.Getenv("CMS_METRICS_ADDR"))
+	if addr == "" {
+		return nil, nil
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("CMS_METRICS_ADDR: %w", err)
+	}
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.GET("/metrics", Metrics)
+	server := &http.Server{Handler: router, ReadHeaderTimeout: 10 * time.Second}
+	return func() error { return server.Serve(listener) }, nil
+}
+
+// Metrics exposes write-lock contention and rate limiting in the Prometheus
+// text format.
+func Metrics(c *gin.Context) {
+	stats := db.CurrentWriteStats()
+	c.Header("Content-Type", "text/plain; version=0.0.4")
+	c.Status(http.StatusOK)
+	for _, metric := range []struct {
+		name, kind, help string
+		value            string
+	}{
+		{"cms_write_lock_acquired_total", "counter", "Transactions granted the write slot.", strconv.FormatUint(stats.Acquired, 10)},
+		{"cms_write_lock_waiting", "gauge", "Transactions queued for the write slot.", strconv.FormatInt(stats.Waiting, 10)},
+		{"cms_write_lock_timeouts_total", "counter", "Transactions that gave up waiting for the write slot.", strconv.FormatUint(stats.Timeouts, 10)},
+		{"cms_write_lock_wait_seconds_total", "counter", "Time transactions spent queued for the write slot.", strconv.FormatFloat(stats.WaitTime.Seconds(), 'f', -1, 64)},
+		{"cms_write_lock_held_seconds_total", "counter", "Time the write slot was held.", strconv.FormatFloat(stats.HeldTime.Seconds(), 'f', -1, 64)},
+		{"cms_sqlite_busy_errors_total", "counter", "Statements that failed because SQLite was locked.", strconv.FormatUint(stats.BusyErrors, 10)},
+		{"cms_rate_limited_total", "counter", "Requests answered 429 for going over their rate limit.", strconv.FormatUint(rateLimited.Load(), 10)},
+	} {
+		fmt.Fprintf(c.Writer, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", metric.name, metric.help, metric.name, metric.kind, metric.name, metric.value)
+	}
+}
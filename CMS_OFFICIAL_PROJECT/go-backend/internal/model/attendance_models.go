@@ -1,10 +1,6 @@
 package model
 
 import (
-	"errors"
-	"fmt"
-	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
@@ -21,6 +17,8 @@ type AttendanceBatch struct {
 	ID          uint               `gorm:"column:id;primaryKey" json:"id"`
 	Name        string             `gorm:"column:name" json:"name"`
 	Description string             `gorm:"column:description" json:"description"`
+	ProjectID   *uint              `gorm:"column:project_id" json:"project_id,omitempty"`
+	OrgID       *uint              `gorm:"column:org_id" json:"-"`
 	CreatedAt   time.Time          `gorm:"column:created_at" json:"created_at"`
 	Members     []AttendanceMember `gorm:"foreignKey:BatchID" json:"members,omitempty"`
 }
@@ -67,28 +65,15 @@ func (AttendanceRecord) TableName() string {
 	return "construction_attendancerecord"
 }
 
-var attendanceCodePattern = regexp.MustCompile(`^ATT-(\d{4})$`)
-
 func (r *AttendanceRecord) generateAttendanceCode(tx *gorm.DB) error {
 	if r.AttendanceCode != "" {
 		return nil
 	}
-	var last AttendanceRecord
-	err := tx.Order("attendance_code desc").Limit(1).First(&last).Error
-	seq := 1
-	if err == nil && attendanceCodePattern.MatchString(last.AttendanceCode) {
-		matches := attendanceCodePattern.FindStringSubmatch(last.AttendanceCode)
-		if len(matches) == 2 {
-			v, parseErr := strconv.Atoi(matches[1])
-			if parseErr == nil {
-				seq = v + 1
-			}
-		}
-	}
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+	code, err := NextCode(tx, SequenceAttendance)
+	if err != nil {
 		return err
 	}
-	r.AttendanceCode = fmt.Sprintf("ATT-%04d", seq)
+	r.AttendanceCode = code
 	return nil
 }
 
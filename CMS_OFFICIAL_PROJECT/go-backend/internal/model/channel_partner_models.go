@@ -1,6 +1,10 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // ChannelPartner mirrors the construction_channelpartner table.
 type ChannelPartner struct {
@@ -13,8 +17,22 @@ type ChannelPartner struct {
 	ChannelPartnerReraNumber   string    `gorm:"column:channel_partner_rera_number" json:"rera_number"`
 	ChannelPartnerCreatedAt    time.Time `gorm:"column:channel_partner_created_at" json:"created_at"`
 	ChannelPartnerUpdatedAt    time.Time `gorm:"column:channel_partner_updated_at" json:"updated_at"`
+	OrgID                      *uint     `gorm:"column:org_id" json:"-"`
 }
 
 func (ChannelPartner) TableName() string {
 	return "construction_channelpartner"
 }
+
+// BeforeCreate assigns the next channel partner code when none was supplied.
+func (p *ChannelPartner) BeforeCreate(tx *gorm.DB) error {
+	if p.ChannelPartnerCode != "" {
+		return nil
+	}
+	code, err := NextCode(tx, SequenceChannelPartner)
+	if err != nil {
+		return err
+	}
+	p.ChannelPartnerCode = code
+	return nil
+}
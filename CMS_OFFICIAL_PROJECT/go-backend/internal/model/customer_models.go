@@ -1,6 +1,10 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // Customer mirrors the construction_customer table.
 type Customer struct {
@@ -25,8 +29,22 @@ type Customer struct {
 	CustomerPasswordHash            string    `gorm:"column:customer_password_hash" json:"password_hash"`
 	CustomerCreatedAt               time.Time `gorm:"column:customer_created_at" json:"created_at"`
 	CustomerUpdatedAt               time.Time `gorm:"column:customer_updated_at" json:"updated_at"`
+	OrgID                           *uint     `gorm:"column:org_id" json:"-"`
 }
 
 func (Customer) TableName() string {
 	return "construction_customer"
 }
+
+// BeforeCreate assigns the next customer code when none was supplied.
+func (c *Customer) BeforeCreate(tx *gorm.DB) error {
+	if c.CustomerCode != "" {
+		return nil
+	}
+	code, err := NextCode(tx, SequenceCustomer)
+	if err != nil {
+		return err
+	}
+	c.CustomerCode = code
+	return nil
+}
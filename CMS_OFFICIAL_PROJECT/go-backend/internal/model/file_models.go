@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// StoredFile records an upload: where the store keeps it, what it is and who
+// sent it. Key is also what the Django file column holds, so a payment's
+// receipt or a profile's avatar leads back here. Images carry the key of a
+// JPEG thumbnail.
+type StoredFile struct {
+	ID             uint      `gorm:"column:id;primaryKey" json:"id"`
+	Key            string    `gorm:"column:key;size:255;not null;uniqueIndex" json:"key"`
+	Name           string    `gorm:"column:name;size:255;not null" json:"name"`
+	ContentType    string    `gorm:"column:content_type;size:100;not null" json:"content_type"`
+	Size           int64     `gorm:"column:size;not null" json:"size"`
+	SHA256         string    `gorm:"column:sha256;size:64;not null" json:"sha256"`
+	ThumbnailKey   string    `gorm:"column:thumbnail_key;size:255" json:"thumbnail_key"`
+	Purpose        string    `gorm:"column:purpose;size:32;not null" json:"purpose"`
+	OrganizationID uint      `gorm:"column:organization_id;not null;index" json:"organization_id"`
+	UploadedByID   uint      `gorm:"column:uploaded_by_id;not null" json:"uploaded_by_id"`
+	CreatedAt      time.Time `gorm:"column:created_at;not null" json:"created_at"`
+}
+
+func (StoredFile) TableName() string {
+	return "cms_file"
+}
@@ -10,6 +10,7 @@ type MaterialItem struct {
 	MaterialItemIsActive    bool      `gorm:"column:material_item_is_active" json:"is_active"`
 	MaterialItemCreatedAt   time.Time `gorm:"column:material_item_created_at" json:"created_at"`
 	MaterialItemUpdatedAt   time.Time `gorm:"column:material_item_updated_at" json:"updated_at"`
+	OrgID                   *uint     `gorm:"column:org_id" json:"-"`
 }
 
 func (MaterialItem) TableName() string {
@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+// Member roles within an organization. Owners and admins manage members;
+// every member sees the organization's data.
+const (
+	MemberRoleOwner  = "owner"
+	MemberRoleAdmin  = "admin"
+	MemberRoleMember = "member"
+)
+
+// Organization is the company that owns projects, vendors, customers and
+// the rest; all of them carry its id in org_id. The table belongs to the Go
+// service.
+type Organization struct {
+	ID        uint                 `gorm:"column:id;primaryKey" json:"id"`
+	Name      string               `gorm:"column:name;size:255;not null" json:"name"`
+	CreatedAt time.Time            `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time            `gorm:"column:updated_at" json:"updated_at"`
+	Members   []OrganizationMember `gorm:"foreignKey:OrganizationID" json:"members,omitempty"`
+}
+
+func (Organization) TableName() string {
+	return "cms_organization"
+}
+
+// OrganizationMember puts a user in an organization. A user belongs to one
+// organization at most.
+type OrganizationMember struct {
+	ID             uint      `gorm:"column:id;primaryKey" json:"id"`
+	OrganizationID uint      `gorm:"column:organization_id;not null;index" json:"organization_id"`
+	UserID         uint      `gorm:"column:user_id;not null;uniqueIndex" json:"user_id"`
+	Role           string    `gorm:"column:role;size:16;not null;default:'member'" json:"role"`
+	CreatedAt      time.Time `gorm:"column:created_at" json:"created_at"`
+	User           *User     `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (OrganizationMember) TableName() string {
+	return "cms_organization_member"
+}
+
+// Invite asks someone, by email or phone, to create an account in an
+// organization. Its token is stored hashed and works once, until ExpiresAt.
+type Invite struct {
+	ID             uint       `gorm:"column:id;primaryKey" json:"id"`
+	OrganizationID uint       `gorm:"column:organization_id;not null;index" json:"organization_id"`
+	Email          string     `gorm:"column:email;size:254" json:"email"`
+	Phone          string     `gorm:"column:phone;size:32" json:"phone"`
+	UserType       string     `gorm:"column:user_type;size:32;not null" json:"user_type"`
+	Role           string     `gorm:"column:role;size:16;not null" json:"role"`
+	TokenHash      string     `gorm:"column:token_hash;size:64;not null;uniqueIndex" json:"-"`
+	InvitedByID    uint       `gorm:"column:invited_by_id;not null" json:"invited_by_id"`
+	CreatedAt      time.Time  `gorm:"column:created_at" json:"created_at"`
+	ExpiresAt      time.Time  `gorm:"column:expires_at" json:"expires_at"`
+	AcceptedAt     *time.Time `gorm:"column:accepted_at" json:"accepted_at"`
+	AcceptedUserID *uint      `gorm:"column:accepted_user_id" json:"accepted_user_id"`
+	RevokedAt      *time.Time `gorm:"column:revoked_at" json:"revoked_at"`
+}
+
+func (Invite) TableName() string {
+	return "cms_organization_invite"
+}
@@ -28,6 +28,8 @@ type Project struct {
 	ProjectCreatedAt            time.Time         `gorm:"column:project_created_at" json:"project_created_at"`
 	ProjectUpdatedAt            time.Time         `gorm:"column:project_updated_at" json:"project_updated_at"`
 	ProjectDeletedAt            *time.Time        `gorm:"column:project_deleted_at" json:"project_deleted_at,omitempty"`
+	ProjectVersion              uint              `gorm:"column:project_version;default:1" json:"version"`
+	OrgID                       *uint             `gorm:"column:org_id" json:"-"`
 	Blocks                      []ProjectBlock    `gorm:"foreignKey:ProjectBlockProjectID" json:"blocks,omitempty"`
 	ManpowerExpenses            []ManpowerExpense `gorm:"foreignKey:ManpowerExpenseProjectID" json:"manpower_expenses,omitempty"`
 	MaterialExpenses            []MaterialExpense `gorm:"foreignKey:MaterialExpenseProjectID" json:"material_expenses,omitempty"`
@@ -52,6 +54,7 @@ type ProjectBlock struct {
 	ProjectBlockUnitLayout    datatypes.JSON `gorm:"column:project_block_unit_layout_template" json:"project_block_unit_layout_template"`
 	ProjectBlockCreatedAt     time.Time      `gorm:"column:project_block_created_at" json:"project_block_created_at"`
 	ProjectBlockUpdatedAt     time.Time      `gorm:"column:project_block_updated_at" json:"project_block_updated_at"`
+	ProjectBlockVersion       uint           `gorm:"column:project_block_version;default:1" json:"version"`
 	Units                     []ProjectUnit  `gorm:"foreignKey:ProjectUnitBlockID" json:"units,omitempty"`
 	ProjectBlockProject       Project        `gorm:"foreignKey:ProjectBlockProjectID" json:"-"`
 }
@@ -84,6 +87,7 @@ type ProjectUnit struct {
 	ProjectUnitUpdatedAt             time.Time    `gorm:"column:project_unit_updated_at" json:"updated_at"`
 	ProjectUnitBuyerCustomerID       *uint        `gorm:"column:project_unit_buyer_customer_id" json:"buyer_customer_id,omitempty"`
 	ProjectUnitBuyerChannelPartnerID *uint        `gorm:"column:project_unit_buyer_channel_partner_id" json:"buyer_channel_partner_id,omitempty"`
+	ProjectUnitVersion               uint         `gorm:"column:project_unit_version;default:1" json:"version"`
 	ProjectUnitBlock                 ProjectBlock `gorm:"foreignKey:ProjectUnitBlockID" json:"-"`
 }
 
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// PaymentReceipt is the numbered receipt issued for one payment. Ledger says
+// which payment table PaymentID points into ("projects", "flats" or
+// "plots"); FileKey is the stored PDF, empty until it has been rendered.
+// Numbers come from the organization's own counter of the receipt code
+// sequence and are never reused within the organization.
+type PaymentReceipt struct {
+	ID             uint      `gorm:"column:id;primaryKey" json:"id"`
+	Number         string    `gorm:"column:number;size:32;not null;uniqueIndex:cms_payment_receipt_number,priority:2" json:"number"`
+	Ledger         string    `gorm:"column:ledger;size:16;not null;uniqueIndex:cms_payment_receipt_payment" json:"ledger"`
+	PaymentID      uint      `gorm:"column:payment_id;not null;uniqueIndex:cms_payment_receipt_payment" json:"payment_id"`
+	OrganizationID uint      `gorm:"column:organization_id;not null;uniqueIndex:cms_payment_receipt_number,priority:1" json:"organization_id"`
+	Payer          string    `gorm:"column:payer;size:255;not null" json:"payer"`
+	Amount         float64   `gorm:"column:amount;not null" json:"amount"`
+	FileKey        string    `gorm:"column:file_key;size:255" json:"file_key"`
+	IssuedByID     uint      `gorm:"column:issued_by_id;not null" json:"issued_by_id"`
+	IssuedAt       time.Time `gorm:"column:issued_at;not null" json:"issued_at"`
+}
+
+func (PaymentReceipt) TableName() string {
+	return "cms_payment_receipt"
+}
+
+// Letterhead is what an organization prints at the top and bottom of its
+// receipts. LogoKey is an uploaded JPEG or PNG in file storage.
+type Letterhead struct {
+	OrganizationID uint      `gorm:"column:organization_id;primaryKey" json:"organization_id"`
+	Name           string    `gorm:"column:name;size:255;not null" json:"name"`
+	Address        string    `gorm:"column:address;size:1000" json:"address"`
+	Phone          string    `gorm:"column:phone;size:64" json:"phone"`
+	Email          string    `gorm:"column:email;size:254" json:"email"`
+	TaxID          string    `gorm:"column:tax_id;size:32" json:"tax_id"`
+	Footer         string    `gorm:"column:footer;size:1000" json:"footer"`
+	LogoKey        string    `gorm:"column:logo_key;size:255" json:"logo_key"`
+	UpdatedAt      time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (Letterhead) TableName() string {
+	return "cms_letterhead"
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// RefDataVersion counts writes to one reference table. SQLite triggers bump
+// it on every insert, update and delete so cached lists know when to reload.
+type RefDataVersion struct {
+	Name      string    `gorm:"column:name;primaryKey;size:100" json:"name"`
+	Version   int64     `gorm:"column:version;not null;default:0" json:"version"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (RefDataVersion) TableName() string {
+	return "cms_refdata_version"
+}
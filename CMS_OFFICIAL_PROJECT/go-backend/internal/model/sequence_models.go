@@ -0,0 +1,151 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Sequence names understood by NextCode.
+const (
+	SequenceAttendance     = "attendance"
+	SequenceVendor         = "vendor"
+	SequenceSupervisor     = "supervisor"
+	SequenceCustomer       = "customer"
+	SequenceChannelPartner = "channel_partner"
+	SequenceReceipt        = "receipt"
+)
+
+// CodeSequence configures how one kind of entity code looks. Rows live in
+// cms_code_sequence so prefixes, padding and yearly resets can be changed
+// without a deploy; DefaultCodeSequences seeds the table.
+type CodeSequence struct {
+	Name        string `gorm:"column:name;primaryKey;size:64" json:"name"`
+	Prefix      string `gorm:"column:prefix;not null" json:"prefix"`
+	Padding     int    `gorm:"column:padding;not null;default:4" json:"padding"`
+	ResetYearly bool   `gorm:"column:reset_yearly;not null;default:false" json:"reset_yearly"`
+}
+
+func (CodeSequence) TableName() string {
+	return "cms_code_sequence"
+}
+
+// CodeCounter holds the last value handed out for a sequence and period.
+// Period is empty for sequences that never reset, otherwise the year.
+type CodeCounter struct {
+	Sequence  string `gorm:"column:sequence_name;primaryKey;size:64" json:"sequence"`
+	Period    string `gorm:"column:period;primaryKey;size:16" json:"period"`
+	LastValue int64  `gorm:"column:last_value;not null" json:"last_value"`
+}
+
+func (CodeCounter) TableName() string {
+	return "cms_code_counter"
+}
+
+// DefaultCodeSequences keeps the formats the Django backend already uses.
+var DefaultCodeSequences = []CodeSequence{
+	{Name: SequenceAttendance, Prefix: "ATT-", Padding: 4},
+	{Name: SequenceVendor, Prefix: "VND-", Padding: 4},
+	{Name: SequenceSupervisor, Prefix: "SUP-", Padding: 4},
+	{Name: SequenceCustomer, Prefix: "CUS-", Padding: 4},
+	{Name: SequenceChannelPartner, Prefix: "CP-", Padding: 4},
+	{Name: SequenceReceipt, Prefix: "RCP-", Padding: 5, ResetYearly: true},
+}
+
+// codeColumns says where each sequence's codes are stored, so the counter can
+// skip past codes written by Django or by older builds of this service. org
+// is the organization column of sequences counted per organization.
+var codeColumns = map[string]struct{ table, column, org string }{
+	SequenceAttendance:     {"construction_attendancerecord", "attendance_code", ""},
+	SequenceVendor:         {"construction_vendor", "vendor_code", ""},
+	SequenceSupervisor:     {"construction_supervisor", "supervisor_code", ""},
+	SequenceCustomer:       {"construction_customer", "customer_code", ""},
+	SequenceChannelPartner: {"construction_channelpartner", "channel_partner_code", ""},
+	SequenceReceipt:        {"cms_payment_receipt", "number", "organization_id"},
+}
+
+func loadCodeSequence(tx *gorm.DB, name string) (CodeSequence, error) {
+	var seq CodeSequence
+	err := tx.Where("name = ?", name).First(&seq).Error
+	if err == nil {
+		return seq, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return seq, err
+	}
+	for _, def := range DefaultCodeSequences {
+		if def.Name == name {
+			return def, nil
+		}
+	}
+	return seq, fmt.Errorf("unknown code sequence %q", name)
+}
+
+// NextCode hands out the next code of a sequence, e.g. "VND-0042" or
+// "ATT-2026-0007" for a yearly sequence. The counter is bumped with a single
+// upsert, so concurrent writers never get the same number; run it in the
+// same transaction as the insert that uses the code so a rollback returns it.
+// The first bump of a period starts after the highest numeric code already
+// stored, and the counter never falls behind codes written elsewhere.
+func NextCode(tx *gorm.DB, name string) (string, error) {
+	return nextCode(tx, name, 0)
+}
+
+// NextOrganizationCode is NextCode with a counter of its own for orgID,
+// kept under the sequence name "<name>:<orgID>"; prefix, padding and resets
+// still come from name. Two organizations may therefore hold the same code.
+func NextOrganizationCode(tx *gorm.DB, name string, orgID uint) (string, error) {
+	if orgID == 0 {
+		return "", fmt.Errorf("code sequence %q needs an organization", name)
+	}
+	return nextCode(tx, name, orgID)
+}
+
+func nextCode(tx *gorm.DB, name string, orgID uint) (string, error) {
+	seq, err := loadCodeSequence(tx, name)
+	if err != nil {
+		return "", err
+	}
+
+	prefix, period := seq.Prefix, ""
+	if seq.ResetYearly {
+		period = time.Now().Format("2006")
+		prefix = fmt.Sprintf("%s%s-", seq.Prefix, period)
+	}
+
+	counter := name
+	if orgID != 0 {
+		counter = fmt.Sprintf("%s:%d", name, orgID)
+	}
+	stored, storedArgs := "0", []interface{}(nil)
+	if target, ok := codeColumns[name]; ok {
+		// GLOB keeps "ATT-2026-0001" out of the plain "ATT-" sequence.
+		stored = fmt.Sprintf(
+			"SELECT COALESCE(MAX(CAST(SUBSTR(%[1]s, %[3]d) AS INTEGER)), 0) FROM %[2]s WHERE %[1]s LIKE ? ESCAPE '\\' AND SUBSTR(%[1]s, %[3]d) NOT GLOB '*[^0-9]*'",
+			target.column, target.table, len(prefix)+1,
+		)
+		storedArgs = []interface{}{escapeLike(prefix) + "%"}
+		if orgID != 0 && target.org != "" {
+			stored += " AND " + target.org + " = ?"
+			storedArgs = append(storedArgs, orgID)
+		}
+		stored = "(" + stored + ")"
+	}
+
+	var value int64
+	query := "INSERT INTO cms_code_counter (sequence_name, period, last_value) VALUES (?, ?, " + stored + " + 1) " +
+		"ON CONFLICT (sequence_name, period) DO UPDATE SET last_value = MAX(cms_code_counter.last_value + 1, excluded.last_value) " +
+		"RETURNING last_value"
+	args := append([]interface{}{counter, period}, storedArgs...)
+	if err := tx.Raw(query, args...).Scan(&value).Error; err != nil {
+		return "", fmt.Errorf("failed to bump %s sequence: %w", name, err)
+	}
+	return fmt.Sprintf("%s%0*d", prefix, seq.Padding, value), nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer("%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func sequenceDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	database, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "codes.sqlite3")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.AutoMigrate(&CodeSequence{}, &CodeCounter{}, &Vendor{}, &Supervisor{}, &PaymentReceipt{}); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+// TestCodeSequences checks codes run on without gaps within an organization,
+// and that organizations and sequences count independently.
+func TestCodeSequences(t *testing.T) {
+	database := sequenceDB(t)
+	year := time.Now().Format("2006")
+	steps := []struct {
+		sequence string
+		org      uint
+		want     string
+	}{
+		{SequenceReceipt, 1, "RCP-" + year + "-00001"},
+		{SequenceReceipt, 1, "RCP-" + year + "-00002"},
+		{SequenceReceipt, 2, "RCP-" + year + "-00001"},
+		{SequenceReceipt, 1, "RCP-" + year + "-00003"},
+		{SequenceVendor, 0, "VND-0001"},
+		{SequenceReceipt, 2, "RCP-" + year + "-00002"},
+		{SequenceVendor, 0, "VND-0002"},
+		{SequenceSupervisor, 0, "SUP-0001"},
+	}
+	for _, step := range steps {
+		var code string
+		var err error
+		if step.org != 0 {
+			code, err = NextOrganizationCode(database, step.sequence, step.org)
+		} else {
+			code, err = NextCode(database, step.sequence)
+		}
+		if err != nil {
+			t.Fatalf("%s for org %d: %v", step.sequence, step.org, err)
+		}
+		if code != step.want {
+			t.Errorf("%s for org %d = %s, want %s", step.sequence, step.org, code, step.want)
+		}
+	}
+
+	if _, err := NextOrganizationCode(database, SequenceReceipt, 0); err == nil {
+		t.Error("NextOrganizationCode without an organization succeeded")
+	}
+}
+
+// TestCodeRollback checks a code drawn in a transaction that rolls back is
+// handed out again.
+func TestCodeRollback(t *testing.T) {
+	database := sequenceDB(t)
+	errAbort := errors.New("abort")
+	err := database.Transaction(func(tx *gorm.DB) error {
+		code, err := NextCode(tx, SequenceVendor)
+		if err != nil {
+			return err
+		}
+		if code != "VND-0001" {
+			return fmt.Errorf("first code is %s, want VND-0001", code)
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatal(err)
+	}
+	if code, err := NextCode(database, SequenceVendor); err != nil || code != "VND-0001" {
+		t.Errorf("after the rollback NextCode = %s, %v; want VND-0001 again", code, err)
+	}
+}
+
+// TestCodeSkipsStoredCodes checks a counter starts after codes already
+// written, here by Django, and ignores codes of another format.
+func TestCodeSkipsStoredCodes(t *testing.T) {
+	database := sequenceDB(t)
+	for _, code := range []string{"VND-0041", "VND-2026-0900", "VND-00x9"} {
+		if err := database.Exec("INSERT INTO construction_vendor (vendor_code) VALUES (?)", code).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if code, err := NextCode(database, SequenceVendor); err != nil || code != "VND-0042" {
+		t.Errorf("NextCode = %s, %v; want VND-0042", code, err)
+	}
+}
@@ -2,6 +2,8 @@ package model
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Vendor mirrors construction_vendor
@@ -21,6 +23,8 @@ type Vendor struct {
 	VendorCreatedAt            time.Time `gorm:"column:vendor_created_at" json:"vendor_created_at"`
 	VendorUpdatedAt            time.Time `gorm:"column:vendor_updated_at" json:"vendor_updated_at"`
 	VendorCreatedByID          *uint     `gorm:"column:vendor_created_by_id" json:"vendor_created_by_id"`
+	VendorVersion              uint      `gorm:"column:vendor_version;default:1" json:"version"`
+	OrgID                      *uint     `gorm:"column:org_id" json:"-"`
 
 	// Relations
 	VendorCreatedBy *User `gorm:"foreignKey:VendorCreatedByID" json:"-"`
@@ -29,3 +33,16 @@ type Vendor struct {
 func (Vendor) TableName() string {
 	return "construction_vendor"
 }
+
+// BeforeCreate assigns the next vendor code when none was supplied.
+func (v *Vendor) BeforeCreate(tx *gorm.DB) error {
+	if v.VendorCode != "" {
+		return nil
+	}
+	code, err := NextCode(tx, SequenceVendor)
+	if err != nil {
+		return err
+	}
+	v.VendorCode = code
+	return nil
+}
@@ -0,0 +1,322 @@
+// Package openapi builds an OpenAPI 3.1 document from the route table and
+// the request/response structs, which it reads by reflection.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/quickgeo/cms-official-go/internal/listquery"
+	"github.com/quickgeo/cms-official-go/internal/responses"
+)
+
+// Spec describes what one handler accepts and returns. Request and Response
+// are zero values of the body and of the envelope's data field; a map value
+// such as gin.H{"units": []UnitResponse{}} documents an object with those
+// keys, and nil documents a free-form value.
+type Spec struct {
+	Summary  string
+	Request  interface{}
+	Response interface{}
+	// Status is the success status, 200 when zero.
+	Status int
+	// Query lists optional query parameters besides the list parameters.
+	Query []string
+	// Headers lists optional request headers such as If-Match.
+	Headers []string
+	// List documents cursor paging, sorting and filters, and the
+	// pagination block of the response.
+	List *listquery.Spec
+	// Bare responses are not wrapped in the success/data/message envelope.
+	Bare bool
+	// ContentType overrides application/json for bare responses.
+	ContentType string
+	// Upload names the form field of a multipart/form-data request body
+	// carrying one file; it replaces Request.
+	Upload string
+	// Export documents the format parameter that downloads the list as a
+	// CSV or XLSX file instead.
+	Export bool
+}
+
+// Endpoint is one route with the Spec of the handler serving it.
+type Endpoint struct {
+	Method     string
+	Path       string
+	Deprecated bool
+	Spec       Spec
+}
+
+// Info is the document's info object.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Document is an OpenAPI 3.1 document.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// PathItem maps lower-case methods to operations.
+type PathItem map[string]*Operation
+
+// Components holds the named schemas operations refer to.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Operation is one method on one path.
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Deprecated  bool                `json:"deprecated,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter is a path, query or header parameter.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody is a JSON or multipart request body.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is one documented response.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType wraps the schema of a body.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON Schema the generator emits.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+}
+
+const (
+	jsonType      = "application/json"
+	multipartType = "multipart/form-data"
+	csvType       = "text/csv"
+	xlsxType      = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// Build documents endpoints. Gin paths are rewritten to OpenAPI templates
+// (/projects/:id becomes /projects/{id}).
+func Build(info Info, endpoints []Endpoint) Document {
+	reg := newRegistry()
+	doc := Document{OpenAPI: "3.1.0", Info: info, Paths: make(map[string]PathItem)}
+	for _, e := range endpoints {
+		path, params := template(e.Path)
+		op := &Operation{
+			OperationID: operationID(e.Method, e.Path),
+			Summary:     e.Spec.Summary,
+			Tags:        []string{tag(e.Path)},
+			Deprecated:  e.Deprecated,
+			Parameters:  params,
+			Responses:   make(map[string]Response),
+		}
+		for _, name := range e.Spec.Query {
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: "query", Schema: &Schema{Type: "string"}})
+		}
+		for _, name := range e.Spec.Headers {
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: "header", Schema: &Schema{Type: "string"}})
+		}
+		if e.Spec.List != nil {
+			op.Parameters = append(op.Parameters, listParameters(*e.Spec.List)...)
+		}
+		if e.Spec.Export {
+			op.Parameters = append(op.Parameters, Parameter{Name: "format", In: "query", Schema: &Schema{Type: "string", Enum: []string{"json", "csv", "xlsx"}}})
+		}
+		if e.Spec.Request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{jsonType: {Schema: reg.of(e.Spec.Request)}},
+			}
+		}
+		if e.Spec.Upload != "" {
+			form := &Schema{
+				Type:       "object",
+				Properties: map[string]*Schema{e.Spec.Upload: {Type: "string", Format: "binary"}},
+				Required:   []string{e.Spec.Upload},
+			}
+			op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{multipartType: {Schema: form}}}
+		}
+
+		status := e.Spec.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		body := reg.of(e.Spec.Response)
+		contentType := jsonType
+		if e.Spec.Bare {
+			if e.Spec.ContentType != "" {
+				contentType = e.Spec.ContentType
+			}
+		} else {
+			body = reg.envelope(body, e.Spec.List != nil)
+		}
+		response := Response{Description: http.StatusText(status)}
+		if status != http.StatusNoContent {
+			response.Content = map[string]MediaType{contentType: {Schema: body}}
+		}
+		if e.Spec.Export {
+			for _, fileType := range []string{csvType, xlsxType} {
+				response.Content[fileType] = MediaType{Schema: &Schema{Type: "string", Format: "binary"}}
+			}
+		}
+		op.Responses[fmt.Sprint(status)] = response
+		op.Responses["default"] = Response{
+			Description: "Error",
+			Content:     map[string]MediaType{jsonType: {Schema: reg.errorSchema()}},
+		}
+
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(e.Method)] = op
+	}
+	doc.Components.Schemas = reg.components
+	return doc
+}
+
+// Has reports whether the document covers method on a Gin path.
+func (d Document) Has(method, path string) bool {
+	key, _ := template(path)
+	_, ok := d.Paths[key][strings.ToLower(method)]
+	return ok
+}
+
+// template rewrites Gin's :param and *param segments and lists them as
+// path parameters; ids are integers, everything else strings.
+func template(path string) (string, []Parameter) {
+	segments := strings.Split(path, "/")
+	var params []Parameter
+	for i, segment := range segments {
+		if segment == "" || (segment[0] != ':' && segment[0] != '*') {
+			continue
+		}
+		name := segment[1:]
+		schema := &Schema{Type: "string"}
+		if name == "id" || strings.HasSuffix(name, "_id") {
+			schema = &Schema{Type: "integer"}
+		}
+		params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+		segments[i] = "{" + name + "}"
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// tag groups operations by the first segment after the version.
+func tag(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if segment == "api" || (strings.HasPrefix(segment, "v") && i == 1) {
+			continue
+		}
+		return segment
+	}
+	return "api"
+}
+
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		segment = strings.TrimLeft(segment, ":*")
+		segment = strings.NewReplacer("-", "_", ".", "_").Replace(segment)
+		if segment != "" {
+			id += "_" + segment
+		}
+	}
+	return id
+}
+
+// listParameters documents what listquery.Parse reads for spec.
+func listParameters(spec listquery.Spec) []Parameter {
+	sorts := make([]string, 0, 2*len(spec.Sorts))
+	for key := range spec.Sorts {
+		sorts = append(sorts, key, "-"+key)
+	}
+	sort.Strings(sorts)
+	params := []Parameter{
+		{Name: "cursor", In: "query", Schema: &Schema{Type: "string"}},
+		{Name: "page_size", In: "query", Schema: &Schema{Type: "integer"}},
+		{Name: "sort", In: "query", Schema: &Schema{Type: "string", Enum: sorts}},
+	}
+	filters := make([]string, 0, len(spec.Filters))
+	for name := range spec.Filters {
+		filters = append(filters, name)
+	}
+	sort.Strings(filters)
+	for _, name := range filters {
+		params = append(params, Parameter{Name: name, In: "query", Schema: &Schema{Type: "string"}})
+	}
+	if spec.DateColumn != "" {
+		for _, name := range []string{"date_from", "date_to"} {
+			params = append(params, Parameter{Name: name, In: "query", Schema: &Schema{Type: "string", Format: "date"}})
+		}
+	}
+	return params
+}
+
+// envelope wraps data in the responses.APIResponse shape.
+func (r *registry) envelope(data *Schema, paged bool) *Schema {
+	schema := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+			"message": {Type: "string"},
+			"data":    data,
+		},
+		Required: []string{"success", "message"},
+	}
+	if paged {
+		schema.Properties["pagination"] = r.of(responses.Pagination{})
+		schema.Required = append(schema.Required, "pagination")
+	}
+	return schema
+}
+
+// errorSchema is the envelope every failed request gets.
+func (r *registry) errorSchema() *Schema {
+	if _, ok := r.components["Error"]; !ok {
+		r.components["Error"] = &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"success": {Type: "boolean"},
+				"message": {Type: "string"},
+			},
+			Required: []string{"success", "message"},
+		}
+	}
+	return &Schema{Ref: "#/components/schemas/Error"}
+}
@@ -0,0 +1,75 @@
+// Package passwords hashes and checks auth_user passwords in Django's
+// pbkdf2_sha256 format, so accounts created here can log in to Django and
+// the other way round.
+package passwords
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	algorithm  = "pbkdf2_sha256"
+	iterations = 600000
+)
+
+// Hash encodes password as pbkdf2_sha256$<iterations>$<salt>$<hash>.
+func Hash(password string) (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	salt := base64.RawURLEncoding.EncodeToString(raw)
+	key := pbkdf2([]byte(password), []byte(salt), iterations)
+	return fmt.Sprintf("%s$%d$%s$%s", algorithm, iterations, salt, base64.StdEncoding.EncodeToString(key)), nil
+}
+
+// Check reports whether password matches encoded. Rows written before
+// passwords were hashed hold the password itself and are compared as is;
+// any other Django hasher is rejected.
+func Check(password, encoded string) bool {
+	if encoded == "" {
+		return false
+	}
+	parts := strings.Split(encoded, "$")
+	if len(parts) == 1 {
+		return subtle.ConstantTimeCompare([]byte(password), []byte(encoded)) == 1
+	}
+	if len(parts) != 4 || parts[0] != algorithm {
+		return false
+	}
+	rounds, err := strconv.Atoi(parts[1])
+	if err != nil || rounds <= 0 {
+		return false
+	}
+	want, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(pbkdf2([]byte(password), []byte(parts[2]), rounds), want) == 1
+}
+
+// pbkdf2 derives one SHA-256 sized block, which is all Django asks for.
+func pbkdf2(password, salt []byte, rounds int) []byte {
+	prf := hmac.New(sha256.New, password)
+	prf.Write(salt)
+	prf.Write(binary.BigEndian.AppendUint32(nil, 1))
+	u := prf.Sum(nil)
+	key := append([]byte(nil), u...)
+	for i := 1; i < rounds; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
@@ -0,0 +1,300 @@
+// Package pdf writes small PDF documents: pages of text in the standard
+// Helvetica fonts, lines and JPEG images. It covers what generated
+// paperwork such as receipts needs and embeds no fonts, so text is limited
+// to the Windows-1252 character set.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"strings"
+	"time"
+)
+
+// A4 page size in points.
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Font is one of the standard fonts every PDF reader has.
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+var fontNames = [...]string{"Helvetica", "Helvetica-Bold"}
+
+// Image is a picture added to a document, drawn on any of its pages.
+type Image struct {
+	id     int
+	data   []byte
+	width  int
+	height int
+	space  string
+}
+
+// Width and Height are the image's size in pixels.
+func (img *Image) Width() int  { return img.width }
+func (img *Image) Height() int { return img.height }
+
+// Document collects pages and writes them out as one PDF.
+type Document struct {
+	Title   string
+	Author  string
+	Created time.Time
+	pages   []*Page
+	images  []*Image
+}
+
+// New starts an empty document.
+func New() *Document {
+	return &Document{Created: time.Now()}
+}
+
+// Page is drawn on with coordinates in points from the top-left corner.
+type Page struct {
+	width, height float64
+	content       bytes.Buffer
+	images        map[int]*Image
+}
+
+// AddPage appends a page of the given size.
+func (d *Document) AddPage(width, height float64) *Page {
+	page := &Page{width: width, height: height, images: make(map[int]*Image)}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// JPEG adds a baseline JPEG image as is.
+func (d *Document) JPEG(data []byte) (*Image, error) {
+	config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	space := "DeviceRGB"
+	switch config.ColorModel {
+	case color.GrayModel:
+		space = "DeviceGray"
+	case color.CMYKModel:
+		return nil, fmt.Errorf("pdf: CMYK JPEG images are not supported")
+	}
+	img := &Image{id: len(d.images) + 1, data: data, width: config.Width, height: config.Height, space: space}
+	d.images = append(d.images, img)
+	return img, nil
+}
+
+// Text draws s with its baseline at y.
+func (p *Page) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td (%s) Tj ET\n",
+		font+1, num(size), num(x), num(p.height-y), escape(s))
+}
+
+// TextRight draws s so that it ends at x.
+func (p *Page) TextRight(x, y float64, font Font, size float64, s string) {
+	p.Text(x-Width(font, size, s), y, font, size, s)
+}
+
+// TextCenter draws s centred on x.
+func (p *Page) TextCenter(x, y float64, font Font, size float64, s string) {
+	p.Text(x-Width(font, size, s)/2, y, font, size, s)
+}
+
+// Gray sets the fill colour of the text drawn next, 0 black to 1 white.
+func (p *Page) Gray(level float64) {
+	fmt.Fprintf(&p.content, "%s g\n", num(level))
+}
+
+// Line strokes a line width points wide.
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n",
+		num(width), num(x1), num(p.height-y1), num(x2), num(p.height-y2))
+}
+
+// Rect strokes a rectangle whose top-left corner is x, y.
+func (p *Page) Rect(x, y, w, h, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s %s %s re S\n",
+		num(width), num(x), num(p.height-y-h), num(w), num(h))
+}
+
+// Image draws img into the w×h box whose top-left corner is x, y.
+func (p *Page) Image(img *Image, x, y, w, h float64) {
+	p.images[img.id] = img
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /Im%d Do Q\n",
+		num(w), num(h), num(x), num(p.height-y-h), img.id)
+}
+
+// Width measures s set in font at size points.
+func Width(font Font, size float64, s string) float64 {
+	widths := &helveticaWidths
+	if font == HelveticaBold {
+		widths = &helveticaBoldWidths
+	}
+	total := 0
+	for _, b := range encode(s) {
+		if b >= 32 && b < 127 {
+			total += widths[b-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// Wrap breaks s into lines no wider than width, at spaces where it can.
+func Wrap(font Font, size float64, s string, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if line != "" && Width(font, size, candidate) > width {
+				lines = append(lines, line)
+				candidate = word
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Write renders the document.
+func (d *Document) Write(w io.Writer) error {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) int {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+		return len(offsets)
+	}
+	stream := func(dict string, data []byte) int {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n<< %s /Length %d >>\nstream\n", len(offsets), dict, len(data))
+		out.Write(data)
+		out.WriteString("\nendstream\nendobj\n")
+		return len(offsets)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	// Objects 1 and 2 are the catalog and the page tree, written first
+	// so the pages can point at their parent.
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	pagesAt := len(offsets)
+	offsets = append(offsets, 0)
+
+	var fonts strings.Builder
+	for i, name := range fontNames {
+		id := object("<< /Type /Font /Subtype /Type1 /BaseFont /" + name + " /Encoding /WinAnsiEncoding >>")
+		fmt.Fprintf(&fonts, "/F%d %d 0 R ", i+1, id)
+	}
+	imageIDs := make(map[int]int)
+	for _, img := range d.images {
+		imageIDs[img.id] = stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /%s /BitsPerComponent 8 /Filter /DCTDecode",
+			img.width, img.height, img.space), img.data)
+	}
+
+	var kids strings.Builder
+	for _, page := range d.pages {
+		var packed bytes.Buffer
+		zw := zlib.NewWriter(&packed)
+		zw.Write(page.content.Bytes())
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		contents := stream("/Filter /FlateDecode", packed.Bytes())
+		var xobjects strings.Builder
+		for id := range page.images {
+			fmt.Fprintf(&xobjects, "/Im%d %d 0 R ", id, imageIDs[id])
+		}
+		resources := "<< /Font << " + fonts.String() + ">>"
+		if xobjects.Len() > 0 {
+			resources += " /XObject << " + xobjects.String() + ">>"
+		}
+		resources += " >>"
+		id := object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources %s /Contents %d 0 R >>",
+			num(page.width), num(page.height), resources, contents))
+		fmt.Fprintf(&kids, "%d 0 R ", id)
+	}
+	offsets[pagesAt] = out.Len()
+	fmt.Fprintf(&out, "2 0 obj\n<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", kids.String(), len(d.pages))
+
+	info := object(fmt.Sprintf("<< /Title (%s) /Author (%s) /Producer (CMS) /CreationDate (D:%s) >>",
+		escape(d.Title), escape(d.Author), d.Created.UTC().Format("20060102150405Z")))
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, info, xref)
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+// Bytes renders the document into memory.
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	err := d.Write(&buf)
+	return buf.Bytes(), err
+}
+
+// encode maps s to Windows-1252, replacing what it cannot hold with "?".
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r < 128 || (r >= 0xA0 && r <= 0xFF):
+			out = append(out, byte(r))
+		case winAnsi[r] != 0:
+			out = append(out, winAnsi[r])
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// winAnsi holds the Windows-1252 characters outside Latin-1.
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// escape encodes s as the body of a PDF literal string.
+func escape(s string) string {
+	var b strings.Builder
+	for _, c := range encode(s) {
+		switch c {
+		case '\\', '(', ')':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n', '\r', '\t':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// num prints a coordinate with at most two decimals.
+func num(v float64) string {
+	s := fmt.Sprintf("%.2f", v)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
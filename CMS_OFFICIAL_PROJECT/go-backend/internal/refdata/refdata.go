@@ -0,0 +1,131 @@
+// Package refdata caches reference lists (material items, labor work types,
+// vendor choices, presets, choice maps) in process. Each cached entry
+// remembers the write versions of the tables it was built from; SQLite
+// triggers bump those versions on every write, from this service or Django,
+// so a stale entry is rebuilt on its next read.
+package refdata
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/quickgeo/cms-official-go/internal/model"
+	"gorm.io/gorm"
+)
+
+// Tracked reference tables.
+const (
+	MaterialItems  = "construction_materialitem"
+	LaborWorkTypes = "construction_laborworktype"
+	Vendors        = "construction_vendor"
+	ProjectPresets = "construction_projectpreset"
+)
+
+var tracked = []string{MaterialItems, LaborWorkTypes, Vendors, ProjectPresets}
+
+// started stands in for the modification time of static data and of tables
+// not written since the triggers were installed.
+var started = time.Now().UTC().Truncate(time.Second)
+
+// Ensure installs the version triggers on the tracked tables that exist.
+func Ensure(db *gorm.DB) error {
+	for _, table := range tracked {
+		if !db.Migrator().HasTable(table) {
+			continue
+		}
+		bump := fmt.Sprintf("INSERT INTO cms_refdata_version (name, version, updated_at) VALUES ('%s', 1, CURRENT_TIMESTAMP) "+
+			"ON CONFLICT (name) DO UPDATE SET version = version + 1, updated_at = CURRENT_TIMESTAMP", table)
+		for _, event := range []string{"INSERT", "UPDATE", "DELETE"} {
+			stmt := fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS cms_refdata_%s_%s AFTER %s ON %s BEGIN %s; END", table, event, event, table, bump)
+			if err := db.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("failed to track %s: %w", table, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Entry is one cached, already serialized list.
+type Entry struct {
+	Data     json.RawMessage
+	ETag     string
+	Modified time.Time
+	versions map[string]int64
+}
+
+// Cache holds entries by key. Keys are chosen by the caller and may carry a
+// scope, e.g. "vendor-choices:1".
+type Cache struct {
+	db      *gorm.DB
+	mu      sync.Mutex
+	entries map[string]*Entry
+}
+
+// New returns an empty cache reading table versions through db.
+func New(db *gorm.DB) *Cache {
+	return &Cache{db: db, entries: make(map[string]*Entry)}
+}
+
+// Get returns the entry for key, calling load when there is none yet or
+// when one of tables was written since it was built. Static data passes no
+// tables and is loaded once.
+func (c *Cache) Get(key string, tables []string, load func() (interface{}, error)) (*Entry, error) {
+	versions := map[string]int64{}
+	modified := started
+	if len(tables) > 0 {
+		var rows []model.RefDataVersion
+		if err := c.db.Where("name IN ?", tables).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			versions[row.Name] = row.Version
+			if row.UpdatedAt.After(modified) {
+				modified = row.UpdatedAt.UTC()
+			}
+		}
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && sameVersions(entry.versions, versions) {
+		return entry, nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	entry = &Entry{
+		Data:     data,
+		ETag:     `"` + hex.EncodeToString(sum[:8]) + `"`,
+		Modified: modified,
+		versions: versions,
+	}
+
+	c.mu.Lock()
+	c.entries[key] = entry
+	c.mu.Unlock()
+	return entry, nil
+}
+
+func sameVersions(a, b map[string]int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, version := range a {
+		if other, ok := b[name]; !ok || other != version {
+			return false
+		}
+	}
+	return true
+}
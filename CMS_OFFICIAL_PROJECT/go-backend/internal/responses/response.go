@@ -4,9 +4,19 @@ import "github.com/gin-gonic/gin"
 
 // APIResponse standardizes JSON output similar to the Django backend.
 type APIResponse struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data,omitempty"`
-	Message string      `json:"message"`
+	Success    bool        `json:"success"`
+	Data       interface{} `json:"data,omitempty"`
+	Message    string      `json:"message"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// Pagination tells list clients how to fetch the next page.
+type Pagination struct {
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+	PageSize   int    `json:"page_size"`
+	Total      int64  `json:"total"`
+	Sort       string `json:"sort"`
 }
 
 // JSON writes a standardized response.
@@ -17,3 +27,13 @@ func JSON(c *gin.Context, status int, success bool, data interface{}, message st
 		Message: message,
 	})
 }
+
+// Page writes a successful list response together with its pagination block.
+func Page(c *gin.Context, status int, data interface{}, message string, pagination Pagination) {
+	c.JSON(status, APIResponse{
+		Success:    true,
+		Data:       data,
+		Message:    message,
+		Pagination: &pagination,
+	})
+}
@@ -0,0 +1,227 @@
+// Package search keeps an SQLite FTS5 index over the records people look up
+// by name or phone (customers, units, vendors, channel partners, projects)
+// and answers ranked queries against it.
+//
+// The index is maintained by SQLite triggers on the source tables, so rows
+// written by the Django backend are indexed exactly like rows written here.
+// Each entry carries its row's organization in org_id.
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	indexTable = "cms_search_index"
+	// vocabTable was a vocabulary view over every organization's entries;
+	// it is dropped where it still exists.
+	vocabTable = "cms_search_vocab"
+)
+
+// Result kinds.
+const (
+	KindCustomer       = "customer"
+	KindUnit           = "unit"
+	KindVendor         = "vendor"
+	KindChannelPartner = "channel_partner"
+	KindProject        = "project"
+)
+
+// source describes how one table is flattened into the index. Expressions
+// are SQL over the row placeholder {r}; the trigger and rebuild statements
+// swap in NEW/OLD or a table alias.
+type source struct {
+	kind   string
+	slot   int // index rowid = id * kindSlots + slot, so updates delete by rowid
+	table  string
+	title  string
+	detail string
+	phones []string
+	org    string
+}
+
+const kindSlots = 8
+
+var sources = []source{
+	{
+		kind: KindCustomer, slot: 1, table: "construction_customer",
+		title:  "{r}.customer_name",
+		detail: joinText("{r}.customer_code", "{r}.customer_company_name", "{r}.customer_email", "{r}.customer_address"),
+		phones: []string{"{r}.customer_primary_phone_number", "{r}.customer_secondary_phone_number"},
+		org:    "{r}.org_id",
+	},
+	{
+		kind: KindUnit, slot: 2, table: "construction_projectunit",
+		title: "{r}.project_unit_label",
+		detail: joinText("{r}.project_unit_buyer_name", "{r}.project_unit_buyer_email",
+			"(SELECT p.project_code FROM construction_projectblock b JOIN construction_project p ON p.id = b.project_block_project_id WHERE b.id = {r}.project_unit_block_id)"),
+		phones: []string{"{r}.project_unit_buyer_phone"},
+		org:    "(SELECT p.org_id FROM construction_projectblock b JOIN construction_project p ON p.id = b.project_block_project_id WHERE b.id = {r}.project_unit_block_id)",
+	},
+	{
+		kind: KindVendor, slot: 3, table: "construction_vendor",
+		title:  "{r}.vendor_company_name",
+		detail: joinText("{r}.vendor_code", "{r}.vendor_first_name", "{r}.vendor_last_name", "{r}.vendor_email"),
+		phones: []string{"{r}.vendor_primary_phone_number", "{r}.vendor_secondary_phone_number"},
+		org:    "{r}.org_id",
+	},
+	{
+		kind: KindChannelPartner, slot: 4, table: "construction_channelpartner",
+		title:  "{r}.channel_partner_name",
+		detail: joinText("{r}.channel_partner_code", "{r}.channel_partner_city", "{r}.channel_partner_email", "{r}.channel_partner_rera_number"),
+		phones: []string{"{r}.channel_partner_primary_phone_number"},
+		org:    "{r}.org_id",
+	},
+	{
+		kind: KindProject, slot: 5, table: "construction_project",
+		title:  "{r}.project_name",
+		detail: joinText("{r}.project_code", "{r}.project_land_address"),
+		org:    "{r}.org_id",
+	},
+}
+
+// joinText concatenates nullable text expressions with spaces.
+func joinText(exprs ...string) string {
+	parts := make([]string, len(exprs))
+	for i, expr := range exprs {
+		parts[i] = "COALESCE(" + expr + ", '')"
+	}
+	return strings.Join(parts, " || ' ' || ")
+}
+
+// phoneDigits strips the usual separators so "+91 98765-43210" indexes as
+// 919876543210 plus its last ten digits, and a typed prefix matches either.
+func phoneDigits(expr string) string {
+	digits := "COALESCE(" + expr + ", '')"
+	for _, sep := range []string{" ", "-", "+", "(", ")", "."} {
+		digits = "REPLACE(" + digits + ", '" + sep + "', '')"
+	}
+	return digits + " || ' ' || SUBSTR(" + digits + ", -10)"
+}
+
+func (s source) phonesExpr() string {
+	if len(s.phones) == 0 {
+		return "''"
+	}
+	parts := make([]string, len(s.phones))
+	for i, phone := range s.phones {
+		parts[i] = phoneDigits(phone)
+	}
+	return strings.Join(parts, " || ' ' || ")
+}
+
+// insertSQL indexes the row(s) produced by from; alias replaces {r}.
+func (s source) insertSQL(alias, from string) string {
+	expr := func(sql string) string { return strings.ReplaceAll(sql, "{r}", alias) }
+	return fmt.Sprintf(
+		"INSERT INTO %s (rowid, kind, ref_id, title, detail, phones, org_id) SELECT %s.id * %d + %d, '%s', %s.id, %s, %s, %s, %s%s",
+		indexTable, alias, kindSlots, s.slot, s.kind, alias,
+		expr("COALESCE("+s.title+", '')"), expr(s.detail), expr(s.phonesExpr()), expr(s.org), from,
+	)
+}
+
+func (s source) deleteSQL(alias string) string {
+	return fmt.Sprintf("DELETE FROM %s WHERE rowid = %s.id * %d + %d", indexTable, alias, kindSlots, s.slot)
+}
+
+func (s source) triggers() []string {
+	name := "cms_search_" + s.kind
+	return []string{
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_ai AFTER INSERT ON %s BEGIN %s; END", name, s.table, s.insertSQL("NEW", "")),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_au AFTER UPDATE ON %s BEGIN %s; %s; END", name, s.table, s.deleteSQL("OLD"), s.insertSQL("NEW", "")),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %s_ad AFTER DELETE ON %s BEGIN %s; END", name, s.table, s.deleteSQL("OLD")),
+	}
+}
+
+// unitSource is used by the project trigger that refreshes unit entries
+// when a project code changes.
+func unitSource() source {
+	for _, s := range sources {
+		if s.kind == KindUnit {
+			return s
+		}
+	}
+	panic("search: unit source missing")
+}
+
+// Ensure creates the index and the sync triggers for
+// every source table that exists. A freshly created index is filled from
+// the current rows; an index from before org_id is dropped, with its
+// triggers, and built again.
+func Ensure(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if migrator.HasTable(indexTable) && !migrator.HasColumn(indexTable, "org_id") {
+		if err := drop(db); err != nil {
+			return err
+		}
+	}
+	created := !migrator.HasTable(indexTable)
+
+	statements := []string{
+		"CREATE VIRTUAL TABLE IF NOT EXISTS " + indexTable + " USING fts5(kind UNINDEXED, ref_id UNINDEXED, title, detail, phones, org_id UNINDEXED, tokenize = 'unicode61 remove_diacritics 2', prefix = '2 3')",
+		"DROP TABLE IF EXISTS " + vocabTable,
+	}
+	for _, s := range sources {
+		if migrator.HasTable(s.table) {
+			statements = append(statements, s.triggers()...)
+		}
+	}
+	if migrator.HasTable("construction_project") && migrator.HasTable("construction_projectblock") && migrator.HasTable("construction_projectunit") {
+		unit := unitSource()
+		units := "construction_projectunit u WHERE u.project_unit_block_id IN (SELECT id FROM construction_projectblock WHERE project_block_project_id = NEW.id)"
+		statements = append(statements, fmt.Sprintf(
+			"CREATE TRIGGER IF NOT EXISTS cms_search_project_units_au AFTER UPDATE OF project_code, org_id ON construction_project BEGIN "+
+				"DELETE FROM %s WHERE rowid IN (SELECT u.id * %d + %d FROM %s); %s; END",
+			indexTable, kindSlots, unit.slot, units, unit.insertSQL("u", " FROM "+units),
+		))
+	}
+
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to prepare search index: %w", err)
+		}
+	}
+	if created {
+		return Rebuild(db)
+	}
+	return nil
+}
+
+// drop removes the index, its vocabulary view and every sync trigger.
+func drop(db *gorm.DB) error {
+	var triggers []string
+	if err := db.Raw("SELECT name FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'cms_search_%'").Scan(&triggers).Error; err != nil {
+		return err
+	}
+	statements := []string{"DROP TABLE IF EXISTS " + vocabTable, "DROP TABLE IF EXISTS " + indexTable}
+	for _, name := range triggers {
+		statements = append(statements, "DROP TRIGGER IF EXISTS "+name)
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to drop the old search index: %w", err)
+		}
+	}
+	return nil
+}
+
+// Rebuild drops every index entry and re-reads all source tables.
+func Rebuild(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM " + indexTable).Error; err != nil {
+			return err
+		}
+		for _, s := range sources {
+			if !tx.Migrator().HasTable(s.table) {
+				continue
+			}
+			if err := tx.Exec(s.insertSQL("r", " FROM "+s.table+" r")).Error; err != nil {
+				return fmt.Errorf("failed to index %s: %w", s.table, err)
+			}
+		}
+		return nil
+	})
+}
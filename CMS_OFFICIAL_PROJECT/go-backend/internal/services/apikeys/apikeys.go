@@ -0,0 +1,256 @@
+// Package apikeys issues and checks the API keys scripts use instead of a
+// login.
+package apikeys
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quickgeo/cms-official-go/internal/model"
+	"github.com/quickgeo/cms-official-go/internal/services"
+	utils "github.com/quickgeo/cms-official-go/internal/utilities/organization_page_app"
+	"gorm.io/gorm"
+)
+
+// TokenPrefix starts every API key.
+const TokenPrefix = "cms_k_"
+
+// prefixLength is how much of a key is stored in clear.
+const prefixLength = len(TokenPrefix) + 8
+
+// usedEvery is how stale last_used_at may get before a request writes it
+// back.
+const usedEvery = time.Minute
+
+// Resources are what a scope can grant: "<resource>:read" allows GET and
+// HEAD, "<resource>:write" every other method.
+var Resources = []string{"projects", "leads", "payments", "attendance", "expenses", "directory", "reports", "search"}
+
+// AdminScopes guard actions no resource scope implies; each has to be
+// granted by name. "directory:admin" regenerates vendor and supervisor
+// logins.
+var AdminScopes = []string{"directory:admin"}
+
+// Service creates, lists, revokes and checks API keys. Management takes the
+// acting member, who must be an owner.
+type Service interface {
+	// Create issues a key for actor's organization and returns it; only its
+	// hash is stored.
+	Create(ctx context.Context, actor model.OrganizationMember, req utils.CreateAPIKeyRequest) (model.APIKey, string, error)
+	// List returns the organization's keys, newest first.
+	List(ctx context.Context, actor model.OrganizationMember) ([]model.APIKey, error)
+	// Revoke disables a key for good.
+	Revoke(ctx context.Context, actor model.OrganizationMember, keyID uint) error
+	// Authenticate returns the live key token belongs to when ip may use it,
+	// and notes that it was just used.
+	Authenticate(ctx context.Context, token, ip string) (model.APIKey, error)
+}
+
+type service struct {
+	db *gorm.DB
+}
+
+// New builds the API key service on db.
+func New(db *gorm.DB) Service {
+	return &service{db: db}
+}
+
+// Scopes splits a key's stored scopes.
+func Scopes(key model.APIKey) []string {
+	return split(key.Scopes)
+}
+
+// AllowedIPs splits a key's stored IP allowlist.
+func AllowedIPs(key model.APIKey) []string {
+	return split(key.AllowedIPs)
+}
+
+// Allows reports whether key carries scope.
+func Allows(key model.APIKey, scope string) bool {
+	for _, granted := range Scopes(key) {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func ownerOnly(actor model.OrganizationMember) error {
+	if actor.OrganizationID == 0 || actor.Role != model.MemberRoleOwner {
+		return services.Fail(services.ErrForbidden, "Only organization owners can manage API keys")
+	}
+	return nil
+}
+
+func (s *service) Create(ctx context.Context, actor model.OrganizationMember, req utils.CreateAPIKeyRequest) (model.APIKey, string, error) {
+	if err := ownerOnly(actor); err != nil {
+		return model.APIKey{}, "", err
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return model.APIKey{}, "", services.Fail(services.ErrInvalid, "Name is required")
+	}
+	scopes, err := checkScopes(req.Scopes)
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+	allowed, err := checkIPs(req.AllowedIPs)
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+	now := time.Now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return model.APIKey{}, "", services.Fail(services.ErrInvalid, "expires_at must be in the future")
+	}
+
+	token, hash, err := services.NewToken(TokenPrefix)
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+	key := model.APIKey{
+		OrganizationID: actor.OrganizationID,
+		Name:           name,
+		Prefix:         token[:prefixLength],
+		TokenHash:      hash,
+		Scopes:         strings.Join(scopes, ","),
+		AllowedIPs:     strings.Join(allowed, ","),
+		CreatedByID:    actor.UserID,
+		CreatedAt:      now,
+		ExpiresAt:      req.ExpiresAt,
+	}
+	if err := s.db.WithContext(ctx).Create(&key).Error; err != nil {
+		return model.APIKey{}, "", err
+	}
+	return key, token, nil
+}
+
+func (s *service) List(ctx context.Context, actor model.OrganizationMember) ([]model.APIKey, error) {
+	if err := ownerOnly(actor); err != nil {
+		return nil, err
+	}
+	var keys []model.APIKey
+	err := s.db.WithContext(ctx).Where("organization_id = ?", actor.OrganizationID).
+		Order("created_at DESC, id DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (s *service) Revoke(ctx context.Context, actor model.OrganizationMember, keyID uint) error {
+	if err := ownerOnly(actor); err != nil {
+		return err
+	}
+	result := s.db.WithContext(ctx).Model(&model.APIKey{}).
+		Where("id = ? AND organization_id = ? AND revoked_at IS NULL", keyID, actor.OrganizationID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return services.Fail(services.ErrNotFound, "API key not found")
+	}
+	return nil
+}
+
+func (s *service) Authenticate(ctx context.Context, token, ip string) (model.APIKey, error) {
+	var key model.APIKey
+	now := time.Now()
+	found := s.db.WithContext(ctx).
+		Where("token_hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", services.HashToken(token), now).
+		Limit(1).Find(&key)
+	if found.Error != nil {
+		return key, found.Error
+	}
+	if found.RowsAffected == 0 {
+		return key, services.Fail(services.ErrNotFound, "API key is invalid, expired or revoked")
+	}
+	if !ipAllowed(AllowedIPs(key), ip) {
+		return key, services.Fail(services.ErrForbidden, "This API key cannot be used from "+ip)
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= usedEvery || key.LastUsedIP != ip {
+		key.LastUsedAt, key.LastUsedIP = &now, ip
+		err := s.db.WithContext(ctx).Model(&key).
+			UpdateColumns(map[string]interface{}{"last_used_at": now, "last_used_ip": ip}).Error
+		if err != nil {
+			return key, err
+		}
+	}
+	return key, nil
+}
+
+// checkScopes accepts "<resource>:read" and "<resource>:write" for the known
+// resources and the admin scopes, and returns them sorted without
+// duplicates.
+func checkScopes(requested []string) ([]string, error) {
+	known := map[string]bool{}
+	for _, resource := range Resources {
+		known[resource+":read"] = true
+		known[resource+":write"] = true
+	}
+	for _, scope := range AdminScopes {
+		known[scope] = true
+	}
+	seen := map[string]bool{}
+	var scopes []string
+	for _, scope := range requested {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !known[scope] {
+			return nil, services.Fail(services.ErrInvalid, "Unknown scope "+scope+"; use <resource>:read or <resource>:write with one of "+strings.Join(Resources, ", ")+", or "+strings.Join(AdminScopes, ", "))
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, services.Fail(services.ErrInvalid, "At least one scope is required")
+	}
+	sort.Strings(scopes)
+	return scopes, nil
+}
+
+// checkIPs accepts addresses and CIDR ranges.
+func checkIPs(requested []string) ([]string, error) {
+	var allowed []string
+	for _, entry := range requested {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+			return nil, services.Fail(services.ErrInvalid, "Invalid IP address or range "+entry)
+		}
+		allowed = append(allowed, entry)
+	}
+	return allowed, nil
+}
+
+// ipAllowed reports whether ip matches the allowlist; an empty list allows
+// every address.
+func ipAllowed(allowed []string, ip string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, entry := range allowed {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(addr) {
+				return true
+			}
+		} else if other := net.ParseIP(entry); other != nil && other.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func split(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
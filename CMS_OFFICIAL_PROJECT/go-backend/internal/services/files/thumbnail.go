@@ -0,0 +1,81 @@
+package files
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+
+	"github.com/quickgeo/cms-official-go/internal/services"
+)
+
+// thumbSize bounds the longer side of a thumbnail.
+const thumbSize = 256
+
+// maxPixels refuses images that would take too much memory to decode.
+const maxPixels = 40_000_000
+
+// thumbnail decodes an image and returns a JPEG scaled to fit thumbSize,
+// flattened onto white. Images smaller than that keep their size.
+func thumbnail(data []byte) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, services.Fail(services.ErrInvalid, "Image could not be read")
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return nil, services.Fail(services.ErrInvalid, "Image dimensions are too large")
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, services.Fail(services.ErrInvalid, "Image could not be read")
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, shrink(src, thumbSize), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// shrink scales src to fit a size×size box by averaging the source pixels
+// under each target pixel.
+func shrink(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	tw, th := w, h
+	if w > size || h > size {
+		if w >= h {
+			tw, th = size, max(1, h*size/w)
+		} else {
+			tw, th = max(1, w*size/h), size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := bounds.Min.Y+y*h/th, bounds.Min.Y+(y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := bounds.Min.X+x*w/tw, bounds.Min.X+(x+1)*w/tw
+			var r, g, b, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					// Premultiplied, so adding the missing alpha puts the
+					// pixel on white.
+					r += uint64(pr + 0xffff - pa)
+					g += uint64(pg + 0xffff - pa)
+					b += uint64(pb + 0xffff - pa)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
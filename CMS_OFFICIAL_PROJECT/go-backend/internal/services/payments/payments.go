@@ -0,0 +1,219 @@
+// Package payments records project, flat and plot payments and decides which
+// projects each payment ledger covers.
+package payments
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/quickgeo/cms-official-go/internal/model"
+	"github.com/quickgeo/cms-official-go/internal/services"
+	"github.com/quickgeo/cms-official-go/internal/services/projects"
+	utils "github.com/quickgeo/cms-official-go/internal/utilities/payments_page_app"
+	"gorm.io/gorm"
+)
+
+// Service records payments.
+type Service interface {
+	// FlatProjectIDs lists the accessible projects that sell flats.
+	FlatProjectIDs(ctx context.Context, userID uint) ([]uint, error)
+	// PlotProjectIDs lists the accessible multi-plot projects.
+	PlotProjectIDs(ctx context.Context, userID uint) ([]uint, error)
+	CreateProjectPayment(ctx context.Context, req utils.CreateProjectPaymentRequest) (model.ProjectPayment, error)
+	// CreateFlatPayment and CreatePlotPayment reject a unit that is not part
+	// of the payment's project.
+	CreateFlatPayment(ctx context.Context, req utils.CreateUnitPaymentRequest) (model.FlatPayment, error)
+	CreatePlotPayment(ctx context.Context, req utils.CreateUnitPaymentRequest) (model.PlotPayment, error)
+	// Attachment returns the file path on a payment of ledger: a project
+	// payment's document or a flat or plot payment's receipt.
+	Attachment(ctx context.Context, ledger Ledger, paymentID uint) (string, error)
+	// Attach points a payment's file column at path.
+	Attach(ctx context.Context, ledger Ledger, paymentID uint, path string) error
+}
+
+// Ledger names one of the payment tables.
+type Ledger string
+
+const (
+	ProjectLedger Ledger = "projects"
+	FlatLedger    Ledger = "flats"
+	PlotLedger    Ledger = "plots"
+)
+
+// attachments are the file and project columns of each ledger.
+var attachments = map[Ledger]struct {
+	model   interface{}
+	file    string
+	project string
+}{
+	ProjectLedger: {&model.ProjectPayment{}, "project_payment_document", "project_payment_project_id"},
+	FlatLedger:    {&model.FlatPayment{}, "flat_payment_receipt", "flat_payment_project_id"},
+	PlotLedger:    {&model.PlotPayment{}, "plot_payment_receipt", "plot_payment_project_id"},
+}
+
+type service struct {
+	db       *gorm.DB
+	projects projects.Service
+}
+
+// New builds the payment service on db, using projects for access checks.
+func New(db *gorm.DB, projects projects.Service) Service {
+	return &service{db: db, projects: projects}
+}
+
+func (s *service) FlatProjectIDs(ctx context.Context, userID uint) ([]uint, error) {
+	return s.projectIDs(ctx, userID, func(p model.Project) bool {
+		return strings.Contains(strings.ToLower(p.ProjectFlatConfiguration), "flat")
+	})
+}
+
+func (s *service) PlotProjectIDs(ctx context.Context, userID uint) ([]uint, error) {
+	return s.projectIDs(ctx, userID, func(p model.Project) bool {
+		return p.ProjectFlatConfiguration == "multi_plot"
+	})
+}
+
+func (s *service) projectIDs(ctx context.Context, userID uint, keep func(model.Project) bool) ([]uint, error) {
+	accessible, err := s.projects.Accessible(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	projectIDs := []uint{}
+	for _, p := range accessible {
+		if keep(p) {
+			projectIDs = append(projectIDs, p.ID)
+		}
+	}
+	return projectIDs, nil
+}
+
+func (s *service) CreateProjectPayment(ctx context.Context, req utils.CreateProjectPaymentRequest) (model.ProjectPayment, error) {
+	payment := model.ProjectPayment{
+		ProjectID:   req.ProjectID,
+		Amount:      req.Amount,
+		Type:        req.Type,
+		Date:        paymentDate(req.Date),
+		Description: req.Description,
+		CreatedAt:   time.Now(),
+	}
+	if err := s.checkProject(ctx, req.ProjectID); err != nil {
+		return payment, err
+	}
+	err := s.db.WithContext(ctx).Create(&payment).Error
+	return payment, err
+}
+
+func (s *service) CreateFlatPayment(ctx context.Context, req utils.CreateUnitPaymentRequest) (model.FlatPayment, error) {
+	payment := model.FlatPayment{
+		ProjectID: req.ProjectID,
+		UnitID:    req.UnitID,
+		Amount:    req.Amount,
+		Stage:     req.Stage,
+		Method:    req.Method,
+		Reference: req.Reference,
+		Remarks:   req.Remarks,
+		Date:      paymentDate(req.Date),
+		CreatedAt: time.Now(),
+	}
+	if err := s.checkUnit(ctx, req.ProjectID, req.UnitID); err != nil {
+		return payment, err
+	}
+	err := s.db.WithContext(ctx).Create(&payment).Error
+	return payment, err
+}
+
+func (s *service) CreatePlotPayment(ctx context.Context, req utils.CreateUnitPaymentRequest) (model.PlotPayment, error) {
+	payment := model.PlotPayment{
+		ProjectID: req.ProjectID,
+		UnitID:    req.UnitID,
+		Amount:    req.Amount,
+		Stage:     req.Stage,
+		Method:    req.Method,
+		Reference: req.Reference,
+		Remarks:   req.Remarks,
+		Date:      paymentDate(req.Date),
+		CreatedAt: time.Now(),
+	}
+	if err := s.checkUnit(ctx, req.ProjectID, req.UnitID); err != nil {
+		return payment, err
+	}
+	err := s.db.WithContext(ctx).Create(&payment).Error
+	return payment, err
+}
+
+func (s *service) Attachment(ctx context.Context, ledger Ledger, paymentID uint) (string, error) {
+	table := attachments[ledger]
+	var rows []struct {
+		ProjectID uint
+		File      string
+	}
+	err := s.db.WithContext(ctx).Model(table.model).
+		Select(table.project+" AS project_id, COALESCE("+table.file+", '') AS file").
+		Where("id = ?", paymentID).
+		Limit(1).
+		Scan(&rows).Error
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", services.Fail(services.ErrNotFound, "Payment not found")
+	}
+	// A payment of another organization's project does not exist for the
+	// caller.
+	if err := s.checkProject(ctx, rows[0].ProjectID); errors.Is(err, services.ErrNotFound) {
+		return "", services.Fail(services.ErrNotFound, "Payment not found")
+	} else if err != nil {
+		return "", err
+	}
+	return rows[0].File, nil
+}
+
+func (s *service) Attach(ctx context.Context, ledger Ledger, paymentID uint, path string) error {
+	if _, err := s.Attachment(ctx, ledger, paymentID); err != nil {
+		return err
+	}
+	table := attachments[ledger]
+	return s.db.WithContext(ctx).Model(table.model).Where("id = ?", paymentID).Update(table.file, path).Error
+}
+
+// checkProject makes sure projectID is one of the organization's projects.
+func (s *service) checkProject(ctx context.Context, projectID uint) error {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&model.Project{}).Where("id = ?", projectID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return services.Fail(services.ErrNotFound, "Project not found")
+	}
+	return nil
+}
+
+// checkUnit makes sure unitID sits in a block of projectID, one of the
+// organization's projects.
+func (s *service) checkUnit(ctx context.Context, projectID uint, unitID uint) error {
+	if err := s.checkProject(ctx, projectID); err != nil {
+		return err
+	}
+	var count int64
+	err := s.db.WithContext(ctx).Model(&model.ProjectUnit{}).
+		Joins("JOIN construction_projectblock ON construction_projectblock.id = construction_projectunit.project_unit_block_id").
+		Where("construction_projectunit.id = ? AND construction_projectblock.project_block_project_id = ?", unitID, projectID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return services.Fail(services.ErrInvalid, "Unit does not belong to project")
+	}
+	return nil
+}
+
+// paymentDate defaults a missing payment date to now.
+func paymentDate(date time.Time) time.Time {
+	if date.IsZero() {
+		return time.Now()
+	}
+	return date
+}
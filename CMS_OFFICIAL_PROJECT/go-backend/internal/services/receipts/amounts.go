@@ -0,0 +1,98 @@
+package receipts
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	smallNumbers = []string{"", "One", "Two", "Three", "Four", "Five", "Six", "Seven", "Eight", "Nine", "Ten",
+		"Eleven", "Twelve", "Thirteen", "Fourteen", "Fifteen", "Sixteen", "Seventeen", "Eighteen", "Nineteen"}
+	tens = []string{"", "", "Twenty", "Thirty", "Forty", "Fifty", "Sixty", "Seventy", "Eighty", "Ninety"}
+)
+
+// paise rounds a rupee amount to whole paise, half up. Scaling by 100 alone
+// leaves 1.005 a hair under 100.5, so the amount is first rounded to
+// hundredths of a paisa to shed that binary error.
+func paise(amount float64) int64 {
+	return int64(math.Round(math.Round(math.Abs(amount)*10000) / 100))
+}
+
+// Figures writes an amount with Indian digit grouping, e.g. "Rs. 12,34,567.50".
+func Figures(amount float64) string {
+	p := paise(amount)
+	rupees := strconv.FormatInt(p/100, 10)
+	grouped := rupees
+	if len(rupees) > 3 {
+		head, tail := rupees[:len(rupees)-3], rupees[len(rupees)-3:]
+		var groups []string
+		for len(head) > 2 {
+			groups = append([]string{head[len(head)-2:]}, groups...)
+			head = head[:len(head)-2]
+		}
+		groups = append([]string{head}, groups...)
+		grouped = strings.Join(groups, ",") + "," + tail
+	}
+	sign := ""
+	if amount < 0 && p > 0 {
+		sign = "-"
+	}
+	return sign + "Rs. " + grouped + "." + twoDigits(p%100)
+}
+
+// Words spells an amount out the Indian way, in crores, lakhs and thousands:
+// "Rupees One Lakh Twenty-Five Thousand and Fifty Paise Only".
+func Words(amount float64) string {
+	p := paise(amount)
+	rupees, rest := p/100, p%100
+	var words string
+	switch {
+	case rupees == 0 && rest == 0:
+		words = "Rupees Zero"
+	case rupees == 0:
+		words = spell(rest) + " Paise"
+	case rest == 0:
+		words = "Rupees " + spell(rupees)
+	default:
+		words = "Rupees " + spell(rupees) + " and " + spell(rest) + " Paise"
+	}
+	if amount < 0 && p > 0 {
+		words = "Minus " + words
+	}
+	return words + " Only"
+}
+
+// spell writes n in words; crores repeat, so 10^9 is "One Hundred Crore".
+func spell(n int64) string {
+	var parts []string
+	if n >= 10000000 {
+		parts = append(parts, spell(n/10000000), "Crore")
+		n %= 10000000
+	}
+	for _, unit := range []struct {
+		size int64
+		name string
+	}{{100000, "Lakh"}, {1000, "Thousand"}, {100, "Hundred"}} {
+		if n >= unit.size {
+			parts = append(parts, spell(n/unit.size), unit.name)
+			n %= unit.size
+		}
+	}
+	switch {
+	case n >= 20 && n%10 != 0:
+		parts = append(parts, tens[n/10]+"-"+smallNumbers[n%10])
+	case n >= 20:
+		parts = append(parts, tens[n/10])
+	case n > 0:
+		parts = append(parts, smallNumbers[n])
+	}
+	return strings.Join(parts, " ")
+}
+
+func twoDigits(n int64) string {
+	if n < 10 {
+		return "0" + strconv.FormatInt(n, 10)
+	}
+	return strconv.FormatInt(n, 10)
+}
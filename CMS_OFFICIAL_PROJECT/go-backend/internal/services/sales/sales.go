@@ -0,0 +1,277 @@
+// Package sales owns the multi-flat block rules: naming and sequencing new
+// blocks, growing them, and generating the units their layout describes.
+package sales
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/quickgeo/cms-official-go/internal/model"
+	"github.com/quickgeo/cms-official-go/internal/services"
+	salesUtils "github.com/quickgeo/cms-official-go/internal/utilities/sales_page_app"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Service creates and grows multi-flat blocks.
+type Service interface {
+	// CreateBlock adds a block to the project with code and generates its
+	// units. It returns the block and the number of units created.
+	CreateBlock(ctx context.Context, projectCode string, req salesUtils.CreateBlockRequest) (model.ProjectBlock, int, error)
+	// UpdateBlock applies req to block, which must hold the version the
+	// caller last saw, and generates any units the new size adds. Floor and
+	// unit counts only ever grow. ErrConflict means another writer saved
+	// first.
+	UpdateBlock(ctx context.Context, block *model.ProjectBlock, req salesUtils.UpdateBlockRequest) (int, error)
+	// GenerateMissingUnits creates every floor/unit slot of block that has no
+	// unit yet, filled in from template (the block layout when nil).
+	GenerateMissingUnits(ctx context.Context, block *model.ProjectBlock, template interface{}) (int, error)
+}
+
+type service struct {
+	db *gorm.DB
+}
+
+// New builds the sales service on db.
+func New(db *gorm.DB) Service {
+	return &service{db: db}
+}
+
+func (s *service) CreateBlock(ctx context.Context, projectCode string, req salesUtils.CreateBlockRequest) (model.ProjectBlock, int, error) {
+	var block model.ProjectBlock
+	var project model.Project
+	if err := s.db.WithContext(ctx).Where("project_code = ?", projectCode).First(&project).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return block, 0, services.Fail(services.ErrNotFound, "Project not found")
+		}
+		return block, 0, err
+	}
+
+	created := 0
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		blocks := func() *gorm.DB {
+			return tx.Model(&model.ProjectBlock{}).Where("project_block_project_id = ?", project.ID)
+		}
+
+		// Auto-generate name if empty
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			var count int64
+			if err := blocks().Count(&count).Error; err != nil {
+				return err
+			}
+			name = fmt.Sprintf("Block %s", string(rune('A'+(count%26))))
+		}
+
+		var existing int64
+		if err := blocks().Where("project_block_name = ?", name).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return services.Fail(services.ErrInvalid, "Block name exists")
+		}
+
+		var maxSeq *uint
+		if err := blocks().Select("MAX(project_block_sequence)").Scan(&maxSeq).Error; err != nil {
+			return err
+		}
+		nextSeq := uint(1)
+		if maxSeq != nil {
+			nextSeq = *maxSeq + 1
+		}
+
+		block = model.ProjectBlock{
+			ProjectBlockProjectID:     project.ID,
+			ProjectBlockName:          name,
+			ProjectBlockSequence:      nextSeq,
+			ProjectBlockFloorCount:    uint(wholeNumber(req.FloorCount, 1)),
+			ProjectBlockUnitsPerFloor: uint(wholeNumber(req.UnitsPerFloor, 1)),
+			ProjectBlockNotes:         req.Notes,
+		}
+		if req.UnitLayoutTemplate != nil {
+			jsonBytes, _ := json.Marshal(req.UnitLayoutTemplate)
+			block.ProjectBlockUnitLayout = datatypes.JSON(jsonBytes)
+		}
+		if err := tx.Create(&block).Error; err != nil {
+			return err
+		}
+
+		var err error
+		if created, err = createMissingUnits(tx, &block, req.UnitLayoutTemplate); err != nil {
+			return err
+		}
+		return syncBlockCount(tx, project.ID)
+	})
+	return block, created, err
+}
+
+func (s *service) UpdateBlock(ctx context.Context, block *model.ProjectBlock, req salesUtils.UpdateBlockRequest) (int, error) {
+	if req.Name != "" {
+		block.ProjectBlockName = req.Name
+	}
+	if req.Notes != nil {
+		block.ProjectBlockNotes = *req.Notes
+	}
+	// Shrinking would orphan units that may already be sold.
+	if req.FloorCount != nil {
+		if val := uint(wholeNumber(req.FloorCount, 0)); val > block.ProjectBlockFloorCount {
+			block.ProjectBlockFloorCount = val
+		}
+	}
+	if req.UnitsPerFloor != nil {
+		if val := uint(wholeNumber(req.UnitsPerFloor, 0)); val > block.ProjectBlockUnitsPerFloor {
+			block.ProjectBlockUnitsPerFloor = val
+		}
+	}
+	if req.UnitLayoutTemplate != nil {
+		jsonBytes, _ := json.Marshal(req.UnitLayoutTemplate)
+		block.ProjectBlockUnitLayout = datatypes.JSON(jsonBytes)
+	}
+
+	expected := block.ProjectBlockVersion
+	block.ProjectBlockVersion++
+	block.ProjectBlockUpdatedAt = time.Now()
+
+	created := 0
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		saved, err := services.SaveVersioned(tx, block, "project_block_version", expected)
+		if err != nil {
+			return err
+		}
+		if !saved {
+			return services.Fail(services.ErrConflict, "Record was changed by someone else; reload and retry")
+		}
+		created, err = createMissingUnits(tx, block, req.UnitLayoutTemplate)
+		return err
+	})
+	return created, err
+}
+
+func (s *service) GenerateMissingUnits(ctx context.Context, block *model.ProjectBlock, template interface{}) (int, error) {
+	if template == nil {
+		template = block.ProjectBlockUnitLayout
+	}
+	created := 0
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		created, err = createMissingUnits(tx, block, template)
+		return err
+	})
+	return created, err
+}
+
+// createMissingUnits mirrors _create_missing_units_for_block.
+func createMissingUnits(tx *gorm.DB, block *model.ProjectBlock, template interface{}) (int, error) {
+	// template can be json.RawMessage, []interface{}, string, etc.
+	var templateList []map[string]interface{}
+	if tList, ok := template.([]interface{}); ok {
+		for _, item := range tList {
+			if m, ok := item.(map[string]interface{}); ok {
+				templateList = append(templateList, m)
+			}
+		}
+	} else if tJSON, ok := template.(datatypes.JSON); ok {
+		json.Unmarshal(tJSON, &templateList)
+	}
+
+	templateMap := make(map[int]map[string]interface{})
+	for _, item := range templateList {
+		if numVal, ok := item["unit_number"]; ok {
+			if num := wholeNumber(numVal, 0); num > 0 {
+				templateMap[num] = item
+			}
+		}
+	}
+
+	var existingUnits []model.ProjectUnit
+	if err := tx.Where("project_unit_block_id = ?", block.ID).Find(&existingUnits).Error; err != nil {
+		return 0, err
+	}
+	existingSet := make(map[string]bool)
+	for _, u := range existingUnits {
+		key := fmt.Sprintf("%d-%d", u.ProjectUnitFloorNumber, u.ProjectUnitNumber)
+		existingSet[key] = true
+	}
+
+	newUnits := []model.ProjectUnit{}
+	for floor := 1; floor <= int(block.ProjectBlockFloorCount); floor++ {
+		for unitNum := 1; unitNum <= int(block.ProjectBlockUnitsPerFloor); unitNum++ {
+			key := fmt.Sprintf("%d-%d", floor, unitNum)
+			if existingSet[key] {
+				continue
+			}
+
+			tData := templateMap[unitNum]
+			bhk := ""
+			if v, ok := tData["bhk_configuration"].(string); ok {
+				bhk = v
+			}
+			face := ""
+			if v, ok := tData["facing"].(string); ok {
+				face = v
+			}
+
+			var area *float64
+			if v, ok := tData["area_sqft"]; ok {
+				if f, ok := v.(float64); ok {
+					area = &f
+				}
+				if s, ok := v.(string); ok {
+					if f, err := strconv.ParseFloat(s, 64); err == nil {
+						area = &f
+					}
+				}
+			}
+
+			newUnits = append(newUnits, model.ProjectUnit{
+				ProjectUnitBlockID:          block.ID,
+				ProjectUnitFloorNumber:      uint(floor),
+				ProjectUnitNumber:           uint(unitNum),
+				ProjectUnitLabel:            fmt.Sprintf("%s-F%d-U%d", block.ProjectBlockName, floor, unitNum),
+				ProjectUnitBHKConfiguration: bhk,
+				ProjectUnitFacing:           face,
+				ProjectUnitAreaSqft:         area,
+				ProjectUnitStatus:           "available",
+				ProjectUnitCRMStage:         "visitor",
+			})
+		}
+	}
+
+	if len(newUnits) > 0 {
+		if err := tx.Create(&newUnits).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(newUnits), nil
+}
+
+// syncBlockCount stores the project's current number of blocks.
+func syncBlockCount(tx *gorm.DB, projectID uint) error {
+	var totalBlocks int64
+	if err := tx.Model(&model.ProjectBlock{}).Where("project_block_project_id = ?", projectID).Count(&totalBlocks).Error; err != nil {
+		return err
+	}
+	return tx.Model(&model.Project{}).Where("id = ?", projectID).Updates(map[string]interface{}{
+		"project_block_count": totalBlocks,
+		"project_version":     gorm.Expr("project_version + 1"),
+	}).Error
+}
+
+// wholeNumber reads a JSON number or numeric string, falling back when the
+// value is neither.
+func wholeNumber(v interface{}, fallback int) int {
+	switch n := v.(type) {
+	case float64:
+		return int(n)
+	case string:
+		if i, err := strconv.Atoi(strings.TrimSpace(n)); err == nil {
+			return i
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,116 @@
+// Package stock keeps per-project material balances, including the Django
+// rule that usage never exceeds the allocation.
+package stock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/quickgeo/cms-official-go/internal/model"
+	"github.com/quickgeo/cms-official-go/internal/services"
+	stockUtils "github.com/quickgeo/cms-official-go/internal/utilities/stock_management_page_app"
+	"gorm.io/gorm"
+)
+
+// Service reads and updates stock balances.
+type Service interface {
+	// Balances lists every material with its balance on the project.
+	Balances(ctx context.Context, projectID uint) ([]stockUtils.StockItem, error)
+	// Update creates or changes one material balance on the project.
+	Update(ctx context.Context, projectID uint, req stockUtils.UpdateStockRequest) (model.StockBalance, error)
+}
+
+type service struct {
+	db *gorm.DB
+}
+
+// New builds the stock service on db.
+func New(db *gorm.DB) Service {
+	return &service{db: db}
+}
+
+func (s *service) Balances(ctx context.Context, projectID uint) ([]stockUtils.StockItem, error) {
+	var materials []model.MaterialItem
+	if err := s.db.WithContext(ctx).Find(&materials).Error; err != nil {
+		return nil, err
+	}
+
+	var balances []model.StockBalance
+	if err := s.db.WithContext(ctx).Where("stock_project_id = ?", projectID).Find(&balances).Error; err != nil {
+		return nil, err
+	}
+	balanceMap := make(map[uint]model.StockBalance)
+	for _, b := range balances {
+		balanceMap[b.StockMaterialItemID] = b
+	}
+
+	items := []stockUtils.StockItem{}
+	for _, m := range materials {
+		item := stockUtils.StockItem{
+			MaterialItemID:      m.ID,
+			MaterialName:        m.MaterialItemName,
+			MaterialDisplayName: m.MaterialItemDisplayName,
+		}
+		if b, ok := balanceMap[m.ID]; ok {
+			item.TotalAllocated = b.StockTotalAllocated
+			item.Used = b.StockUsed
+			item.Notes = b.StockNotes
+		}
+		item.Remaining = item.TotalAllocated - item.Used
+		if item.Remaining < 0 {
+			item.Remaining = 0
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (s *service) Update(ctx context.Context, projectID uint, req stockUtils.UpdateStockRequest) (model.StockBalance, error) {
+	var balance model.StockBalance
+	err := s.db.WithContext(ctx).Where("stock_project_id = ? AND stock_material_item_id = ?", projectID, req.MaterialItemID).First(&balance).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Only the organization's own materials can be stocked.
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&model.MaterialItem{}).Where("id = ?", req.MaterialItemID).Count(&count).Error; err != nil {
+			return balance, err
+		}
+		if count == 0 {
+			return balance, services.Fail(services.ErrNotFound, "Material not found")
+		}
+		balance = model.StockBalance{
+			StockProjectID:      projectID,
+			StockMaterialItemID: req.MaterialItemID,
+			StockCreatedAt:      time.Now(),
+		}
+	} else if err != nil {
+		return balance, err
+	}
+
+	if req.TotalAllocated != nil {
+		v, _ := strconv.ParseFloat(fmt.Sprintf("%v", req.TotalAllocated), 64)
+		balance.StockTotalAllocated = v
+	}
+	if req.Used != nil {
+		v, _ := strconv.ParseFloat(fmt.Sprintf("%v", req.Used), 64)
+		balance.StockUsed = v
+	}
+	if req.Notes != "" {
+		balance.StockNotes = req.Notes
+	}
+	balance.StockUpdatedAt = time.Now()
+
+	// model.py: "if self.stock_used > self.stock_total_allocated: self.stock_used = self.stock_total_allocated"
+	if balance.StockUsed > balance.StockTotalAllocated {
+		balance.StockUsed = balance.StockTotalAllocated
+	}
+
+	if balance.ID == 0 {
+		err = s.db.WithContext(ctx).Create(&balance).Error
+	} else {
+		err = s.db.WithContext(ctx).Save(&balance).Error
+	}
+	return balance, err
+}
@@ -0,0 +1,324 @@
+// Package users lets organization owners invite people and manage the
+// accounts of their members.
+package users
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/quickgeo/cms-official-go/internal/model"
+	"github.com/quickgeo/cms-official-go/internal/passwords"
+	"github.com/quickgeo/cms-official-go/internal/services"
+	"github.com/quickgeo/cms-official-go/internal/services/sessions"
+	authUtils "github.com/quickgeo/cms-official-go/internal/utilities/auth_page_app"
+	utils "github.com/quickgeo/cms-official-go/internal/utilities/organization_page_app"
+	"gorm.io/gorm"
+)
+
+// InviteTokenPrefix starts every invite token.
+const InviteTokenPrefix = "cms_i_"
+
+// Invite lifetimes, in hours.
+const (
+	defaultInviteHours = 72
+	maxInviteHours     = 720
+)
+
+// userTypes are the profile types an owner can give a member.
+var userTypes = map[string]bool{"builder": true, "supervisor": true}
+
+// Service manages the accounts of an organization's members. Everything but
+// AcceptInvite takes the acting member, who must be an owner.
+type Service interface {
+	// List loads the members with their logins; active filters on
+	// auth_user.is_active when set.
+	List(ctx context.Context, actor model.OrganizationMember, active *bool) ([]model.OrganizationMember, error)
+	// SetUserType changes a member's Profile.UserType.
+	SetUserType(ctx context.Context, actor model.OrganizationMember, userID uint, userType string) (model.OrganizationMember, error)
+	// Deactivate blocks a member's login and revokes their sessions. Owners
+	// and the actor themselves cannot be deactivated.
+	Deactivate(ctx context.Context, actor model.OrganizationMember, userID uint) (model.OrganizationMember, error)
+	// Reactivate lets a deactivated member log in again.
+	Reactivate(ctx context.Context, actor model.OrganizationMember, userID uint) (model.OrganizationMember, error)
+	// Sessions lists a member's live sessions.
+	Sessions(ctx context.Context, actor model.OrganizationMember, userID uint) ([]model.Session, error)
+	// SignOut revokes one of a member's sessions, or all of them when
+	// sessionID is 0, and returns how many it ended.
+	SignOut(ctx context.Context, actor model.OrganizationMember, userID, sessionID uint) (int64, error)
+	// Invite records an invite and returns its token, which is not stored.
+	Invite(ctx context.Context, actor model.OrganizationMember, req utils.InviteRequest) (model.Invite, string, error)
+	// Invites lists the organization's invites, newest first.
+	Invites(ctx context.Context, actor model.OrganizationMember) ([]model.Invite, error)
+	// RevokeInvite withdraws an invite that has not been accepted.
+	RevokeInvite(ctx context.Context, actor model.OrganizationMember, inviteID uint) error
+	// AcceptInvite creates the invited account and puts it in the
+	// organization.
+	AcceptInvite(ctx context.Context, req authUtils.AcceptInviteRequest) (model.User, error)
+}
+
+type service struct {
+	db       *gorm.DB
+	sessions sessions.Service
+}
+
+// New builds the user service on db; deactivation revokes through sessions.
+func New(db *gorm.DB, sessions sessions.Service) Service {
+	return &service{db: db, sessions: sessions}
+}
+
+func ownerOnly(actor model.OrganizationMember) error {
+	if actor.OrganizationID == 0 || actor.Role != model.MemberRoleOwner {
+		return services.Fail(services.ErrForbidden, "Only organization owners can manage users")
+	}
+	return nil
+}
+
+func (s *service) List(ctx context.Context, actor model.OrganizationMember, active *bool) ([]model.OrganizationMember, error) {
+	if err := ownerOnly(actor); err != nil {
+		return nil, err
+	}
+	query := s.db.WithContext(ctx).Preload("User.Profile").
+		Where("organization_id = ?", actor.OrganizationID).
+		Order("created_at, id")
+	if active != nil {
+		query = query.Where("user_id IN (SELECT id FROM auth_user WHERE is_active = ?)", *active)
+	}
+	var members []model.OrganizationMember
+	err := query.Find(&members).Error
+	return members, err
+}
+
+func (s *service) SetUserType(ctx context.Context, actor model.OrganizationMember, userID uint, userType string) (model.OrganizationMember, error) {
+	if err := ownerOnly(actor); err != nil {
+		return model.OrganizationMember{}, err
+	}
+	userType = strings.ToLower(strings.TrimSpace(userType))
+	if !userTypes[userType] {
+		return model.OrganizationMember{}, services.Fail(services.ErrInvalid, "User type must be builder or supervisor")
+	}
+
+	var member model.OrganizationMember
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if member, err = s.member(tx, actor, userID); err != nil {
+			return err
+		}
+		profile := member.User.Profile
+		if profile == nil {
+			profile = &model.Profile{UserID: userID, UserType: userType, CreatedAt: time.Now()}
+			member.User.Profile = profile
+			return tx.Create(profile).Error
+		}
+		profile.UserType = userType
+		return tx.Model(profile).Update("profile_user_type", userType).Error
+	})
+	return member, err
+}
+
+func (s *service) Deactivate(ctx context.Context, actor model.OrganizationMember, userID uint) (model.OrganizationMember, error) {
+	if err := ownerOnly(actor); err != nil {
+		return model.OrganizationMember{}, err
+	}
+	if userID == actor.UserID {
+		return model.OrganizationMember{}, services.Fail(services.ErrConflict, "You cannot deactivate your own account")
+	}
+	member, err := s.setActive(ctx, actor, userID, false)
+	if err != nil {
+		return member, err
+	}
+	_, err = s.sessions.RevokeUser(ctx, userID, 0)
+	return member, err
+}
+
+func (s *service) Reactivate(ctx context.Context, actor model.OrganizationMember, userID uint) (model.OrganizationMember, error) {
+	if err := ownerOnly(actor); err != nil {
+		return model.OrganizationMember{}, err
+	}
+	return s.setActive(ctx, actor, userID, true)
+}
+
+func (s *service) setActive(ctx context.Context, actor model.OrganizationMember, userID uint, active bool) (model.OrganizationMember, error) {
+	var member model.OrganizationMember
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if member, err = s.member(tx, actor, userID); err != nil {
+			return err
+		}
+		if !active && member.Role == model.MemberRoleOwner {
+			return services.Fail(services.ErrConflict, "Owners cannot be deactivated; change their role first")
+		}
+		member.User.IsActive = active
+		return tx.Model(member.User).Update("is_active", active).Error
+	})
+	return member, err
+}
+
+func (s *service) Sessions(ctx context.Context, actor model.OrganizationMember, userID uint) ([]model.Session, error) {
+	if err := ownerOnly(actor); err != nil {
+		return nil, err
+	}
+	if _, err := s.member(s.db.WithContext(ctx), actor, userID); err != nil {
+		return nil, err
+	}
+	return s.sessions.List(ctx, userID)
+}
+
+func (s *service) SignOut(ctx context.Context, actor model.OrganizationMember, userID, sessionID uint) (int64, error) {
+	if err := ownerOnly(actor); err != nil {
+		return 0, err
+	}
+	if _, err := s.member(s.db.WithContext(ctx), actor, userID); err != nil {
+		return 0, err
+	}
+	if sessionID == 0 {
+		return s.sessions.RevokeUser(ctx, userID, 0)
+	}
+	if err := s.sessions.Revoke(ctx, userID, sessionID); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+func (s *service) Invite(ctx context.Context, actor model.OrganizationMember, req utils.InviteRequest) (model.Invite, string, error) {
+	if err := ownerOnly(actor); err != nil {
+		return model.Invite{}, "", err
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	phone := strings.TrimSpace(req.Phone)
+	if email == "" && phone == "" {
+		return model.Invite{}, "", services.Fail(services.ErrInvalid, "Email or phone is required")
+	}
+	if email != "" && !strings.Contains(email, "@") {
+		return model.Invite{}, "", services.Fail(services.ErrInvalid, "Invalid email")
+	}
+	userType := strings.ToLower(strings.TrimSpace(req.UserType))
+	if userType == "" {
+		userType = "supervisor"
+	}
+	if !userTypes[userType] {
+		return model.Invite{}, "", services.Fail(services.ErrInvalid, "User type must be builder or supervisor")
+	}
+	role := req.Role
+	if role == "" {
+		role = model.MemberRoleMember
+	}
+	if role != model.MemberRoleOwner && role != model.MemberRoleAdmin && role != model.MemberRoleMember {
+		return model.Invite{}, "", services.Fail(services.ErrInvalid, "Role must be owner, admin or member")
+	}
+	hours := req.ExpiresInHours
+	if hours == 0 {
+		hours = defaultInviteHours
+	}
+	if hours < 0 || hours > maxInviteHours {
+		return model.Invite{}, "", services.Fail(services.ErrInvalid, "Invites expire within 720 hours")
+	}
+
+	token, hash, err := services.NewToken(InviteTokenPrefix)
+	if err != nil {
+		return model.Invite{}, "", err
+	}
+	now := time.Now()
+	invite := model.Invite{
+		OrganizationID: actor.OrganizationID,
+		Email:          email,
+		Phone:          phone,
+		UserType:       userType,
+		Role:           role,
+		TokenHash:      hash,
+		InvitedByID:    actor.UserID,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(time.Duration(hours) * time.Hour),
+	}
+	if err := s.db.WithContext(ctx).Create(&invite).Error; err != nil {
+		return model.Invite{}, "", err
+	}
+	return invite, token, nil
+}
+
+func (s *service) Invites(ctx context.Context, actor model.OrganizationMember) ([]model.Invite, error) {
+	if err := ownerOnly(actor); err != nil {
+		return nil, err
+	}
+	var invites []model.Invite
+	err := s.db.WithContext(ctx).Where("organization_id = ?", actor.OrganizationID).
+		Order("created_at DESC, id DESC").Find(&invites).Error
+	return invites, err
+}
+
+func (s *service) RevokeInvite(ctx context.Context, actor model.OrganizationMember, inviteID uint) error {
+	if err := ownerOnly(actor); err != nil {
+		return err
+	}
+	result := s.db.WithContext(ctx).Model(&model.Invite{}).
+		Where("id = ? AND organization_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", inviteID, actor.OrganizationID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return services.Fail(services.ErrNotFound, "Invite not found")
+	}
+	return nil
+}
+
+func (s *service) AcceptInvite(ctx context.Context, req authUtils.AcceptInviteRequest) (model.User, error) {
+	username := strings.TrimSpace(req.Username)
+	if msg := authUtils.CheckCredentials(username, req.Password, req.PasswordConfirm); msg != "" {
+		return model.User{}, services.Fail(services.ErrInvalid, msg)
+	}
+	hashed, err := passwords.Hash(req.Password)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	var user model.User
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var invite model.Invite
+		err := tx.Where("token_hash = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", services.HashToken(req.Token), time.Now()).
+			First(&invite).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return services.Fail(services.ErrNotFound, "Invite is invalid or has expired")
+		}
+		if err != nil {
+			return err
+		}
+
+		var taken int64
+		if err := tx.Model(&model.User{}).Where("username = ?", username).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return services.Fail(services.ErrInvalid, "Username already exists")
+		}
+
+		now := time.Now()
+		user = model.User{Username: username, Password: hashed, Email: invite.Email, IsActive: true, DateJoined: now}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		profile := model.Profile{UserID: user.ID, UserType: invite.UserType, PhoneNumber: invite.Phone, CreatedAt: now}
+		if err := tx.Create(&profile).Error; err != nil {
+			return err
+		}
+		user.Profile = &profile
+		member := model.OrganizationMember{OrganizationID: invite.OrganizationID, UserID: user.ID, Role: invite.Role, CreatedAt: now}
+		if err := tx.Create(&member).Error; err != nil {
+			return err
+		}
+		return tx.Model(&invite).Updates(map[string]interface{}{"accepted_at": now, "accepted_user_id": user.ID}).Error
+	})
+	return user, err
+}
+
+// member loads userID's membership in actor's organization with the login
+// and profile.
+func (s *service) member(tx *gorm.DB, actor model.OrganizationMember, userID uint) (model.OrganizationMember, error) {
+	var member model.OrganizationMember
+	err := tx.Preload("User.Profile").Where("organization_id = ? AND user_id = ?", actor.OrganizationID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && member.User == nil) {
+		return member, services.Fail(services.ErrNotFound, "Member not found")
+	}
+	return member, err
+}
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBucket is an in-memory S3 bucket that serves path-style requests for
+// one bucket and rejects any that are not signed the way S3 expects.
+type fakeBucket struct {
+	t       *testing.T
+	bucket  string
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	body        []byte
+	contentType string
+	modTime     time.Time
+}
+
+func (f *fakeBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+f.bucket+"/")
+	if !ok {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	sum := sha256.Sum256(body)
+	if got := r.Header.Get("X-Amz-Content-Sha256"); got != hex.EncodeToString(sum[:]) {
+		f.t.Errorf("%s %s: payload hash %q does not match the body", r.Method, key, got)
+	}
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=test-key/") ||
+		!strings.Contains(auth, "/us-east-1/s3/aws4_request, SignedHeaders=") {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[key] = fakeObject{body: body, contentType: r.Header.Get("Content-Type"), modTime: time.Now()}
+	case http.MethodGet:
+		object, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		w.Header().Set("Last-Modified", object.modTime.UTC().Format(http.TimeFormat))
+		w.Write(object.body)
+	case http.MethodDelete:
+		if _, ok := f.objects[key]; !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func newFakeS3(t *testing.T) (*S3, *fakeBucket) {
+	t.Helper()
+	bucket := &fakeBucket{t: t, bucket: "cms-media", objects: map[string]fakeObject{}}
+	server := httptest.NewServer(bucket)
+	t.Cleanup(server.Close)
+	store, err := NewS3(S3Config{Endpoint: server.URL, Bucket: "cms-media", AccessKey: "test-key", SecretKey: "test-secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, bucket
+}
+
+func TestS3PutOpenDelete(t *testing.T) {
+	store, bucket := newFakeS3(t)
+	ctx := context.Background()
+	key := "payments/receipts/2026/10/3f9c.pdf"
+	content := []byte("%PDF-1.4 receipt")
+
+	if err := store.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "application/pdf"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, ok := bucket.objects[key]; !ok {
+		t.Fatalf("Put did not store %s in the bucket", key)
+	}
+
+	body, object, err := store.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	got, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		t.Fatalf("reading %s: %v", key, err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("Open returned %q, want %q", got, content)
+	}
+	if object.Size != int64(len(content)) || object.ContentType != "application/pdf" || object.ModTime.IsZero() {
+		t.Errorf("Open described the file as %+v", object)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := bucket.objects[key]; ok {
+		t.Errorf("Delete left %s in the bucket", key)
+	}
+}
+
+func TestS3NotFound(t *testing.T) {
+	store, _ := newFakeS3(t)
+	ctx := context.Background()
+
+	if _, _, err := store.Open(ctx, "payments/missing.pdf"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open of a missing key returned %v, want ErrNotFound", err)
+	}
+	if err := store.Delete(ctx, "payments/missing.pdf"); err != nil {
+		t.Errorf("Delete of a missing key returned %v, want nil", err)
+	}
+}
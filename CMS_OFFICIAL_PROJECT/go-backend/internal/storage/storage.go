@@ -0,0 +1,75 @@
+// Package storage keeps uploaded files, on local disk by default or in an
+// S3-compatible bucket. Files are addressed by slash-separated keys such as
+// "payments/receipts/2026/10/3f9c.pdf", which is also what the Django file
+// columns store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by Open for a key nothing is stored under.
+var ErrNotFound = errors.New("storage: object not found")
+
+// Object describes a stored file.
+type Object struct {
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Store reads and writes files by key.
+type Store interface {
+	// Put stores size bytes read from body under key, replacing any file
+	// already there.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Open reads the file under key; the caller closes it.
+	Open(ctx context.Context, key string) (io.ReadCloser, Object, error)
+	// Delete removes the file under key. Deleting a missing key is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+}
+
+// FromEnv builds the store CMS_STORAGE names: "disk" (the default) keeps
+// files under CMS_MEDIA_ROOT, or root when that is unset; "s3" uses the
+// bucket CMS_S3_BUCKET at CMS_S3_ENDPOINT.
+func FromEnv(root string) (Store, error) {
+	switch kind := os.Getenv("CMS_STORAGE"); kind {
+	case "", "disk":
+		if dir := os.Getenv("CMS_MEDIA_ROOT"); dir != "" {
+			root = dir
+		}
+		return NewDisk(root), nil
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:  os.Getenv("CMS_S3_ENDPOINT"),
+			Bucket:    os.Getenv("CMS_S3_BUCKET"),
+			Region:    os.Getenv("CMS_S3_REGION"),
+			AccessKey: os.Getenv("CMS_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("CMS_S3_SECRET_KEY"),
+		})
+	default:
+		return nil, fmt.Errorf("CMS_STORAGE must be disk or s3, not %q", kind)
+	}
+}
+
+// CheckKey rejects keys that are empty, absolute, not clean or that climb
+// out of the store with "..".
+func CheckKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) || path.Clean(key) != key {
+		return fmt.Errorf("storage: invalid key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." {
+			return fmt.Errorf("storage: invalid key %q", key)
+		}
+	}
+	return nil
+}
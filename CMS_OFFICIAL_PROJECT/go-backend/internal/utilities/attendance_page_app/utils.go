@@ -1,6 +1,7 @@
 package attendance_page_app
 
 import (
+	"fmt"
 	"strings"
 	"time"
 )
@@ -27,6 +28,7 @@ type AttendanceBatchRequest struct {
 	Description string   `json:"description"`
 	Members     []string `json:"members"`
 	Role        string   `json:"role"`
+	ProjectID   *uint    `json:"project_id"`
 }
 
 // AttendanceRecordRequest captures the attendance marking payload.
@@ -45,6 +47,51 @@ type ChartEntry struct {
 	Total int    `json:"total"`
 }
 
+// StatsRange echoes the window and zone the attendance stats were built for.
+type StatsRange struct {
+	From     string `json:"from,omitempty"`
+	To       string `json:"to"`
+	Timezone string `json:"timezone"`
+}
+
+// UTCOffsetModifier returns the SQLite date modifier (e.g. "+330 minutes")
+// that shifts UTC timestamps into loc as of at.
+func UTCOffsetModifier(loc *time.Location, at time.Time) string {
+	_, offset := at.In(loc).Zone()
+	return fmt.Sprintf("%+d minutes", offset/60)
+}
+
+// NextOffsetChange returns the first instant after from, and before until,
+// at which loc's UTC offset changes (a DST transition). ok is false when the
+// offset stays the same throughout.
+func NextOffsetChange(loc *time.Location, from, until time.Time) (change time.Time, ok bool) {
+	offset := func(t time.Time) int {
+		_, seconds := t.In(loc).Zone()
+		return seconds
+	}
+	// Offsets change at most a few times a year: find the day a change falls
+	// in, then the second.
+	start := offset(from)
+	lo := from
+	for lo.Before(until) {
+		hi := lo.Add(24 * time.Hour)
+		if offset(hi) == start {
+			lo = hi
+			continue
+		}
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if offset(mid) == start {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		return hi.Truncate(time.Second), hi.Before(until)
+	}
+	return time.Time{}, false
+}
+
 // DateOnly truncates time to midnight.
 func DateOnly(t time.Time) time.Time {
 	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
@@ -1,12 +1,20 @@
 package auth_page_app
 
-import "strings"
+import (
+	"regexp"
+	"strings"
+	"time"
+)
 
-// LoginRequest mirrors request payload for login.
+// LoginRequest mirrors request payload for login. DeviceName and Platform
+// (desktop, web or mobile) label the session; without a platform it is
+// guessed from the User-Agent.
 type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Role     string `json:"login_role"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	Role       string `json:"login_role"`
+	DeviceName string `json:"device_name"`
+	Platform   string `json:"platform"`
 }
 
 // RegisterRequest mirrors request payload for registration.
@@ -17,13 +25,58 @@ type RegisterRequest struct {
 	UserType        string `json:"usertype"`
 }
 
+// AcceptInviteRequest creates the account an invite was sent for.
+type AcceptInviteRequest struct {
+	Token           string `json:"token" binding:"required"`
+	Username        string `json:"username"`
+	Password        string `json:"password"`
+	PasswordConfirm string `json:"password_confirm"`
+	DeviceName      string `json:"device_name"`
+	Platform        string `json:"platform"`
+}
+
+// SessionResponse is one signed-in device. Current marks the session the
+// request was made with.
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	DeviceName string    `json:"device_name"`
+	Platform   string    `json:"platform"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{5,}$`)
+
+// CheckCredentials applies register_view's rules to a new login and returns
+// the message to show, or "" when they pass.
+func CheckCredentials(username, password, confirm string) string {
+	if username == "" || password == "" {
+		return "Username and password required"
+	}
+	if !usernamePattern.MatchString(username) {
+		return "Username must be alphanumeric (min 5 chars)"
+	}
+	if len(password) < 8 {
+		return "Password must be at least 8 characters"
+	}
+	if confirm == "" || password != confirm {
+		return "Passwords do not match"
+	}
+	return ""
+}
+
 // GetUserRole returns the user type or defaults to 'builder'.
 // This logic mirrors the Django helper:
 // def get_user_role(user):
-//     try:
-//         return user.profile.user_type
-//     except Exception:
-//         return 'builder'
+//
+//	try:
+//	    return user.profile.user_type
+//	except Exception:
+//	    return 'builder'
 func GetUserRole(userType string) string {
 	if userType == "" {
 		return "builder"
@@ -34,7 +87,8 @@ func GetUserRole(userType string) string {
 // IsBuilderRole checks if the role is 'builder' or 'organization'.
 // Logic mirrors Django:
 // def is_builder_role(role):
-//     return role in ('builder', 'organization')
+//
+//	return role in ('builder', 'organization')
 func IsBuilderRole(role string) bool {
 	role = strings.ToLower(role)
 	return role == "builder" || role == "organization"
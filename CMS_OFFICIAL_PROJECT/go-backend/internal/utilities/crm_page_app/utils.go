@@ -18,3 +18,22 @@ var KanbanStages = []KanbanStage{
 type UpdateStageRequest struct {
 	Stage string `json:"stage"`
 }
+
+// CreateCustomerRequest mirrors the quick-add customer form in the CRM.
+type CreateCustomerRequest struct {
+	Name           string `json:"name"`
+	PrimaryPhone   string `json:"phone"`
+	SecondaryPhone string `json:"secondary_phone"`
+	Email          string `json:"email"`
+	Address        string `json:"address"`
+	CompanyName    string `json:"company_name"`
+}
+
+// CreateChannelPartnerRequest mirrors the quick-add channel partner form in the CRM.
+type CreateChannelPartnerRequest struct {
+	Name         string `json:"name"`
+	PrimaryPhone string `json:"phone"`
+	Email        string `json:"email"`
+	City         string `json:"city"`
+	ReraNumber   string `json:"rera_number"`
+}
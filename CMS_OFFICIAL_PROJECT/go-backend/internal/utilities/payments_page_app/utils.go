@@ -47,3 +47,22 @@ type CreateUnitPaymentRequest struct {
 	Remarks   string    `json:"remarks"`
 	Date      time.Time `json:"payment_date"`
 }
+
+// IssueReceiptRequest names who the receipt is made out to. Flat and plot
+// receipts default to the unit's buyer, project receipts to the project's
+// customer.
+type IssueReceiptRequest struct {
+	Payer string `json:"payer"`
+}
+
+// ReceiptResponse is an issued receipt with a signed link to its PDF.
+type ReceiptResponse struct {
+	Number    string    `json:"number"`
+	Ledger    string    `json:"ledger"`
+	PaymentID uint      `json:"payment_id"`
+	Payer     string    `json:"payer"`
+	Amount    float64   `json:"amount"`
+	IssuedAt  time.Time `json:"issued_at"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
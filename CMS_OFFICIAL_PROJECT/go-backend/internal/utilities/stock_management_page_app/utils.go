@@ -7,3 +7,14 @@ type UpdateStockRequest struct {
 	Used           interface{} `json:"used"`
 	Notes          string      `json:"notes"`
 }
+
+// StockItem is one material with its balance for a project.
+type StockItem struct {
+	MaterialItemID      uint    `json:"material_item_id"`
+	MaterialName        string  `json:"material_name"`
+	MaterialDisplayName string  `json:"material_display_name"`
+	TotalAllocated      float64 `json:"total_allocated"`
+	Used                float64 `json:"used"`
+	Remaining           float64 `json:"remaining"`
+	Notes               string  `json:"notes"`
+}
@@ -38,4 +38,5 @@ type SupervisorResponse struct {
 	Address            string     `json:"address"`
 	AssignedProjectIDs []uint     `json:"assigned_project_ids"`
 	PageAccess         PageAccess `json:"page_access"`
+	Version            uint       `json:"version"`
 }
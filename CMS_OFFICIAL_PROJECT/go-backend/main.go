@@ -3,17 +3,32 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/quickgeo/cms-official-go/internal/audit"
 	"github.com/quickgeo/cms-official-go/internal/db"
 	"github.com/quickgeo/cms-official-go/internal/handlers"
+	"github.com/quickgeo/cms-official-go/internal/storage"
+	"github.com/quickgeo/cms-official-go/internal/tenant"
 )
 
 func main() {
 	router := gin.New()
+	if err := handlers.TrustProxies(router); err != nil {
+		fmt.Fprintf(os.Stderr, "could not configure trusted proxies: %v\n", err)
+		os.Exit(1)
+	}
 	router.Use(gin.Recovery())
-	router.Use(cors.Default())
+	router.Use(handlers.RequestID())
+	corsMiddleware, err := handlers.CORS()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not configure CORS: %v\n", err)
+		os.Exit(1)
+	}
+	router.Use(corsMiddleware)
+	router.Use(handlers.SecurityHeaders())
+	router.Use(handlers.LimitBodies())
 
 	dbPath := os.Getenv("CMS_SQLITE_PATH")
 	if dbPath == "" {
@@ -26,19 +41,73 @@ func main() {
 		os.Exit(1)
 	}
 
-	h := handlers.New(database)
-	h.Register(router)
+	if err := db.EnsureSchema(database); err != nil {
+		fmt.Fprintf(os.Stderr, "could not prepare database schema: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := tenant.Enforce(database); err != nil {
+		fmt.Fprintf(os.Stderr, "could not enable organization scoping: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := audit.Record(database); err != nil {
+		fmt.Fprintf(os.Stderr, "could not enable the audit log: %v\n", err)
+		os.Exit(1)
+	}
+
+	if os.Getenv("CMS_DEBUG_QUERIES") == "1" {
+		if err := db.CountQueries(database); err != nil {
+			fmt.Fprintf(os.Stderr, "could not enable query counting: %v\n", err)
+			os.Exit(1)
+		}
+		router.Use(handlers.QueryCountHeader())
+	}
+
+	if err := db.TrackBusy(database); err != nil {
+		fmt.Fprintf(os.Stderr, "could not enable lock metrics: %v\n", err)
+		os.Exit(1)
+	}
+	if err := db.GateWrites(database); err != nil {
+		fmt.Fprintf(os.Stderr, "could not serialize writes: %v\n", err)
+		os.Exit(1)
+	}
+	router.Use(handlers.BusyWrites())
+
+	store, err := storage.FromEnv(filepath.Join(filepath.Dir(dbPath), "media"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not configure file storage: %v\n", err)
+		os.Exit(1)
+	}
+
+	h := handlers.New(database, store)
+	if err := h.Register(router); err != nil {
+		fmt.Fprintf(os.Stderr, "could not register routes: %v\n", err)
+		os.Exit(1)
+	}
 
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"success": true, "message": "go backend is healthy"})
 	})
 
+	serveMetrics, err := handlers.ListenMetrics()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not listen for metrics: %v\n", err)
+		os.Exit(1)
+	}
+	if serveMetrics != nil {
+		go func() {
+			fmt.Fprintf(os.Stderr, "metrics server exited: %v\n", serveMetrics())
+			os.Exit(1)
+		}()
+	}
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	if err := router.Run(":" + port); err != nil {
+	if err := handlers.Server(":"+port, router).ListenAndServe(); err != nil {
 		fmt.Fprintf(os.Stderr, "server exited: %v\n", err)
 		os.Exit(1)
 	}